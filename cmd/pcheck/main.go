@@ -0,0 +1,97 @@
+// The pcheck command performs a static type check of Puppet language files and prints the problems
+// that it finds. It is intended to be run as a CI step.
+//
+// Usage:
+//
+//	pcheck [flags] file...
+//
+// Flags:
+//
+//	--modulepath PATH    the module_path setting used when resolving functions and types
+//	--environment NAME   the environment setting
+//
+// The exit status is 1 when a problem is found or when a file cannot be read or parsed, and 2 when
+// the flags are invalid.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/typecheck"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run checks the files given by the arguments and returns the exit status
+func run(args []string, out, errOut io.Writer) int {
+	fs := flag.NewFlagSet(`pcheck`, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	modulePath := fs.String(`modulepath`, ``, `the module_path setting`)
+	environment := fs.String(`environment`, ``, `the environment setting`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(errOut, `pcheck: at least one file must be given`)
+		return 2
+	}
+
+	defer eval.Puppet.Reset()
+	status := 0
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		if *modulePath != `` {
+			eval.Puppet.Set(`module_path`, types.WrapString(*modulePath))
+		}
+		if *environment != `` {
+			eval.Puppet.Set(`environment`, types.WrapString(*environment))
+		}
+		// Plans are checked too
+		eval.Puppet.Set(`tasks`, types.Boolean_TRUE)
+		for _, file := range fs.Args() {
+			if !check(c, file, out, errOut) {
+				status = 1
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+	return status
+}
+
+// check checks one file and returns true if no problems were found
+func check(c eval.Context, file string, out, errOut io.Writer) (ok bool) {
+	defer func() {
+		if e := recover(); e != nil {
+			err, isErr := e.(error)
+			if !isErr {
+				panic(e)
+			}
+			fmt.Fprintln(errOut, err.Error())
+			ok = false
+		}
+	}()
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(eval.Error(eval.EVAL_UNABLE_TO_READ_FILE, issue.H{`path`: file, `detail`: err.Error()}))
+	}
+	warnings := typecheck.Check(c, c.ParseAndValidate(file, string(content), false))
+	for _, w := range warnings {
+		fmt.Fprintln(out, w.Error())
+	}
+	return len(warnings) == 0
+}
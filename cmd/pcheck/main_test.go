@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runPcheck(t *testing.T, files map[string]string, args ...string) (int, string, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir(``, `pcheck`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, path)
+	}
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	status := run(args, out, errOut)
+	return status, strings.Replace(out.String(), dir+string(filepath.Separator), ``, -1), errOut.String()
+}
+
+func TestRun_clean(t *testing.T) {
+	status, out, _ := runPcheck(t, map[string]string{`ok.pp`: "plan p(String $x) { notice($x) }\n"})
+	if status != 0 || out != `` {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_warnings(t *testing.T) {
+	status, out, _ := runPcheck(t, map[string]string{`bad.pp`: "function f(Integer $x) { $x }\nf('a')\n"})
+	expected := "Call to 'f' can never succeed: parameter 1 expects an Integer value, got String (file: bad.pp, line: 2, column: 1)\n"
+	if status != 1 || out != expected {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_parseError(t *testing.T) {
+	status, _, errOut := runPcheck(t, map[string]string{`bad.pp`: "function f( {\n"})
+	if status != 1 || !strings.Contains(errOut, `bad.pp`) {
+		t.Errorf("unexpected result %d %q", status, errOut)
+	}
+}
+
+func TestRun_noFiles(t *testing.T) {
+	status, _, _ := runPcheck(t, nil)
+	if status != 2 {
+		t.Errorf("unexpected status %d", status)
+	}
+}
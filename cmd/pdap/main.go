@@ -0,0 +1,46 @@
+// The pdap command is a Debug Adapter Protocol server that debugs the evaluation of Puppet language
+// files and plans. It communicates with the editor over stdin and stdout.
+//
+// Usage:
+//
+//	pdap [flags]
+//
+// Flags:
+//
+//	--modulepath PATH    the module_path setting used when resolving functions, types, and plans
+//	--environment NAME   the environment setting
+//
+// The program to evaluate, and optionally the plan to run with its arguments, are given by the
+// arguments of the launch request.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lyraproj/puppet-evaluator/dap"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func main() {
+	modulePath := flag.String(`modulepath`, ``, `the module_path setting`)
+	environment := flag.String(`environment`, ``, `the environment setting`)
+	flag.Parse()
+
+	if *modulePath != `` {
+		eval.Puppet.Set(`module_path`, types.WrapString(*modulePath))
+	}
+	if *environment != `` {
+		eval.Puppet.Set(`environment`, types.WrapString(*environment))
+	}
+	eval.Puppet.Set(`tasks`, types.Boolean_TRUE)
+	if err := dap.NewServer(eval.Puppet.RootContext(), os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,91 @@
+// The pdoc command writes reference documentation for the functions, plans, tasks, and types of a
+// module. Without a module directory, it documents what the loaders find without any modules, i.e.
+// the functions written in Go and the built in types.
+//
+// Usage:
+//
+//	pdoc [flags] [module_dir]
+//
+// Flags:
+//
+//	--format FORMAT      the output format, markdown or json (default markdown)
+//	--output FILE        the file to write the documentation to instead of stdout
+//
+// The exit status is 1 when the module cannot be loaded or the output cannot be written, and 2 when
+// the flags are invalid.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/lyraproj/puppet-evaluator/doc"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run writes the documentation given by the arguments and returns the exit status
+func run(args []string, out, errOut io.Writer) int {
+	fs := flag.NewFlagSet(`pdoc`, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	format := fs.String(`format`, `markdown`, `the output format, markdown or json`)
+	output := fs.String(`output`, ``, `the file to write the documentation to instead of stdout`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != `markdown` && *format != `json` {
+		fmt.Fprintf(errOut, "pdoc: unknown format '%s'\n", *format)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(errOut, `pdoc: at most one module directory can be given`)
+		return 2
+	}
+
+	defer eval.Puppet.Reset()
+	var r *doc.Reference
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		// Plans and tasks are documented too
+		eval.Puppet.Set(`tasks`, types.Boolean_TRUE)
+		if fs.NArg() == 1 {
+			r = doc.Module(c, fs.Arg(0))
+		} else {
+			r = doc.Collect(c, c.Loader(), nil)
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+
+	b := &bytes.Buffer{}
+	if *format == `json` {
+		err = r.WriteJSON(b)
+	} else {
+		err = r.WriteMarkdown(b)
+	}
+	if err == nil {
+		if *output == `` {
+			_, err = out.Write(b.Bytes())
+		} else {
+			err = ioutil.WriteFile(*output, b.Bytes(), 0644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+	return 0
+}
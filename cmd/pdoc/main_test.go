@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeModule(t *testing.T) (string, func()) {
+	t.Helper()
+	tmpDir, err := ioutil.TempDir(``, `pdoc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(tmpDir, `mymod`, `functions`)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, `twice.pp`), []byte("function mymod::twice(Integer $x) >> Integer {\n  $x * 2\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(tmpDir, `mymod`), func() { os.RemoveAll(tmpDir) }
+}
+
+func TestRun_markdown(t *testing.T) {
+	dir, cleanup := writeModule(t)
+	defer cleanup()
+	out := &bytes.Buffer{}
+	if status := run([]string{dir}, out, os.Stderr); status != 0 {
+		t.Fatalf(`unexpected status %d`, status)
+	}
+	if !strings.Contains(out.String(), "mymod::twice(Integer $x) >> Integer\n") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestRun_json(t *testing.T) {
+	dir, cleanup := writeModule(t)
+	defer cleanup()
+	output := filepath.Join(filepath.Dir(dir), `doc.json`)
+	if status := run([]string{`--format`, `json`, `--output`, output, dir}, os.Stdout, os.Stderr); status != 0 {
+		t.Fatalf(`unexpected status %d`, status)
+	}
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r struct {
+		Functions []struct{ Name string }
+	}
+	if err = json.Unmarshal(content, &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Functions) != 1 || r.Functions[0].Name != `mymod::twice` {
+		t.Errorf("unexpected output:\n%s", content)
+	}
+}
+
+func TestRun_goFunctions(t *testing.T) {
+	out := &bytes.Buffer{}
+	if status := run(nil, out, os.Stderr); status != 0 {
+		t.Fatalf(`unexpected status %d`, status)
+	}
+	if !strings.Contains(out.String(), "### `notice`\n\nImplemented in Go.") {
+		t.Errorf(`Go function notice is not documented`)
+	}
+}
+
+func TestRun_badFormat(t *testing.T) {
+	errOut := &bytes.Buffer{}
+	if status := run([]string{`--format`, `html`}, os.Stdout, errOut); status != 2 {
+		t.Errorf(`unexpected status %d`, status)
+	}
+}
@@ -0,0 +1,278 @@
+// The peval command evaluates Puppet language code and prints the result.
+//
+// Usage:
+//
+//	peval [flags] [file]
+//
+// The code is read from the given file, from the -e flag, or from stdin when neither is given or
+// when the file is '-'. Flags:
+//
+//	-e CODE              evaluate the given code
+//	--modulepath PATH    the module_path setting
+//	--environment NAME   the environment setting
+//	--strict LEVEL       the strict setting, one of off, warning, or error
+//	--facts FILE         a JSON or YAML file with a hash that is assigned to $facts
+//	--format FORMAT      pretty, expanded, pretty_expanded, or a String format, e.g. '%#p' or {Integer => '%x'}
+//	--json               print the result as rich data JSON instead
+//	--profile FILE       write a pprof profile of the function calls to the given file
+//	--profile-report     write a report of the function calls to stderr
+//	--coverage FILE      write the code coverage to the given file in the LCOV format
+//	--coverage-html FILE write the code coverage to the given file as HTML
+//
+// The exit status is 1 when the evaluation fails and 2 when the flags are invalid.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/coverage"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/profiler"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"gopkg.in/yaml.v3"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+type options struct {
+	code        string
+	file        string
+	modulePath  string
+	environment string
+	strict      string
+	facts       string
+	format      string
+	json        bool
+	profile     string
+	report      bool
+	lcov        string
+	html        string
+}
+
+// run evaluates the code given by the arguments and returns the exit status
+func run(args []string, in io.Reader, out, errOut io.Writer) int {
+	o := &options{}
+	fs := flag.NewFlagSet(`peval`, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.StringVar(&o.code, `e`, ``, `evaluate the given code`)
+	fs.StringVar(&o.modulePath, `modulepath`, ``, `the module_path setting`)
+	fs.StringVar(&o.environment, `environment`, ``, `the environment setting`)
+	fs.StringVar(&o.strict, `strict`, ``, `the strict setting, one of off, warning, or error`)
+	fs.StringVar(&o.facts, `facts`, ``, `a JSON or YAML file with a hash that is assigned to $facts`)
+	fs.StringVar(&o.format, `format`, ``, `pretty, expanded, pretty_expanded, or a String format`)
+	fs.BoolVar(&o.json, `json`, false, `print the result as rich data JSON`)
+	fs.StringVar(&o.profile, `profile`, ``, `write a pprof profile of the function calls to the given file`)
+	fs.BoolVar(&o.report, `profile-report`, false, `write a report of the function calls to stderr`)
+	fs.StringVar(&o.lcov, `coverage`, ``, `write the code coverage to the given file in the LCOV format`)
+	fs.StringVar(&o.html, `coverage-html`, ``, `write the code coverage to the given file as HTML`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		o.file = fs.Arg(0)
+	default:
+		fmt.Fprintln(errOut, `peval: at most one file can be given`)
+		return 2
+	}
+	if o.code != `` && o.file != `` {
+		fmt.Fprintln(errOut, `peval: -e cannot be combined with a file`)
+		return 2
+	}
+
+	// The settings must be applied before the root context and its loaders are created
+	defer eval.Puppet.Reset()
+	if err := applySettings(o); err != nil {
+		fmt.Fprintf(errOut, "peval: %s\n", err.Error())
+		return 2
+	}
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		name, src := source(o, in)
+		if o.facts != `` {
+			c.Scope().Set(`facts`, readFacts(c, o.facts))
+		}
+		expr := c.ParseAndValidate(name, src, false)
+		c.AddDefinitions(expr)
+		var profile *profiler.Profile
+		if o.profile != `` || o.report {
+			profile = profiler.NewProfile()
+			c.SetProfiler(profile.Profiler())
+		}
+		var cov *coverage.Coverage
+		if o.lcov != `` || o.html != `` {
+			cov = coverage.NewCoverage()
+			c.SetCoverage(cov)
+		}
+		result, err := eval.TopEvaluate(c, expr)
+		if profile != nil {
+			writeProfile(o, profile, errOut)
+		}
+		if cov != nil {
+			writeCoverage(c, o, cov)
+		}
+		if err != nil {
+			return err
+		}
+		write(c, o, result, out)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(errOut, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// applySettings assigns the settings given by the options. An error is returned when a value is
+// not valid for its setting.
+func applySettings(o *options) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf(`%v`, r)
+			}
+		}
+	}()
+	if o.modulePath != `` {
+		eval.Puppet.Set(`module_path`, types.WrapString(o.modulePath))
+	}
+	if o.environment != `` {
+		eval.Puppet.Set(`environment`, types.WrapString(o.environment))
+	}
+	if o.strict != `` {
+		eval.Puppet.Set(`strict`, types.WrapString(o.strict))
+	}
+	return nil
+}
+
+// source returns the name and the content of the code to evaluate
+func source(o *options, in io.Reader) (string, string) {
+	if o.code != `` {
+		return ``, o.code
+	}
+	var (
+		content []byte
+		err     error
+	)
+	if o.file == `` || o.file == `-` {
+		content, err = ioutil.ReadAll(in)
+	} else {
+		content, err = ioutil.ReadFile(o.file)
+	}
+	if err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+	}
+	return o.file, string(content)
+}
+
+// readFacts reads a hash from the given JSON or YAML file. The format is determined by the extension.
+func readFacts(c eval.Context, path string) eval.Value {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+	}
+	var facts eval.Value
+	switch strings.ToLower(filepath.Ext(path)) {
+	case `.yaml`, `.yml`:
+		var data interface{}
+		if err = yaml.Unmarshal(content, &data); err != nil {
+			panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: fmt.Sprintf(`%s: %s`, path, err.Error())}))
+		}
+		facts = eval.Wrap(c, data)
+	default:
+		col := serialization.NewCollector()
+		serialization.JsonToData(path, bytes.NewReader(content), col)
+		facts = col.Value()
+	}
+	if _, ok := facts.(eval.OrderedMap); !ok {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: fmt.Sprintf(`%s: facts must be a hash`, path)}))
+	}
+	return facts
+}
+
+// writeProfile writes the pprof profile and the report requested by the options
+func writeProfile(o *options, profile *profiler.Profile, errOut io.Writer) {
+	if o.report {
+		profile.WriteReport(errOut)
+	}
+	if o.profile != `` {
+		writeFile(o.profile, profile.WritePprof)
+	}
+}
+
+// writeCoverage writes the coverage reports requested by the options
+func writeCoverage(c eval.Context, o *options, cov *coverage.Coverage) {
+	report := cov.Report(c)
+	if o.lcov != `` {
+		writeFile(o.lcov, report.WriteLCOV)
+	}
+	if o.html != `` {
+		writeFile(o.html, report.WriteHTML)
+	}
+}
+
+// writeFile creates the file with the given path and calls write with it
+func writeFile(path string, write func(w io.Writer) error) {
+	f, err := os.Create(path)
+	if err == nil {
+		err = write(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+	}
+}
+
+func write(c eval.Context, o *options, result eval.Value, out io.Writer) {
+	if o.json {
+		serialization.NewSerializer(c, eval.EMPTY_MAP).Convert(result, serialization.NewJsonStreamer(out))
+		io.WriteString(out, "\n")
+		return
+	}
+	var fc eval.FormatContext
+	switch o.format {
+	case ``:
+		fc = eval.DEFAULT_FORMAT_CONTEXT
+	case `pretty`:
+		fc = eval.PRETTY
+	case `expanded`:
+		fc = types.EXPANDED
+	case `pretty_expanded`:
+		fc = eval.PRETTY_EXPANDED
+	default:
+		// The format is given in the Puppet language, e.g. '%#p' or {Integer => '%x'}
+		fv := o.format
+		if strings.HasPrefix(fv, `%`) {
+			fv = `'` + fv + `'`
+		}
+		f, ir := eval.TopEvaluate(c, c.ParseAndValidate(`format`, fv, true))
+		if ir != nil {
+			panic(ir)
+		}
+		var err error
+		if fc, err = eval.NewFormatContext3(result, f); err != nil {
+			panic(err)
+		}
+	}
+	result.ToString(out, fc, nil)
+	io.WriteString(out, "\n")
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runPeval(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	status := run(args, strings.NewReader(stdin), out, errOut)
+	return status, out.String(), errOut.String()
+}
+
+func TestRun_code(t *testing.T) {
+	status, out, _ := runPeval(t, ``, `-e`, `[1, {a => 2}]`)
+	if status != 0 || out != "[1, {'a' => 2}]\n" {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_stdin(t *testing.T) {
+	status, out, _ := runPeval(t, `$x = 3; $x * 2`)
+	if status != 0 || out != "6\n" {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_format(t *testing.T) {
+	status, out, _ := runPeval(t, ``, `--format`, `{Integer => '%x'}`, `-e`, `255`)
+	if status != 0 || out != "ff\n" {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_json(t *testing.T) {
+	status, out, _ := runPeval(t, ``, `--json`, `-e`, `{a => Timestamp('2018-01-01')}`)
+	expected := `{"a":{"__ptype":"Timestamp","__pvalue":"2018-01-01T00:00:00.000000000 UTC"}}` + "\n"
+	if status != 0 || out != expected {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_facts(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `peval`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	facts := filepath.Join(dir, `facts.yaml`)
+	if err = ioutil.WriteFile(facts, []byte("os:\n  family: Debian\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, out, _ := runPeval(t, ``, `--facts`, facts, `-e`, `$facts[os][family]`)
+	if status != 0 || out != "Debian\n" {
+		t.Errorf("unexpected result %d %q", status, out)
+	}
+}
+
+func TestRun_file(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `peval`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pp`)
+	if err = ioutil.WriteFile(file, []byte("\n$x = 1 +\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, _, errOut := runPeval(t, ``, file)
+	if status != 1 || !strings.Contains(errOut, `(file: `+file+`, line: 3, column: 1)`) {
+		t.Errorf("unexpected result %d %q", status, errOut)
+	}
+}
+
+func TestRun_modulePath(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `peval`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	functions := filepath.Join(dir, `mymod`, `functions`)
+	if err = os.MkdirAll(functions, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(functions, `hello.pp`), []byte("function mymod::hello() { 'hello' }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	status, out, errOut := runPeval(t, ``, `--modulepath`, dir, `-e`, `mymod::hello()`)
+	if status != 0 || out != "hello\n" {
+		t.Errorf("unexpected result %d %q %q", status, out, errOut)
+	}
+}
+
+func TestRun_profile(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `peval`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `profile.pb.gz`)
+	status, out, errOut := runPeval(t, ``, `--profile`, file, `--profile-report`, `-e`, `[1, 2].map |$x| { $x * 2 }`)
+	if status != 0 || out != "[2, 4]\n" || !strings.Contains(errOut, `lambda`) || !strings.Contains(errOut, `map`) {
+		t.Errorf("unexpected result %d %q %q", status, out, errOut)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Size() == 0 {
+		t.Errorf(`no profile was written`)
+	}
+}
+
+func TestRun_coverage(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `peval`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `test.pp`)
+	if err = ioutil.WriteFile(file, []byte("if 1 > 2 {\n  'a'\n} else {\n  'b'\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lcov := filepath.Join(dir, `coverage.info`)
+	html := filepath.Join(dir, `coverage.html`)
+	status, out, errOut := runPeval(t, ``, `--coverage`, lcov, `--coverage-html`, html, file)
+	if status != 0 || out != "b\n" {
+		t.Fatalf("unexpected result %d %q %q", status, out, errOut)
+	}
+	content, err := ioutil.ReadFile(lcov)
+	if err != nil || !strings.Contains(string(content), "SF:"+file+"\nBRDA:1,0,0,0\nBRDA:1,0,1,1\n") {
+		t.Errorf("unexpected LCOV output %q", content)
+	}
+	if fi, err := os.Stat(html); err != nil || fi.Size() == 0 {
+		t.Errorf(`no HTML report was written`)
+	}
+}
+
+func TestRun_badFlags(t *testing.T) {
+	if status, _, _ := runPeval(t, ``, `-e`, `1`, `file.pp`); status != 2 {
+		t.Errorf("expected exit status 2, got %d", status)
+	}
+	if status, _, errOut := runPeval(t, ``, `--strict`, `bogus`, `-e`, `1`); status != 2 || !strings.Contains(errOut, `strict`) {
+		t.Errorf("unexpected result %d %q", status, errOut)
+	}
+}
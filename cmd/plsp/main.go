@@ -0,0 +1,42 @@
+// The plsp command is a Language Server Protocol server for the Puppet language. It communicates
+// with the editor using JSON-RPC over stdin and stdout.
+//
+// Usage:
+//
+//	plsp [flags]
+//
+// Flags:
+//
+//	--modulepath PATH    the module_path setting used when resolving functions, types, and plans
+//	--environment NAME   the environment setting
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/lsp"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func main() {
+	modulePath := flag.String(`modulepath`, ``, `the module_path setting`)
+	environment := flag.String(`environment`, ``, `the environment setting`)
+	flag.Parse()
+
+	if *modulePath != `` {
+		eval.Puppet.Set(`module_path`, types.WrapString(*modulePath))
+	}
+	if *environment != `` {
+		eval.Puppet.Set(`environment`, types.WrapString(*environment))
+	}
+	if err := lsp.NewServer(eval.Puppet.RootContext(), os.Stdin, os.Stdout).Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,40 @@
+// The prepl command starts an interactive read-eval-print loop for the Puppet language.
+//
+// Usage:
+//
+//	prepl [flags]
+//
+// Flags:
+//
+//	--modulepath PATH    the module_path setting
+//	--environment NAME   the environment setting
+//
+// Enter :help at the prompt to list the available commands. Input is line buffered. To list the
+// completions of the last word of a line, end the line with a TAB and press Enter.
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/repl"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func main() {
+	modulePath := flag.String(`modulepath`, ``, `the module_path setting`)
+	environment := flag.String(`environment`, ``, `the environment setting`)
+	flag.Parse()
+
+	if *modulePath != `` {
+		eval.Puppet.Set(`module_path`, types.WrapString(*modulePath))
+	}
+	if *environment != `` {
+		eval.Puppet.Set(`environment`, types.WrapString(*environment))
+	}
+	repl.NewRepl(eval.Puppet.RootContext(), os.Stdout).Run(os.Stdin)
+}
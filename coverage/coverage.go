@@ -0,0 +1,281 @@
+// Package coverage records which parts of the Puppet code are evaluated and reports the line and
+// branch coverage of the source files.
+//
+// A Coverage is assigned to an eval.Context using SetCoverage. It then records a hit for each
+// expression that is evaluated using the context, or using a context forked from it. A context
+// without a coverage pays no more than a nil check per evaluated expression.
+//
+// The Report of a coverage holds the files that have been hit together with the files of the
+// functions, plans, and types that the loaders of the given context can find. A line is covered
+// when a statement that starts on the line has been evaluated. A branch is an arm of an if,
+// unless, case, or selector expression. An arm is covered when its body has been evaluated.
+package coverage
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// key identifies an expression independently of the parse that created it
+type key struct {
+	file   string
+	offset int
+	length int
+}
+
+func keyOf(e parser.Expression) key {
+	return key{e.File(), e.ByteOffset(), e.ByteLength()}
+}
+
+// Coverage records the hits of evaluated expressions. It is safe to use a coverage from multiple
+// goroutines.
+type Coverage struct {
+	lock    sync.Mutex
+	hits    map[key]int
+	sources map[string]string
+}
+
+// NewCoverage creates a coverage without hits
+func NewCoverage() *Coverage {
+	return &Coverage{hits: make(map[key]int), sources: make(map[string]string)}
+}
+
+// Hit records a hit for the expression at the given location. Locations that aren't expressions
+// are ignored.
+func (cv *Coverage) Hit(location issue.Location) {
+	e, ok := location.(parser.Expression)
+	if !ok {
+		return
+	}
+	k := keyOf(e)
+	cv.lock.Lock()
+	n, found := cv.hits[k]
+	if !found {
+		if _, found = cv.sources[k.file]; !found {
+			cv.sources[k.file] = e.Locator().String()
+		}
+	}
+	cv.hits[k] = n + 1
+	cv.lock.Unlock()
+}
+
+// A Report holds the coverage of source files
+type Report struct {
+	// Files holds the files sorted by name
+	Files []*File
+}
+
+// A File holds the coverage of one source file
+type File struct {
+	// Name is the path of the file
+	Name string
+
+	// Source is the content of the file
+	Source string
+
+	// Lines holds the lines that have statements, in ascending order
+	Lines []Line
+
+	// Branches holds the arms of the branching expressions in the order they appear in the file
+	Branches []Branch
+}
+
+// A Line is a line that has statements
+type Line struct {
+	// Number is the line number, starting with 1
+	Number int
+
+	// Hits is the highest number of evaluations of a statement that starts on the line
+	Hits int
+}
+
+// A Branch is one arm of an if, unless, case, or selector expression
+type Branch struct {
+	// Line is the line of the branching expression
+	Line int
+
+	// Block is the index of the branching expression among the branching expressions of the file
+	Block int
+
+	// Arm is the index of the arm in the branching expression. The arms of an if or unless
+	// expression are the then part and the else part.
+	Arm int
+
+	// Evaluated is true when the branching expression has been evaluated
+	Evaluated bool
+
+	// Hits is the number of times the arm has been taken
+	Hits int
+}
+
+// LinesHit returns the number of lines that have been hit
+func (f *File) LinesHit() int {
+	n := 0
+	for _, l := range f.Lines {
+		if l.Hits > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// BranchesHit returns the number of branches that have been taken
+func (f *File) BranchesHit() int {
+	n := 0
+	for _, b := range f.Branches {
+		if b.Hits > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Percent returns the percentage of the lines of the file that have been hit
+func (f *File) Percent() float64 {
+	if len(f.Lines) == 0 {
+		return 0
+	}
+	return 100 * float64(f.LinesHit()) / float64(len(f.Lines))
+}
+
+// Report creates a report of the files that have been hit and of the files that define the
+// functions, plans, and types that the loaders of the given context can find. Files that cannot be
+// read or parsed, and code that has been evaluated without a file name, are not reported.
+func (cv *Coverage) Report(c eval.Context) *Report {
+	sources := make(map[string]string)
+	for _, tn := range c.Loader().Discover(c, func(tn eval.TypedName) bool {
+		ns := tn.Namespace()
+		return ns == eval.NsFunction || ns == eval.NsPlan || ns == eval.NsType
+	}) {
+		le := c.Loader().LoadEntry(c, tn)
+		if le == nil || le.Origin() == nil {
+			continue
+		}
+		if file := le.Origin().File(); strings.HasSuffix(file, `.pp`) {
+			sources[file] = ``
+		}
+	}
+
+	cv.lock.Lock()
+	defer cv.lock.Unlock()
+	for file, source := range cv.sources {
+		sources[file] = source
+	}
+	report := &Report{Files: make([]*File, 0, len(sources))}
+	for file, source := range sources {
+		if file == `` {
+			continue
+		}
+		if source == `` {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			source = string(content)
+		}
+		if f := cv.file(file, source); f != nil {
+			report.Files = append(report.Files, f)
+		}
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Name < report.Files[j].Name })
+	return report
+}
+
+// file parses the given source and computes its coverage from the recorded hits. It returns nil if
+// the source cannot be parsed.
+func (cv *Coverage) file(name, source string) *File {
+	program, result := eval.Puppet.NewParser().Parse(name, source)
+	if program == nil || result != nil && result.Error() {
+		return nil
+	}
+	w := &walker{hits: cv.hits, lines: make(map[int]int)}
+	w.visit(program)
+	program.AllContents(nil, func(path []parser.Expression, e parser.Expression) { w.visit(e) })
+
+	f := &File{Name: name, Source: source, Lines: make([]Line, 0, len(w.lines)), Branches: w.branches}
+	for n, hits := range w.lines {
+		f.Lines = append(f.Lines, Line{n, hits})
+	}
+	sort.Slice(f.Lines, func(i, j int) bool { return f.Lines[i].Number < f.Lines[j].Number })
+	return f
+}
+
+// walker collects the statements and the branches of a parsed file
+type walker struct {
+	hits     map[key]int
+	lines    map[int]int
+	branches []Branch
+	blocks   int
+}
+
+func (w *walker) visit(e parser.Expression) {
+	switch e := e.(type) {
+	case *parser.Program:
+		w.body(e.Body())
+	case *parser.FunctionDefinition:
+		w.body(e.Body())
+	case *parser.PlanDefinition:
+		w.body(e.Body())
+	case *parser.LambdaExpression:
+		w.body(e.Body())
+	case *parser.IfExpression:
+		w.body(e.Then())
+		w.body(e.Else())
+		w.branch(e, e.Then(), e.Else())
+	case *parser.UnlessExpression:
+		w.body(e.Then())
+		w.body(e.Else())
+		w.branch(e, e.Then(), e.Else())
+	case *parser.CaseExpression:
+		arms := make([]parser.Expression, len(e.Options()))
+		for i, o := range e.Options() {
+			arms[i] = o.(*parser.CaseOption).Then()
+			w.body(arms[i])
+		}
+		w.branch(e, arms...)
+	case *parser.SelectorExpression:
+		arms := make([]parser.Expression, len(e.Selectors()))
+		for i, s := range e.Selectors() {
+			arms[i] = s.(*parser.SelectorEntry).Value()
+			w.body(arms[i])
+		}
+		w.branch(e, arms...)
+	}
+}
+
+// body adds the statements of the given body
+func (w *walker) body(e parser.Expression) {
+	switch e := e.(type) {
+	case *parser.Nop:
+	case *parser.BlockExpression:
+		for _, s := range e.Statements() {
+			if _, ok := s.(parser.Definition); !ok {
+				w.statement(s)
+			}
+		}
+	default:
+		w.statement(e)
+	}
+}
+
+func (w *walker) statement(e parser.Expression) {
+	line := e.Line()
+	hits := w.hits[keyOf(e)]
+	if prev, ok := w.lines[line]; !ok || hits > prev {
+		w.lines[line] = hits
+	}
+}
+
+func (w *walker) branch(e parser.Expression, arms ...parser.Expression) {
+	evaluated := w.hits[keyOf(e)] > 0
+	for i, arm := range arms {
+		w.branches = append(w.branches, Branch{Line: e.Line(), Block: w.blocks, Arm: i, Evaluated: evaluated, Hits: w.hits[keyOf(arm)]})
+	}
+	w.blocks++
+}
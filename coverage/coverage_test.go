@@ -0,0 +1,150 @@
+package coverage_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/coverage"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const src = `function kind(Integer $x) >> String {
+  if $x > 10 {
+    'big'
+  } elsif $x < 0 {
+    'negative'
+  } else {
+    'small'
+  }
+}
+$r = [1, 20].map |$n| { kind($n) }
+$s = $r[0] ? { 'small' => 1, default => 2 }
+case $s {
+  1: { 'one' }
+  2: { 'two' }
+}
+unless $s == 1 { 'never' }
+`
+
+func evaluate(t *testing.T, c eval.Context, cov *coverage.Coverage) {
+	c.SetCoverage(cov)
+	expr := c.ParseAndValidate(`test.pp`, src, false)
+	c.AddDefinitions(expr)
+	if _, err := eval.TopEvaluate(c, expr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReport_WriteLCOV(t *testing.T) {
+	cov := coverage.NewCoverage()
+	b := bytes.NewBufferString(``)
+	eval.Puppet.Do(func(c eval.Context) {
+		evaluate(t, c, cov)
+		if err := cov.Report(c).WriteLCOV(b); err != nil {
+			t.Fatal(err)
+		}
+	})
+	expected := `TN:
+SF:test.pp
+BRDA:2,0,0,1
+BRDA:2,0,1,1
+BRDA:4,1,0,0
+BRDA:4,1,1,1
+BRDA:11,2,0,1
+BRDA:11,2,1,0
+BRDA:12,3,0,1
+BRDA:12,3,1,0
+BRDA:16,4,0,0
+BRDA:16,4,1,1
+BRF:10
+BRH:6
+DA:2,2
+DA:3,1
+DA:4,1
+DA:5,0
+DA:7,1
+DA:10,2
+DA:11,1
+DA:12,1
+DA:13,1
+DA:14,0
+DA:16,1
+LF:11
+LH:9
+end_of_record
+`
+	if b.String() != expected {
+		t.Errorf("unexpected LCOV output:\n%s", b.String())
+	}
+}
+
+func TestReport_WriteHTML(t *testing.T) {
+	cov := coverage.NewCoverage()
+	b := bytes.NewBufferString(``)
+	eval.Puppet.Do(func(c eval.Context) {
+		evaluate(t, c, cov)
+		if err := cov.Report(c).WriteHTML(b); err != nil {
+			t.Fatal(err)
+		}
+	})
+	html := b.String()
+	for _, s := range []string{
+		`<option value="file0">test.pp (81.8%)</option>`,
+		`<span class="cov0" title="0">    &#39;negative&#39;</span>`,
+		`<span class="partial" title="1">case $s {</span>`,
+		`<span class="cov8" title="2">$r = [1, 20].map |$n| { kind($n) }</span>`,
+		"\n  } else {\n",
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("HTML does not contain %q", s)
+		}
+	}
+}
+
+func TestReport_loaderFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `coverage`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fnDir := filepath.Join(tmpDir, `mymod`, `functions`)
+	if err = os.MkdirAll(fnDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	used := filepath.Join(fnDir, `used.pp`)
+	unused := filepath.Join(fnDir, `unused.pp`)
+	if err = ioutil.WriteFile(used, []byte("function mymod::used() {\n  1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(unused, []byte("function mymod::unused() {\n  1\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eval.Puppet.Reset()
+	eval.Puppet.Set(`module_path`, types.WrapString(tmpDir))
+	defer eval.Puppet.Reset()
+
+	cov := coverage.NewCoverage()
+	var report *coverage.Report
+	eval.Puppet.Do(func(c eval.Context) {
+		c.SetCoverage(cov)
+		if _, err := eval.TopEvaluate(c, c.ParseAndValidate(``, `mymod::used()`, false)); err != nil {
+			t.Fatal(err)
+		}
+		report = cov.Report(c)
+	})
+	hits := map[string]int{}
+	for _, f := range report.Files {
+		hits[f.Name] = f.LinesHit()
+	}
+	if len(hits) != 2 || hits[used] != 1 || hits[unused] != 0 {
+		t.Errorf(`unexpected files %v`, hits)
+	}
+}
@@ -0,0 +1,157 @@
+package coverage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// htmlFile is a file as presented by the HTML template
+type htmlFile struct {
+	Name     string
+	Coverage float64
+	Body     template.HTML
+}
+
+// WriteHTML writes the receiver as an HTML page that presents the source of each file with the
+// covered lines in green, the lines that aren't covered in red, and the covered lines that have
+// branches that were never taken in yellow. The layout follows the one of 'go tool cover -html'.
+func (r *Report) WriteHTML(w io.Writer) error {
+	files := make([]htmlFile, len(r.Files))
+	for i, f := range r.Files {
+		files[i] = htmlFile{Name: f.Name, Coverage: f.Percent(), Body: f.htmlBody()}
+	}
+	return htmlTemplate.Execute(w, files)
+}
+
+// htmlBody returns the escaped source of the receiver with a span around each line that has
+// statements
+func (f *File) htmlBody() template.HTML {
+	hits := make(map[int]int, len(f.Lines))
+	for _, l := range f.Lines {
+		hits[l.Number] = l.Hits
+	}
+	partial := make(map[int]bool)
+	for _, b := range f.Branches {
+		if b.Evaluated && b.Hits == 0 {
+			partial[b.Line] = true
+		}
+	}
+
+	b := bytes.NewBufferString(``)
+	for i, line := range strings.SplitAfter(f.Source, "\n") {
+		n := i + 1
+		text := template.HTMLEscapeString(strings.TrimSuffix(line, "\n"))
+		h, ok := hits[n]
+		switch {
+		case !ok:
+			b.WriteString(text)
+		case h == 0:
+			fmt.Fprintf(b, `<span class="cov0" title="0">%s</span>`, text)
+		case partial[n]:
+			fmt.Fprintf(b, `<span class="partial" title="%d">%s</span>`, h, text)
+		default:
+			fmt.Fprintf(b, `<span class="cov8" title="%d">%s</span>`, h, text)
+		}
+		if strings.HasSuffix(line, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return template.HTML(b.String())
+}
+
+var htmlTemplate = template.Must(template.New(`coverage`).Parse(`<!DOCTYPE html>
+<html>
+	<head>
+		<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+		<title>Puppet Coverage Report</title>
+		<style>
+			body {
+				background: black;
+				color: rgb(80, 80, 80);
+			}
+			body, pre, #legend span {
+				font-family: Menlo, monospace;
+				font-weight: bold;
+			}
+			#topbar {
+				background: black;
+				position: fixed;
+				top: 0; left: 0; right: 0;
+				height: 42px;
+				border-bottom: 1px solid rgb(80, 80, 80);
+			}
+			#content {
+				margin-top: 50px;
+			}
+			#nav, #legend {
+				float: left;
+				margin-left: 10px;
+			}
+			#legend {
+				margin-top: 12px;
+			}
+			#nav {
+				margin-top: 10px;
+			}
+			#legend span {
+				margin: 0 5px;
+			}
+			.cov0 { color: rgb(192, 0, 0) }
+			.partial { color: rgb(230, 200, 60) }
+			.cov8 { color: rgb(44, 212, 149) }
+		</style>
+	</head>
+	<body>
+		<div id="topbar">
+			<div id="nav">
+				<select id="files">
+				{{range $i, $f := .}}
+				<option value="file{{$i}}">{{$f.Name}} ({{printf "%.1f" $f.Coverage}}%)</option>
+				{{end}}
+				</select>
+			</div>
+			<div id="legend">
+				<span>not tracked</span>
+				<span class="cov0">not covered</span>
+				<span class="partial">branch not taken</span>
+				<span class="cov8">covered</span>
+			</div>
+		</div>
+		<div id="content">
+		{{range $i, $f := .}}
+		<pre class="file" id="file{{$i}}" style="display: none">{{$f.Body}}</pre>
+		{{end}}
+		</div>
+	</body>
+	<script>
+	(function() {
+		var files = document.getElementById('files');
+		var visible;
+		files.addEventListener('change', onChange, false);
+		function select(part) {
+			if (visible)
+				visible.style.display = 'none';
+			visible = document.getElementById(part);
+			if (!visible)
+				return;
+			files.value = part;
+			visible.style.display = 'block';
+			location.hash = part;
+		}
+		function onChange() {
+			select(files.value);
+			window.scrollTo(0, 0);
+		}
+		if (location.hash != "") {
+			select(location.hash.substr(1));
+		}
+		if (!visible) {
+			select("file0");
+		}
+	})();
+	</script>
+</html>
+`))
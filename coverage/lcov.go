@@ -0,0 +1,32 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WriteLCOV writes the receiver in the LCOV tracefile format
+func (r *Report) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Files {
+		fmt.Fprintln(bw, `TN:`)
+		fmt.Fprintf(bw, "SF:%s\n", f.Name)
+		for _, b := range f.Branches {
+			taken := `-`
+			if b.Evaluated {
+				taken = fmt.Sprint(b.Hits)
+			}
+			fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", b.Line, b.Block, b.Arm, taken)
+		}
+		fmt.Fprintf(bw, "BRF:%d\n", len(f.Branches))
+		fmt.Fprintf(bw, "BRH:%d\n", f.BranchesHit())
+		for _, l := range f.Lines {
+			fmt.Fprintf(bw, "DA:%d,%d\n", l.Number, l.Hits)
+		}
+		fmt.Fprintf(bw, "LF:%d\n", len(f.Lines))
+		fmt.Fprintf(bw, "LH:%d\n", f.LinesHit())
+		fmt.Fprintln(bw, `end_of_record`)
+	}
+	return bw.Flush()
+}
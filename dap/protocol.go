@@ -0,0 +1,142 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+type (
+	request struct {
+		Seq       int             `json:"seq"`
+		Type      string          `json:"type"`
+		Command   string          `json:"command"`
+		Arguments json.RawMessage `json:"arguments,omitempty"`
+	}
+
+	response struct {
+		Seq        int         `json:"seq"`
+		Type       string      `json:"type"`
+		RequestSeq int         `json:"request_seq"`
+		Success    bool        `json:"success"`
+		Command    string      `json:"command"`
+		Message    string      `json:"message,omitempty"`
+		Body       interface{} `json:"body,omitempty"`
+	}
+
+	event struct {
+		Seq   int         `json:"seq"`
+		Type  string      `json:"type"`
+		Event string      `json:"event"`
+		Body  interface{} `json:"body,omitempty"`
+	}
+
+	Source struct {
+		Name string `json:"name,omitempty"`
+		Path string `json:"path,omitempty"`
+	}
+
+	SourceBreakpoint struct {
+		Line int `json:"line"`
+	}
+
+	Breakpoint struct {
+		Verified bool `json:"verified"`
+		Line     int  `json:"line"`
+	}
+
+	StackFrame struct {
+		ID     int     `json:"id"`
+		Name   string  `json:"name"`
+		Source *Source `json:"source,omitempty"`
+		Line   int     `json:"line"`
+		Column int     `json:"column"`
+	}
+
+	Scope struct {
+		Name               string `json:"name"`
+		VariablesReference int    `json:"variablesReference"`
+		Expensive          bool   `json:"expensive"`
+	}
+
+	Variable struct {
+		Name               string `json:"name"`
+		Value              string `json:"value"`
+		Type               string `json:"type,omitempty"`
+		VariablesReference int    `json:"variablesReference"`
+	}
+
+	Thread struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	LaunchArguments struct {
+		// Program is the path of the file to evaluate
+		Program string `json:"program"`
+
+		// StopOnEntry pauses the evaluation before the first expression is evaluated
+		StopOnEntry bool `json:"stopOnEntry"`
+
+		// Plan is the name of a plan to run once the program has been evaluated
+		Plan string `json:"plan,omitempty"`
+
+		// Arguments are the arguments for the plan
+		Arguments json.RawMessage `json:"arguments,omitempty"`
+	}
+
+	SetBreakpointsArguments struct {
+		Source      Source             `json:"source"`
+		Breakpoints []SourceBreakpoint `json:"breakpoints"`
+	}
+
+	StackTraceArguments struct {
+		ThreadID int `json:"threadId"`
+	}
+
+	ScopesArguments struct {
+		FrameID int `json:"frameId"`
+	}
+
+	VariablesArguments struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+
+	EvaluateArguments struct {
+		Expression string `json:"expression"`
+		FrameID    int    `json:"frameId"`
+	}
+)
+
+// readMessage reads the body of one message framed by a Content-Length header
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get(`Content-Length`))
+	if err != nil {
+		return nil, fmt.Errorf(`invalid Content-Length header: %s`, err.Error())
+	}
+	body := make([]byte, length)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeMessage writes the given message framed by a Content-Length header
+func writeMessage(w io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,580 @@
+// Package dap provides a Debug Adapter Protocol server that debugs the evaluation of a Puppet
+// language file, and optionally of a plan that the file defines or that the loaders can find. The
+// server communicates with the editor over a pair of streams, typically stdin and stdout.
+//
+// The evaluation runs on its own goroutine under the control of a debugger.Debugger. Requests that
+// inspect a paused evaluation, such as stackTrace, variables, and evaluate, are carried out on that
+// goroutine so that the evaluation context is never shared between goroutines.
+package dap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/debugger"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// threadID is the ID of the only thread that the server reports
+const threadID = 1
+
+// Server is a Debug Adapter Protocol server
+type Server struct {
+	c        eval.Context
+	in       *bufio.Reader
+	out      io.Writer
+	debugger *debugger.Debugger
+	launch   *LaunchArguments
+
+	// lock guards seq, out, paused, and stopping
+	lock     sync.Mutex
+	seq      int
+	paused   bool
+	stopping bool
+
+	// entry is true until the evaluation pauses for the first time when it should stop on entry
+	entry bool
+
+	// commands passes inspections and actions to the paused evaluation
+	commands chan command
+
+	// done is closed when the evaluation has ended. It is nil until the evaluation starts.
+	done chan struct{}
+
+	// after is called when the response to the current request has been written
+	after func()
+
+	// refs holds the values that variable references handed out during the current pause refer to.
+	// It is only used by the evaluating goroutine.
+	refs []interface{}
+}
+
+type command struct {
+	inspect func(p *debugger.Pause)
+	action  debugger.Action
+	done    chan struct{}
+}
+
+type handler func(s *Server, args json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	`initialize`:              (*Server).initialize,
+	`launch`:                  (*Server).launchRequest,
+	`setBreakpoints`:          (*Server).setBreakpoints,
+	`setExceptionBreakpoints`: (*Server).setExceptionBreakpoints,
+	`configurationDone`:       (*Server).configurationDone,
+	`threads`:                 (*Server).threads,
+	`stackTrace`:              (*Server).stackTrace,
+	`scopes`:                  (*Server).scopes,
+	`variables`:               (*Server).variables,
+	`evaluate`:                (*Server).evaluateRequest,
+	`continue`:                (*Server).continueRequest,
+	`next`:                    (*Server).next,
+	`stepIn`:                  (*Server).stepIn,
+	`stepOut`:                 (*Server).stepOut,
+	`pause`:                   (*Server).pauseRequest,
+	`disconnect`:              (*Server).disconnect,
+}
+
+var errNotPaused = fmt.Errorf(`the evaluation is not paused`)
+
+// NewServer creates a server that reads requests from in and writes responses and events to out.
+// The evaluation uses a context that is derived from the given context.
+func NewServer(c eval.Context, in io.Reader, out io.Writer) *Server {
+	s := &Server{c: c, in: bufio.NewReader(in), out: out, commands: make(chan command)}
+	s.debugger = debugger.NewDebugger(s.pause)
+	return s
+}
+
+// Run processes requests until a disconnect request is received or the input is exhausted. An
+// evaluation that is still running when Run returns is terminated.
+func (s *Server) Run() error {
+	defer s.stop()
+	for {
+		body, err := readMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		req := &request{}
+		if err = json.Unmarshal(body, req); err != nil {
+			return err
+		}
+		if req.Type != `request` {
+			continue
+		}
+		if err = s.dispatch(req); err != nil {
+			return err
+		}
+		if req.Command == `disconnect` {
+			return nil
+		}
+	}
+}
+
+func (s *Server) dispatch(req *request) error {
+	s.after = nil
+	var (
+		body interface{}
+		err  error
+	)
+	if h, ok := handlers[req.Command]; ok {
+		body, err = h(s, req.Arguments)
+	} else {
+		err = fmt.Errorf(`command '%s' is not supported`, req.Command)
+	}
+	rsp := &response{Type: `response`, RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		rsp.Message = err.Error()
+	}
+	if err = s.write(func(seq int) interface{} { rsp.Seq = seq; return rsp }); err != nil {
+		return err
+	}
+	if s.after != nil {
+		s.after()
+	}
+	return nil
+}
+
+// write assigns the next sequence number to the message produced by f and writes it. It is safe
+// to call this method from any goroutine.
+func (s *Server) write(f func(seq int) interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.seq++
+	return writeMessage(s.out, f(s.seq))
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.write(func(seq int) interface{} { return &event{Seq: seq, Type: `event`, Event: name, Body: body} })
+}
+
+func (s *Server) output(category, text string) {
+	s.event(`output`, map[string]interface{}{`category`: category, `output`: text})
+}
+
+func (s *Server) initialize(args json.RawMessage) (interface{}, error) {
+	s.after = func() { s.event(`initialized`, nil) }
+	return map[string]interface{}{
+		`supportsConfigurationDoneRequest`: true,
+		`supportsEvaluateForHovers`:        true,
+	}, nil
+}
+
+func (s *Server) launchRequest(args json.RawMessage) (interface{}, error) {
+	a := &LaunchArguments{}
+	if err := json.Unmarshal(args, a); err != nil {
+		return nil, err
+	}
+	if a.Program == `` {
+		return nil, fmt.Errorf(`launch requires a program`)
+	}
+	program, err := filepath.Abs(a.Program)
+	if err != nil {
+		return nil, err
+	}
+	a.Program = program
+	s.launch = a
+	return nil, nil
+}
+
+func (s *Server) setBreakpoints(args json.RawMessage) (interface{}, error) {
+	a := &SetBreakpointsArguments{}
+	if err := json.Unmarshal(args, a); err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(a.Source.Path)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]int, len(a.Breakpoints))
+	bps := make([]Breakpoint, len(a.Breakpoints))
+	for i, bp := range a.Breakpoints {
+		lines[i] = bp.Line
+		bps[i] = Breakpoint{Verified: true, Line: bp.Line}
+	}
+	s.debugger.SetBreakpoints(path, lines...)
+	return map[string]interface{}{`breakpoints`: bps}, nil
+}
+
+func (s *Server) setExceptionBreakpoints(args json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) configurationDone(args json.RawMessage) (interface{}, error) {
+	if s.launch == nil {
+		return nil, fmt.Errorf(`configurationDone received before launch`)
+	}
+	if s.done != nil {
+		return nil, fmt.Errorf(`the evaluation has already started`)
+	}
+	s.after = s.start
+	return nil, nil
+}
+
+func (s *Server) threads(args json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{`threads`: []Thread{{threadID, `main`}}}, nil
+}
+
+func (s *Server) stackTrace(args json.RawMessage) (interface{}, error) {
+	var frames []StackFrame
+	err := s.inspect(func(p *debugger.Pause) {
+		for i, f := range p.Frames() {
+			frame := StackFrame{ID: i + 1, Name: f.Name}
+			if loc := f.Location; loc != nil {
+				if file := loc.File(); file != `` {
+					frame.Source = &Source{Name: filepath.Base(file), Path: file}
+				}
+				frame.Line = loc.Line()
+				frame.Column = loc.Pos()
+			}
+			frames = append(frames, frame)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`stackFrames`: frames, `totalFrames`: len(frames)}, nil
+}
+
+// scopes returns the local scope of the innermost frame. The scopes of the calling frames are not
+// retained by the evaluator so they cannot be inspected.
+func (s *Server) scopes(args json.RawMessage) (interface{}, error) {
+	a := &ScopesArguments{}
+	if err := json.Unmarshal(args, a); err != nil {
+		return nil, err
+	}
+	scopes := []Scope{}
+	err := s.inspect(func(p *debugger.Pause) {
+		if a.FrameID == 1 {
+			scopes = append(scopes, Scope{Name: `Locals`, VariablesReference: s.reference(p.Scope())})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`scopes`: scopes}, nil
+}
+
+func (s *Server) variables(args json.RawMessage) (interface{}, error) {
+	a := &VariablesArguments{}
+	if err := json.Unmarshal(args, a); err != nil {
+		return nil, err
+	}
+	vars := []Variable{}
+	err := s.inspect(func(p *debugger.Pause) {
+		if a.VariablesReference < 1 || a.VariablesReference > len(s.refs) {
+			return
+		}
+		switch container := s.refs[a.VariablesReference-1].(type) {
+		case eval.Scope:
+			for _, name := range container.VariableNames() {
+				if v, ok := container.Get(name); ok {
+					vars = append(vars, s.variable(name, v))
+				}
+			}
+		case eval.OrderedMap:
+			container.EachPair(func(k, v eval.Value) {
+				vars = append(vars, s.variable(display(k), v))
+			})
+		case eval.List:
+			container.EachWithIndex(func(v eval.Value, i int) {
+				vars = append(vars, s.variable(fmt.Sprintf(`[%d]`, i), v))
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`variables`: vars}, nil
+}
+
+func (s *Server) evaluateRequest(args json.RawMessage) (interface{}, error) {
+	a := &EvaluateArguments{}
+	if err := json.Unmarshal(args, a); err != nil {
+		return nil, err
+	}
+	var (
+		body map[string]interface{}
+		err  error
+	)
+	if ierr := s.inspect(func(p *debugger.Pause) {
+		var v eval.Value
+		if v, err = p.Evaluate(a.Expression); err == nil {
+			vr := s.variable(``, v)
+			body = map[string]interface{}{`result`: vr.Value, `type`: vr.Type, `variablesReference`: vr.VariablesReference}
+		}
+	}); ierr != nil {
+		return nil, ierr
+	}
+	return body, err
+}
+
+func (s *Server) continueRequest(args json.RawMessage) (interface{}, error) {
+	if err := s.resume(debugger.Continue); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{`allThreadsContinued`: true}, nil
+}
+
+func (s *Server) next(args json.RawMessage) (interface{}, error) {
+	return nil, s.resume(debugger.StepOver)
+}
+
+func (s *Server) stepIn(args json.RawMessage) (interface{}, error) {
+	return nil, s.resume(debugger.StepIn)
+}
+
+func (s *Server) stepOut(args json.RawMessage) (interface{}, error) {
+	return nil, s.resume(debugger.StepOut)
+}
+
+func (s *Server) pauseRequest(args json.RawMessage) (interface{}, error) {
+	if s.done == nil {
+		return nil, fmt.Errorf(`the evaluation has not started`)
+	}
+	s.debugger.RequestPause()
+	return nil, nil
+}
+
+func (s *Server) disconnect(args json.RawMessage) (interface{}, error) {
+	s.after = s.stop
+	return nil, nil
+}
+
+// start starts the evaluation on a new goroutine
+func (s *Server) start() {
+	s.done = make(chan struct{})
+	if s.launch.StopOnEntry {
+		s.entry = true
+		s.debugger.RequestPause()
+	}
+	go s.run()
+}
+
+// stop terminates the evaluation if it is running and waits for it to end
+func (s *Server) stop() {
+	if s.done == nil {
+		return
+	}
+	s.debugger.Terminate()
+	s.lock.Lock()
+	paused := s.paused
+	s.paused = false
+	s.stopping = true
+	s.lock.Unlock()
+	if paused {
+		s.commands <- command{action: debugger.Terminate}
+	}
+	<-s.done
+}
+
+// resume makes the paused evaluation resume with the given action once the response has been written
+func (s *Server) resume(action debugger.Action) error {
+	s.lock.Lock()
+	paused := s.paused
+	s.paused = false
+	s.lock.Unlock()
+	if !paused {
+		return errNotPaused
+	}
+	s.after = func() { s.commands <- command{action: action} }
+	return nil
+}
+
+// inspect calls f with the current pause on the evaluating goroutine and waits for it to return
+func (s *Server) inspect(f func(p *debugger.Pause)) error {
+	s.lock.Lock()
+	paused := s.paused
+	s.lock.Unlock()
+	if !paused {
+		return errNotPaused
+	}
+	done := make(chan struct{})
+	s.commands <- command{inspect: f, done: done}
+	<-done
+	return nil
+}
+
+// pause is the debugger.Handler of the server. It reports the pause and then carries out the
+// inspections that it receives until it receives an action.
+func (s *Server) pause(p *debugger.Pause) debugger.Action {
+	s.lock.Lock()
+	if s.stopping {
+		s.lock.Unlock()
+		return debugger.Terminate
+	}
+	s.paused = true
+	s.lock.Unlock()
+
+	reason := p.Reason()
+	if s.entry {
+		reason = `entry`
+		s.entry = false
+	}
+	defer func() { s.refs = nil }()
+	s.event(`stopped`, map[string]interface{}{`reason`: reason, `threadId`: threadID, `allThreadsStopped`: true})
+	for cmd := range s.commands {
+		if cmd.inspect == nil {
+			return cmd.action
+		}
+		func() {
+			defer close(cmd.done)
+			cmd.inspect(p)
+		}()
+	}
+	return debugger.Terminate
+}
+
+// run evaluates the program, runs the plan and outputs its result, and closes the done channel
+func (s *Server) run() {
+	exitCode := 0
+	defer func() {
+		s.event(`exited`, map[string]interface{}{`exitCode`: exitCode})
+		s.event(`terminated`, nil)
+		close(s.done)
+	}()
+
+	c := impl.WithParent(s.c, impl.NewEvaluator, eval.NewParentedLoader(s.c.Loader()), &outputLogger{s}, s.c.ImplementationRegistry())
+	c.SetDebugger(s.debugger)
+	eval.DoWithContext(c, func(c eval.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				exitCode = 1
+				if err, ok := r.(error); ok {
+					s.output(`stderr`, err.Error()+"\n")
+				} else {
+					s.output(`stderr`, fmt.Sprintln(r))
+				}
+			}
+		}()
+		content, err := ioutil.ReadFile(s.launch.Program)
+		if err != nil {
+			panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+		}
+		expr := c.ParseAndValidate(s.launch.Program, string(content), false)
+		c.AddDefinitions(expr)
+		if _, ir := eval.TopEvaluate(c, expr); ir != nil {
+			panic(ir)
+		}
+		if s.launch.Plan != `` {
+			s.output(`console`, display(s.runPlan(c))+"\n")
+		}
+	})
+}
+
+// runPlan calls the plan named in the launch arguments. The arguments are given as a JSON object
+// that is keyed by parameter name. A parameter without an argument gets its default value.
+func (s *Server) runPlan(c eval.Context) eval.Value {
+	f, ok := eval.Load(c, eval.NewTypedName(eval.NsPlan, s.launch.Plan))
+	if !ok {
+		panic(eval.Error(eval.EVAL_UNKNOWN_PLAN, issue.H{`name`: s.launch.Plan}))
+	}
+	plan := f.(eval.Function)
+	named := eval.EMPTY_MAP
+	if len(s.launch.Arguments) > 0 {
+		col := serialization.NewCollector()
+		serialization.JsonToData(`arguments`, bytes.NewReader(s.launch.Arguments), col)
+		if named, ok = col.Value().(eval.OrderedMap); !ok {
+			panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: `plan arguments must be a JSON object`}))
+		}
+	}
+
+	var args []eval.Value
+	params := plan.Dispatchers()[0].Parameters()
+	for i, p := range params {
+		if v, ok := named.Get4(p.Name()); ok {
+			for len(args) < i {
+				args = append(args, defaultValue(c, params[len(args)]))
+			}
+			args = append(args, v)
+		}
+	}
+	return plan.Call(c, nil, args...)
+}
+
+func defaultValue(c eval.Context, p eval.Parameter) eval.Value {
+	if !p.HasValue() {
+		return eval.UNDEF
+	}
+	d := p.Value()
+	if df, ok := d.(types.Deferred); ok {
+		d = df.Resolve(c)
+	}
+	return d
+}
+
+// reference returns a variables reference for the given container
+func (s *Server) reference(container interface{}) int {
+	s.refs = append(s.refs, container)
+	return len(s.refs)
+}
+
+// variable creates a Variable for the given value. Hashes and arrays that have elements get a
+// reference so that their elements can be expanded.
+func (s *Server) variable(name string, v eval.Value) Variable {
+	vr := Variable{Name: name, Value: display(v), Type: v.PType().Name()}
+	switch v := v.(type) {
+	case eval.OrderedMap:
+		if v.Len() > 0 {
+			vr.VariablesReference = s.reference(v)
+		}
+	case eval.List:
+		if v.Len() > 0 {
+			vr.VariablesReference = s.reference(v)
+		}
+	}
+	return vr
+}
+
+// displayFormat presents values the way they are written in the Puppet language, e.g. with quoted strings
+var displayFormat = eval.NewFormatContext(types.DefaultAnyType(), types.DEFAULT_PROGRAM_FORMAT, types.DEFAULT_INDENTATION)
+
+func display(v eval.Value) string {
+	return eval.ToString2(v, displayFormat)
+}
+
+// outputLogger is an eval.Logger that sends the log entries to the client as output events since
+// the streams of the process are used by the protocol
+type outputLogger struct {
+	s *Server
+}
+
+func (l *outputLogger) Log(level eval.LogLevel, args ...eval.Value) {
+	b := bytes.NewBufferString(``)
+	fmt.Fprintf(b, `%s: `, level)
+	for _, arg := range args {
+		eval.ToString3(arg, b)
+	}
+	b.WriteByte('\n')
+	l.s.output(category(level), b.String())
+}
+
+func (l *outputLogger) Logf(level eval.LogLevel, format string, args ...interface{}) {
+	l.s.output(category(level), fmt.Sprintf(`%s: `, level)+fmt.Sprintf(format, args...)+"\n")
+}
+
+func (l *outputLogger) LogIssue(i issue.Reported) {
+	l.s.output(`stderr`, i.String()+"\n")
+}
+
+func category(level eval.LogLevel) string {
+	switch level {
+	case eval.DEBUG, eval.INFO, eval.NOTICE:
+		return `stdout`
+	default:
+		return `stderr`
+	}
+}
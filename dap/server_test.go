@@ -0,0 +1,240 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/dap"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const src = `function add(Integer $a, Integer $b) {
+  $sum = $a + $b
+  $sum
+}
+$x = 1
+$y = add($x, 2)
+notice($y)
+`
+
+// client drives a server that runs on its own goroutine
+type client struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	msgs   chan map[string]interface{}
+	seq    int
+	output string
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	cl := &client{t: t, in: inW, msgs: make(chan map[string]interface{}, 100), done: make(chan error, 1)}
+	go func() {
+		err := dap.NewServer(eval.Puppet.RootContext(), inR, outW).Run()
+		outW.Close()
+		cl.done <- err
+	}()
+	go readMessages(bufio.NewReader(outR), cl.msgs)
+	return cl
+}
+
+// readMessages reads the messages written by the server so that the server never blocks
+func readMessages(r *bufio.Reader, msgs chan map[string]interface{}) {
+	defer close(msgs)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(header.Get(`Content-Length`))
+		body := make([]byte, n)
+		if _, err = io.ReadFull(r, body); err != nil {
+			return
+		}
+		m := map[string]interface{}{}
+		if json.Unmarshal(body, &m) == nil {
+			msgs <- m
+		}
+	}
+}
+
+// request sends a request and returns the body of its response. Events that arrive before the
+// response are discarded except for output events, which are collected.
+func (cl *client) request(command string, args interface{}) map[string]interface{} {
+	cl.t.Helper()
+	cl.seq++
+	body, _ := json.Marshal(map[string]interface{}{`seq`: cl.seq, `type`: `request`, `command`: command, `arguments`: args})
+	fmt.Fprintf(cl.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	for {
+		m := cl.read()
+		if m[`type`] == `response` {
+			if m[`success`] != true {
+				cl.t.Fatalf(`%s failed: %v`, command, m[`message`])
+			}
+			b, _ := m[`body`].(map[string]interface{})
+			return b
+		}
+		cl.collect(m)
+	}
+}
+
+// expect reads messages until the given event arrives and returns its body
+func (cl *client) expect(name string) map[string]interface{} {
+	cl.t.Helper()
+	for {
+		m := cl.read()
+		if m[`type`] == `event` && m[`event`] == name {
+			b, _ := m[`body`].(map[string]interface{})
+			return b
+		}
+		cl.collect(m)
+	}
+}
+
+func (cl *client) collect(m map[string]interface{}) {
+	if m[`event`] == `output` {
+		cl.output += m[`body`].(map[string]interface{})[`output`].(string)
+	}
+}
+
+func (cl *client) read() map[string]interface{} {
+	cl.t.Helper()
+	m, ok := <-cl.msgs
+	if !ok {
+		cl.t.Fatal(`the server closed the connection`)
+	}
+	return m
+}
+
+// stopped waits for a stopped event with the given reason and returns the innermost frame
+func (cl *client) stopped(reason string) map[string]interface{} {
+	cl.t.Helper()
+	if b := cl.expect(`stopped`); b[`reason`] != reason {
+		cl.t.Fatalf(`expected a stop caused by %s, got %v`, reason, b[`reason`])
+	}
+	frames := cl.request(`stackTrace`, map[string]interface{}{`threadId`: 1})[`stackFrames`].([]interface{})
+	return frames[0].(map[string]interface{})
+}
+
+func writeProgram(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir(``, `dap`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, `test.pp`)
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestServer_session(t *testing.T) {
+	path, cleanup := writeProgram(t, src)
+	defer cleanup()
+
+	cl := newClient(t)
+	cl.request(`initialize`, map[string]interface{}{`adapterID`: `puppet`})
+	cl.expect(`initialized`)
+	cl.request(`launch`, map[string]interface{}{`program`: path})
+	bps := cl.request(`setBreakpoints`, map[string]interface{}{
+		`source`: map[string]interface{}{`path`: path}, `breakpoints`: []interface{}{map[string]interface{}{`line`: 6}}})
+	if n := len(bps[`breakpoints`].([]interface{})); n != 1 {
+		t.Fatalf(`expected one breakpoint, got %d`, n)
+	}
+	cl.request(`configurationDone`, nil)
+
+	frame := cl.stopped(`breakpoint`)
+	if frame[`name`] != `main` || frame[`line`] != 6.0 || frame[`source`].(map[string]interface{})[`path`] != path {
+		t.Fatalf(`unexpected frame %v`, frame)
+	}
+
+	cl.request(`stepIn`, nil)
+	if frame = cl.stopped(`step`); frame[`name`] != `add` || frame[`line`] != 2.0 {
+		t.Fatalf(`unexpected frame %v`, frame)
+	}
+	scopes := cl.request(`scopes`, map[string]interface{}{`frameId`: frame[`id`]})[`scopes`].([]interface{})
+	ref := scopes[0].(map[string]interface{})[`variablesReference`]
+	vars := cl.request(`variables`, map[string]interface{}{`variablesReference`: ref})[`variables`].([]interface{})
+	if s := fmt.Sprint(vars); s != `[map[name:a type:Integer value:1 variablesReference:0] map[name:b type:Integer value:2 variablesReference:0] map[name:x type:Integer value:1 variablesReference:0]]` {
+		t.Errorf(`unexpected variables %s`, s)
+	}
+	result := cl.request(`evaluate`, map[string]interface{}{`expression`: `[$a, $b * 10]`, `frameId`: frame[`id`]})
+	if result[`result`] != `[1, 20]` {
+		t.Errorf(`unexpected result %v`, result)
+	}
+	elems := cl.request(`variables`, map[string]interface{}{`variablesReference`: result[`variablesReference`]})[`variables`].([]interface{})
+	if len(elems) != 2 || elems[1].(map[string]interface{})[`value`] != `20` {
+		t.Errorf(`unexpected elements %v`, elems)
+	}
+
+	cl.request(`stepOut`, nil)
+	if frame = cl.stopped(`step`); frame[`name`] != `main` || frame[`line`] != 7.0 {
+		t.Fatalf(`unexpected frame %v`, frame)
+	}
+
+	cl.request(`continue`, nil)
+	if b := cl.expect(`exited`); b[`exitCode`] != 0.0 {
+		t.Errorf(`unexpected exit %v`, b)
+	}
+	cl.expect(`terminated`)
+	if cl.output != "notice: 3\n" {
+		t.Errorf(`unexpected output %q`, cl.output)
+	}
+	cl.request(`disconnect`, nil)
+	if err := <-cl.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_stopOnEntryAndDisconnect(t *testing.T) {
+	path, cleanup := writeProgram(t, src)
+	defer cleanup()
+
+	cl := newClient(t)
+	cl.request(`initialize`, nil)
+	cl.request(`launch`, map[string]interface{}{`program`: path, `stopOnEntry`: true})
+	cl.request(`configurationDone`, nil)
+	if frame := cl.stopped(`entry`); frame[`line`] != 1.0 {
+		t.Fatalf(`unexpected frame %v`, frame)
+	}
+	cl.request(`disconnect`, nil)
+	if err := <-cl.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServer_plan(t *testing.T) {
+	path, cleanup := writeProgram(t, "plan greet(String $greeting = 'hello', String $name) {\n  \"${greeting} ${name}\"\n}\n")
+	defer cleanup()
+
+	eval.Puppet.Set(`tasks`, types.Boolean_TRUE)
+	defer eval.Puppet.Reset()
+	cl := newClient(t)
+	cl.request(`initialize`, nil)
+	cl.request(`launch`, map[string]interface{}{`program`: path, `plan`: `greet`, `arguments`: map[string]interface{}{`name`: `world`}})
+	cl.request(`setBreakpoints`, map[string]interface{}{
+		`source`: map[string]interface{}{`path`: path}, `breakpoints`: []interface{}{map[string]interface{}{`line`: 2}}})
+	cl.request(`configurationDone`, nil)
+	cl.stopped(`breakpoint`)
+	cl.request(`continue`, nil)
+	cl.expect(`terminated`)
+	if cl.output != "'hello world'\n" {
+		t.Errorf(`unexpected output %q`, cl.output)
+	}
+	cl.request(`disconnect`, nil)
+	<-cl.done
+}
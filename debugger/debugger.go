@@ -0,0 +1,198 @@
+// Package debugger provides a step debugger for the evaluator.
+//
+// A Debugger is assigned to an eval.Context using SetDebugger. It pauses the evaluation when a
+// breakpoint is reached, when a step completes, or when a pause has been requested. A paused
+// evaluation calls the Handler of the debugger on the evaluating goroutine and resumes when the
+// handler returns. The handler inspects the paused evaluation using the given Pause and decides
+// how the evaluation resumes by returning an Action.
+//
+// Steps are line based. A step completes when the evaluation reaches a new line in a frame. Frames
+// are created by function calls, so stepping works the same way across Puppet functions, plans, and
+// the lambdas that they pass to other functions.
+package debugger
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// An Action determines how a paused evaluation resumes
+type Action int
+
+const (
+	// Continue resumes the evaluation until a breakpoint is reached or a pause is requested
+	Continue = Action(iota)
+
+	// StepIn resumes the evaluation until a new line is reached in any frame
+	StepIn
+
+	// StepOver resumes the evaluation until a new line is reached in the current frame or in one
+	// of its callers
+	StepOver
+
+	// StepOut resumes the evaluation until a new line is reached in one of the callers of the
+	// current frame
+	StepOut
+
+	// Terminate aborts the evaluation
+	Terminate
+)
+
+// Reasons for a pause
+const (
+	REASON_BREAKPOINT = `breakpoint`
+	REASON_PAUSE      = `pause`
+	REASON_STEP       = `step`
+)
+
+// A Handler is called when the evaluation pauses. The evaluation resumes according to the returned
+// Action when the handler returns.
+type Handler func(p *Pause) Action
+
+// position is the line that is evaluated in a frame together with the location that started the
+// evaluation of that line
+type position struct {
+	file  string
+	line  int
+	start issue.Location
+}
+
+// Debugger is an eval.Debugger that supports breakpoints and stepping
+type Debugger struct {
+	handler Handler
+	lock    sync.Mutex
+
+	breakpoints map[string]map[int]bool
+
+	// action is the Action returned by the handler after the last pause
+	action Action
+
+	// depth is the stack depth at the last pause
+	depth int
+
+	// lines holds the position of the last step for each stack depth
+	lines []position
+
+	pauseRequested bool
+	terminate      bool
+
+	// paused is true while the handler is called. Expressions evaluated by the handler are not
+	// subject to debugging
+	paused bool
+}
+
+// NewDebugger creates a Debugger that calls the given handler each time the evaluation pauses
+func NewDebugger(handler Handler) *Debugger {
+	return &Debugger{handler: handler, breakpoints: make(map[string]map[int]bool)}
+}
+
+// SetBreakpoints replaces the breakpoints of the given file with breakpoints at the given lines.
+// The file must be given exactly as it is given when the file is parsed.
+func (d *Debugger) SetBreakpoints(file string, lines ...int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(lines) == 0 {
+		delete(d.breakpoints, file)
+		return
+	}
+	bps := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		bps[line] = true
+	}
+	d.breakpoints[file] = bps
+}
+
+// Breakpoints returns the sorted lines of the breakpoints of the given file
+func (d *Debugger) Breakpoints(file string) []int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	lines := make([]int, 0, len(d.breakpoints[file]))
+	for line := range d.breakpoints[file] {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// RequestPause makes the evaluation pause before the next expression is evaluated. It is safe to
+// call this method from any goroutine.
+func (d *Debugger) RequestPause() {
+	d.lock.Lock()
+	d.pauseRequested = true
+	d.lock.Unlock()
+}
+
+// Terminate makes the evaluation abort before the next expression is evaluated. It is safe to call
+// this method from any goroutine.
+func (d *Debugger) Terminate() {
+	d.lock.Lock()
+	d.terminate = true
+	d.lock.Unlock()
+}
+
+// BeforeStep is called by the context before the expression at the given location is evaluated.
+// It calls the handler of the receiver when the evaluation should pause.
+func (d *Debugger) BeforeStep(c eval.Context, location issue.Location) {
+	d.lock.Lock()
+	if d.paused {
+		d.lock.Unlock()
+		return
+	}
+	if d.terminate {
+		d.lock.Unlock()
+		panic(c.Fail(`evaluation terminated by the debugger`))
+	}
+
+	// Track the line of each frame. A frame that is left forgets its line so that it starts anew
+	// when it is entered again. A line is also new when the location that started it is evaluated
+	// again, which happens when a lambda is called repeatedly.
+	depth := len(c.Stack())
+	if depth+1 < len(d.lines) {
+		d.lines = d.lines[:depth+1]
+	}
+	for len(d.lines) <= depth {
+		d.lines = append(d.lines, position{})
+	}
+	pos := &d.lines[depth]
+	newLine := false
+	if pos.file != location.File() || pos.line != location.Line() || pos.start == location {
+		*pos = position{location.File(), location.Line(), location}
+		newLine = pos.line > 0
+	}
+
+	reason := ``
+	switch {
+	case d.pauseRequested:
+		reason = REASON_PAUSE
+	case !newLine:
+	case d.action == StepIn, d.action == StepOver && depth <= d.depth, d.action == StepOut && depth < d.depth:
+		reason = REASON_STEP
+	case d.breakpoints[pos.file][pos.line]:
+		reason = REASON_BREAKPOINT
+	}
+	if reason == `` {
+		d.lock.Unlock()
+		return
+	}
+	d.pauseRequested = false
+	d.paused = true
+	d.lock.Unlock()
+
+	action := Terminate
+	func() {
+		defer func() {
+			d.lock.Lock()
+			d.paused = false
+			d.action = action
+			d.depth = depth
+			d.lock.Unlock()
+		}()
+		action = d.handler(&Pause{reason: reason, location: location, c: c})
+	}()
+	if action == Terminate {
+		panic(c.Fail(`evaluation terminated by the debugger`))
+	}
+}
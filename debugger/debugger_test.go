@@ -0,0 +1,101 @@
+package debugger_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/debugger"
+	"github.com/lyraproj/puppet-evaluator/eval"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const src = `function add(Integer $a, Integer $b) {
+  $sum = $a + $b
+  $sum
+}
+$x = 1
+$y = add($x, 2)
+[1, 2].each |$i| {
+  notice($i)
+}
+notice($y)
+`
+
+func evaluate(c eval.Context, d *debugger.Debugger) error {
+	c.SetDebugger(d)
+	expr := c.ParseAndValidate(`test.pp`, src, false)
+	c.AddDefinitions(expr)
+	_, err := eval.TopEvaluate(c, expr)
+	return err
+}
+
+func ExampleDebugger() {
+	actions := []debugger.Action{debugger.StepIn, debugger.StepIn, debugger.StepOut, debugger.StepOver, debugger.StepIn, debugger.Continue}
+	eval.Puppet.Do(func(c eval.Context) {
+		d := debugger.NewDebugger(func(p *debugger.Pause) debugger.Action {
+			fmt.Printf("%s at line %d:", p.Reason(), p.Location().Line())
+			for _, f := range p.Frames() {
+				fmt.Printf(" %s:%d", f.Name, f.Location.Line())
+			}
+			fmt.Println(` vars`, p.Scope().VariableNames())
+			action := actions[0]
+			actions = actions[1:]
+			return action
+		})
+		d.SetBreakpoints(`test.pp`, 6, 8)
+		if err := evaluate(c, d); err != nil {
+			fmt.Println(err)
+		}
+	})
+	// Output:
+	// breakpoint at line 6: main:6 vars [x]
+	// step at line 2: add:2 main:6 vars [a b x]
+	// step at line 3: add:3 main:6 vars [a b sum x]
+	// step at line 7: main:7 vars [x y]
+	// breakpoint at line 8: each:8 main:7 vars [i x y]
+	// notice: 1
+	// step at line 8: each:8 main:7 vars [i x y]
+	// notice: 2
+	// notice: 3
+}
+
+func TestPause_Evaluate(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		var result string
+		d := debugger.NewDebugger(func(p *debugger.Pause) debugger.Action {
+			v, err := p.Evaluate(`$a * $b`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = v.String()
+			if _, err = p.Evaluate(`$a +`); err == nil {
+				t.Error(`expected a syntax error`)
+			}
+			return debugger.Continue
+		})
+		d.SetBreakpoints(`test.pp`, 2)
+		if err := evaluate(c, d); err != nil {
+			t.Fatal(err)
+		}
+		if result != `2` {
+			t.Errorf(`expected 2, got %s`, result)
+		}
+	})
+}
+
+func TestDebugger_Terminate(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		d := debugger.NewDebugger(func(p *debugger.Pause) debugger.Action {
+			return debugger.Terminate
+		})
+		d.SetBreakpoints(`test.pp`, 3)
+		if err := evaluate(c, d); err == nil {
+			t.Error(`expected the evaluation to be terminated`)
+		}
+		if lines := d.Breakpoints(`test.pp`); len(lines) != 1 || lines[0] != 3 {
+			t.Errorf(`unexpected breakpoints %v`, lines)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package debugger
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// A Frame is an active function call, or the evaluation of the program itself
+type Frame struct {
+	// Name is the name of the called function, or "main" for the program
+	Name string
+
+	// Location is the location in the frame that is currently evaluated
+	Location issue.Location
+}
+
+// A Pause describes a paused evaluation. It is only valid during the call to the Handler that
+// received it.
+type Pause struct {
+	reason   string
+	location issue.Location
+	c        eval.Context
+}
+
+// Reason returns REASON_BREAKPOINT, REASON_PAUSE, or REASON_STEP
+func (p *Pause) Reason() string {
+	return p.reason
+}
+
+// Location returns the location of the expression that will be evaluated when the evaluation resumes
+func (p *Pause) Location() issue.Location {
+	return p.location
+}
+
+// Context returns the context of the paused evaluation
+func (p *Pause) Context() eval.Context {
+	return p.c
+}
+
+// Scope returns the scope of the innermost frame
+func (p *Pause) Scope() eval.Scope {
+	return p.c.Scope()
+}
+
+// Frames returns the active frames, innermost frame first
+func (p *Pause) Frames() []Frame {
+	stack := p.c.Stack()
+	frames := make([]Frame, 0, len(stack))
+	location := p.location
+	for i := len(stack) - 1; i >= 0; i-- {
+		site := stack[i]
+		if i > 0 && stack[i-1] == site {
+			// A program is pushed twice when it is evaluated
+			continue
+		}
+		frames = append(frames, Frame{frameName(site), location})
+		location = site
+	}
+	return frames
+}
+
+// Evaluate evaluates the given source in the scope of the innermost frame. Breakpoints are ignored
+// during the evaluation.
+func (p *Pause) Evaluate(source string) (result eval.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if err, ok = r.(error); !ok {
+				panic(r)
+			}
+		}
+	}()
+	return eval.Evaluate(p.c, p.c.ParseAndValidate(``, source, false)), nil
+}
+
+// frameName returns the name of the function called at the given call site, or "main"
+func frameName(site issue.Location) string {
+	switch site := site.(type) {
+	case *parser.CallNamedFunctionExpression:
+		switch f := site.Functor().(type) {
+		case *parser.QualifiedName:
+			return f.Name()
+		case *parser.QualifiedReference:
+			return f.Name() + `.new`
+		}
+		return `new`
+	case *parser.CallMethodExpression:
+		if na, ok := site.Functor().(*parser.NamedAccessExpression); ok {
+			if qn, ok := na.Rhs().(*parser.QualifiedName); ok {
+				return qn.Name()
+			}
+		}
+	}
+	return `main`
+}
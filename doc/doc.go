@@ -0,0 +1,359 @@
+// Package doc generates reference documentation from what the loaders know.
+//
+// Collect gathers the functions, plans, tasks, Object types, TypeSets, and type aliases that a loader
+// can find into a Reference. Functions written in Go, i.e. those registered using
+// eval.RegisterGoFunction, are found by the loader of any context. Module collects what a module
+// directory contains by walking it with a file based loader. A Reference is written as Markdown by
+// WriteMarkdown and as JSON by WriteJSON.
+package doc
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// A Reference holds the documentation of everything that has been collected
+type Reference struct {
+	Functions   []*Function `json:"functions,omitempty"`
+	Plans       []*Function `json:"plans,omitempty"`
+	Tasks       []*Task     `json:"tasks,omitempty"`
+	Objects     []*Object   `json:"objects,omitempty"`
+	TypeSets    []*TypeSet  `json:"type_sets,omitempty"`
+	TypeAliases []*Alias    `json:"type_aliases,omitempty"`
+}
+
+// A Source is the location of a definition written in the Puppet language. It is nil for
+// definitions written in Go.
+type Source struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// A Function is a function or a plan
+type Function struct {
+	Name       string       `json:"name"`
+	Source     *Source      `json:"source,omitempty"`
+	Signatures []*Signature `json:"signatures"`
+}
+
+// A Signature is one of the dispatchers of a function
+type Signature struct {
+	Parameters []*Parameter `json:"parameters"`
+	Block      *Parameter   `json:"block,omitempty"`
+	ReturnType string       `json:"return_type"`
+}
+
+// A Parameter is a parameter of a signature or a task
+type Parameter struct {
+	// Name is the name of the parameter. It is empty for the unnamed parameters of Go functions.
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type"`
+	Default     string `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// Optional is true when the parameter can be omitted
+	Optional bool `json:"optional,omitempty"`
+
+	// Repeated is true when the parameter captures the remaining arguments
+	Repeated bool `json:"repeated,omitempty"`
+}
+
+// A Task is a task found by a loader
+type Task struct {
+	Name         string       `json:"name"`
+	Description  string       `json:"description,omitempty"`
+	Parameters   []*Parameter `json:"parameters,omitempty"`
+	SupportsNoop bool         `json:"supports_noop,omitempty"`
+	Private      bool         `json:"private,omitempty"`
+}
+
+// An Object is an Object type
+type Object struct {
+	Name        string       `json:"name"`
+	Parent      string       `json:"parent,omitempty"`
+	Annotations string       `json:"annotations,omitempty"`
+	Attributes  []*Attribute `json:"attributes,omitempty"`
+	Functions   []*Member    `json:"functions,omitempty"`
+}
+
+// An Attribute is an attribute of an Object type
+type Attribute struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Kind        string `json:"kind,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Annotations string `json:"annotations,omitempty"`
+}
+
+// A Member is a function of an Object type or a type of a TypeSet
+type Member struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Annotations string `json:"annotations,omitempty"`
+}
+
+// A TypeSet is a TypeSet and the names of its types and references
+type TypeSet struct {
+	Name         string        `json:"name"`
+	Version      string        `json:"version,omitempty"`
+	PcoreVersion string        `json:"pcore_version,omitempty"`
+	Types        []*Member     `json:"types,omitempty"`
+	References   []*TypeSetRef `json:"references,omitempty"`
+}
+
+// A TypeSetRef is a reference from a TypeSet to another TypeSet
+type TypeSetRef struct {
+	Alias        string `json:"alias"`
+	Name         string `json:"name"`
+	VersionRange string `json:"version_range"`
+}
+
+// An Alias is a type alias and the type that it resolves to
+type Alias struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// all is a predicate that accepts every name
+func all(tn eval.TypedName) bool {
+	return true
+}
+
+// Collect returns a Reference for the functions, plans, tasks, and types that the given loader can
+// find and that satisfy the given predicate. A nil predicate accepts all names. The entries are
+// sorted by name.
+func Collect(c eval.Context, loader eval.Loader, predicate func(tn eval.TypedName) bool) *Reference {
+	if predicate == nil {
+		predicate = all
+	}
+	r := &Reference{}
+	c.DoWithLoader(loader, func() {
+		for _, tn := range loader.Discover(c, predicate) {
+			le := loader.LoadEntry(c, tn)
+			if le == nil || le.Value() == nil {
+				continue
+			}
+			r.add(c, tn, le)
+		}
+	})
+	sort.Slice(r.Functions, func(i, j int) bool { return r.Functions[i].Name < r.Functions[j].Name })
+	sort.Slice(r.Plans, func(i, j int) bool { return r.Plans[i].Name < r.Plans[j].Name })
+	sort.Slice(r.Tasks, func(i, j int) bool { return r.Tasks[i].Name < r.Tasks[j].Name })
+	sort.Slice(r.Objects, func(i, j int) bool { return r.Objects[i].Name < r.Objects[j].Name })
+	sort.Slice(r.TypeSets, func(i, j int) bool { return r.TypeSets[i].Name < r.TypeSets[j].Name })
+	sort.Slice(r.TypeAliases, func(i, j int) bool { return r.TypeAliases[i].Name < r.TypeAliases[j].Name })
+	return r
+}
+
+// Module returns a Reference for the functions, plans, tasks, and types of the module in the given
+// directory. The name of the module is the name of the directory. Entries that the loader of the
+// given context can find without the module are excluded.
+func Module(c eval.Context, dir string) *Reference {
+	known := make(map[string]bool)
+	for _, tn := range c.Loader().Discover(c, all) {
+		known[tn.MapKey()] = true
+	}
+	ml := eval.NewFilebasedLoader(c.Loader(), dir, filepath.Base(dir),
+		eval.PUPPET_FUNCTION_PATH, eval.PUPPET_DATA_TYPE_PATH, eval.PLAN_PATH, eval.TASK_PATH)
+	return Collect(c, ml, func(tn eval.TypedName) bool { return !known[tn.MapKey()] })
+}
+
+func (r *Reference) add(c eval.Context, tn eval.TypedName, le eval.LoaderEntry) {
+	switch tn.Namespace() {
+	case eval.NsFunction:
+		if f, ok := le.Value().(eval.Function); ok {
+			r.Functions = append(r.Functions, function(f, le))
+		}
+	case eval.NsPlan:
+		if f, ok := le.Value().(eval.Function); ok {
+			r.Plans = append(r.Plans, function(f, le))
+		}
+	case eval.NsTask:
+		if t, ok := le.Value().(eval.PuppetObject); ok {
+			r.Tasks = append(r.Tasks, task(t))
+		}
+	case eval.NsType:
+		switch t := le.Value().(type) {
+		case *types.TypeAliasType:
+			r.TypeAliases = append(r.TypeAliases, &Alias{Name: t.Name(), Type: t.ResolvedType().String()})
+		case eval.TypeSet:
+			if t != types.DefaultTypeSetType() {
+				r.TypeSets = append(r.TypeSets, typeSet(t))
+			}
+		case eval.ObjectType:
+			if t != eval.ObjectType(types.DefaultObjectType()) {
+				r.Objects = append(r.Objects, object(c, t))
+			}
+		}
+	}
+}
+
+func function(f eval.Function, le eval.LoaderEntry) *Function {
+	fd := &Function{Name: f.Name()}
+	if o := le.Origin(); o != nil && o.File() != `` {
+		fd.Source = &Source{File: o.File(), Line: o.Line()}
+	}
+	for _, d := range f.Dispatchers() {
+		fd.Signatures = append(fd.Signatures, signature(d))
+	}
+	return fd
+}
+
+func signature(d eval.Lambda) *Signature {
+	s := d.Signature()
+	sd := &Signature{Parameters: []*Parameter{}, ReturnType: `Any`}
+	if rt := s.ReturnType(); rt != nil {
+		sd.ReturnType = rt.String()
+	}
+	required := len(d.Parameters())
+	if tt, ok := s.ParametersType().(*types.TupleType); ok {
+		required = int(tt.Size().Min())
+	}
+	for i, p := range d.Parameters() {
+		pd := &Parameter{Name: p.Name(), Type: p.Type().String(), Optional: i >= required, Repeated: p.CapturesRest()}
+		if _, err := strconv.Atoi(pd.Name); err == nil {
+			pd.Name = ``
+		}
+		if p.HasValue() {
+			pd.Default = display(p.Value())
+		}
+		sd.Parameters = append(sd.Parameters, pd)
+	}
+	if bt := s.BlockType(); bt != nil {
+		bd := &Parameter{Name: s.BlockName(), Type: bt.String()}
+		if ot, ok := bt.(*types.OptionalType); ok {
+			bd.Type = ot.ContainedType().String()
+			bd.Optional = true
+		}
+		sd.Block = bd
+	}
+	return sd
+}
+
+func task(t eval.PuppetObject) *Task {
+	td := &Task{Name: stringAttr(t, `name`), Description: stringAttr(t, `description`)}
+	if v, ok := t.Get(`supports_noop`); ok {
+		td.SupportsNoop = eval.PuppetEquals(v, types.Boolean_TRUE)
+	}
+	if v, ok := t.Get(`private`); ok {
+		td.Private = eval.PuppetEquals(v, types.Boolean_TRUE)
+	}
+	if v, ok := t.Get(`parameters`); ok {
+		if params, ok := v.(eval.OrderedMap); ok {
+			params.EachPair(func(k, v eval.Value) {
+				ph := v.(eval.OrderedMap)
+				pd := &Parameter{Name: k.String(), Type: ph.Get5(`type`, types.DefaultDataType()).String()}
+				if d, ok := ph.Get4(`description`); ok {
+					pd.Description = d.String()
+				}
+				if d, ok := ph.Get4(`default`); ok {
+					pd.Default = display(d)
+					pd.Optional = true
+				} else if _, ok := ph.Get5(`type`, types.DefaultDataType()).(*types.OptionalType); ok {
+					pd.Optional = true
+				}
+				td.Parameters = append(td.Parameters, pd)
+			})
+		}
+	}
+	return td
+}
+
+func object(c eval.Context, t eval.ObjectType) *Object {
+	od := &Object{Name: t.Name(), Annotations: annotations(t.Annotations(c))}
+	if p := t.Parent(); p != nil {
+		od.Parent = p.Name()
+	}
+	// The attributes info lacks derived and constant attributes
+	if ea, ok := t.(interface {
+		EachAttribute(includeParent bool, consumer func(eval.Attribute))
+	}); ok {
+		ea.EachAttribute(false, func(a eval.Attribute) {
+			ad := &Attribute{Name: a.Name(), Type: a.Type().String(), Kind: string(a.Kind()), Annotations: memberAnnotations(a)}
+			if a.HasValue() {
+				ad.Default = display(a.Value())
+			}
+			od.Attributes = append(od.Attributes, ad)
+		})
+	}
+	for _, f := range t.Functions(false) {
+		od.Functions = append(od.Functions, &Member{Name: f.Name(), Type: f.Type().String(), Annotations: memberAnnotations(f)})
+	}
+	return od
+}
+
+func typeSet(t eval.TypeSet) *TypeSet {
+	td := &TypeSet{Name: t.Name()}
+	if v := t.Version(); v != nil {
+		td.Version = v.String()
+	}
+	g, _ := t.(interface {
+		Get(key string) (eval.Value, bool)
+	})
+	if g != nil {
+		if v, ok := g.Get(eval.KEY_PCORE_VERSION); ok && v != eval.UNDEF {
+			td.PcoreVersion = v.String()
+		}
+	}
+	t.Types().EachPair(func(k, v eval.Value) {
+		md := &Member{Name: k.String(), Type: v.String()}
+		if at, ok := v.(*types.TypeAliasType); ok {
+			md.Type = at.ResolvedType().String()
+		}
+		td.Types = append(td.Types, md)
+	})
+	if g != nil {
+		if v, ok := g.Get(types.KEY_REFERENCES); ok {
+			if refs, ok := v.(eval.OrderedMap); ok {
+				refs.EachPair(func(k, v eval.Value) {
+					rh := v.(eval.OrderedMap)
+					td.References = append(td.References, &TypeSetRef{
+						Alias:        k.String(),
+						Name:         rh.Get5(`name`, eval.EMPTY_STRING).String(),
+						VersionRange: rh.Get5(`version_range`, eval.EMPTY_STRING).String(),
+					})
+				})
+				sort.Slice(td.References, func(i, j int) bool { return td.References[i].Alias < td.References[j].Alias })
+			}
+		}
+	}
+	return td
+}
+
+func stringAttr(o eval.PuppetObject, name string) string {
+	if v, ok := o.Get(name); ok && v != eval.UNDEF {
+		return v.String()
+	}
+	return ``
+}
+
+// memberAnnotations returns the annotations of the given member as a string, or an empty string
+// if the member has no annotations
+func memberAnnotations(m eval.AnnotatedMember) string {
+	if v, ok := m.InitHash().Get4(`annotations`); ok {
+		if a, ok := v.(eval.OrderedMap); ok {
+			return annotations(a)
+		}
+	}
+	return ``
+}
+
+func annotations(a eval.OrderedMap) string {
+	if a == nil || a.Len() == 0 {
+		return ``
+	}
+	return display(a)
+}
+
+// display presents values the way they are written in the Puppet language. The String method of a
+// container quotes the strings that it contains but a string itself is not quoted.
+func display(v eval.Value) string {
+	if s, ok := v.(*types.StringValue); ok {
+		return eval.ToString2(s, types.PROGRAM)
+	}
+	return v.String()
+}
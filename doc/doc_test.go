@@ -0,0 +1,162 @@
+package doc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/doc"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+var moduleFiles = map[string]string{
+	`functions/greet.pp`: `function mymod::greet(String $name, Integer $times = 1, *$rest) >> String {
+  $name
+}
+`,
+	`plans/deploy.pp`: `plan mymod::deploy(Array[String] $targets, Boolean $noop = false) {
+  $targets
+}
+`,
+	`types/address.pp`: `type Mymod::Address = Object[
+  attributes => {
+    street => String,
+    zip => { type => Integer, value => 12345 },
+    full => { type => String, kind => derived, annotations => { Mymod::Doc => { text => 'Street and zip' } } }
+  },
+  functions => {
+    format => Callable[[String], String]
+  }
+]
+`,
+	`types/doc.pp`: `type Mymod::Doc = Object[parent => Annotation, attributes => { text => String }]
+`,
+	`types/port.pp`: `type Mymod::Port = Integer[1, 65535]
+`,
+	`types/net.pp`: `type Mymod::Net = TypeSet[{
+  pcore_version => '1.0.0',
+  version => '2.1.0',
+  types => { Host => Object[attributes => { name => String }] },
+  references => { Core => { name => 'Mymod::Other', version_range => '1.x' } }
+}]
+`,
+	`types/other.pp`: `type Mymod::Other = TypeSet[{
+  pcore_version => '1.0.0',
+  version => '1.2.0',
+  types => { Count => Integer[0] }
+}]
+`,
+	`tasks/install.json`: `{
+  "description": "Installs a package",
+  "supports_noop": true,
+  "parameters": {
+    "package": { "type": "String[1]", "description": "The package to install" },
+    "version": { "type": "Optional[String]" }
+  }
+}
+`,
+	`tasks/install.sh`: "#!/bin/sh\n",
+}
+
+func collectModule(t *testing.T) *doc.Reference {
+	t.Helper()
+	tmpDir, err := ioutil.TempDir(``, `doc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dir := filepath.Join(tmpDir, `mymod`)
+	for name, content := range moduleFiles {
+		path := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eval.Puppet.Reset()
+	eval.Puppet.Set(`tasks`, types.Boolean_TRUE)
+	defer eval.Puppet.Reset()
+	var r *doc.Reference
+	err = eval.Puppet.Try(func(c eval.Context) error {
+		r = doc.Module(c, dir)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make the sources independent of the temporary directory
+	for _, f := range append(r.Functions, r.Plans...) {
+		if f.Source != nil {
+			f.Source.File = strings.TrimPrefix(f.Source.File, dir+string(filepath.Separator))
+		}
+	}
+	return r
+}
+
+func TestModule_WriteMarkdown(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := collectModule(t).WriteMarkdown(b); err != nil {
+		t.Fatal(err)
+	}
+	assertMarkdown(t, `testdata/module.md`, b.String())
+}
+
+func TestModule_WriteJSON(t *testing.T) {
+	b := &bytes.Buffer{}
+	if err := collectModule(t).WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	var r doc.Reference
+	if err := json.Unmarshal(b.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Functions) != 1 || len(r.Plans) != 1 || len(r.Tasks) != 1 || len(r.Objects) != 2 || len(r.TypeSets) != 2 || len(r.TypeAliases) != 1 {
+		t.Fatalf("unexpected content:\n%s", b.String())
+	}
+	p := r.Functions[0].Signatures[0].Parameters[2]
+	if p.Name != `rest` || p.Type != `Any` || !p.Optional || !p.Repeated {
+		t.Errorf(`unexpected parameter %+v`, p)
+	}
+	if ref := r.TypeSets[0].References[0]; ref.Alias != `Core` || ref.Name != `Mymod::Other` || ref.VersionRange != `1.x` {
+		t.Errorf(`unexpected reference %+v`, ref)
+	}
+	if tp := r.Tasks[0].Parameters[0]; tp.Name != `package` || tp.Type != `String[1]` || tp.Description != `The package to install` || tp.Optional {
+		t.Errorf(`unexpected task parameter %+v`, tp)
+	}
+}
+
+func TestCollect_goFunctions(t *testing.T) {
+	var r *doc.Reference
+	eval.Puppet.Do(func(c eval.Context) {
+		r = doc.Collect(c, c.Loader(), func(tn eval.TypedName) bool {
+			return tn.Namespace() == eval.NsFunction && (tn.Name() == `map` || tn.Name() == `notice`)
+		})
+	})
+	b := &bytes.Buffer{}
+	if err := r.WriteMarkdown(b); err != nil {
+		t.Fatal(err)
+	}
+	assertMarkdown(t, `testdata/go.md`, b.String())
+}
+
+func assertMarkdown(t *testing.T, expectedFile, actual string) {
+	t.Helper()
+	expected, err := ioutil.ReadFile(expectedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != string(expected) {
+		t.Errorf("markdown differs from %s:\n%s", expectedFile, actual)
+	}
+}
@@ -0,0 +1,171 @@
+package doc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteJSON writes the receiver to the given writer as indented JSON
+func (r *Reference) WriteJSON(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent(``, `  `)
+	return e.Encode(r)
+}
+
+// WriteMarkdown writes the receiver to the given writer as a Markdown document with one section
+// for each kind of entry. Sections without entries are omitted.
+func (r *Reference) WriteMarkdown(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, `# Reference`)
+	writeFunctions(b, `Functions`, r.Functions)
+	writeFunctions(b, `Plans`, r.Plans)
+
+	if len(r.Tasks) > 0 {
+		fmt.Fprint(b, "\n## Tasks\n")
+		for _, t := range r.Tasks {
+			fmt.Fprintf(b, "\n### `%s`\n", t.Name)
+			if t.Description != `` {
+				fmt.Fprintf(b, "\n%s\n", t.Description)
+			}
+			if len(t.Parameters) > 0 {
+				fmt.Fprint(b, "\n| Parameter | Type | Default | Description |\n| --- | --- | --- | --- |\n")
+				for _, p := range t.Parameters {
+					fmt.Fprintf(b, "| `%s` | %s | %s | %s |\n", p.Name, code(p.Type), code(p.Default), cell(p.Description))
+				}
+			}
+			if t.SupportsNoop {
+				fmt.Fprint(b, "\nThe task supports no-op mode.\n")
+			}
+			if t.Private {
+				fmt.Fprint(b, "\nThe task is private.\n")
+			}
+		}
+	}
+
+	if len(r.Objects) > 0 {
+		fmt.Fprint(b, "\n## Object types\n")
+		for _, o := range r.Objects {
+			fmt.Fprintf(b, "\n### `%s`\n", o.Name)
+			if o.Parent != `` {
+				fmt.Fprintf(b, "\nInherits `%s`.\n", o.Parent)
+			}
+			if o.Annotations != `` {
+				fmt.Fprintf(b, "\nAnnotations: `%s`\n", o.Annotations)
+			}
+			if len(o.Attributes) > 0 {
+				fmt.Fprint(b, "\n| Attribute | Type | Kind | Default | Annotations |\n| --- | --- | --- | --- | --- |\n")
+				for _, a := range o.Attributes {
+					fmt.Fprintf(b, "| `%s` | %s | %s | %s | %s |\n", a.Name, code(a.Type), cell(a.Kind), code(a.Default), code(a.Annotations))
+				}
+			}
+			if len(o.Functions) > 0 {
+				fmt.Fprint(b, "\n| Function | Type | Annotations |\n| --- | --- | --- |\n")
+				for _, f := range o.Functions {
+					fmt.Fprintf(b, "| `%s` | %s | %s |\n", f.Name, code(f.Type), code(f.Annotations))
+				}
+			}
+		}
+	}
+
+	if len(r.TypeSets) > 0 {
+		fmt.Fprint(b, "\n## Type sets\n")
+		for _, t := range r.TypeSets {
+			fmt.Fprintf(b, "\n### `%s`\n", t.Name)
+			if t.Version != `` {
+				fmt.Fprintf(b, "\nVersion %s", t.Version)
+				if t.PcoreVersion != `` {
+					fmt.Fprintf(b, ", pcore version %s", t.PcoreVersion)
+				}
+				fmt.Fprintln(b, `.`)
+			}
+			if len(t.Types) > 0 {
+				fmt.Fprint(b, "\n| Type | Definition |\n| --- | --- |\n")
+				for _, m := range t.Types {
+					fmt.Fprintf(b, "| `%s` | %s |\n", m.Name, code(m.Type))
+				}
+			}
+			if len(t.References) > 0 {
+				fmt.Fprint(b, "\n| Reference | Type set | Version range |\n| --- | --- | --- |\n")
+				for _, ref := range t.References {
+					fmt.Fprintf(b, "| `%s` | `%s` | %s |\n", ref.Alias, ref.Name, code(ref.VersionRange))
+				}
+			}
+		}
+	}
+
+	if len(r.TypeAliases) > 0 {
+		fmt.Fprint(b, "\n## Type aliases\n")
+		for _, a := range r.TypeAliases {
+			fmt.Fprintf(b, "\n### `%s`\n\n```puppet\ntype %s = %s\n```\n", a.Name, a.Name, a.Type)
+		}
+	}
+	return b.Flush()
+}
+
+func writeFunctions(b *bufio.Writer, title string, fs []*Function) {
+	if len(fs) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n", title)
+	for _, f := range fs {
+		fmt.Fprintf(b, "\n### `%s`\n\n", f.Name)
+		if f.Source != nil {
+			fmt.Fprintf(b, "Defined in `%s` on line %d.\n\n", f.Source.File, f.Source.Line)
+		} else {
+			fmt.Fprint(b, "Implemented in Go.\n\n")
+		}
+		fmt.Fprintln(b, "```puppet")
+		for _, s := range f.Signatures {
+			fmt.Fprintln(b, s.String(f.Name))
+		}
+		fmt.Fprintln(b, "```")
+	}
+}
+
+// String returns the signature as it would be declared by a function with the given name. Optional
+// parameters without a default value are enclosed in brackets.
+func (s *Signature) String(name string) string {
+	params := make([]string, 0, len(s.Parameters)+1)
+	for _, p := range s.Parameters {
+		params = append(params, p.declaration(`$`))
+	}
+	if s.Block != nil {
+		params = append(params, s.Block.declaration(`&$`))
+	}
+	return fmt.Sprintf(`%s(%s) >> %s`, name, strings.Join(params, `, `), s.ReturnType)
+}
+
+func (p *Parameter) declaration(prefix string) string {
+	d := p.Type
+	if p.Name != `` {
+		if p.Repeated {
+			prefix = `*` + prefix
+		}
+		d += ` ` + prefix + p.Name
+	} else if p.Repeated {
+		d += `...`
+	}
+	if p.Default != `` {
+		return d + ` = ` + p.Default
+	}
+	if p.Optional && !p.Repeated {
+		return `[` + d + `]`
+	}
+	return d
+}
+
+// code returns the given string as inline code that can be used in a table cell
+func code(s string) string {
+	if s == `` {
+		return ``
+	}
+	return "`" + strings.Replace(s, `|`, `\|`, -1) + "`"
+}
+
+// cell escapes the given string so that it can be used in a table cell
+func cell(s string) string {
+	return strings.Replace(strings.Replace(s, "\n", ` `, -1), `|`, `\|`, -1)
+}
@@ -2,7 +2,6 @@ package eval
 
 import (
 	"context"
-	"github.com/lyraproj/puppet-evaluator/threadlocal"
 
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-parser/parser"
@@ -22,6 +21,12 @@ type Context interface {
 	// AddTypes Makes the given types known to the loader appointed by this context
 	AddTypes(types ...Type)
 
+	// Coverage returns the coverage of the receiver or nil if no coverage is assigned
+	Coverage() Coverage
+
+	// Debugger returns the debugger of the receiver or nil if no debugger is assigned
+	Debugger() Debugger
+
 	// Delete deletes the given key from the context variable map
 	Delete(key string)
 
@@ -63,6 +68,9 @@ type Context interface {
 	// ImplementationRegistry returns the registry that holds mappings between Type and reflect.Type
 	ImplementationRegistry() ImplementationRegistry
 
+	// Limits returns the resource budget that applies to the receiver
+	Limits() Limits
+
 	// Loader returns the loader of the receiver.
 	Loader() Loader
 
@@ -84,6 +92,9 @@ type Context interface {
 	// to a Type
 	ParseType2(typeString string) Type
 
+	// Profiler returns the profiler of the receiver or nil if no profiler is assigned
+	Profiler() Profiler
+
 	// Reflector returns a Reflector capable of converting to and from refleced values
 	// and types
 	Reflector() Reflector
@@ -101,9 +112,32 @@ type Context interface {
 	// Set adds or replaces the context variable for the given key with the given value
 	Set(key string, value interface{})
 
+	// SetCoverage assigns a coverage to the receiver. The coverage is shared with contexts forked
+	// from the receiver. A nil coverage removes the coverage.
+	SetCoverage(coverage Coverage)
+
+	// SetDebugger assigns a debugger to the receiver. The debugger is inherited by contexts forked
+	// from the receiver. A nil debugger removes the debugger.
+	SetDebugger(debugger Debugger)
+
+	// SetProfiler assigns a profiler to the receiver. Contexts that are forked from the receiver
+	// get a fork of the profiler. A nil profiler removes the profiler.
+	SetProfiler(profiler Profiler)
+
+	// SetLimits assigns new resource limits to the receiver and resets the step count. Contexts
+	// forked from the receiver after this call will share the new limits.
+	SetLimits(limits Limits)
+
+	// Sandbox returns the sandbox policy of the receiver or nil if the receiver isn't sandboxed
+	Sandbox() *SandboxPolicy
+
 	// Scope returns the scope
 	Scope() Scope
 
+	// SetSandbox assigns a sandbox policy to the receiver. The policy is inherited by contexts
+	// forked from the receiver. A nil policy removes all sandbox restrictions.
+	SetSandbox(policy *SandboxPolicy)
+
 	// Stack returns the full stack. The returned value must not be modified.
 	Stack() []issue.Location
 
@@ -111,12 +145,18 @@ type Context interface {
 	StackPop()
 
 	// StackPush pushes a location onto the stack. The location is typically the
-	// currently evaluated expression.
+	// currently evaluated expression. It will panic with an issue.Reported if the push
+	// exceeds the maximum stack depth.
 	StackPush(location issue.Location)
 
 	// StackTop returns the top of the stack
 	StackTop() issue.Location
 
+	// Step counts the evaluation of the expression at the given location and notifies the coverage
+	// and the debugger of the receiver, if any. It will panic with an issue.Reported if the step count exceeds the
+	// maximum number of steps.
+	Step(location issue.Location)
+
 	// Static returns true during evaluation of type expressions. It is used to prevent
 	// dynamic expressions within such expressions
 	Static() bool
@@ -126,18 +166,6 @@ type Context interface {
 // block.
 var Call func(c Context, name string, args []Value, block Lambda) Value
 
-func DoWithContext(ctx Context, actor func(Context)) {
-	if saveCtx, ok := threadlocal.Get(PuppetContextKey); ok {
-		defer func() {
-			threadlocal.Set(PuppetContextKey, saveCtx)
-		}()
-	} else {
-		threadlocal.Init()
-	}
-	threadlocal.Set(PuppetContextKey, ctx)
-	actor(ctx)
-}
-
 // TopEvaluate resolves all pending definitions prior to evaluating. The evaluated expression is not
 // allowed ot contain return, next, or break.
 var TopEvaluate func(c Context, expr parser.Expression) (result Value, err issue.Reported)
@@ -146,5 +174,3 @@ var TopEvaluate func(c Context, expr parser.Expression) (result Value, err issue
 func Evaluate(c Context, expr parser.Expression) Value {
 	return c.GetEvaluator().Eval(expr)
 }
-
-var CurrentContext func() Context
@@ -0,0 +1,11 @@
+package eval
+
+import "github.com/lyraproj/issue/issue"
+
+// A Coverage is notified of each expression that is evaluated using a Context that has the
+// coverage assigned. The coverage is shared with forked contexts so it must be safe to use from
+// multiple goroutines.
+type Coverage interface {
+	// Hit is called before the expression at the given location is evaluated
+	Hit(location issue.Location)
+}
@@ -0,0 +1,12 @@
+package eval
+
+import "github.com/lyraproj/issue/issue"
+
+// A Debugger is notified before each expression is evaluated using a Context that has the debugger
+// assigned. The debugger pauses the evaluation by not returning from BeforeStep until the evaluation
+// should resume. The debugger may panic to abort the evaluation.
+type Debugger interface {
+	// BeforeStep is called before the expression at the given location is evaluated using the
+	// given context. The stack of the context holds the call sites of the active frames.
+	BeforeStep(c Context, location issue.Location)
+}
@@ -2,7 +2,6 @@ package eval
 
 import (
 	"github.com/lyraproj/issue/issue"
-	"github.com/lyraproj/puppet-evaluator/threadlocal"
 	"github.com/lyraproj/puppet-parser/parser"
 )
 
@@ -20,26 +19,19 @@ type ParserExtension interface {
 	Evaluate(e Evaluator) Value
 }
 
-// Go calls the given function in a new go routine. The CurrentContext is forked and becomes
-// the CurrentContext for that routine.
-func Go(f ContextDoer) {
-	Fork(CurrentContext(), f)
-}
-
-// Fork calls the given function in a new go routine. The given context is forked and becomes
-// the CurrentContext for that routine.
+// Fork calls the given function in a new go routine. The given context is forked and the
+// fork is passed to the function.
 func Fork(c Context, doer ContextDoer) {
-	go func() {
-		defer threadlocal.Cleanup()
-		threadlocal.Init()
-		cf := c.Fork()
-		threadlocal.Set(PuppetContextKey, cf)
-		doer(cf)
-	}()
+	cf := c.Fork()
+	go DoWithContext(cf, doer)
 }
 
+// LogWarning creates a Reported with the given issue code and arguments and logs it on the
+// logger of the current context.
+//
+// Deprecated: Use Context.Logger().LogIssue() with an explicit context
 func LogWarning(issueCode issue.Code, args issue.H) {
-	CurrentContext().Logger().LogIssue(Warning(issueCode, args))
+	Warning(issueCode, args)
 }
 
 // Error creates a Reported with the given issue code, location from stack top, and arguments
@@ -207,3 +207,21 @@ func TestReflectorAndImplRepo(t *testing.T) {
 		return nil
 	})
 }
+
+func TestForkPassesContext(t *testing.T) {
+	eval.Puppet.Try(func(c eval.Context) error {
+		c.Set(`marker`, `parent`)
+		done := make(chan eval.Context)
+		eval.Fork(c, func(fc eval.Context) {
+			done <- fc
+		})
+		fc := <-done
+		if fc == c {
+			t.Error(`Fork did not pass a forked context`)
+		}
+		if v, ok := fc.Get(`marker`); !ok || v != `parent` {
+			t.Error(`forked context does not inherit variables`)
+		}
+		return nil
+	})
+}
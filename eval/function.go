@@ -93,6 +93,10 @@ type (
 		Returns(typeString string)
 		Returns2(puppetType Type)
 
+		// Requires declares that the dispatch requires the given capabilities in order to
+		// execute in a sandboxed context
+		Requires(capabilities ...Capability)
+
 		Function(f DispatchFunction)
 		Function2(f DispatchFunctionWithBlock)
 	}
@@ -5,6 +5,12 @@ import (
 )
 
 const (
+	EVAL_ACTIVITY_CYCLE                            = `EVAL_ACTIVITY_CYCLE`
+	EVAL_ACTIVITY_DUPLICATE_OUTPUT                 = `EVAL_ACTIVITY_DUPLICATE_OUTPUT`
+	EVAL_ACTIVITY_ITERATION_NOT_SUPPORTED          = `EVAL_ACTIVITY_ITERATION_NOT_SUPPORTED`
+	EVAL_ACTIVITY_MISSING_OUTPUT                   = `EVAL_ACTIVITY_MISSING_OUTPUT`
+	EVAL_ACTIVITY_UNPRODUCIBLE_INPUT               = `EVAL_ACTIVITY_UNPRODUCIBLE_INPUT`
+	EVAL_ACTIVITY_UNPRODUCIBLE_OUTPUT              = `EVAL_ACTIVITY_UNPRODUCIBLE_OUTPUT`
 	EVAL_ARGUMENTS_ERROR                           = `EVAL_ARGUMENTS_ERROR`
 	EVAL_ATTEMPT_TO_REDEFINE                       = `EVAL_ATTEMPT_TO_REDEFINE`
 	EVAL_ATTEMPT_TO_SET_UNSETTABLE                 = `EVAL_ATTEMPT_TO_SET_UNSETTABLE`
@@ -14,15 +20,22 @@ const (
 	EVAL_BAD_JSON_PATH                             = `EVAL_BAD_JSON_PATH`
 	EVAL_BAD_TYPE_STRING                           = `EVAL_BAD_TYPE_STRING`
 	EVAL_BOTH_CONSTANT_AND_ATTRIBUTE               = `EVAL_BOTH_CONSTANT_AND_ATTRIBUTE`
+	EVAL_CHECK_ARGUMENTS_MISMATCH                  = `EVAL_CHECK_ARGUMENTS_MISMATCH`
+	EVAL_CHECK_TYPE_MISMATCH                       = `EVAL_CHECK_TYPE_MISMATCH`
+	EVAL_CHECK_UNDEFINED_VARIABLE                  = `EVAL_CHECK_UNDEFINED_VARIABLE`
+	EVAL_CHECK_UNREACHABLE_CASE_OPTION             = `EVAL_CHECK_UNREACHABLE_CASE_OPTION`
 	EVAL_CONSTANT_REQUIRES_VALUE                   = `EVAL_CONSTANT_REQUIRES_VALUE`
 	EVAL_CONSTANT_WITH_FINAL                       = `EVAL_CONSTANT_WITH_FINAL`
 	EVAL_CTOR_NOT_FOUND                            = `EVAL_CTOR_NOT_FOUND`
+	EVAL_DEFERRED_RECURSION                        = `EVAL_DEFERRED_RECURSION`
+	EVAL_DIVISION_BY_ZERO                          = `EVAL_DIVISION_BY_ZERO`
 	EVAL_DUPLICATE_KEY                             = `EVAL_DUPLICATE_KEY`
 	EVAL_EMPTY_TYPE_PARAMETER_LIST                 = `EVAL_EMPTY_TYPE_PARAMETER_LIST`
 	EVAL_EQUALITY_ATTRIBUTE_NOT_FOUND              = `EVAL_EQUALITY_ATTRIBUTE_NOT_FOUND`
 	EVAL_EQUALITY_NOT_ATTRIBUTE                    = `EVAL_EQUALITY_NOT_ATTRIBUTE`
 	EVAL_EQUALITY_ON_CONSTANT                      = `EVAL_EQUALITY_ON_CONSTANT`
 	EVAL_EQUALITY_REDEFINED                        = `EVAL_EQUALITY_REDEFINED`
+	EVAL_EXPECTED_ONE_TARGET                       = `EVAL_EXPECTED_ONE_TARGET`
 	EVAL_FAILURE                                   = `EVAL_FAILURE`
 	EVAL_FILE_NOT_FOUND                            = `EVAL_FILE_NOT_FOUND`
 	EVAL_FILE_READ_DENIED                          = `EVAL_FILE_READ_DENIED`
@@ -41,8 +54,11 @@ const (
 	EVAL_ILLEGAL_WHEN_STATIC_EXPRESSION            = `EVAL_ILLEGAL_WHEN_STATIC_EXPRESSION`
 	EVAL_IMPL_ALREDY_REGISTERED                    = `EVAL_IMPL_ALREDY_REGISTERED`
 	EVAL_ILLEGAL_REASSIGNMENT                      = `EVAL_ILLEGAL_REASSIGNMENT`
+	EVAL_ILLEGAL_SPEC_ARGUMENT                     = `EVAL_ILLEGAL_SPEC_ARGUMENT`
 	EVAL_INSTANCE_DOES_NOT_RESPOND                 = `EVAL_INSTANCE_DOES_NOT_RESPOND`
 	EVAL_IMPOSSIBLE_OPTIONAL                       = `EVAL_IMPOSSIBLE_OPTIONAL`
+	EVAL_INTEGER_OVERFLOW                          = `EVAL_INTEGER_OVERFLOW`
+	EVAL_INVENTORY_INVALID                         = `EVAL_INVENTORY_INVALID`
 	EVAL_INVALID_CHARACTERS_IN_NAME                = `EVAL_INVALID_CHARACTERS_IN_NAME`
 	EVAL_INVALID_REGEXP                            = `EVAL_INVALID_REGEXP`
 	EVAL_INVALID_SOURCE_FOR_GET                    = `EVAL_INVALID_SOURCE_FOR_GET`
@@ -56,8 +72,16 @@ const (
 	EVAL_INVALID_VERSION                           = `EVAL_INVALID_VERSION`
 	EVAL_INVALID_VERSION_RANGE                     = `EVAL_INVALID_VERSION_RANGE`
 	EVAL_IS_DIRECTORY                              = `EVAL_IS_DIRECTORY`
+	EVAL_JSON_SCHEMA_INVALID_NAME                  = `EVAL_JSON_SCHEMA_INVALID_NAME`
+	EVAL_JSON_SCHEMA_INVALID_VALUE                 = `EVAL_JSON_SCHEMA_INVALID_VALUE`
+	EVAL_JSON_SCHEMA_UNSUPPORTED_KEYWORD           = `EVAL_JSON_SCHEMA_UNSUPPORTED_KEYWORD`
+	EVAL_JSON_SCHEMA_UNSUPPORTED_REFERENCE         = `EVAL_JSON_SCHEMA_UNSUPPORTED_REFERENCE`
+	EVAL_JSON_SCHEMA_UNSUPPORTED_TYPE              = `EVAL_JSON_SCHEMA_UNSUPPORTED_TYPE`
 	EVAL_MATCH_NOT_REGEXP                          = `EVAL_MATCH_NOT_REGEXP`
 	EVAL_MATCH_NOT_STRING                          = `EVAL_MATCH_NOT_STRING`
+	EVAL_MAX_COLLECTION_SIZE_EXCEEDED              = `EVAL_MAX_COLLECTION_SIZE_EXCEEDED`
+	EVAL_MAX_STACK_DEPTH_EXCEEDED                  = `EVAL_MAX_STACK_DEPTH_EXCEEDED`
+	EVAL_MAX_STEPS_EXCEEDED                        = `EVAL_MAX_STEPS_EXCEEDED`
 	EVAL_MEMBER_NAME_CONFLICT                      = `EVAL_MEMBER_NAME_CONFLICT`
 	EVAL_MISSING_MULTI_ASSIGNMENT_KEY              = `EVAL_MISSING_MULTI_ASSIGNMENT_KEY`
 	EVAL_MISSING_REGEXP_IN_TYPE                    = `EVAL_MISSING_REGEXP_IN_TYPE`
@@ -66,6 +90,7 @@ const (
 	EVAL_NO_ATTRIBUTE_READER                       = `EVAL_NO_ATTRIBUTE_READER`
 	EVAL_NO_CURRENT_CONTEXT                        = `EVAL_NO_CURRENT_CONTEXT`
 	EVAL_NO_DEFINITION                             = `EVAL_NO_DEFINITION`
+	EVAL_NO_PROTO_MESSAGE                          = `EVAL_NO_PROTO_MESSAGE`
 	EVAL_NOT_COLLECTION_AT                         = `EVAL_NOT_COLLECTION_AT`
 	EVAL_NOT_EXPECTED_TYPESET                      = `EVAL_NOT_EXPECTED_TYPESET`
 	EVAL_NOT_INTEGER                               = `EVAL_NOT_INTEGER`
@@ -83,6 +108,15 @@ const (
 	EVAL_OVERRIDE_OF_FINAL                         = `EVAL_OVERRIDE_OF_FINAL`
 	EVAL_OVERRIDE_IS_MISSING                       = `EVAL_OVERRIDE_IS_MISSING`
 	EVAL_PARSE_ERROR                               = `EVAL_PARSE_ERROR`
+	EVAL_PLAN_ONLY_FUNCTION                        = `EVAL_PLAN_ONLY_FUNCTION`
+	EVAL_PROTO_FIELD_NUMBER_CONFLICT               = `EVAL_PROTO_FIELD_NUMBER_CONFLICT`
+	EVAL_PROTO_FIELD_NUMBER_COUNT                  = `EVAL_PROTO_FIELD_NUMBER_COUNT`
+	EVAL_PROTO_FIELD_NUMBER_REQUIRED               = `EVAL_PROTO_FIELD_NUMBER_REQUIRED`
+	EVAL_PROTO_MESSAGE_NAME_CONFLICT               = `EVAL_PROTO_MESSAGE_NAME_CONFLICT`
+	EVAL_RUN_FAILED                                = `EVAL_RUN_FAILED`
+	EVAL_SANDBOX_CAPABILITY_DENIED                 = `EVAL_SANDBOX_CAPABILITY_DENIED`
+	EVAL_SANDBOX_FUNCTION_DENIED                   = `EVAL_SANDBOX_FUNCTION_DENIED`
+	EVAL_SANDBOX_PATH_DENIED                       = `EVAL_SANDBOX_PATH_DENIED`
 	EVAL_SERIALIZATION_ATTRIBUTE_NOT_FOUND         = `EVAL_SERIALIZATION_ATTRIBUTE_NOT_FOUND`
 	EVAL_SERIALIZATION_NOT_ATTRIBUTE               = `EVAL_SERIALIZATION_NOT_ATTRIBUTE`
 	EVAL_SERIALIZATION_BAD_KIND                    = `EVAL_SERIALIZATION_BAD_KIND`
@@ -90,9 +124,14 @@ const (
 	EVAL_SERIALIZATION_ENDLESS_RECURSION           = `EVAL_SERIALIZATION_ENDLESS_RECURSION`
 	EVAL_SERIALIZATION_REQUIRED_AFTER_OPTIONAL     = `EVAL_SERIALIZATION_REQUIRED_AFTER_OPTIONAL`
 	EVAL_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING = `EVAL_SERIALIZATION_UNKNOWN_CONVERTED_TO_STRING`
+	EVAL_TASK_BAD_FILE_REFERENCE                   = `EVAL_TASK_BAD_FILE_REFERENCE`
+	EVAL_TASK_BAD_INPUT_METHOD                     = `EVAL_TASK_BAD_INPUT_METHOD`
 	EVAL_TASK_BAD_JSON                             = `EVAL_TASK_BAD_JSON`
+	EVAL_TASK_BAD_METADATA                         = `EVAL_TASK_BAD_METADATA`
+	EVAL_TASK_IMPLEMENTATION_NOT_FOUND             = `EVAL_TASK_IMPLEMENTATION_NOT_FOUND`
 	EVAL_TASK_INITIALIZER_NOT_FOUND                = `EVAL_TASK_INITIALIZER_NOT_FOUND`
 	EVAL_TASK_NO_EXECUTABLE_FOUND                  = `EVAL_TASK_NO_EXECUTABLE_FOUND`
+	EVAL_TASK_NO_SUITABLE_IMPLEMENTATION           = `EVAL_TASK_NO_SUITABLE_IMPLEMENTATION`
 	EVAL_TASK_NOT_JSON_OBJECT                      = `EVAL_TASK_NOT_JSON_OBJECT`
 	EVAL_TASK_TOO_MANY_FILES                       = `EVAL_TASK_TOO_MANY_FILES`
 	EVAL_TIMESPAN_BAD_FSPEC                        = `EVAL_TIMESPAN_BAD_FSPEC`
@@ -116,6 +155,7 @@ const (
 	EVAL_UNKNOWN_FUNCTION                          = `EVAL_UNKNOWN_FUNCTION`
 	EVAL_UNKNOWN_PLAN                              = `EVAL_UNKNOWN_PLAN`
 	EVAL_UNKNOWN_TASK                              = `EVAL_UNKNOWN_TASK`
+	EVAL_UNKNOWN_TRANSPORT                         = `EVAL_UNKNOWN_TRANSPORT`
 	EVAL_UNKNOWN_VARIABLE                          = `EVAL_UNKNOWN_VARIABLE`
 	EVAL_UNREFLECTABLE_RETURN                      = `EVAL_UNREFLECTABLE_RETURN`
 	EVAL_UNREFLECTABLE_VALUE                       = `EVAL_UNREFLECTABLE_VALUE`
@@ -126,6 +166,18 @@ const (
 )
 
 func init() {
+	issue.Hard(EVAL_ACTIVITY_CYCLE, `Workflow %{name} has a dependency cycle: %{cycle}`)
+
+	issue.Hard(EVAL_ACTIVITY_DUPLICATE_OUTPUT, `Workflow %{name} has more than one activity that produces '%{output}': %{first} and %{second}`)
+
+	issue.Hard(EVAL_ACTIVITY_ITERATION_NOT_SUPPORTED, `Activity %{name} uses iteration which is not supported by the local executor`)
+
+	issue.Hard(EVAL_ACTIVITY_MISSING_OUTPUT, `Activity %{name} did not produce a value for its output '%{output}'`)
+
+	issue.Hard(EVAL_ACTIVITY_UNPRODUCIBLE_INPUT, `Workflow %{name} has no activity that produces the input '%{input}' of %{activity}`)
+
+	issue.Hard(EVAL_ACTIVITY_UNPRODUCIBLE_OUTPUT, `Workflow %{name} has no activity that produces its output '%{output}'`)
+
 	issue.Hard2(EVAL_ARGUMENTS_ERROR, `Error when evaluating %{expression}: %{message}`, issue.HF{`expression`: issue.A_an})
 
 	issue.Hard(EVAL_ATTEMPT_TO_REDEFINE, `attempt to redefine %{name}`)
@@ -144,6 +196,14 @@ func init() {
 
 	issue.Hard(EVAL_BOTH_CONSTANT_AND_ATTRIBUTE, `attribute %{label}[%{key}] is defined as both a constant and an attribute`)
 
+	issue.Soft(EVAL_CHECK_ARGUMENTS_MISMATCH, `Call to '%{name}' can never succeed: %{detail}`)
+
+	issue.Soft(EVAL_CHECK_TYPE_MISMATCH, `Type mismatch: %{detail}`)
+
+	issue.Soft(EVAL_CHECK_UNDEFINED_VARIABLE, `Variable '$%{name}' is not defined`)
+
+	issue.Soft(EVAL_CHECK_UNREACHABLE_CASE_OPTION, `Case option %{option} is unreachable: %{detail}`)
+
 	issue.Hard(EVAL_CONSTANT_REQUIRES_VALUE, `%{label} of kind 'constant' requires a value`)
 
 	issue.Hard(EVAL_CTOR_NOT_FOUND, `Unable to load the constructor for data type '%{type}'`)
@@ -151,6 +211,10 @@ func init() {
 	// TRANSLATOR 'final => false' is puppet syntax and should not be translated
 	issue.Hard(EVAL_CONSTANT_WITH_FINAL, `%{label} of kind 'constant' cannot be combined with final => false`)
 
+	issue.Hard(EVAL_DEFERRED_RECURSION, `Endless recursion detected when resolving deferred values in a %{type_name} value`)
+
+	issue.Hard(EVAL_DIVISION_BY_ZERO, `Division by zero`)
+
 	issue.Hard(EVAL_DUPLICATE_KEY, `The key '%{key}' is declared more than once`)
 
 	issue.Hard(EVAL_EMPTY_TYPE_PARAMETER_LIST, `The %{label}-Type cannot be parameterized using an empty parameter list`)
@@ -163,6 +227,8 @@ func init() {
 
 	issue.Hard(EVAL_EQUALITY_REDEFINED, `%{label} equality is referencing %{attribute} which is included in equality of %{including_parent}`)
 
+	issue.Hard(EVAL_EXPECTED_ONE_TARGET, `Expected '%{spec}' to denote exactly one target, got %{count}`)
+
 	issue.Hard(EVAL_FAILURE, `%{message}`)
 
 	issue.Hard(EVAL_FILE_NOT_FOUND, `File '%{path}' does not exist`)
@@ -203,6 +269,8 @@ func init() {
 
 	issue.Hard(EVAL_ILLEGAL_REASSIGNMENT, `Cannot reassign variable '$%{var}'`)
 
+	issue.Hard2(EVAL_ILLEGAL_SPEC_ARGUMENT, `%{function} does not accept %{type}`, issue.HF{`type`: issue.A_an})
+
 	issue.Hard(EVAL_IMPL_ALREDY_REGISTERED, `The type %{type} is already present in the implementation registry`)
 
 	issue.Hard(EVAL_IS_DIRECTORY, `The path '%{path}' is a directory`)
@@ -211,6 +279,20 @@ func init() {
 
 	issue.Hard(EVAL_INSTANCE_DOES_NOT_RESPOND, `An instance of %{type} does not respond to %{message}`)
 
+	issue.Hard(EVAL_INTEGER_OVERFLOW, `Integer overflow when evaluating %{left} %{operator} %{right}`)
+
+	issue.Hard(EVAL_INVENTORY_INVALID, `Invalid inventory at %{path}: %{message}`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_INVALID_NAME, `The JSON Schema definition '%{name}' cannot be converted since it is not a valid Pcore type name`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_INVALID_VALUE, `The value of the JSON Schema keyword '%{keyword}' must be of type %{expected}, not %{actual}`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_UNSUPPORTED_KEYWORD, `The JSON Schema keyword '%{keyword}' has no Pcore type equivalent`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_UNSUPPORTED_REFERENCE, `The JSON Schema reference '%{ref}' cannot be converted. Only references to entries in "$defs" are supported`)
+
+	issue.Hard(EVAL_JSON_SCHEMA_UNSUPPORTED_TYPE, `The type %{type} has no JSON Schema equivalent`)
+
 	issue.Hard(EVAL_INVALID_CHARACTERS_IN_NAME, `Name '%{name} contains invalid characters. Must start with letter and only contain letters, digits, and underscore'`)
 
 	issue.Hard(EVAL_INVALID_REGEXP, `Cannot compile regular expression '${pattern}': %{detail}`)
@@ -239,6 +321,12 @@ func init() {
 
 	issue.Hard2(EVAL_MATCH_NOT_STRING, `"Left match operand must result in a String value. Got %{left}`, issue.HF{`left`: issue.A_an})
 
+	issue.Hard2(EVAL_MAX_COLLECTION_SIZE_EXCEEDED, `%{type} of size %{size} exceeds the maximum allowed size %{max}`, issue.HF{`type`: issue.A_anUc})
+
+	issue.Hard(EVAL_MAX_STACK_DEPTH_EXCEEDED, `Maximum stack depth %{max} exceeded`)
+
+	issue.Hard(EVAL_MAX_STEPS_EXCEEDED, `Maximum number of evaluation steps %{max} exceeded`)
+
 	issue.Hard(EVAL_MEMBER_NAME_CONFLICT, `%{label} conflicts with attribute with the same name`)
 
 	issue.Hard(EVAL_MISSING_MULTI_ASSIGNMENT_KEY, `No value for required key '%{name}' in assignment to variables from hash`)
@@ -257,6 +345,8 @@ func init() {
 
 	issue.Hard(EVAL_NO_DEFINITION, `The code loaded from %{source} does not define the %{type} '%{name}`)
 
+	issue.Hard(EVAL_NO_PROTO_MESSAGE, `The protobuf schema has no message for the type %{type}`)
+
 	issue.Hard(EVAL_NOT_COLLECTION_AT, `The given data does not contain a Collection at %{walked_path}, got '%{klass}'`)
 
 	issue.Hard(EVAL_NOT_INTEGER, `The value '%{value}' cannot be converted to an Integer`)
@@ -292,6 +382,24 @@ func init() {
 
 	issue.Hard(EVAL_PARSE_ERROR, `Unable to parse %{language}. Detail: %{detail}`)
 
+	issue.Hard(EVAL_PLAN_ONLY_FUNCTION, `The function '%{name}' can only be called from within a plan`)
+
+	issue.Hard(EVAL_PROTO_FIELD_NUMBER_CONFLICT, `The protobuf field number %{number} of %{label} is also used by %{other}`)
+
+	issue.Hard(EVAL_PROTO_FIELD_NUMBER_COUNT, `The Protobuf::Field annotation of %{label} must have %{expected} numbers, one for each type of the Variant, got %{actual}`)
+
+	issue.Hard(EVAL_PROTO_FIELD_NUMBER_REQUIRED, `The protobuf field numbers of %{type} cannot be kept stable when assigned by position. Add a Protobuf::Field annotation to %{label}`)
+
+	issue.Hard(EVAL_PROTO_MESSAGE_NAME_CONFLICT, `The protobuf message name %{name} of %{type} is already used by another type`)
+
+	issue.Hard(EVAL_RUN_FAILED, `%{action} failed on %{count} target(s): %{names}`)
+
+	issue.Hard(EVAL_SANDBOX_CAPABILITY_DENIED, `The sandbox policy does not grant the capability '%{capability}' required by %{subject}`)
+
+	issue.Hard(EVAL_SANDBOX_FUNCTION_DENIED, `The sandbox policy does not permit calls to function '%{name}'`)
+
+	issue.Hard(EVAL_SANDBOX_PATH_DENIED, `The sandbox policy does not permit access to '%{path}'`)
+
 	issue.Hard(EVAL_SERIALIZATION_ATTRIBUTE_NOT_FOUND, `%{label} serialization is referencing non existent attribute '%{attribute}'`)
 
 	issue.Hard(EVAL_SERIALIZATION_NOT_ATTRIBUTE, `{label} serialization is referencing %{attribute}. Only attribute references are allowed`)
@@ -306,12 +414,22 @@ func init() {
 
 	issue.Hard(EVAL_SERIALIZATION_REQUIRED_AFTER_OPTIONAL, `%{label} serialization is referencing required %{required} after optional %{optional}. Optional attributes must be last`)
 
+	issue.Hard(EVAL_TASK_BAD_FILE_REFERENCE, `Task %{name} has an invalid file reference '%{file}'. Expected a path of the form <module>/files/<path>, <module>/lib/<path>, or <module>/tasks/<path>`)
+
+	issue.Hard(EVAL_TASK_BAD_INPUT_METHOD, `Task %{name} has an invalid input_method '%{input_method}'. Expected one of 'stdin', 'environment', 'powershell', or 'both'`)
+
 	issue.Hard(EVAL_TASK_BAD_JSON, `Unable to parse task metadata from '%{path}': %{detail}`)
 
+	issue.Hard(EVAL_TASK_BAD_METADATA, `Invalid task metadata in '%{path}': %{detail}`)
+
+	issue.Hard(EVAL_TASK_IMPLEMENTATION_NOT_FOUND, `Task %{name} declares the implementation '%{implementation}' but no such file exists in directory %{directory}`)
+
 	issue.Hard(EVAL_TASK_INITIALIZER_NOT_FOUND, `Unable to load the initializer for the Task data`)
 
 	issue.Hard(EVAL_TASK_NO_EXECUTABLE_FOUND, `No source besides task metadata was found in directory %{directory} for task %{name}`)
 
+	issue.Hard(EVAL_TASK_NO_SUITABLE_IMPLEMENTATION, `Task %{name} has no implementation that is suitable for a target with the features [%{features}]`)
+
 	issue.Hard(EVAL_TASK_NOT_JSON_OBJECT, `The content of '%{path}' does not represent a JSON Object`)
 
 	issue.Hard(EVAL_TASK_TOO_MANY_FILES, `Only one file can exists besides the .json file for task %{name} in directory %{directory}`)
@@ -358,6 +476,8 @@ func init() {
 
 	issue.Hard(EVAL_UNKNOWN_TASK, `Task not found: '%{name}'`)
 
+	issue.Hard(EVAL_UNKNOWN_TRANSPORT, `No transport named '%{name}' is available for target '%{target}'`)
+
 	issue.Hard(EVAL_UNKNOWN_VARIABLE, `Unknown variable: '$%{name}'`)
 
 	issue.Hard(EVAL_UNREFLECTABLE_RETURN, `Unable to reflect return type of method %{type}.%{method}`)
@@ -0,0 +1,60 @@
+package eval
+
+import "github.com/lyraproj/issue/issue"
+
+// Limits is the resource budget that applies to all evaluation performed using a Context. A
+// value of zero in any of the fields means that no limit is imposed.
+type Limits struct {
+	// MaxSteps is the maximum number of expressions that may be evaluated
+	MaxSteps int64
+
+	// MaxStackDepth is the maximum number of locations that can be pushed onto the stack
+	MaxStackDepth int
+
+	// MaxCollectionSize is the maximum size of an Array, Hash, or String produced by an
+	// evaluated expression
+	MaxCollectionSize int
+}
+
+// LimitsFromSettings returns the Limits that are configured using the settings max_steps,
+// max_stack_depth, and max_collection_size
+func LimitsFromSettings() Limits {
+	return Limits{
+		MaxSteps:          intSetting(`max_steps`),
+		MaxStackDepth:     int(intSetting(`max_stack_depth`)),
+		MaxCollectionSize: int(intSetting(`max_collection_size`)),
+	}
+}
+
+func intSetting(name string) int64 {
+	if Puppet == nil {
+		return 0
+	}
+	if iv, ok := Puppet.Get(name, func() Value { return UNDEF }).(NumericValue); ok {
+		return iv.Int()
+	}
+	return 0
+}
+
+// AssertCollectionSize panics with an EVAL_MAX_COLLECTION_SIZE_EXCEEDED error when size exceeds the
+// MaxCollectionSize of the given Context. It is called before a value of the given type grows so that
+// the limit is enforced before the memory is allocated. A nil location denotes the top of the stack.
+func AssertCollectionSize(c Context, location issue.Location, typeName string, size int) {
+	if max := c.Limits().MaxCollectionSize; max > 0 && size > max {
+		panic(c.Error(location, EVAL_MAX_COLLECTION_SIZE_EXCEEDED, issue.H{`type`: typeName, `size`: size, `max`: max}))
+	}
+}
+
+// AsLimitedArray returns the elements of the given Iterator as a List. The MaxCollectionSize of the
+// given Context is asserted for each collected element so that an unbounded Iterator fails early.
+func AsLimitedArray(c Context, iter Iterator) List {
+	if c.Limits().MaxCollectionSize <= 0 {
+		return iter.AsArray()
+	}
+	size := 0
+	return iter.Map(iter.ElementType(), func(v Value) Value {
+		size++
+		AssertCollectionSize(c, nil, `Array`, size)
+		return v
+	}).AsArray()
+}
@@ -20,6 +20,10 @@ type (
 
 		// NameAuthority returns the name authority
 		NameAuthority() URI
+
+		// Discover returns the names of all entries that are known to this loader or that it can find, and
+		// for which the given predicate returns true. Names known to the parent loaders are included.
+		Discover(c Context, predicate func(tn TypedName) bool) []TypedName
 	}
 
 	DefiningLoader interface {
@@ -55,6 +59,7 @@ type (
 )
 
 const (
+	PUPPET_ACTIVITY_PATH  = PathType(`puppetActivity`)
 	PUPPET_DATA_TYPE_PATH = PathType(`puppetDataType`)
 	PUPPET_FUNCTION_PATH  = PathType(`puppetFunction`)
 	PLAN_PATH             = PathType(`plan`)
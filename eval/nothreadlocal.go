@@ -0,0 +1,39 @@
+//go:build nothreadlocal
+// +build nothreadlocal
+
+package eval
+
+import (
+	"runtime"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// CurrentContext always panics with an EVAL_NO_CURRENT_CONTEXT issue since the association between
+// a Context and a go routine has been compiled out.
+//
+// Deprecated: Pass the Context explicitly
+func CurrentContext() Context {
+	_, file, line, _ := runtime.Caller(1)
+	panic(issue.NewReported(EVAL_NO_CURRENT_CONTEXT, issue.SEVERITY_ERROR, issue.NO_ARGS, issue.NewLocation(file, line, 0)))
+}
+
+// TryCurrentContext always returns nil and false since the association between a Context and a go
+// routine has been compiled out.
+//
+// Deprecated: Pass the Context explicitly
+func TryCurrentContext() (Context, bool) {
+	return nil, false
+}
+
+// SetCurrentContext is a no-op since the association between a Context and a go routine has been
+// compiled out.
+//
+// Deprecated: Pass the Context explicitly
+func SetCurrentContext(c Context) {
+}
+
+// DoWithContext calls the given actor with the given Context
+func DoWithContext(ctx Context, actor func(Context)) {
+	actor(ctx)
+}
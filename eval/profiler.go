@@ -0,0 +1,19 @@
+package eval
+
+import "github.com/lyraproj/issue/issue"
+
+// A Profiler is notified when a function or lambda is called using a Context that has the profiler
+// assigned. A profiler is only used by one goroutine at a time. A forked context gets a profiler
+// that is created by calling Fork on the profiler of the forked context.
+type Profiler interface {
+	// Enter is called before the function with the given name is called. The location is the
+	// definition of a function, plan, or lambda written in the Puppet language, or nil when the
+	// function is written in Go.
+	Enter(name string, location issue.Location)
+
+	// Exit is called when the call that was entered last returns, also when it returns by a panic
+	Exit()
+
+	// Fork returns a profiler that records into the same profile as the receiver
+	Fork() Profiler
+}
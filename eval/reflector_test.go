@@ -1,10 +1,12 @@
 package eval_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/lyraproj/puppet-evaluator/eval"
 	"github.com/lyraproj/puppet-evaluator/types"
 	"github.com/lyraproj/semver/semver"
+	"net/url"
 	"os"
 	"reflect"
 	"regexp"
@@ -1160,3 +1162,188 @@ func ExampleReflect_TypeFromReflect_structAnonField() {
 	//   }
 	// }]
 }
+
+type goTypes struct {
+	Created  time.Time
+	Timeout  time.Duration
+	Pattern  *regexp.Regexp
+	Location *url.URL
+	Version  semver.Version
+	Expires  *time.Time
+	Labels   map[string]string
+	Ports    []int
+}
+
+type embeddingInterface struct {
+	A
+	Name string
+}
+
+func ExampleReflect_TypeFromReflect_goTypes() {
+	eval.Puppet.Do(func(c eval.Context) {
+		x := c.Reflector().TypeFromReflect(`X`, nil, reflect.TypeOf(&goTypes{}))
+		c.AddTypes(x)
+		x.ToString(os.Stdout, eval.PRETTY_EXPANDED, nil)
+		fmt.Println()
+	})
+
+	// Output:
+	// Object[{
+	//   name => 'X',
+	//   attributes => {
+	//     'created' => Timestamp,
+	//     'timeout' => Timespan,
+	//     'pattern' => {
+	//       'type' => Optional[Regexp],
+	//       'value' => undef
+	//     },
+	//     'location' => {
+	//       'type' => Optional[URI],
+	//       'value' => undef
+	//     },
+	//     'version' => SemVer,
+	//     'expires' => {
+	//       'type' => Optional[Timestamp],
+	//       'value' => undef
+	//     },
+	//     'labels' => Hash[String, String],
+	//     'ports' => Array[Integer]
+	//   }
+	// }]
+}
+
+func ExampleReflect_TypeFromReflect_goTypesRoundtrip() {
+	eval.Puppet.Do(func(c eval.Context) {
+		x := c.Reflector().TypeFromReflect(`X`, nil, reflect.TypeOf(&goTypes{}))
+		c.AddTypes(x)
+
+		created, _ := time.Parse(time.RFC3339, `2018-05-11T06:31:22Z`)
+		location, _ := url.Parse(`http://example.com/path`)
+		version, _ := semver.ParseVersion(`1.2.3`)
+		src := &goTypes{
+			Created:  created,
+			Timeout:  3 * time.Second,
+			Pattern:  regexp.MustCompile(`[a-z]+`),
+			Location: location,
+			Version:  version,
+			Labels:   map[string]string{`a`: `x`},
+			Ports:    []int{80, 443}}
+
+		v := eval.Wrap(c, src)
+		fmt.Println(eval.ToPrettyString(v))
+
+		// Create a new instance from the attribute values and reflect it back to Go
+		nv := eval.New(c, x, v.(eval.PuppetObject).InitHash())
+		dst := &goTypes{}
+		c.Reflector().ReflectTo(nv, reflect.ValueOf(dst).Elem())
+		fmt.Println(dst.Created.Equal(src.Created), dst.Timeout, dst.Pattern, dst.Location, dst.Version, dst.Expires, dst.Labels, dst.Ports)
+	})
+
+	// Output:
+	// X(
+	//   'created' => 2018-05-11T06:31:22.000000000 UTC,
+	//   'timeout' => 0-00:00:03.0,
+	//   'version' => SemVer('1.2.3'),
+	//   'labels' => {
+	//     'a' => 'x'
+	//   },
+	//   'ports' => [80, 443],
+	//   'pattern' => /[a-z]+/,
+	//   'location' => URI('http://example.com/path')
+	// )
+	// true 3s [a-z]+ http://example.com/path 1.2.3 <nil> map[a:x] [80 443]
+}
+
+func ExampleReflect_TypeFromReflect_embeddedInterface() {
+	eval.Puppet.Do(func(c eval.Context) {
+		x := c.Reflector().TypeFromReflect(`X`, nil, reflect.TypeOf(&embeddingInterface{}))
+		c.AddTypes(x)
+		x.ToString(os.Stdout, eval.PRETTY_EXPANDED, nil)
+		fmt.Println()
+	})
+
+	// Output:
+	// Object[{
+	//   name => 'X',
+	//   attributes => {
+	//     'name' => String
+	//   },
+	//   functions => {
+	//     'int' => Callable[
+	//       [0, 0],
+	//       Integer],
+	//     'x' => Callable[
+	//       [0, 0],
+	//       String],
+	//     'y' => Callable[
+	//       [Any],
+	//       Any]
+	//   }
+	// }]
+}
+
+type contextUser struct {
+	Prefix string
+}
+
+func (u *contextUser) Greet(ctx context.Context, name string) string {
+	if ctx == nil {
+		return ``
+	}
+	return u.Prefix + ` ` + name
+}
+
+func (u *contextUser) Lookup(c eval.Context, name string) (string, error) {
+	if v, ok := c.Scope().Get(name); ok {
+		return v.String(), nil
+	}
+	return ``, fmt.Errorf(`no variable named '%s'`, name)
+}
+
+func ExampleReflect_TypeFromReflect_contextInjection() {
+	eval.Puppet.Do(func(c eval.Context) {
+		x := c.Reflector().TypeFromReflect(`X`, nil, reflect.TypeOf(&contextUser{}))
+		c.AddTypes(x)
+		x.ToString(os.Stdout, eval.PRETTY_EXPANDED, nil)
+		fmt.Println()
+
+		c.Scope().Set(`obj`, eval.Wrap(c, &contextUser{`Hello`}))
+		c.Scope().Set(`name`, types.WrapString(`Bob`))
+		fmt.Println(eval.Evaluate(c, c.ParseAndValidate(``, `$obj.greet('Alice')`, false)))
+		fmt.Println(eval.Evaluate(c, c.ParseAndValidate(``, `$obj.lookup('name')`, false)))
+	})
+
+	// Output:
+	// Object[{
+	//   name => 'X',
+	//   attributes => {
+	//     'prefix' => String
+	//   },
+	//   functions => {
+	//     'greet' => Callable[
+	//       [String],
+	//       String],
+	//     'lookup' => Callable[
+	//       [String],
+	//       String]
+	//   }
+	// }]
+	// Hello Alice
+	// Bob
+}
+
+func ExampleReflect_TypeFromReflect_contextInjectionError() {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		x := c.Reflector().TypeFromReflect(`X`, nil, reflect.TypeOf(&contextUser{}))
+		c.AddTypes(x)
+		c.Scope().Set(`obj`, eval.Wrap(c, &contextUser{`Hello`}))
+		eval.Evaluate(c, c.ParseAndValidate(``, `$obj.lookup('nope')`, false))
+		return nil
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+
+	// Output:
+	// Go function Lookup returned error 'no variable named 'nope'' (line: 1, column: 1)
+}
@@ -0,0 +1,113 @@
+package eval
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// A Capability is a named permission to access a host resource. Go function dispatchers that
+// touch the host declare the capabilities that they require using Dispatch.Requires.
+type Capability string
+
+const (
+	// CAPABILITY_FILE_READ permits reading files beneath the file roots of the SandboxPolicy
+	CAPABILITY_FILE_READ = Capability(`file_read`)
+
+	// CAPABILITY_DISK_LOADING permits loaders to read functions, types, plans, and tasks from disk
+	CAPABILITY_DISK_LOADING = Capability(`disk_loading`)
+
+	// CAPABILITY_EXEC permits plans to run commands, scripts, and tasks using a Transport
+	CAPABILITY_EXEC = Capability(`exec`)
+)
+
+// A SandboxPolicy declares what host access is granted to code evaluated using a Context. A
+// Context without a policy is not sandboxed.
+type SandboxPolicy struct {
+	// FileRoots are the directories that files may be read from. The CAPABILITY_FILE_READ is
+	// only granted when at least one root is present.
+	FileRoots []string
+
+	// DiskLoading grants the CAPABILITY_DISK_LOADING when true
+	DiskLoading bool
+
+	// Exec grants the CAPABILITY_EXEC when true
+	Exec bool
+
+	// Functions is the set of names of Go functions that may be called. All Go functions are
+	// callable when Functions is nil.
+	Functions map[string]bool
+}
+
+// Grants returns true if the receiver grants the given capability
+func (p *SandboxPolicy) Grants(capability Capability) bool {
+	switch capability {
+	case CAPABILITY_FILE_READ:
+		return len(p.FileRoots) > 0
+	case CAPABILITY_DISK_LOADING:
+		return p.DiskLoading
+	case CAPABILITY_EXEC:
+		return p.Exec
+	default:
+		return false
+	}
+}
+
+// AllowsFunction returns true if the receiver permits calls to the Go function with the given name
+func (p *SandboxPolicy) AllowsFunction(name string) bool {
+	return p.Functions == nil || p.Functions[name]
+}
+
+// AllowsPath returns true if the given path, once made absolute and stripped from symbolic
+// links, is contained in one of the file roots of the receiver
+func (p *SandboxPolicy) AllowsPath(path string) bool {
+	path = realPath(path)
+	for _, root := range p.FileRoots {
+		rel, err := filepath.Rel(realPath(root), path)
+		if err == nil && rel != `..` && !strings.HasPrefix(rel, `..`+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertCapability panics with an issue.Reported if the given context is sandboxed and the
+// capability required by subject isn't granted by its policy
+func AssertCapability(c Context, capability Capability, subject string) {
+	if p := c.Sandbox(); p != nil && !p.Grants(capability) {
+		panic(c.Error(nil, EVAL_SANDBOX_CAPABILITY_DENIED, issue.H{`capability`: capability, `subject`: subject}))
+	}
+}
+
+// AssertFunctionAllowed panics with an issue.Reported if the given context is sandboxed and its
+// policy does not permit calls to the Go function with the given name
+func AssertFunctionAllowed(c Context, name string) {
+	if p := c.Sandbox(); p != nil && !p.AllowsFunction(name) {
+		panic(c.Error(nil, EVAL_SANDBOX_FUNCTION_DENIED, issue.H{`name`: name}))
+	}
+}
+
+// AssertPathAllowed panics with an issue.Reported if the given context is sandboxed and the given
+// path is outside of the file roots of its policy
+func AssertPathAllowed(c Context, path string) {
+	if p := c.Sandbox(); p != nil && !(p.Grants(CAPABILITY_FILE_READ) && p.AllowsPath(path)) {
+		panic(c.Error(nil, EVAL_SANDBOX_PATH_DENIED, issue.H{`path`: path}))
+	}
+}
+
+func realPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+
+	// Path does not exist. Resolve what's possible using the directory
+	dir, file := filepath.Split(path)
+	if dir != path && dir != `` {
+		return filepath.Join(realPath(filepath.Clean(dir)), file)
+	}
+	return path
+}
@@ -48,5 +48,8 @@ type (
 
 		// State returns NotFound, Global, or Local
 		State(name string) VariableState
+
+		// VariableNames returns the sorted names of all named variables that are visible in this scope
+		VariableNames() []string
 	}
 )
@@ -0,0 +1,72 @@
+//go:build !nothreadlocal
+// +build !nothreadlocal
+
+package eval
+
+import (
+	"runtime"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/threadlocal"
+)
+
+// This file contains the compatibility shim that associates a Context with the current go routine. New
+// code should pass the Context explicitly. The shim is compiled out when building with the tag
+// nothreadlocal, in which case CurrentContext will always panic and DoWithContext and Fork will pass
+// the context along without associating it with the go routine.
+
+// CurrentContext returns the Context that is associated with the current go routine. It panics with
+// an EVAL_NO_CURRENT_CONTEXT issue when no such Context exists.
+//
+// Deprecated: Pass the Context explicitly
+func CurrentContext() Context {
+	if c, ok := TryCurrentContext(); ok {
+		return c
+	}
+	_, file, line, _ := runtime.Caller(1)
+	panic(issue.NewReported(EVAL_NO_CURRENT_CONTEXT, issue.SEVERITY_ERROR, issue.NO_ARGS, issue.NewLocation(file, line, 0)))
+}
+
+// TryCurrentContext returns the Context that is associated with the current go routine and true, or
+// nil and false when no such Context exists.
+//
+// Deprecated: Pass the Context explicitly
+func TryCurrentContext() (Context, bool) {
+	if c, ok := threadlocal.Get(PuppetContextKey); ok {
+		return c.(Context), true
+	}
+	return nil, false
+}
+
+// SetCurrentContext associates the given Context with the current go routine
+//
+// Deprecated: Pass the Context explicitly
+func SetCurrentContext(c Context) {
+	if _, ok := threadlocal.Get(PuppetContextKey); !ok {
+		threadlocal.Init()
+	}
+	threadlocal.Set(PuppetContextKey, c)
+}
+
+// DoWithContext calls the given actor with the given Context. The Context is associated with the
+// current go routine during the call.
+func DoWithContext(ctx Context, actor func(Context)) {
+	if saveCtx, ok := threadlocal.Get(PuppetContextKey); ok {
+		defer func() {
+			threadlocal.Set(PuppetContextKey, saveCtx)
+		}()
+	} else {
+		threadlocal.Init()
+		defer threadlocal.Cleanup()
+	}
+	threadlocal.Set(PuppetContextKey, ctx)
+	actor(ctx)
+}
+
+// Go calls the given function in a new go routine. The CurrentContext is forked and becomes
+// the CurrentContext for that routine.
+//
+// Deprecated: Use Fork with an explicit Context
+func Go(f ContextDoer) {
+	Fork(CurrentContext(), f)
+}
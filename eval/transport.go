@@ -0,0 +1,94 @@
+package eval
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/lyraproj/issue/issue"
+)
+
+// PlanContextKey is the key of the context variable that holds the name of the plan that is
+// currently executing
+const PlanContextKey = `puppet.plan`
+
+// A Transport runs commands, scripts, and tasks on a Target and uploads files to it. A Transport
+// reports failures of the executed command as an `_error` entry in the returned value. The returned
+// error is reserved for failures to communicate with the target.
+type Transport interface {
+	// Name returns the name that the transport is registered under
+	Name() string
+
+	// Available returns true if the target can be reached using this transport
+	Available(c Context, target PuppetObject) bool
+
+	// RunCommand runs the given command on the target
+	RunCommand(c Context, target PuppetObject, command string, options OrderedMap) (OrderedMap, error)
+
+	// RunScript runs the script at the given local path with the given arguments on the target
+	RunScript(c Context, target PuppetObject, script string, arguments []string, options OrderedMap) (OrderedMap, error)
+
+	// RunTask runs the given Task with the given arguments on the target
+	RunTask(c Context, target PuppetObject, task PuppetObject, arguments OrderedMap, options OrderedMap) (OrderedMap, error)
+
+	// UploadFile uploads the file or directory at the given local path to the destination on the target
+	UploadFile(c Context, target PuppetObject, source, destination string, options OrderedMap) (OrderedMap, error)
+}
+
+var transportsLock sync.RWMutex
+var transports = map[string]Transport{}
+
+// RegisterTransport makes the given transport available under its name. A previously registered
+// transport with the same name is replaced.
+func RegisterTransport(transport Transport) {
+	transportsLock.Lock()
+	transports[transport.Name()] = transport
+	transportsLock.Unlock()
+}
+
+// GetTransport returns the transport that is registered under the given name
+func GetTransport(name string) (Transport, bool) {
+	transportsLock.RLock()
+	t, ok := transports[name]
+	transportsLock.RUnlock()
+	return t, ok
+}
+
+// TransportFor returns the transport to use for the given target. The name of the transport is
+// taken from the `transport` option of the target, from the scheme of its host, or is `local`
+// when the host is `localhost` and `ssh` otherwise. This function panics with an issue.Reported
+// if no transport is registered under that name.
+func TransportFor(c Context, target PuppetObject) Transport {
+	host := ``
+	if h, ok := target.Get(`host`); ok {
+		host = h.String()
+	}
+
+	name := ``
+	if ov, ok := target.Get(`options`); ok {
+		if tn, ok := ov.(OrderedMap).Get4(`transport`); ok {
+			name = tn.String()
+		}
+	}
+	if name == `` {
+		switch {
+		case strings.Index(host, `://`) > 0:
+			name = host[:strings.Index(host, `://`)]
+		case host == `localhost`:
+			name = `local`
+		default:
+			name = `ssh`
+		}
+	}
+
+	if t, ok := GetTransport(name); ok {
+		return t
+	}
+	panic(c.Error(nil, EVAL_UNKNOWN_TRANSPORT, issue.H{`name`: name, `target`: host}))
+}
+
+// AssertInPlan panics with an issue.Reported unless the given context is executing a plan
+func AssertInPlan(c Context, function string) {
+	if _, ok := c.Get(PlanContextKey); !ok {
+		panic(c.Error(nil, EVAL_PLAN_ONLY_FUNCTION, issue.H{`name`: function}))
+	}
+}
@@ -457,6 +457,10 @@ var NewTypeAlias func(name, typeDecl string) Type
 
 var NewGoType func(name string, zeroValue interface{}) ObjectType
 
+// NewGoType2 is like NewGoType but the created type inherits from the type with the given name. The Go
+// struct of the zero value must embed the struct of the parent as its first field.
+var NewGoType2 func(name, parentName string, zeroValue interface{}) ObjectType
+
 var NewObjectType func(name, typeDecl string, creators ...DispatchFunction) ObjectType
 
 var NewTypeSet func(name, typeDecl string) TypeSet
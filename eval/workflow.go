@@ -0,0 +1,27 @@
+package eval
+
+import "github.com/lyraproj/puppet-parser/parser"
+
+// An Activity is a workflow, resource, action, or stateless activity. An activity consumes the
+// values of its input parameters and produces the values of its output parameters. A workflow
+// runs the activities that it contains in an order where each activity runs after the activities
+// that produce its input.
+type Activity interface {
+	Value
+
+	// Name returns the fully qualified name of the activity
+	Name() string
+
+	// Style returns the style of the activity
+	Style() parser.ActivityStyle
+
+	// Input returns the input parameters of the activity
+	Input() []Parameter
+
+	// Output returns the output parameters of the activity
+	Output() []Parameter
+
+	// Run runs the activity with the given input and returns a hash with the value of each output
+	// parameter. Input parameters that are missing in the given input get their default value.
+	Run(c Context, input OrderedMap) OrderedMap
+}
@@ -8,6 +8,7 @@ import (
 func init() {
 	eval.NewGoFunction(`binary_file`,
 		func(d eval.Dispatch) {
+			d.Requires(eval.CAPABILITY_FILE_READ)
 			d.Param(`String`)
 			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
 				return types.BinaryFromFile(c, args[0].String())
@@ -5,22 +5,34 @@ import (
 	"github.com/lyraproj/puppet-evaluator/types"
 )
 
+// selected calls grow when the given result selects an element
+func selected(result eval.Value, grow func()) bool {
+	if eval.IsTruthy(result) {
+		grow()
+		return true
+	}
+	return false
+}
+
 func selectIterator(c eval.Context, arg eval.IterableValue, block eval.Lambda) eval.List {
-	return arg.Iterator().Select(func(v eval.Value) bool { return eval.IsTruthy(block.Call(c, nil, v)) }).AsArray()
+	grow := sizeLimit(c)
+	return arg.Iterator().Select(func(v eval.Value) bool { return selected(block.Call(c, nil, v), grow) }).AsArray()
 }
 
 func selectIndexIterator(c eval.Context, iter eval.IterableValue, block eval.Lambda) eval.List {
+	grow := sizeLimit(c)
 	index := int64(-1)
 	return iter.Iterator().Select(func(v eval.Value) bool {
 		index++
-		return eval.IsTruthy(block.Call(c, nil, types.WrapInteger(index), v))
+		return selected(block.Call(c, nil, types.WrapInteger(index), v), grow)
 	}).AsArray()
 }
 
 func selectHashIterator(c eval.Context, iter eval.IterableValue, block eval.Lambda) eval.List {
+	grow := sizeLimit(c)
 	return iter.Iterator().Select(func(v eval.Value) bool {
 		vi := v.(eval.List)
-		return eval.IsTruthy(block.Call(c, nil, vi.At(0), vi.At(1)))
+		return selected(block.Call(c, nil, vi.At(0), vi.At(1)), grow)
 	}).AsArray()
 }
 
@@ -2,20 +2,49 @@ package functions
 
 import (
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
 )
 
+// flatSize returns the number of elements produced by flattening the given value. Counting stops as
+// soon as the count exceeds max.
+func flatSize(v eval.Value, max int) int {
+	switch v.(type) {
+	case *types.ArrayValue:
+		size := 0
+		v.(*types.ArrayValue).Find(func(e eval.Value) bool {
+			size += flatSize(e, max-size)
+			return size > max
+		})
+		return size
+	case *types.HashEntry:
+		he := v.(*types.HashEntry)
+		size := flatSize(he.Key(), max)
+		if size <= max {
+			size += flatSize(he.Value(), max-size)
+		}
+		return size
+	default:
+		return 1
+	}
+}
+
 func init() {
 	eval.NewGoFunction(`flatten`,
 		func(d eval.Dispatch) {
 			d.Param(`Iterable`)
 			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				var l eval.List
 				arg := args[0]
 				switch arg.(type) {
 				case eval.List:
-					return arg.(eval.List).Flatten()
+					l = arg.(eval.List)
 				default:
-					return arg.(eval.IterableValue).Iterator().AsArray().Flatten()
+					l = eval.AsLimitedArray(c, arg.(eval.IterableValue).Iterator())
+				}
+				if max := c.Limits().MaxCollectionSize; max > 0 {
+					eval.AssertCollectionSize(c, nil, `Array`, flatSize(l, max))
 				}
+				return l.Flatten()
 			})
 		},
 	)
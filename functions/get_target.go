@@ -0,0 +1,21 @@
+package functions
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`get_target`,
+		func(d eval.Dispatch) {
+			d.Param(targetsType)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `get_target`)
+				targets := targetsFromSpec(c, args[0])
+				if len(targets) != 1 {
+					panic(c.Error(c.StackTop(), eval.EVAL_EXPECTED_ONE_TARGET, issue.H{`spec`: args[0].String(), `count`: len(targets)}))
+				}
+				return targets[0]
+			})
+		})
+}
@@ -0,0 +1,22 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`get_targets`,
+		func(d eval.Dispatch) {
+			d.Param(targetsType)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `get_targets`)
+				targets := targetsFromSpec(c, args[0])
+				values := make([]eval.Value, len(targets))
+				for i, t := range targets {
+					values[i] = t
+				}
+				return types.WrapValues(values)
+			})
+		})
+}
@@ -5,20 +5,38 @@ import (
 	"github.com/lyraproj/puppet-evaluator/types"
 )
 
+// sizeLimit returns a function that is called each time an Array is about to grow by one element. It
+// asserts that the size of the Array stays within the maximum collection size of the given Context.
+func sizeLimit(c eval.Context) func() {
+	size := 0
+	return func() {
+		size++
+		eval.AssertCollectionSize(c, nil, `Array`, size)
+	}
+}
+
 func mapIterator(c eval.Context, arg eval.IterableValue, block eval.Lambda) eval.List {
-	return arg.Iterator().Map(block.Signature().ReturnType(), func(v eval.Value) eval.Value { return block.Call(c, nil, v) }).AsArray()
+	grow := sizeLimit(c)
+	return arg.Iterator().Map(block.Signature().ReturnType(), func(v eval.Value) eval.Value {
+		grow()
+		return block.Call(c, nil, v)
+	}).AsArray()
 }
 
 func mapIndexIterator(c eval.Context, iter eval.IterableValue, block eval.Lambda) eval.List {
+	grow := sizeLimit(c)
 	index := int64(-1)
 	return iter.Iterator().Map(block.Signature().ReturnType(), func(v eval.Value) eval.Value {
+		grow()
 		index++
 		return block.Call(c, nil, types.WrapInteger(index), v)
 	}).AsArray()
 }
 
 func mapHashIterator(c eval.Context, iter eval.IterableValue, block eval.Lambda) eval.List {
+	grow := sizeLimit(c)
 	return iter.Iterator().Map(block.Signature().ReturnType(), func(v eval.Value) eval.Value {
+		grow()
 		vi := v.(eval.List)
 		return block.Call(c, nil, vi.At(0), vi.At(1))
 	}).AsArray()
@@ -0,0 +1,59 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/inventory"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// targetsType is the parameter type used by plan functions that operate on targets
+const targetsType = `TargetSpec`
+
+// targetsFromSpec returns the targets that the given TargetSpec denotes in the inventory of
+// the given context
+func targetsFromSpec(c eval.Context, spec eval.Value) []eval.PuppetObject {
+	return inventory.Get(c).Targets(c, spec)
+}
+
+// runOnTargets calls run with the transport of each given target and returns the results in a
+// ResultSet. An error returned from run is converted into an error Result. The function panics
+// with an issue.Reported if some result is an error unless the option _catch_errors is true.
+func runOnTargets(c eval.Context, action string, targets []eval.PuppetObject, options eval.OrderedMap,
+	run func(transport eval.Transport, target eval.PuppetObject) (eval.OrderedMap, error)) *types.ResultSetValue {
+	results := make([]*types.ResultValue, len(targets))
+	for i, target := range targets {
+		value, err := run(eval.TransportFor(c, target), target)
+		if err != nil {
+			results[i] = types.NewErrorResult(target, err.Error(), `puppetlabs.tasks/connect-error`, nil)
+		} else {
+			results[i] = types.NewResult(target, value)
+		}
+	}
+	return assertResultSet(c, action, types.NewResultSet(results), options)
+}
+
+func assertResultSet(c eval.Context, action string, rs *types.ResultSetValue, options eval.OrderedMap) *types.ResultSetValue {
+	if rs.Ok() || eval.IsTruthy(options.Get5(`_catch_errors`, types.Boolean_FALSE)) {
+		return rs
+	}
+	failed := rs.ErrorSet()
+	names, _ := failed.Get(`names`)
+	panic(c.Error(c.StackTop(), eval.EVAL_RUN_FAILED, issue.H{
+		`action`: action, `count`: len(failed.Results()), `names`: strings.Join(stringSlice(names.(eval.List)), `, `)}))
+}
+
+func optionsArg(args []eval.Value, index int) eval.OrderedMap {
+	if len(args) > index {
+		return args[index].(eval.OrderedMap)
+	}
+	return eval.EMPTY_MAP
+}
+
+func stringSlice(list eval.List) []string {
+	strs := make([]string, list.Len())
+	list.EachWithIndex(func(e eval.Value, i int) { strs[i] = e.String() })
+	return strs
+}
@@ -0,0 +1,24 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`run_command`,
+		func(d eval.Dispatch) {
+			d.Requires(eval.CAPABILITY_EXEC)
+			d.Param(`String[1]`)
+			d.Param(targetsType)
+			d.OptionalParam(`Hash[String[1], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `run_command`)
+				command := args[0].String()
+				options := optionsArg(args, 2)
+				return runOnTargets(c, `run_command`, targetsFromSpec(c, args[1]), options,
+					func(transport eval.Transport, target eval.PuppetObject) (eval.OrderedMap, error) {
+						return transport.RunCommand(c, target, command, options)
+					})
+			})
+		})
+}
@@ -0,0 +1,29 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`run_script`,
+		func(d eval.Dispatch) {
+			d.Requires(eval.CAPABILITY_FILE_READ, eval.CAPABILITY_EXEC)
+			d.Param(`String[1]`)
+			d.Param(targetsType)
+			d.OptionalParam(`Struct[{Optional[arguments] => Array[String], Optional['_catch_errors'] => Boolean}]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `run_script`)
+				script := args[0].String()
+				eval.AssertPathAllowed(c, script)
+				options := optionsArg(args, 2)
+				var arguments []string
+				if al, ok := options.Get4(`arguments`); ok {
+					arguments = stringSlice(al.(eval.List))
+				}
+				return runOnTargets(c, `run_script`, targetsFromSpec(c, args[1]), options,
+					func(transport eval.Transport, target eval.PuppetObject) (eval.OrderedMap, error) {
+						return transport.RunScript(c, target, script, arguments, options)
+					})
+			})
+		})
+}
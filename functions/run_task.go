@@ -0,0 +1,41 @@
+package functions
+
+import (
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`run_task`,
+		func(d eval.Dispatch) {
+			d.Requires(eval.CAPABILITY_EXEC)
+			d.Param(`Variant[Task, String[1]]`)
+			d.Param(targetsType)
+			d.OptionalParam(`Hash[String[1], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `run_task`)
+				task := loadTask(c, args[0])
+				options := optionsArg(args, 2)
+				// Options that start with an underscore control the run and are not passed to the task
+				arguments := options.RejectPairs(func(k, v eval.Value) bool { return strings.HasPrefix(k.String(), `_`) }).(eval.OrderedMap)
+				arguments = types.ValidateTaskParameters(task, arguments)
+				return runOnTargets(c, `run_task`, targetsFromSpec(c, args[1]), options,
+					func(transport eval.Transport, target eval.PuppetObject) (eval.OrderedMap, error) {
+						return transport.RunTask(c, target, task, arguments, options)
+					})
+			})
+		})
+}
+
+func loadTask(c eval.Context, tv eval.Value) eval.PuppetObject {
+	if name, ok := tv.(*types.StringValue); ok {
+		if t, ok := eval.Load(c, eval.NewTypedName(eval.NsTask, name.String())); ok {
+			return t.(eval.PuppetObject)
+		}
+		panic(c.Error(c.StackTop(), eval.EVAL_UNKNOWN_TASK, issue.H{`name`: name.String()}))
+	}
+	return tv.(eval.PuppetObject)
+}
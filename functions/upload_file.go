@@ -0,0 +1,27 @@
+package functions
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func init() {
+	eval.NewGoFunction(`upload_file`,
+		func(d eval.Dispatch) {
+			d.Requires(eval.CAPABILITY_FILE_READ, eval.CAPABILITY_EXEC)
+			d.Param(`String[1]`)
+			d.Param(`String[1]`)
+			d.Param(targetsType)
+			d.OptionalParam(`Hash[String[1], Any]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `upload_file`)
+				source := args[0].String()
+				eval.AssertPathAllowed(c, source)
+				destination := args[1].String()
+				options := optionsArg(args, 3)
+				return runOnTargets(c, `upload_file`, targetsFromSpec(c, args[2]), options,
+					func(transport eval.Transport, target eval.PuppetObject) (eval.OrderedMap, error) {
+						return transport.UploadFile(c, target, source, destination, options)
+					})
+			})
+		})
+}
@@ -0,0 +1,54 @@
+package functions
+
+import (
+	"time"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func init() {
+	eval.NewGoFunction(`wait_until_available`,
+		func(d eval.Dispatch) {
+			d.Requires(eval.CAPABILITY_EXEC)
+			d.Param(targetsType)
+			d.OptionalParam(`Struct[{Optional[wait_time] => Numeric, Optional[retry_interval] => Numeric, Optional['_catch_errors'] => Boolean}]`)
+			d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+				eval.AssertInPlan(c, `wait_until_available`)
+				options := optionsArg(args, 1)
+				deadline := time.Now().Add(secondsOption(options, `wait_time`, 120))
+				interval := secondsOption(options, `retry_interval`, 1)
+
+				targets := targetsFromSpec(c, args[0])
+				results := make([]*types.ResultValue, len(targets))
+				for i, target := range targets {
+					transport := eval.TransportFor(c, target)
+					for !transport.Available(c, target) {
+						if time.Now().Add(interval).After(deadline) {
+							results[i] = types.NewErrorResult(target, `Timed out waiting for target`, `puppetlabs.tasks/connect-error`, nil)
+							break
+						}
+						select {
+						case <-c.Done():
+							results[i] = types.NewErrorResult(target, c.Err().Error(), `puppetlabs.tasks/connect-error`, nil)
+						case <-time.After(interval):
+						}
+						if results[i] != nil {
+							break
+						}
+					}
+					if results[i] == nil {
+						results[i] = types.NewResult(target, eval.EMPTY_MAP)
+					}
+				}
+				return assertResultSet(c, `wait_until_available`, types.NewResultSet(results), options)
+			})
+		})
+}
+
+func secondsOption(options eval.OrderedMap, key string, dflt float64) time.Duration {
+	if n, ok := options.Get4(key); ok {
+		dflt = n.(eval.NumericValue).Float()
+	}
+	return time.Duration(dflt * float64(time.Second))
+}
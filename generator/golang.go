@@ -0,0 +1,323 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// GenerateGo writes Go source code for the given types to the given writer. The code declares a struct
+// for each Object type and a string type with one constant per value for each Enum, be it a type alias
+// or the type of an attribute. Parents of the given Object types are included. An init function declares
+// the types so that they are mapped to the structs by the ImplementationRegistry once they are resolved.
+//
+// A struct field has a 'puppet' tag when the name, type, kind, or value of its attribute cannot be derived
+// from the field itself. The functions of an Object type are not generated.
+func GenerateGo(packageName string, ts []eval.Type, w io.Writer) {
+	g := &goGenerator{goNames: make(map[string]string), imports: map[string]bool{`github.com/lyraproj/puppet-evaluator/eval`: true}}
+	for _, t := range ts {
+		g.addType(t)
+	}
+
+	bld := bytes.NewBufferString(``)
+	for _, t := range g.objects {
+		g.writeStruct(bld, t)
+	}
+	g.writeInit(bld)
+
+	src := bytes.NewBufferString("// Code generated by the puppet-evaluator Go generator. DO NOT EDIT.\n\n")
+	fmt.Fprintf(src, "package %s\n\n", packageName)
+	g.writeImports(src)
+	for _, e := range g.enums {
+		e.write(src)
+	}
+	src.Write(bld.Bytes())
+
+	fs, err := format.Source(src.Bytes())
+	if err != nil {
+		panic(fmt.Errorf(`unable to format generated Go code: %s`, err.Error()))
+	}
+	w.Write(fs)
+}
+
+// GenerateGoTypeSet is like GenerateGo but generates the types of the given TypeSet
+func GenerateGoTypeSet(packageName string, ts eval.TypeSet, w io.Writer) {
+	tl := make([]eval.Type, 0, ts.Types().Len())
+	ts.Types().EachValue(func(t eval.Value) { tl = append(tl, t.(eval.Type)) })
+	GenerateGo(packageName, tl, w)
+}
+
+type goGenerator struct {
+	// Go name of each generated Object type or Enum alias, keyed by the name of the Pcore type
+	goNames map[string]string
+	imports map[string]bool
+	objects []eval.ObjectType
+	aliases []*types.TypeAliasType
+	enums   []*goEnum
+}
+
+type goEnum struct {
+	name   string
+	source string
+	values []string
+}
+
+func (g *goGenerator) addType(t eval.Type) {
+	if _, ok := g.goNames[t.Name()]; ok {
+		return
+	}
+	switch t := t.(type) {
+	case eval.ObjectType:
+		if t.Name() == `` || t.Equals(types.DefaultObjectType(), nil) {
+			return
+		}
+		if p := t.Parent(); p != nil {
+			g.addType(p)
+		}
+		g.goNames[t.Name()] = g.uniqueGoName(t.Name())
+		g.objects = append(g.objects, t)
+	case *types.TypeAliasType:
+		if et, ok := t.ResolvedType().(*types.EnumType); ok {
+			g.goNames[t.Name()] = g.addEnum(g.uniqueGoName(t.Name()), `the Pcore type `+t.Name(), et)
+		}
+		g.aliases = append(g.aliases, t)
+	}
+}
+
+// addEnum adds a declaration of a string type with the given name and one constant for each value
+// of the given Enum and returns the name. The source describes where the Enum is declared.
+func (g *goGenerator) addEnum(name, source string, t *types.EnumType) string {
+	vs, _ := t.Get(`values`)
+	values := make([]string, 0, vs.(eval.List).Len())
+	vs.(eval.List).Each(func(v eval.Value) { values = append(values, v.String()) })
+	g.enums = append(g.enums, &goEnum{name, source, values})
+	return name
+}
+
+func (g *goGenerator) hasGoName(name string) bool {
+	for _, n := range g.goNames {
+		if n == name {
+			return true
+		}
+	}
+	for _, e := range g.enums {
+		if e.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueGoName returns the Go name for the type with the given qualified name. It is the last segment of
+// the name unless that segment is already used for another type, in which case all segments are joined,
+// e.g. B::Foo becomes BFoo when A::Foo is named Foo.
+func (g *goGenerator) uniqueGoName(name string) string {
+	gn := goName(name)
+	if g.hasGoName(gn) {
+		gn = strings.Replace(name, `::`, ``, -1)
+		if g.hasGoName(gn) {
+			panic(fmt.Errorf(`unable to find a unique Go name for %s`, name))
+		}
+	}
+	return gn
+}
+
+// goType returns the Go type that represents the given Pcore type and the Pcore type that the reflector
+// derives from that Go type, or nil when the derived type never matches. The enumName is the name to use
+// when an Enum type must be declared and the source describes where that Enum is declared.
+func (g *goGenerator) goType(enumName string, t eval.Type, source string) (string, eval.Type) {
+	switch t := t.(type) {
+	case *types.TypeAliasType:
+		if gn, ok := g.goNames[t.Name()]; ok {
+			return gn, types.DefaultStringType()
+		}
+		return g.goType(enumName, t.ResolvedType(), source)
+	case *types.OptionalType:
+		gt, nt := g.goType(enumName, t.ContainedType(), source)
+		if strings.HasPrefix(gt, `*`) || isInterface(gt) {
+			// Already nilable
+			return gt, nt
+		}
+		if nt != nil {
+			nt = types.NewOptionalType(nt)
+		}
+		return `*` + gt, nt
+	case *types.EnumType:
+		if enumName != `` && !g.hasGoName(enumName) {
+			return g.addEnum(enumName, `the type of `+source, t), types.DefaultStringType()
+		}
+		return `string`, types.DefaultStringType()
+	case *types.StringType, *types.PatternType:
+		return `string`, types.DefaultStringType()
+	case *types.IntegerType:
+		return `int64`, types.DefaultIntegerType()
+	case *types.FloatType:
+		return `float64`, types.DefaultFloatType()
+	case *types.BooleanType:
+		return `bool`, types.DefaultBooleanType()
+	case *types.TimestampType:
+		g.imports[`time`] = true
+		return `time.Time`, types.DefaultTimestampType()
+	case *types.TimespanType:
+		g.imports[`time`] = true
+		return `time.Duration`, types.DefaultTimespanType()
+	case *types.SemVerType:
+		g.imports[`github.com/lyraproj/semver/semver`] = true
+		return `semver.Version`, types.DefaultSemVerType()
+	case *types.RegexpType:
+		g.imports[`regexp`] = true
+		return `*regexp.Regexp`, types.NewOptionalType(types.DefaultRegexpType())
+	case *types.UriType:
+		g.imports[`net/url`] = true
+		return `*url.URL`, types.NewOptionalType(types.DefaultUriType())
+	case *types.BinaryType:
+		return `[]byte`, nil
+	case *types.ArrayType:
+		et, nt := g.goType(enumName, t.ElementType(), source)
+		if nt != nil {
+			nt = types.NewArrayType(nt, nil)
+		}
+		return `[]` + et, nt
+	case *types.HashType:
+		kt, nk := g.goType(``, t.KeyType(), source)
+		vt, nv := g.goType(enumName, t.ValueType(), source)
+		var nt eval.Type
+		if nk != nil && nv != nil {
+			nt = types.NewHashType(nk, nv, nil)
+		}
+		return `map[` + kt + `]` + vt, nt
+	case eval.ObjectType:
+		if gn, ok := g.goNames[t.Name()]; ok {
+			return `*` + gn, t
+		}
+		return `eval.PuppetObject`, types.DefaultObjectType()
+	default:
+		return `eval.Value`, types.DefaultAnyType()
+	}
+}
+
+func (g *goGenerator) writeImports(b *bytes.Buffer) {
+	ips := make([]string, 0, len(g.imports))
+	for ip := range g.imports {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	b.WriteString("import (\n")
+	for _, ip := range ips {
+		fmt.Fprintf(b, "\t%q\n", ip)
+	}
+	b.WriteString("\n\t// Initialize the functions used by init\n\t_ \"github.com/lyraproj/puppet-evaluator/types\"\n")
+	b.WriteString(")\n\n")
+}
+
+func (g *goGenerator) writeStruct(b *bytes.Buffer, t eval.ObjectType) {
+	name := g.goNames[t.Name()]
+	fmt.Fprintf(b, "// %s is the Go implementation of the Pcore type %s\n", name, t.Name())
+	fmt.Fprintf(b, "type %s struct {\n", name)
+	if p := t.Parent(); p != nil {
+		fmt.Fprintf(b, "\t%s\n", g.goNames[p.Name()])
+	}
+	// Attributes are generated in declaration order
+	t.(attributeIterator).EachAttribute(false, func(a eval.Attribute) {
+		fieldName := issue.SnakeToCamelCase(a.Name())
+		gt, nt := g.goType(name+fieldName, a.Type(), fmt.Sprintf(`attribute %s[%s]`, t.Name(), a.Name()))
+		fmt.Fprintf(b, "\t%s %s", fieldName, gt)
+		if tag := fieldTag(a, fieldName, nt); tag != `` {
+			fmt.Fprintf(b, " %s", quote(`puppet:`+fmt.Sprintf(`%q`, tag)))
+		}
+		b.WriteByte('\n')
+	})
+	b.WriteString("}\n\n")
+}
+
+type attributeIterator interface {
+	EachAttribute(includeParent bool, consumer func(attr eval.Attribute))
+}
+
+// fieldTag returns the contents of the 'puppet' tag for the struct field of the given attribute or an
+// empty string when the field needs no tag. The nt is the type that the reflector derives from the field.
+func fieldTag(a eval.Attribute, fieldName string, nt eval.Type) string {
+	entries := make([]string, 0, 4)
+	if issue.CamelToSnakeCase(fieldName) != a.Name() {
+		entries = append(entries, fmt.Sprintf(`name=>'%s'`, a.Name()))
+	}
+	if a.Kind() != types.DEFAULT_KIND {
+		entries = append(entries, fmt.Sprintf(`kind=>%s`, a.Kind()))
+	}
+	if a.HasValue() && !eval.Equals(a.Value(), eval.UNDEF) {
+		// An optional attribute without a value gets an implicit undef value
+		entries = append(entries, `value=>`+eval.ToString2(a.Value(), types.PROGRAM))
+	}
+	if nt == nil || !nt.Equals(a.Type(), nil) {
+		entries = append(entries, `type=>`+a.Type().String())
+	}
+	return strings.Join(entries, `, `)
+}
+
+func (g *goGenerator) writeInit(b *bytes.Buffer) {
+	b.WriteString("func init() {\n")
+	for _, a := range g.aliases {
+		fmt.Fprintf(b, "\teval.NewTypeAlias(%s, %s)\n", quote(a.Name()), quote(a.ResolvedType().String()))
+	}
+	for _, t := range g.objects {
+		if p := t.Parent(); p != nil {
+			fmt.Fprintf(b, "\teval.NewGoType2(%s, %s, %s{})\n", quote(t.Name()), quote(p.Name()), g.goNames[t.Name()])
+		} else {
+			fmt.Fprintf(b, "\teval.NewGoType(%s, %s{})\n", quote(t.Name()), g.goNames[t.Name()])
+		}
+	}
+	b.WriteString("}\n")
+}
+
+func (e *goEnum) write(b *bytes.Buffer) {
+	fmt.Fprintf(b, "// %s is the Go representation of %s\n", e.name, e.source)
+	fmt.Fprintf(b, "type %s string\n\nconst (\n", e.name)
+	for _, v := range e.values {
+		fmt.Fprintf(b, "\t%s%s %s = %s\n", e.name, identifier(v), e.name, quote(v))
+	}
+	b.WriteString(")\n\n")
+}
+
+// goName returns the last segment of the given qualified name
+func goName(name string) string {
+	if i := strings.LastIndex(name, `::`); i >= 0 {
+		return name[i+2:]
+	}
+	return name
+}
+
+// identifier returns the camel cased form of the given string with all characters that are not letters
+// or digits removed.
+func identifier(s string) string {
+	return issue.SnakeToCamelCase(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, s))
+}
+
+func isInterface(goType string) bool {
+	switch goType {
+	case `eval.Value`, `eval.PuppetObject`, `semver.Version`:
+		return true
+	}
+	return false
+}
+
+// quote returns the given string as a Go raw string literal, or as an interpreted string literal
+// when it contains a back quote.
+func quote(s string) string {
+	if strings.ContainsRune(s, '`') {
+		return fmt.Sprintf(`%q`, s)
+	}
+	return "`" + s + "`"
+}
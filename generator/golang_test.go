@@ -0,0 +1,182 @@
+package generator_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/generator"
+
+	// Declare the types generated from the typeSet
+	_ "github.com/lyraproj/puppet-evaluator/generator/testdata/my"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const typeSet = `TypeSet[{
+  name => 'My',
+  version => '1.0.0',
+  pcore_version => '1.0.0',
+  types => {
+    Color => Enum[red, green, 'dark blue'],
+    Address => {
+      attributes => {
+        street => String,
+        zip_code => { type => Optional[String], value => '12345' },
+        line_2 => Optional[String],
+        home_page => Optional[URI]
+      }
+    },
+    Person => {
+      attributes => {
+        name => String[1],
+        age => Optional[Integer],
+        address => Address,
+        favorite => Color,
+        mood => Enum[happy, sad],
+        born => Timestamp,
+        tags => Hash[String, Array[String]],
+        extra => Optional[Data]
+      }
+    },
+    ExtendedPerson => {
+      parent => Person,
+      attributes => {
+        enabled => { type => Boolean, value => true },
+        pattern => Regexp,
+        version => SemVer
+      }
+    }
+  }
+}]`
+
+func ExampleGenerateGoTypeSet() {
+	eval.Puppet.Do(func(c eval.Context) {
+		ts := c.ParseType2(typeSet)
+		c.AddTypes(ts)
+		generator.GenerateGoTypeSet(`my`, ts.(eval.TypeSet), os.Stdout)
+	})
+	// Output:
+	// // Code generated by the puppet-evaluator Go generator. DO NOT EDIT.
+	//
+	// package my
+	//
+	// import (
+	// 	"github.com/lyraproj/puppet-evaluator/eval"
+	// 	"github.com/lyraproj/semver/semver"
+	// 	"net/url"
+	// 	"regexp"
+	// 	"time"
+	//
+	// 	// Initialize the functions used by init
+	// 	_ "github.com/lyraproj/puppet-evaluator/types"
+	// )
+	//
+	// // Color is the Go representation of the Pcore type My::Color
+	// type Color string
+	//
+	// const (
+	// 	ColorRed      Color = `red`
+	// 	ColorGreen    Color = `green`
+	// 	ColorDarkBlue Color = `dark blue`
+	// )
+	//
+	// // PersonMood is the Go representation of the type of attribute My::Person[mood]
+	// type PersonMood string
+	//
+	// const (
+	// 	PersonMoodHappy PersonMood = `happy`
+	// 	PersonMoodSad   PersonMood = `sad`
+	// )
+	//
+	// // Address is the Go implementation of the Pcore type My::Address
+	// type Address struct {
+	// 	Street   string
+	// 	ZipCode  *string `puppet:"value=>'12345'"`
+	// 	Line2    *string `puppet:"name=>'line_2'"`
+	// 	HomePage *url.URL
+	// }
+	//
+	// // Person is the Go implementation of the Pcore type My::Person
+	// type Person struct {
+	// 	Name     string `puppet:"type=>String[1]"`
+	// 	Age      *int64
+	// 	Address  *Address
+	// 	Favorite Color      `puppet:"type=>My::Color"`
+	// 	Mood     PersonMood `puppet:"type=>Enum['happy', 'sad']"`
+	// 	Born     time.Time
+	// 	Tags     map[string][]string
+	// 	Extra    eval.Value `puppet:"type=>Optional[Data]"`
+	// }
+	//
+	// // ExtendedPerson is the Go implementation of the Pcore type My::ExtendedPerson
+	// type ExtendedPerson struct {
+	// 	Person
+	// 	Enabled bool           `puppet:"value=>true"`
+	// 	Pattern *regexp.Regexp `puppet:"type=>Regexp"`
+	// 	Version semver.Version
+	// }
+	//
+	// func init() {
+	// 	eval.NewTypeAlias(`My::Color`, `Enum['red', 'green', 'dark blue']`)
+	// 	eval.NewGoType(`My::Address`, Address{})
+	// 	eval.NewGoType(`My::Person`, Person{})
+	// 	eval.NewGoType2(`My::ExtendedPerson`, `My::Person`, ExtendedPerson{})
+	// }
+}
+
+func TestGenerateGoRoundtrip(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		// The types of the package my are declared by the init function of its generated source
+		ts := c.ParseType2(typeSet).(eval.TypeSet)
+		b := bytes.NewBufferString(``)
+		c.DoWithLoader(eval.NewParentedLoader(c.Loader()), func() {
+			c.AddTypes(ts)
+			generator.GenerateGoTypeSet(`my`, ts, b)
+		})
+		src, err := ioutil.ReadFile(`testdata/my/my.go`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != string(src) {
+			t.Fatalf("testdata/my/my.go is not the generated source:\n%s", b)
+		}
+
+		ts.Types().EachValue(func(v eval.Value) {
+			tt := v.(eval.Type)
+			gt, ok := eval.Load(c, eval.NewTypedName(eval.NsType, tt.Name()))
+			if !ok {
+				t.Errorf(`type %s is not declared by the generated source`, tt.Name())
+			} else if !tt.Equals(gt, nil) {
+				t.Errorf(`expected %s, got %s`, eval.ToString2(tt, eval.PRETTY_EXPANDED), eval.ToString2(gt.(eval.Type), eval.PRETTY_EXPANDED))
+			}
+		})
+	})
+}
+
+func TestGenerateGoNameClash(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		c.AddDefinitions(c.ParseAndValidate(``, `
+      type A::Foo = Object[attributes => { x => String }]
+      type B::Foo = Object[attributes => { a => A::Foo }]
+      type C::Foo = Enum[x, y]`, false))
+		c.ResolveDefinitions()
+		var ts []eval.Type
+		for _, n := range []string{`A::Foo`, `B::Foo`, `C::Foo`} {
+			tp, _ := eval.Load(c, eval.NewTypedName(eval.NsType, n))
+			ts = append(ts, tp.(eval.Type))
+		}
+		b := bytes.NewBufferString(``)
+		generator.GenerateGo(`foo`, ts, b)
+		src := b.String()
+		for _, decl := range []string{"type Foo struct", "type BFoo struct", "\tA *Foo\n", "type CFoo string"} {
+			if !strings.Contains(src, decl) {
+				t.Errorf("expected generated source to contain %q:\n%s", decl, src)
+			}
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Code generated by the puppet-evaluator Go generator. DO NOT EDIT.
+
+package my
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/semver/semver"
+	"net/url"
+	"regexp"
+	"time"
+
+	// Initialize the functions used by init
+	_ "github.com/lyraproj/puppet-evaluator/types"
+)
+
+// Color is the Go representation of the Pcore type My::Color
+type Color string
+
+const (
+	ColorRed      Color = `red`
+	ColorGreen    Color = `green`
+	ColorDarkBlue Color = `dark blue`
+)
+
+// PersonMood is the Go representation of the type of attribute My::Person[mood]
+type PersonMood string
+
+const (
+	PersonMoodHappy PersonMood = `happy`
+	PersonMoodSad   PersonMood = `sad`
+)
+
+// Address is the Go implementation of the Pcore type My::Address
+type Address struct {
+	Street   string
+	ZipCode  *string `puppet:"value=>'12345'"`
+	Line2    *string `puppet:"name=>'line_2'"`
+	HomePage *url.URL
+}
+
+// Person is the Go implementation of the Pcore type My::Person
+type Person struct {
+	Name     string `puppet:"type=>String[1]"`
+	Age      *int64
+	Address  *Address
+	Favorite Color      `puppet:"type=>My::Color"`
+	Mood     PersonMood `puppet:"type=>Enum['happy', 'sad']"`
+	Born     time.Time
+	Tags     map[string][]string
+	Extra    eval.Value `puppet:"type=>Optional[Data]"`
+}
+
+// ExtendedPerson is the Go implementation of the Pcore type My::ExtendedPerson
+type ExtendedPerson struct {
+	Person
+	Enabled bool           `puppet:"value=>true"`
+	Pattern *regexp.Regexp `puppet:"type=>Regexp"`
+	Version semver.Version
+}
+
+func init() {
+	eval.NewTypeAlias(`My::Color`, `Enum['red', 'green', 'dark blue']`)
+	eval.NewGoType(`My::Address`, Address{})
+	eval.NewGoType(`My::Person`, Person{})
+	eval.NewGoType2(`My::ExtendedPerson`, `My::Person`, ExtendedPerson{})
+}
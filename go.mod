@@ -1,8 +1,12 @@
 module github.com/lyraproj/puppet-evaluator
 
+go 1.17
+
 require (
+	github.com/golang/protobuf v1.2.0
 	github.com/lyraproj/data-protobuf v0.0.0-20181217135414-3d508204b820
 	github.com/lyraproj/issue v0.0.0-20181208172701-8d203563a8dc
 	github.com/lyraproj/puppet-parser v0.0.0-20181212205830-31c3104fe78d
 	github.com/lyraproj/semver v0.0.0-20181213164306-02ecea2cd6a2
+	gopkg.in/yaml.v3 v3.0.1
 )
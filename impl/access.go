@@ -182,7 +182,7 @@ func eval_ParameterizedTypeExpression(e eval.Evaluator, qr *parser.QualifiedRefe
 	case `hash`:
 		tp = types.NewHashType2(args...)
 	case `init`:
-		tp = types.NewInitType2(args...)
+		tp = types.NewInitType2(args...).Resolve(e)
 	case `integer`:
 		tp = types.NewIntegerType2(args...)
 	case `iterable`:
@@ -190,7 +190,9 @@ func eval_ParameterizedTypeExpression(e eval.Evaluator, qr *parser.QualifiedRefe
 	case `iterator`:
 		tp = types.NewIteratorType2(args...)
 	case `like`:
-		tp = types.NewLikeType2(args...)
+		lt := types.NewLikeType2(args...)
+		lt.Resolve(e)
+		tp = lt
 	case `notundef`:
 		tp = types.NewNotUndefType2(args...)
 	case `object`:
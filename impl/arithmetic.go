@@ -1,6 +1,8 @@
 package impl
 
 import (
+	"math"
+	"math/big"
 	"strconv"
 
 	"github.com/lyraproj/puppet-evaluator/errors"
@@ -11,31 +13,34 @@ import (
 )
 
 func evalArithmeticExpression(e eval.Evaluator, expr *parser.ArithmeticExpression) eval.Value {
-	return calculate(expr, e.Eval(expr.Lhs()), e.Eval(expr.Rhs()))
+	return calculate(e, expr, e.Eval(expr.Lhs()), e.Eval(expr.Rhs()))
 }
 
-func calculate(expr *parser.ArithmeticExpression, a eval.Value, b eval.Value) eval.Value {
+func calculate(e eval.Evaluator, expr *parser.ArithmeticExpression, a eval.Value, b eval.Value) eval.Value {
 	op := expr.Operator()
 	switch a.(type) {
 	case *types.HashValue, *types.ArrayValue, *types.UriValue:
 		switch op {
 		case `+`:
-			return concatenate(expr, a, b)
+			return concatenate(e, expr, a, b)
 		case `-`:
 			return collectionDelete(expr, a, b)
 		case `<<`:
 			if av, ok := a.(*types.ArrayValue); ok {
+				eval.AssertCollectionSize(e, expr, `Array`, av.Len()+1)
 				return av.Add(b)
 			}
 		}
 	case *types.FloatValue:
 		return lhsFloatArithmetic(expr, a.(*types.FloatValue).Float(), b)
 	case *types.IntegerValue:
-		return lhsIntArithmetic(expr, a.(*types.IntegerValue).Int(), b)
+		return lhsIntArithmetic(e, expr, a.(*types.IntegerValue).Int(), b)
+	case *types.BigIntegerValue:
+		return lhsBigIntArithmetic(e, expr, a.(*types.BigIntegerValue), b)
 	case *types.StringValue:
 		sv := a.(*types.StringValue)
 		if iv, err := strconv.ParseInt(sv.String(), 0, 64); err == nil {
-			return lhsIntArithmetic(expr, iv, b)
+			return lhsIntArithmetic(e, expr, iv, b)
 		}
 		if fv, err := strconv.ParseFloat(sv.String(), 64); err == nil {
 			return lhsFloatArithmetic(expr, fv, b)
@@ -45,17 +50,19 @@ func calculate(expr *parser.ArithmeticExpression, a eval.Value, b eval.Value) ev
 	panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE, expr, issue.H{`operator`: op, `left`: a.PType()}))
 }
 
-func lhsIntArithmetic(expr *parser.ArithmeticExpression, ai int64, b eval.Value) eval.Value {
+func lhsIntArithmetic(e eval.Evaluator, expr *parser.ArithmeticExpression, ai int64, b eval.Value) eval.Value {
 	op := expr.Operator()
 	switch b.(type) {
 	case *types.IntegerValue:
-		return types.WrapInteger(intArithmetic(expr, ai, b.(*types.IntegerValue).Int()))
+		return intArithmetic(e, expr, ai, b.(*types.IntegerValue).Int())
+	case *types.BigIntegerValue:
+		return bigIntArithmetic(e, expr, big.NewInt(ai), b.(*types.BigIntegerValue).BigInt())
 	case *types.FloatValue:
 		return types.WrapFloat(floatArithmetic(expr, float64(ai), b.(*types.FloatValue).Float()))
 	case *types.StringValue:
 		bv := b.(*types.StringValue)
 		if iv, err := strconv.ParseInt(bv.String(), 0, 64); err == nil {
-			return types.WrapInteger(intArithmetic(expr, ai, iv))
+			return intArithmetic(e, expr, ai, iv)
 		}
 		if fv, err := strconv.ParseFloat(bv.String(), 64); err == nil {
 			return types.WrapFloat(floatArithmetic(expr, float64(ai), fv))
@@ -66,6 +73,27 @@ func lhsIntArithmetic(expr *parser.ArithmeticExpression, ai int64, b eval.Value)
 	}
 }
 
+func lhsBigIntArithmetic(e eval.Evaluator, expr *parser.ArithmeticExpression, a *types.BigIntegerValue, b eval.Value) eval.Value {
+	switch b.(type) {
+	case *types.IntegerValue, *types.BigIntegerValue:
+		bi, _ := types.ToBigInt(b)
+		return bigIntArithmetic(e, expr, a.BigInt(), bi)
+	case *types.FloatValue:
+		return types.WrapFloat(floatArithmetic(expr, a.Float(), b.(*types.FloatValue).Float()))
+	case *types.StringValue:
+		bv := b.(*types.StringValue)
+		if bi, ok := big.NewInt(0).SetString(bv.String(), 0); ok {
+			return bigIntArithmetic(e, expr, a.BigInt(), bi)
+		}
+		if fv, err := strconv.ParseFloat(bv.String(), 64); err == nil {
+			return types.WrapFloat(floatArithmetic(expr, a.Float(), fv))
+		}
+		panic(evalError(eval.EVAL_NOT_NUMERIC, expr.Rhs(), issue.H{`value`: bv.String()}))
+	default:
+		panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE_WHEN, expr, issue.H{`operator`: expr.Operator(), `left`: `Integer`, `right`: b.PType()}))
+	}
+}
+
 func lhsFloatArithmetic(expr *parser.ArithmeticExpression, af float64, b eval.Value) eval.Value {
 	op := expr.Operator()
 	switch b.(type) {
@@ -73,6 +101,8 @@ func lhsFloatArithmetic(expr *parser.ArithmeticExpression, af float64, b eval.Va
 		return types.WrapFloat(floatArithmetic(expr, af, b.(*types.FloatValue).Float()))
 	case *types.IntegerValue:
 		return types.WrapFloat(floatArithmetic(expr, af, float64(b.(*types.IntegerValue).Int())))
+	case *types.BigIntegerValue:
+		return types.WrapFloat(floatArithmetic(expr, af, b.(*types.BigIntegerValue).Float()))
 	case *types.StringValue:
 		bv := b.(*types.StringValue)
 		if iv, err := strconv.ParseInt(bv.String(), 0, 64); err == nil {
@@ -102,42 +132,213 @@ func floatArithmetic(expr *parser.ArithmeticExpression, a float64, b float64) fl
 	}
 }
 
-func intArithmetic(expr *parser.ArithmeticExpression, a int64, b int64) int64 {
+// intArithmetic performs the arithmetic operation of the given expression. An operation that overflows
+// will either produce a BigIntegerValue or panic with an EVAL_INTEGER_OVERFLOW depending on the setting
+// integer_promotion.
+func intArithmetic(e eval.Evaluator, expr *parser.ArithmeticExpression, a int64, b int64) eval.Value {
+	var r int64
+	overflow := false
 	switch expr.Operator() {
 	case `+`:
-		return a + b
+		r = a + b
+		overflow = (a^r)&(b^r) < 0
 	case `-`:
-		return a - b
+		r = a - b
+		overflow = (a^b)&(a^r) < 0
 	case `*`:
-		return a * b
+		r = a * b
+		overflow = a != 0 && (r/a != b || a == -1 && b == math.MinInt64)
 	case `/`:
-		return a / b
+		if b == 0 {
+			panic(evalError(eval.EVAL_DIVISION_BY_ZERO, expr, issue.NO_ARGS))
+		}
+		overflow = a == math.MinInt64 && b == -1
+		if !overflow {
+			r = a / b
+		}
 	case `%`:
-		return a % b
+		if b == 0 {
+			panic(evalError(eval.EVAL_DIVISION_BY_ZERO, expr, issue.NO_ARGS))
+		}
+		if b != -1 {
+			r = a % b
+		}
 	case `<<`:
-		return a << uint(b)
+		r, overflow = shiftLeft(a, b)
 	case `>>`:
-		return a >> uint(b)
+		r, overflow = shiftLeft(a, negateShift(b))
 	default:
 		panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE, expr, issue.H{`operator`: expr.Operator(), `left`: `Integer`}))
 	}
+	if overflow {
+		return bigIntArithmetic(e, expr, big.NewInt(a), big.NewInt(b))
+	}
+	return types.WrapInteger(r)
+}
+
+// shiftLeft shifts a left by b bits. A negative b shifts right. The returned boolean is true
+// if the shift overflows.
+func shiftLeft(a int64, b int64) (int64, bool) {
+	if b < 0 {
+		if b <= -64 {
+			if a < 0 {
+				return -1, false
+			}
+			return 0, false
+		}
+		return a >> uint(-b), false
+	}
+	if a == 0 {
+		return 0, false
+	}
+	if b >= 64 {
+		return 0, true
+	}
+	r := a << uint(b)
+	return r, r>>uint(b) != a
 }
 
-func concatenate(expr *parser.ArithmeticExpression, a eval.Value, b eval.Value) eval.Value {
+func negateShift(b int64) int64 {
+	if b == math.MinInt64 {
+		return math.MaxInt64
+	}
+	return -b
+}
+
+// bigIntArithmetic performs the arithmetic operation of the given expression using arbitrary precision. The
+// result is normalized into an IntegerValue when possible. The operation will panic with an EVAL_INTEGER_OVERFLOW
+// unless the result fits in an int64 or the setting integer_promotion is enabled.
+func bigIntArithmetic(e eval.Evaluator, expr *parser.ArithmeticExpression, a *big.Int, b *big.Int) eval.Value {
+	assertIntegerBits(e, expr, a, b)
+	r := big.NewInt(0)
+	switch expr.Operator() {
+	case `+`:
+		r.Add(a, b)
+	case `-`:
+		r.Sub(a, b)
+	case `*`:
+		r.Mul(a, b)
+	case `/`:
+		if b.Sign() == 0 {
+			panic(evalError(eval.EVAL_DIVISION_BY_ZERO, expr, issue.NO_ARGS))
+		}
+		r.Quo(a, b)
+	case `%`:
+		if b.Sign() == 0 {
+			panic(evalError(eval.EVAL_DIVISION_BY_ZERO, expr, issue.NO_ARGS))
+		}
+		r.Rem(a, b)
+	case `<<`, `>>`:
+		if !b.IsInt64() || b.Int64() > math.MaxUint32 || b.Int64() < -math.MaxUint32 {
+			panic(evalError(eval.EVAL_INTEGER_OVERFLOW, expr, issue.H{`left`: a, `operator`: expr.Operator(), `right`: b}))
+		}
+		n := b.Int64()
+		if expr.Operator() == `>>` {
+			n = -n
+		}
+		if n < 0 {
+			r.Rsh(a, uint(-n))
+		} else {
+			r.Lsh(a, uint(n))
+		}
+	default:
+		panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE, expr, issue.H{`operator`: expr.Operator(), `left`: `Integer`}))
+	}
+	if !r.IsInt64() && !integerPromotion() {
+		panic(evalError(eval.EVAL_INTEGER_OVERFLOW, expr, issue.H{`left`: a, `operator`: expr.Operator(), `right`: b}))
+	}
+	if max := e.Limits().MaxCollectionSize; max > 0 {
+		if size := integerSize(r.BitLen()); size > max {
+			panic(evalError(eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED, expr, issue.H{`type`: `Integer`, `size`: size, `max`: max}))
+		}
+	}
+	return types.WrapBigInteger(r)
+}
+
+// maxIntegerBits is the maximum number of bits of an integer produced by an arithmetic operation
+const maxIntegerBits = 1 << 20
+
+// assertIntegerBits asserts, before the operation is performed, that the result of the arithmetic
+// operation of the given expression cannot exceed the maximum size of an integer. The maximum is
+// given by maxIntegerBits and by the maximum collection size of the evaluator, where the size of
+// an integer is its number of bytes.
+func assertIntegerBits(e eval.Evaluator, expr *parser.ArithmeticExpression, a *big.Int, b *big.Int) {
+	al := int64(a.BitLen())
+	bl := int64(b.BitLen())
+
+	// The bits are an upper bound for the result. It is exact for shifts and at most one bit too
+	// large for the other operations.
+	var bits int64
+	exact := false
+	switch expr.Operator() {
+	case `+`, `-`:
+		bits = al + 1
+		if bl >= al {
+			bits = bl + 1
+		}
+	case `*`:
+		bits = al + bl
+	case `<<`, `>>`:
+		if !b.IsInt64() {
+			panic(evalError(eval.EVAL_INTEGER_OVERFLOW, expr, issue.H{`left`: a, `operator`: expr.Operator(), `right`: b}))
+		}
+		bits = al
+		if n := b.Int64(); expr.Operator() == `<<` && n > 0 && a.Sign() != 0 {
+			bits = al + n
+		} else if expr.Operator() == `>>` && n < 0 && a.Sign() != 0 {
+			bits = al - n
+		}
+		exact = true
+	default:
+		bits = al
+	}
+
+	max := int64(maxIntegerBits)
+	if exact && !integerPromotion() {
+		// The result of a shift is known to overflow without computing it
+		max = 64
+	}
+	if bits > max {
+		panic(evalError(eval.EVAL_INTEGER_OVERFLOW, expr, issue.H{`left`: a, `operator`: expr.Operator(), `right`: b}))
+	}
+	if mc := e.Limits().MaxCollectionSize; mc > 0 {
+		if !exact {
+			bits--
+		}
+		if size := integerSize(int(bits)); size > mc {
+			panic(evalError(eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED, expr, issue.H{`type`: `Integer`, `size`: size, `max`: mc}))
+		}
+	}
+}
+
+// integerSize returns the number of bytes needed to store an integer with the given number of bits
+func integerSize(bits int) int {
+	return (bits + 7) / 8
+}
+
+func integerPromotion() bool {
+	return eval.GetSetting(`integer_promotion`, types.Boolean_FALSE).(*types.BooleanValue).Bool()
+}
+
+func concatenate(e eval.Evaluator, expr *parser.ArithmeticExpression, a eval.Value, b eval.Value) eval.Value {
 	switch a.(type) {
 	case *types.ArrayValue:
 		av := a.(*types.ArrayValue)
 		switch b.(type) {
 		case *types.ArrayValue:
+			eval.AssertCollectionSize(e, expr, `Array`, av.Len()+b.(*types.ArrayValue).Len())
 			return av.AddAll(b.(*types.ArrayValue))
 
 		case *types.HashValue:
+			eval.AssertCollectionSize(e, expr, `Array`, av.Len()+b.(*types.HashValue).Len())
 			return av.AddAll(b.(*types.HashValue))
 
 		default:
+			eval.AssertCollectionSize(e, expr, `Array`, av.Len()+1)
 			return av.Add(b)
 		}
 	case *types.HashValue:
+		hv := a.(*types.HashValue)
 		switch b.(type) {
 		case *types.ArrayValue:
 			defer func() {
@@ -150,9 +351,13 @@ func concatenate(expr *parser.ArithmeticExpression, a eval.Value, b eval.Value)
 					panic(err)
 				}
 			}()
-			return a.(*types.HashValue).Merge(types.WrapHashFromArray(b.(*types.ArrayValue)))
+			bh := types.WrapHashFromArray(b.(*types.ArrayValue))
+			eval.AssertCollectionSize(e, expr, `Hash`, mergedSize(hv, bh))
+			return hv.Merge(bh)
 		case *types.HashValue:
-			return a.(*types.HashValue).Merge(b.(*types.HashValue))
+			bh := b.(*types.HashValue)
+			eval.AssertCollectionSize(e, expr, `Hash`, mergedSize(hv, bh))
+			return hv.Merge(bh)
 		}
 	case *types.UriValue:
 		switch b.(type) {
@@ -165,6 +370,17 @@ func concatenate(expr *parser.ArithmeticExpression, a eval.Value, b eval.Value)
 	panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE_WHEN, expr, issue.H{`operator`: expr.Operator(), `left`: a.PType(), `right`: b.PType()}))
 }
 
+// mergedSize returns the size of the hash that results from merging b into a
+func mergedSize(a *types.HashValue, b *types.HashValue) int {
+	size := a.Len()
+	b.EachKey(func(k eval.Value) {
+		if !a.IncludesKey(k) {
+			size++
+		}
+	})
+	return size
+}
+
 func collectionDelete(expr *parser.ArithmeticExpression, a eval.Value, b eval.Value) eval.Value {
 	switch a.(type) {
 	case *types.ArrayValue:
@@ -0,0 +1,61 @@
+package impl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		source  string
+		promote bool
+		result  string
+		code    issue.Code
+	}{
+		{`9223372036854775807 + 1`, false, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`-9223372036854775807 - 2`, false, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`4611686018427387904 * 2`, false, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`1 << 63`, false, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`1 / 0`, false, ``, eval.EVAL_DIVISION_BY_ZERO},
+		{`1 % 0`, false, ``, eval.EVAL_DIVISION_BY_ZERO},
+		{`8 << -2`, false, `2`, ``},
+		{`8 >> -2`, false, `32`, ``},
+		{`-8 >> 70`, false, `-1`, ``},
+		{`9223372036854775807 + 1`, true, `9223372036854775808`, ``},
+		{`(9223372036854775807 + 1) - 1`, true, `9223372036854775807`, ``},
+		{`1 << 4294967295`, false, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`1 << 4294967295`, true, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`1 << 9223372036854775807`, true, ``, eval.EVAL_INTEGER_OVERFLOW},
+		{`(1 << 64) =~ Integer`, true, `true`, ``},
+		{`(1 << 64) =~ Integer[0]`, true, `true`, ``},
+		{`(1 << 64) =~ Integer[0, 100]`, true, `false`, ``},
+		{`(0 - (1 << 64)) =~ Integer[0]`, true, `false`, ``},
+		{`(0 - (1 << 64)) =~ Integer[default, 0]`, true, `true`, ``},
+		{`Integer[0, 1 << 64]`, true, ``, eval.EVAL_ARGUMENTS_ERROR},
+		{`(1 << 64) > 9223372036854775807`, true, `true`, ``},
+		{`sprintf('%x', 1 << 64)`, true, `10000000000000000`, ``},
+		{`sprintf('%#b', 1 << 65)`, true, `0b1` + strings.Repeat(`0`, 65), ``},
+	}
+	defer eval.Puppet.Reset()
+	for _, tc := range tests {
+		eval.Puppet.Set(`integer_promotion`, types.WrapBoolean(tc.promote))
+		if result, code := evaluate(nil, tc.source); code != tc.code || result != tc.result {
+			t.Errorf(`%s: expected '%s%s', got '%s%s'`, tc.source, tc.result, tc.code, result, code)
+		}
+	}
+}
+
+func TestBigIntegerCollectionLimit(t *testing.T) {
+	eval.Puppet.Set(`integer_promotion`, types.WrapBoolean(true))
+	defer eval.Puppet.Reset()
+	for _, source := range []string{`1 << 1000`, `(1 << 300) * (1 << 300)`, `(1 << 511) + (1 << 511)`} {
+		_, code := evaluate(func(c eval.Context) { c.SetLimits(eval.Limits{MaxCollectionSize: 64}) }, source)
+		if code != eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED {
+			t.Errorf(`%s: expected issue '%s', got '%s'`, source, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED, code)
+		}
+	}
+}
@@ -108,6 +108,9 @@ func compareMagnitude(expr parser.Expression, op string, a eval.Value, b eval.Va
 	case eval.NumericValue:
 		if rhv, ok := b.(eval.NumericValue); ok {
 			cmp := a.(eval.NumericValue).Float() - rhv.Float()
+			if bc, ok := compareBigIntegers(a, b); ok {
+				cmp = float64(bc)
+			}
 			switch op {
 			case `<`:
 				return cmp < 0.0
@@ -128,6 +131,21 @@ func compareMagnitude(expr parser.Expression, op string, a eval.Value, b eval.Va
 	panic(evalError(eval.EVAL_OPERATOR_NOT_APPLICABLE_WHEN, expr, issue.H{`operator`: op, `left`: a.PType(), `right`: b.PType()}))
 }
 
+// compareBigIntegers compares two integers using arbitrary precision when at least one of them is a
+// BigIntegerValue since float conversion of such a value is lossy
+func compareBigIntegers(a eval.Value, b eval.Value) (int, bool) {
+	_, aBig := a.(*types.BigIntegerValue)
+	_, bBig := b.(*types.BigIntegerValue)
+	if aBig || bBig {
+		if bia, ok := types.ToBigInt(a); ok {
+			if bib, ok := types.ToBigInt(b); ok {
+				return bia.Cmp(bib), true
+			}
+		}
+	}
+	return 0, false
+}
+
 func match(c eval.Context, lhs parser.Expression, rhs parser.Expression, operator string, updateScope bool, a eval.Value, b eval.Value) bool {
 	result := false
 	switch b.(type) {
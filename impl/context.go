@@ -4,14 +4,13 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-evaluator/eval"
-	"github.com/lyraproj/puppet-evaluator/threadlocal"
 	"github.com/lyraproj/puppet-evaluator/types"
 	"github.com/lyraproj/puppet-parser/parser"
 	"github.com/lyraproj/puppet-parser/validator"
-	"runtime"
 )
 
 type (
@@ -26,6 +25,17 @@ type (
 		static       bool
 		definitions  []interface{}
 		vars         map[string]interface{}
+		budget       *budget
+		sandbox      *eval.SandboxPolicy
+		coverage     eval.Coverage
+		debugger     eval.Debugger
+		profiler     eval.Profiler
+	}
+
+	// budget is shared between a context and all contexts forked from it
+	budget struct {
+		eval.Limits
+		steps int64
 	}
 )
 
@@ -41,14 +51,6 @@ func init() {
 		panic(issue.NewReported(eval.EVAL_UNKNOWN_FUNCTION, issue.SEVERITY_ERROR, issue.H{`name`: tn.String()}, c.StackTop()))
 	}
 
-	eval.CurrentContext = func() eval.Context {
-		if ctx, ok := threadlocal.Get(eval.PuppetContextKey); ok {
-			return ctx.(eval.Context)
-		}
-		_, file, line, _ := runtime.Caller(1)
-		panic(issue.NewReported(eval.EVAL_NO_CURRENT_CONTEXT, issue.SEVERITY_ERROR, issue.NO_ARGS, issue.NewLocation(file, line, 0)))
-	}
-
 	eval.RegisterGoFunction = func(function eval.ResolvableFunction) {
 		resolvableFunctionsLock.Lock()
 		resolvableFunctions = append(resolvableFunctions, function)
@@ -60,17 +62,20 @@ func NewContext(evaluatorCtor func(c eval.Context) eval.Evaluator, loader eval.L
 	return WithParent(context.Background(), evaluatorCtor, loader, logger, newImplementationRegistry())
 }
 
+// WithParent creates a new context with the given loader, logger, and implementation registry. A parent
+// that is a context created by this package is forked so that the new context inherits its scope,
+// sandbox policy, limits, coverage, debugger, and profiler.
 func WithParent(parent context.Context, evaluatorCtor func(c eval.Context) eval.Evaluator, loader eval.Loader, logger eval.Logger, ir eval.ImplementationRegistry) eval.Context {
 	var c *evalCtx
 	ir = newParentedImplementationRegistry(ir)
-	if cp, ok := parent.(evalCtx); ok {
-		c = cp.clone()
-		c.Context = parent
+	if cp, ok := parent.(*evalCtx); ok {
+		c = cp.Fork().(*evalCtx)
 		c.loader = loader
 		c.logger = logger
+		c.implRegistry = ir
 		c.evaluator = evaluatorCtor(c)
 	} else {
-		c = &evalCtx{Context: parent, loader: loader, logger: logger, stack: make([]issue.Location, 0, 8), implRegistry: ir}
+		c = &evalCtx{Context: parent, loader: loader, logger: logger, stack: make([]issue.Location, 0, 8), implRegistry: ir, budget: &budget{Limits: eval.LimitsFromSettings()}}
 		c.evaluator = evaluatorCtor(c)
 	}
 	return c
@@ -107,6 +112,14 @@ func (c *evalCtx) DefiningLoader() eval.DefiningLoader {
 	}
 }
 
+func (c *evalCtx) Coverage() eval.Coverage {
+	return c.coverage
+}
+
+func (c *evalCtx) Debugger() eval.Debugger {
+	return c.debugger
+}
+
 func (c *evalCtx) Delete(key string) {
 	if c.vars != nil {
 		delete(c.vars, key)
@@ -166,6 +179,9 @@ func (c *evalCtx) Fork() eval.Context {
 	clone.implRegistry = newParentedImplementationRegistry(clone.implRegistry)
 	clone.evaluator = NewEvaluator(clone)
 	clone.stack = s
+	if c.profiler != nil {
+		clone.profiler = c.profiler.Fork()
+	}
 
 	if c.vars != nil {
 		cv := make(map[string]interface{}, len(c.vars))
@@ -194,6 +210,10 @@ func (c *evalCtx) ImplementationRegistry() eval.ImplementationRegistry {
 	return c.implRegistry
 }
 
+func (c *evalCtx) Limits() eval.Limits {
+	return c.budget.Limits
+}
+
 func (c *evalCtx) Loader() eval.Loader {
 	return c.loader
 }
@@ -220,7 +240,7 @@ func (c *evalCtx) ParseAndValidate(filename, str string, singleExpression bool)
 	if len(issues) > 0 {
 		severity := issue.SEVERITY_IGNORE
 		for _, i := range issues {
-			c.Logger().Log(eval.LogLevel(i.Severity()), types.WrapString(i.String()))
+			c.Logger().LogIssue(i)
 			if i.Severity() > severity {
 				severity = i.Severity()
 			}
@@ -243,6 +263,10 @@ func (c *evalCtx) ParseType2(str string) eval.Type {
 	return c.ResolveType(c.ParseAndValidate(``, str, true))
 }
 
+func (c *evalCtx) Profiler() eval.Profiler {
+	return c.profiler
+}
+
 func (c *evalCtx) Reflector() eval.Reflector {
 	return types.NewReflector(c)
 }
@@ -300,6 +324,10 @@ func (c *evalCtx) ResolveType(expr parser.Expression) eval.Type {
 	panic(fmt.Sprintf(`Expression "%s" does no resolve to a Type`, expr.String()))
 }
 
+func (c *evalCtx) Sandbox() *eval.SandboxPolicy {
+	return c.sandbox
+}
+
 func (c *evalCtx) Scope() eval.Scope {
 	if c.scope == nil {
 		c.scope = NewScope(false)
@@ -315,6 +343,26 @@ func (c *evalCtx) Set(key string, value interface{}) {
 	}
 }
 
+func (c *evalCtx) SetCoverage(coverage eval.Coverage) {
+	c.coverage = coverage
+}
+
+func (c *evalCtx) SetDebugger(debugger eval.Debugger) {
+	c.debugger = debugger
+}
+
+func (c *evalCtx) SetProfiler(profiler eval.Profiler) {
+	c.profiler = profiler
+}
+
+func (c *evalCtx) SetLimits(limits eval.Limits) {
+	c.budget = &budget{Limits: limits}
+}
+
+func (c *evalCtx) SetSandbox(policy *eval.SandboxPolicy) {
+	c.sandbox = policy
+}
+
 func (c *evalCtx) Stack() []issue.Location {
 	return c.stack
 }
@@ -324,6 +372,9 @@ func (c *evalCtx) StackPop() {
 }
 
 func (c *evalCtx) StackPush(location issue.Location) {
+	if max := c.budget.MaxStackDepth; max > 0 && len(c.stack) >= max {
+		panic(c.Error(location, eval.EVAL_MAX_STACK_DEPTH_EXCEEDED, issue.H{`max`: max}))
+	}
 	c.stack = append(c.stack, location)
 }
 
@@ -335,6 +386,18 @@ func (c *evalCtx) StackTop() issue.Location {
 	return c.stack[s-1]
 }
 
+func (c *evalCtx) Step(location issue.Location) {
+	if max := c.budget.MaxSteps; max > 0 && atomic.AddInt64(&c.budget.steps, 1) > max {
+		panic(c.Error(location, eval.EVAL_MAX_STEPS_EXCEEDED, issue.H{`max`: max}))
+	}
+	if c.coverage != nil {
+		c.coverage.Hit(location)
+	}
+	if c.debugger != nil {
+		c.debugger.BeforeStep(c, location)
+	}
+}
+
 func (c *evalCtx) Static() bool {
 	return c.static
 }
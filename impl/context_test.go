@@ -0,0 +1,50 @@
+package impl_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func TestWithParentInheritsPolicies(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		policy := &eval.SandboxPolicy{}
+		c.SetSandbox(policy)
+		c.SetLimits(eval.Limits{MaxSteps: 1000})
+		cc := impl.WithParent(c, impl.NewEvaluator, eval.NewParentedLoader(c.Loader()), eval.NewArrayLogger(), c.ImplementationRegistry())
+		if cc.Sandbox() != policy {
+			t.Errorf(`expected the sandbox policy of the parent, got %v`, cc.Sandbox())
+		}
+		if cc.Limits().MaxSteps != 1000 {
+			t.Errorf(`expected the limits of the parent, got %v`, cc.Limits())
+		}
+		_, err := eval.TopEvaluate(cc, cc.ParseAndValidate(``, `binary_file('/etc/hosts')`, false))
+		if err == nil || err.Code() != eval.EVAL_SANDBOX_CAPABILITY_DENIED {
+			t.Errorf(`expected %s, got %v`, eval.EVAL_SANDBOX_CAPABILITY_DENIED, err)
+		}
+	})
+}
+
+func TestParseAndValidateLogsIssues(t *testing.T) {
+	logger := eval.NewArrayLogger()
+	eval.Puppet.Try(func(c eval.Context) error {
+		c = impl.WithParent(c, impl.NewEvaluator, c.Loader(), logger, c.ImplementationRegistry())
+		c.ParseAndValidate(`test.pp`, `$x::y = 1`, true)
+		return nil
+	})
+	entries := logger.Entries(eval.ERR)
+	if len(entries) != 1 {
+		t.Fatalf(`expected one error, got %d`, len(entries))
+	}
+	re, ok := entries[0].(*eval.ReportedEntry)
+	if !ok {
+		t.Fatalf(`expected the issue to be logged, got %T`, entries[0])
+	}
+	if loc := re.Issue().Location(); loc == nil || loc.File() != `test.pp` {
+		t.Errorf(`expected the issue to have a location in test.pp, got %v`, loc)
+	}
+}
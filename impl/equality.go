@@ -22,10 +22,20 @@ func init() {
 			switch b.(type) {
 			case *types.IntegerValue:
 				return lhs == b.(*types.IntegerValue).Int()
+			case *types.BigIntegerValue:
+				return false
 			case eval.NumericValue:
 				return float64(lhs) == b.(eval.NumericValue).Float()
 			}
 			return false
+		case *types.BigIntegerValue:
+			switch b.(type) {
+			case *types.BigIntegerValue:
+				return a.Equals(b, nil)
+			case *types.FloatValue:
+				return a.(eval.NumericValue).Float() == b.(*types.FloatValue).Float()
+			}
+			return false
 		case *types.FloatValue:
 			lhs := a.(*types.FloatValue).Float()
 			if rhv, ok := b.(eval.NumericValue); ok {
@@ -96,7 +96,11 @@ func init() {
 	eval.TopEvaluate = topEvaluate
 
 	eval.Error = func(issueCode issue.Code, args issue.H) issue.Reported {
-		return issue.NewReported(issueCode, issue.SEVERITY_ERROR, args, eval.CurrentContext().StackTop())
+		var location issue.Location
+		if c, ok := eval.TryCurrentContext(); ok {
+			location = c.StackTop()
+		}
+		return issue.NewReported(issueCode, issue.SEVERITY_ERROR, args, location)
 	}
 
 	eval.Error2 = func(location issue.Location, issueCode issue.Code, args issue.H) issue.Reported {
@@ -104,9 +108,18 @@ func init() {
 	}
 
 	eval.Warning = func(issueCode issue.Code, args issue.H) issue.Reported {
-		c := eval.CurrentContext()
-		ri := issue.NewReported(issueCode, issue.SEVERITY_WARNING, args, c.StackTop())
-		c.Logger().LogIssue(ri)
+		var location issue.Location
+		var logger eval.Logger
+		if c, ok := eval.TryCurrentContext(); ok {
+			location = c.StackTop()
+			logger = c.Logger()
+		} else if eval.Puppet != nil {
+			logger = eval.Puppet.Logger()
+		}
+		ri := issue.NewReported(issueCode, issue.SEVERITY_WARNING, args, location)
+		if logger != nil {
+			logger.LogIssue(ri)
+		}
 		return ri
 	}
 }
@@ -258,7 +271,9 @@ func evalBlockExpression(e eval.Evaluator, expr *parser.BlockExpression) (result
 func evalConcatenatedString(e eval.Evaluator, expr *parser.ConcatenatedString) eval.Value {
 	bld := bytes.NewBufferString(``)
 	for _, s := range expr.Segments() {
-		bld.WriteString(e.Eval(s).(*types.StringValue).String())
+		str := e.Eval(s).(*types.StringValue).String()
+		eval.AssertCollectionSize(e, expr, `String`, bld.Len()+len(str))
+		bld.WriteString(str)
 	}
 	return types.WrapString(bld.String())
 }
@@ -541,6 +556,32 @@ func evalError(code issue.Code, location issue.Location, args issue.H) issue.Rep
 
 // BasicEval is exported to enable the evaluator to be extended
 func BasicEval(e eval.Evaluator, expr parser.Expression) eval.Value {
+	e.Step(expr)
+	return checkSize(e, expr, basicEval(e, expr))
+}
+
+// checkSize asserts that an Array, Hash, or String produced by the given expression doesn't exceed
+// the maximum collection size of the evaluator
+func checkSize(e eval.Evaluator, expr parser.Expression, v eval.Value) eval.Value {
+	if e.Limits().MaxCollectionSize <= 0 {
+		return v
+	}
+	var size int
+	switch v.(type) {
+	case *types.ArrayValue:
+		size = v.(*types.ArrayValue).Len()
+	case *types.HashValue:
+		size = v.(*types.HashValue).Len()
+	case *types.StringValue:
+		size = len(v.(*types.StringValue).String())
+	default:
+		return v
+	}
+	eval.AssertCollectionSize(e, expr, v.PType().Name(), size)
+	return v
+}
+
+func basicEval(e eval.Evaluator, expr parser.Expression) eval.Value {
 	switch expr.(type) {
 	case *parser.AccessExpression:
 		return evalAccessExpression(e, expr.(*parser.AccessExpression))
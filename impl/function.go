@@ -37,6 +37,7 @@ type (
 		blockType     eval.Type
 		optionalBlock bool
 		returnType    eval.Type
+		capabilities  []eval.Capability
 		function      eval.DispatchFunction
 		function2     eval.DispatchFunctionWithBlock
 	}
@@ -47,7 +48,9 @@ type (
 	}
 
 	lambda struct {
-		signature *types.CallableType
+		signature    *types.CallableType
+		name         string
+		capabilities []eval.Capability
 	}
 
 	goLambda struct {
@@ -125,7 +128,14 @@ func (l *lambda) Signature() eval.Signature {
 	return l.signature
 }
 
+func (l *lambda) assertCapabilities(c eval.Context) {
+	for _, cap := range l.capabilities {
+		eval.AssertCapability(c, cap, fmt.Sprintf(`function %s`, l.name))
+	}
+}
+
 func (l *goLambda) Call(c eval.Context, block eval.Lambda, args ...eval.Value) (result eval.Value) {
+	l.assertCapabilities(c)
 	result = l.function(c, args)
 	return
 }
@@ -135,6 +145,7 @@ func (l *goLambda) Parameters() []eval.Parameter {
 }
 
 func (l *goLambdaWithBlock) Call(c eval.Context, block eval.Lambda, args ...eval.Value) (result eval.Value) {
+	l.assertCapabilities(c)
 	result = l.function(c, args, block)
 	return
 }
@@ -229,9 +240,9 @@ func (db *dispatchBuilder) createDispatch(c eval.Context) eval.Lambda {
 		db.returnType = c.ParseType2(r.TypeString())
 	}
 	if db.function2 == nil {
-		return &goLambda{lambda{types.NewCallableType(types.NewTupleType(db.types, types.NewIntegerType(db.min, db.max)), db.returnType, nil)}, db.function}
+		return &goLambda{lambda{types.NewCallableType(types.NewTupleType(db.types, types.NewIntegerType(db.min, db.max)), db.returnType, nil), db.fb.name, db.capabilities}, db.function}
 	}
-	return &goLambdaWithBlock{lambda{types.NewCallableType(types.NewTupleType(db.types, types.NewIntegerType(db.min, db.max)), db.returnType, db.blockType)}, db.function2}
+	return &goLambdaWithBlock{lambda{types.NewCallableType(types.NewTupleType(db.types, types.NewIntegerType(db.min, db.max)), db.returnType, db.blockType), db.fb.name, db.capabilities}, db.function2}
 }
 
 func (db *dispatchBuilder) Name() string {
@@ -314,6 +325,10 @@ func (db *dispatchBuilder) Returns2(tp eval.Type) {
 	db.returnType = tp
 }
 
+func (db *dispatchBuilder) Requires(capabilities ...eval.Capability) {
+	db.capabilities = append(db.capabilities, capabilities...)
+}
+
 func (db *dispatchBuilder) Function(df eval.DispatchFunction) {
 	if _, ok := db.blockType.(*types.CallableType); ok {
 		panic(`Dispatch requires a block. Use FunctionWithBlock`)
@@ -335,6 +350,11 @@ func (db *dispatchBuilder) assertNotAfterRepeated() {
 }
 
 func (f *goFunction) Call(c eval.Context, block eval.Lambda, args ...eval.Value) eval.Value {
+	eval.AssertFunctionAllowed(c, f.name)
+	if p := c.Profiler(); p != nil {
+		p.Enter(f.name, nil)
+		defer p.Exit()
+	}
 	for _, d := range f.dispatchers {
 		if d.Signature().CallableWith(args, block) {
 			return d.Call(c, block, args...)
@@ -400,6 +420,10 @@ func (l *puppetLambda) Call(c eval.Context, block eval.Lambda, args ...eval.Valu
 	if block != nil {
 		panic(errors.NewArgumentsError(`lambda`, `nested lambdas are not supported`))
 	}
+	if p := c.Profiler(); p != nil {
+		p.Enter(`lambda`, l.expression)
+		defer p.Exit()
+	}
 	defer func() {
 		if err := recover(); err != nil {
 			if ni, ok := err.(*errors.NextIteration); ok {
@@ -447,6 +471,10 @@ func (f *puppetFunction) Call(c eval.Context, block eval.Lambda, args ...eval.Va
 	if block != nil {
 		panic(errors.NewArgumentsError(f.Name(), `Puppet functions does not yet support lambdas`))
 	}
+	if p := c.Profiler(); p != nil {
+		p.Enter(f.Name(), f.expression)
+		defer p.Exit()
+	}
 	defer func() {
 		if err := recover(); err != nil {
 			switch err.(type) {
@@ -577,6 +605,23 @@ func NewPuppetPlan(expr *parser.PlanDefinition) *puppetPlan {
 	return &puppetPlan{puppetFunction{expression: &expr.FunctionDefinition}}
 }
 
+// Call calls the plan with the eval.PlanContextKey context variable set to the name of the plan
+// so that plan-only functions can be called. The previous value of the variable is restored when
+// the call returns.
+func (p *puppetPlan) Call(c eval.Context, block eval.Lambda, args ...eval.Value) eval.Value {
+	if prev, ok := c.Get(eval.PlanContextKey); ok {
+		defer c.Set(eval.PlanContextKey, prev)
+	} else {
+		defer c.Delete(eval.PlanContextKey)
+	}
+	c.Set(eval.PlanContextKey, p.Name())
+	return p.puppetFunction.Call(c, block, args...)
+}
+
+func (p *puppetPlan) Dispatchers() []eval.Lambda {
+	return []eval.Lambda{p}
+}
+
 func (p *puppetPlan) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
 	io.WriteString(bld, `plan `)
 	io.WriteString(bld, p.Name())
@@ -629,7 +674,7 @@ func init() {
 	}
 
 	eval.MakeGoAllocator = func(allocFunc eval.DispatchFunction) eval.Lambda {
-		return &goLambda{lambda{types.NewCallableType(types.EmptyTupleType(), nil, nil)}, allocFunc}
+		return &goLambda{lambda{signature: types.NewCallableType(types.EmptyTupleType(), nil, nil)}, allocFunc}
 	}
 
 	eval.MakeGoConstructor = func(typeName string, creators ...eval.DispatchCreator) eval.ResolvableFunction {
@@ -0,0 +1,36 @@
+package impl_test
+
+import (
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+// evaluate parses and evaluates the given source in a new context that is first passed to the
+// given prepare function. It returns the string form of the result, or the code of the issue
+// that was reported.
+func evaluate(prepare func(c eval.Context), source string) (result string, code issue.Code) {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		if prepare != nil {
+			prepare(c)
+		}
+		expr := c.ParseAndValidate(``, source, false)
+		c.AddDefinitions(expr)
+		v, err := eval.TopEvaluate(c, expr)
+		if err != nil {
+			return err
+		}
+		result = v.String()
+		return nil
+	})
+	if err != nil {
+		re, ok := err.(issue.Reported)
+		if !ok {
+			panic(err)
+		}
+		code = re.Code()
+	}
+	return
+}
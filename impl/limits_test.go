@@ -0,0 +1,56 @@
+package impl_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		limits eval.Limits
+		source string
+		code   issue.Code
+	}{
+		{eval.Limits{MaxSteps: 100}, `[1,2,3].each |$x| { $x }`, ``},
+		{eval.Limits{MaxSteps: 100}, `$a = [1,2,3,4,5,6,7,8,9,10] $a.each |$x| { $a.each |$y| { $x + $y } }`, eval.EVAL_MAX_STEPS_EXCEEDED},
+		{eval.Limits{MaxStackDepth: 20}, `function f($x) { f($x + 1) } f(0)`, eval.EVAL_MAX_STACK_DEPTH_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `[1,2,3] + [4,5,6]`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `"abc${'def'}"`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `[1,2,3,4,5] << 6`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `{a=>1,b=>2,c=>3} + {d=>4,e=>5,f=>6}`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `{a=>1,b=>2,c=>3} + {a=>4,b=>5,c=>6}`, ``},
+		{eval.Limits{MaxCollectionSize: 5}, `$a = [1,2,3] [$a,$a].flatten`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `$ten.map |$x| { $x }`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+		{eval.Limits{MaxCollectionSize: 5}, `$ten.filter |$x| { true }`, eval.EVAL_MAX_COLLECTION_SIZE_EXCEEDED},
+	}
+	ten := make([]eval.Value, 10)
+	for i := range ten {
+		ten[i] = types.WrapInteger(int64(i))
+	}
+	for _, tc := range tests {
+		// The variable $ten is assigned before the limits are set so that its size isn't checked
+		prepare := func(c eval.Context) {
+			c.Scope().Set(`ten`, types.WrapValues(ten))
+			c.SetLimits(tc.limits)
+		}
+		if _, code := evaluate(prepare, tc.source); code != tc.code {
+			t.Errorf(`%s: expected issue '%s', got '%s'`, tc.source, tc.code, code)
+		}
+	}
+}
+
+func TestLimitsFromSettings(t *testing.T) {
+	eval.Puppet.Set(`max_steps`, types.WrapInteger(10))
+	defer eval.Puppet.Reset()
+	eval.Puppet.Do(func(c eval.Context) {
+		if c.Limits().MaxSteps != 10 {
+			t.Errorf(`expected max steps 10, got %d`, c.Limits().MaxSteps)
+		}
+		if c.Fork().Limits().MaxSteps != 10 {
+			t.Errorf(`limits not inherited by Fork`)
+		}
+	})
+}
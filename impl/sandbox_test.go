@@ -0,0 +1,43 @@
+package impl_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+func TestSandbox(t *testing.T) {
+	dir, err := ioutil.TempDir(``, `sandbox`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, `data.txt`)
+	if err = ioutil.WriteFile(file, []byte(`hello`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		policy *eval.SandboxPolicy
+		source string
+		code   issue.Code
+	}{
+		{nil, `binary_file('` + file + `')`, ``},
+		{&eval.SandboxPolicy{}, `binary_file('` + file + `')`, eval.EVAL_SANDBOX_CAPABILITY_DENIED},
+		{&eval.SandboxPolicy{FileRoots: []string{dir}}, `binary_file('` + file + `')`, ``},
+		{&eval.SandboxPolicy{FileRoots: []string{dir}}, `binary_file('` + dir + `/../etc/passwd')`, eval.EVAL_SANDBOX_PATH_DENIED},
+		{&eval.SandboxPolicy{Functions: map[string]bool{`each`: true}}, `[1].each |$x| { $x }`, ``},
+		{&eval.SandboxPolicy{Functions: map[string]bool{`each`: true}}, `[1].map |$x| { $x }`, eval.EVAL_SANDBOX_FUNCTION_DENIED},
+		{&eval.SandboxPolicy{Exec: true}, `run_script('` + file + `', 'localhost')`, eval.EVAL_SANDBOX_CAPABILITY_DENIED},
+		{&eval.SandboxPolicy{}, `wait_until_available('localhost')`, eval.EVAL_SANDBOX_CAPABILITY_DENIED},
+	}
+	for _, tc := range tests {
+		if _, code := evaluate(func(c eval.Context) { c.SetSandbox(tc.policy) }, tc.source); code != tc.code {
+			t.Errorf(`%s: expected issue '%s', got '%s'`, tc.source, tc.code, code)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package impl
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/lyraproj/puppet-evaluator/eval"
@@ -153,6 +154,24 @@ func (e *BasicScope) State(name string)  eval.VariableState {
 	return eval.NotFound
 }
 
+func (e *BasicScope) VariableNames() []string {
+	names := make([]string, 0, 16)
+	e.addNames(map[string]bool{}, &names)
+	sort.Strings(names)
+	return names
+}
+
+func (e *BasicScope) addNames(seen map[string]bool, names *[]string) {
+	for _, s := range e.scopes {
+		for k := range s {
+			if k != groupKey && !seen[k] {
+				seen[k] = true
+				*names = append(*names, k)
+			}
+		}
+	}
+}
+
 func (e *parentedScope) Fork() eval.Scope {
 	clone := &parentedScope{}
 	clone.copyFrom(&e.BasicScope)
@@ -205,3 +224,16 @@ func (e *parentedScope) State(name string) eval.VariableState {
 	}
 	return e.parent.State(name)
 }
+
+func (e *parentedScope) VariableNames() []string {
+	seen := map[string]bool{}
+	names := make([]string, 0, 16)
+	e.addNames(seen, &names)
+	for _, k := range e.parent.VariableNames() {
+		if !seen[k] {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
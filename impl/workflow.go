@@ -1,14 +1,319 @@
 package impl
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
 	"github.com/lyraproj/puppet-parser/parser"
 )
 
 var NewPuppetActivity func(c eval.Context, expr *parser.ActivityExpression) eval.Resolvable
 
+type puppetActivity struct {
+	expression *parser.ActivityExpression
+	input      []eval.Parameter
+	output     []eval.Parameter
+	properties eval.OrderedMap
+	inputType  *types.StructType
+	outputType *types.StructType
+
+	// The activities of a workflow in the order that they must run
+	activities []*puppetActivity
+}
+
 func init() {
 	NewPuppetActivity = func(c eval.Context, expr *parser.ActivityExpression) eval.Resolvable {
-		panic("no workflow support in this runtime")
+		return &puppetActivity{expression: expr}
+	}
+}
+
+func (a *puppetActivity) Name() string {
+	return a.expression.Name()
+}
+
+func (a *puppetActivity) Style() parser.ActivityStyle {
+	return a.expression.Style()
+}
+
+func (a *puppetActivity) Input() []eval.Parameter {
+	return a.input
+}
+
+func (a *puppetActivity) Output() []eval.Parameter {
+	return a.output
+}
+
+// Resolve resolves the parameters and properties of the activity. The activities of a workflow
+// are resolved and ordered so that each activity comes after the activities that produce its input.
+// This method panics with an issue.Reported if the input of some activity cannot be produced or if
+// the activities depend on each other in a cycle.
+func (a *puppetActivity) Resolve(c eval.Context) {
+	if a.properties != nil {
+		panic(fmt.Sprintf(`Attempt to resolve already resolved activity %s`, a.Name()))
+	}
+	a.properties = eval.EMPTY_MAP
+	if pe := a.expression.Properties(); pe != nil {
+		a.properties = eval.Evaluate(c, pe).(eval.OrderedMap)
+	}
+	if a.properties.IncludesKey2(`iteration`) {
+		panic(c.Error(a.expression, eval.EVAL_ACTIVITY_ITERATION_NOT_SUPPORTED, issue.H{`name`: a.Name()}))
+	}
+	a.input = a.parameters(`input`)
+	a.output = a.parameters(`output`)
+	a.inputType = structType(a.input, true)
+	a.outputType = structType(a.output, false)
+
+	if a.Style() == parser.ActivityStyleWorkflow {
+		if block, ok := a.expression.Definition().(*parser.BlockExpression); ok {
+			activities := make([]*puppetActivity, 0, len(block.Statements()))
+			for _, s := range block.Statements() {
+				if ae, ok := s.(*parser.ActivityExpression); ok {
+					activity := NewPuppetActivity(c, ae).(*puppetActivity)
+					activity.Resolve(c)
+					activities = append(activities, activity)
+				}
+			}
+			a.activities = a.order(c, activities)
+		}
+	}
+}
+
+func (a *puppetActivity) parameters(key string) []eval.Parameter {
+	pv, ok := a.properties.Get4(key)
+	if !ok {
+		return []eval.Parameter{}
+	}
+	pl := pv.(eval.List)
+	params := make([]eval.Parameter, pl.Len())
+	pl.EachWithIndex(func(p eval.Value, i int) { params[i] = p.(eval.Parameter) })
+	return params
+}
+
+// order returns the given activities ordered so that each activity comes after the activities
+// that produce its input. The declaration order is retained for activities that are independent
+// of each other.
+func (a *puppetActivity) order(c eval.Context, activities []*puppetActivity) []*puppetActivity {
+	producers := make(map[string]*puppetActivity)
+	for _, activity := range activities {
+		for _, o := range activity.output {
+			if p, ok := producers[o.Name()]; ok {
+				panic(c.Error(activity.expression, eval.EVAL_ACTIVITY_DUPLICATE_OUTPUT, issue.H{
+					`name`: a.Name(), `output`: o.Name(), `first`: p.Name(), `second`: activity.Name()}))
+			}
+			producers[o.Name()] = activity
+		}
+	}
+
+	deps := make(map[*puppetActivity][]*puppetActivity, len(activities))
+	for _, activity := range activities {
+		for _, in := range activity.input {
+			if p, ok := producers[in.Name()]; ok {
+				deps[activity] = append(deps[activity], p)
+			} else if !(in.HasValue() || hasParameter(a.input, in.Name())) {
+				panic(c.Error(activity.expression, eval.EVAL_ACTIVITY_UNPRODUCIBLE_INPUT, issue.H{
+					`name`: a.Name(), `input`: in.Name(), `activity`: activity.Name()}))
+			}
+		}
+	}
+	for _, o := range a.output {
+		if _, ok := producers[o.Name()]; !(ok || hasParameter(a.input, o.Name())) {
+			panic(c.Error(a.expression, eval.EVAL_ACTIVITY_UNPRODUCIBLE_OUTPUT, issue.H{`name`: a.Name(), `output`: o.Name()}))
+		}
+	}
+
+	// Depth first topological sort. An activity that is visited again while it is on the path
+	// being visited is part of a cycle.
+	ordered := make([]*puppetActivity, 0, len(activities))
+	done := make(map[*puppetActivity]bool, len(activities))
+	var path []*puppetActivity
+	var visit func(activity *puppetActivity)
+	visit = func(activity *puppetActivity) {
+		if done[activity] {
+			return
+		}
+		for i, p := range path {
+			if p == activity {
+				names := make([]string, 0, len(path)-i+1)
+				for _, cp := range path[i:] {
+					names = append(names, cp.Name())
+				}
+				names = append(names, activity.Name())
+				panic(c.Error(activity.expression, eval.EVAL_ACTIVITY_CYCLE, issue.H{`name`: a.Name(), `cycle`: strings.Join(names, ` -> `)}))
+			}
+		}
+		path = append(path, activity)
+		for _, dep := range deps[activity] {
+			visit(dep)
+		}
+		path = path[:len(path)-1]
+		done[activity] = true
+		ordered = append(ordered, activity)
+	}
+	for _, activity := range activities {
+		visit(activity)
+	}
+	return ordered
+}
+
+// Run runs the activity in the current process. A workflow runs its activities one at a time.
+// A resource creates an instance of its type, or a Hash when it has no type, from its state. An
+// action or stateless activity evaluates its body and takes its output from the returned Hash or,
+// when the body doesn't return a Hash, from the variables that the body assigns.
+func (a *puppetActivity) Run(c eval.Context, input eval.OrderedMap) eval.OrderedMap {
+	input = a.resolveInput(c, input)
+	var output eval.OrderedMap
+	switch a.Style() {
+	case parser.ActivityStyleWorkflow:
+		output = a.runWorkflow(c, input)
+	case parser.ActivityStyleResource:
+		output = a.runResource(c, input)
+	default:
+		output = a.runAction(c, input)
 	}
+	eval.AssertInstance(func() string { return fmt.Sprintf(`Activity %s output:`, a.Name()) }, a.outputType, output)
+	return output
+}
+
+func (a *puppetActivity) resolveInput(c eval.Context, input eval.OrderedMap) eval.OrderedMap {
+	entries := make([]*types.HashEntry, 0, len(a.input))
+	for _, p := range a.input {
+		if v, ok := input.Get4(p.Name()); ok {
+			entries = append(entries, types.WrapHashEntry2(p.Name(), v))
+		} else if p.HasValue() {
+			v = p.Value()
+			if df, ok := v.(types.Deferred); ok {
+				v = df.Resolve(c)
+			}
+			entries = append(entries, types.WrapHashEntry2(p.Name(), v))
+		}
+	}
+	input = types.WrapHash(entries)
+	eval.AssertInstance(func() string { return fmt.Sprintf(`Activity %s input:`, a.Name()) }, a.inputType, input)
+	return input
+}
+
+func (a *puppetActivity) runWorkflow(c eval.Context, input eval.OrderedMap) eval.OrderedMap {
+	values := input
+	for _, activity := range a.activities {
+		values = values.Merge(activity.Run(c, values))
+	}
+	return a.collectOutput(c, func(key eval.Value) (eval.Value, bool) { return values.Get(key) })
+}
+
+func (a *puppetActivity) runResource(c eval.Context, input eval.OrderedMap) eval.OrderedMap {
+	return c.Scope().WithLocalScope(func() eval.Value {
+		a.setVariables(c, input)
+		var state eval.Value = eval.EMPTY_MAP
+		if de := a.expression.Definition(); de != nil {
+			state = types.ResolveDeferred(c, eval.Evaluate(c, de))
+		}
+		if tv, ok := a.properties.Get4(`type`); ok {
+			state = eval.New(c, tv, state)
+		}
+		return a.collectOutput(c, func(key eval.Value) (eval.Value, bool) { return lookup(state, key) })
+	}).(eval.OrderedMap)
+}
+
+func (a *puppetActivity) runAction(c eval.Context, input eval.OrderedMap) eval.OrderedMap {
+	return c.Scope().WithLocalScope(func() eval.Value {
+		a.setVariables(c, input)
+		var result eval.Value = eval.UNDEF
+		if de := a.expression.Definition(); de != nil {
+			result = eval.Evaluate(c, de)
+		}
+		if rh, ok := result.(eval.OrderedMap); ok {
+			return a.collectOutput(c, func(key eval.Value) (eval.Value, bool) { return lookup(rh, key) })
+		}
+		return a.collectOutput(c, func(key eval.Value) (eval.Value, bool) { return lookup(c.Scope(), key) })
+	}).(eval.OrderedMap)
+}
+
+func (a *puppetActivity) setVariables(c eval.Context, input eval.OrderedMap) {
+	scope := c.Scope()
+	input.EachPair(func(k, v eval.Value) { scope.Set(k.String(), v) })
+}
+
+// collectOutput returns a hash with the value of each output parameter. The value is obtained
+// by the given getter using the alias of the parameter, or its name when it has no alias. An alias
+// that is an Array denotes a path to dig into.
+func (a *puppetActivity) collectOutput(c eval.Context, get func(key eval.Value) (eval.Value, bool)) eval.OrderedMap {
+	entries := make([]*types.HashEntry, 0, len(a.output))
+	for _, p := range a.output {
+		var v eval.Value
+		var ok bool
+		if a.Style() == parser.ActivityStyleWorkflow || !p.HasValue() {
+			v, ok = get(types.WrapString(p.Name()))
+		} else if path, isList := p.Value().(eval.List); isList && path.Len() > 0 {
+			v, ok = get(path.At(0))
+			for i := 1; ok && i < path.Len(); i++ {
+				v, ok = lookup(v, path.At(i))
+			}
+		} else {
+			v, ok = get(p.Value())
+		}
+		if !ok {
+			panic(c.Error(a.expression, eval.EVAL_ACTIVITY_MISSING_OUTPUT, issue.H{`name`: a.Name(), `output`: p.Name()}))
+		}
+		entries = append(entries, types.WrapHashEntry2(p.Name(), v))
+	}
+	return types.WrapHash(entries)
+}
+
+// lookup returns the attribute, hash entry, or variable with the given name
+func lookup(container interface{}, key eval.Value) (eval.Value, bool) {
+	switch container.(type) {
+	case eval.OrderedMap:
+		return container.(eval.OrderedMap).Get(key)
+	case eval.PuppetObject:
+		return container.(eval.PuppetObject).Get(key.String())
+	case eval.Scope:
+		return container.(eval.Scope).Get(key.String())
+	}
+	return nil, false
+}
+
+func hasParameter(params []eval.Parameter, name string) bool {
+	for _, p := range params {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// structType returns a Struct type with one entry per parameter. The entry of a parameter that
+// has a default value is optional when optionalDefaults is true.
+func structType(params []eval.Parameter, optionalDefaults bool) *types.StructType {
+	elements := make([]*types.StructElement, len(params))
+	for i, p := range params {
+		if optionalDefaults && p.HasValue() {
+			elements[i] = types.NewStructElement(types.NewOptionalType3(p.Name()), p.Type())
+		} else {
+			elements[i] = types.NewStructElement2(p.Name(), p.Type())
+		}
+	}
+	return types.NewStructType(elements)
+}
+
+func (a *puppetActivity) Equals(other interface{}, guard eval.Guard) bool {
+	return a == other
+}
+
+func (a *puppetActivity) String() string {
+	return eval.ToString(a)
+}
+
+func (a *puppetActivity) ToString(bld io.Writer, format eval.FormatContext, g eval.RDetect) {
+	io.WriteString(bld, string(a.Style()))
+	io.WriteString(bld, ` `)
+	io.WriteString(bld, a.Name())
+}
+
+// PType returns the type of a function that takes the input of the activity and returns its output
+func (a *puppetActivity) PType() eval.Type {
+	return types.NewCallableType(types.NewTupleType([]eval.Type{a.inputType}, nil), a.outputType, nil)
 }
@@ -0,0 +1,103 @@
+package impl_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func TestWorkflow(t *testing.T) {
+	eval.Puppet.Set(`workflow`, types.WrapBoolean(true))
+	defer eval.Puppet.Reset()
+
+	eval.Puppet.Try(func(c eval.Context) error {
+		c.AddDefinitions(c.ParseAndValidate(``, `
+			type Subnet = Object[attributes => { vpc_id => String, cidr => String }]
+
+			workflow deploy {
+				input => (String $region = 'us-east-1', Integer $count),
+				output => (String $vpc_id, String $subnet_cidr, Array[String] $hosts)
+			} {
+				action hosts {
+					input => ($vpc_id, $count),
+					output => (Array[String] $hosts)
+				} {
+					$hosts = [1, 2, 3].filter |$i| { $i <= $count }.map |$i| { "${vpc_id}-host${i}" }
+				}
+
+				resource subnet {
+					input => ($vpc_id),
+					output => (String $subnet_cidr = cidr),
+					type => Subnet
+				} {
+					vpc_id => $vpc_id,
+					cidr => '10.0.0.0/24'
+				}
+
+				resource vpc {
+					input => ($region),
+					output => (String $vpc_id = id)
+				} {
+					id => "vpc-${region}"
+				}
+			}`, false))
+		c.ResolveDefinitions()
+		a, ok := eval.Load(c, eval.NewTypedName(eval.NsActivity, `deploy`))
+		if !ok {
+			t.Fatal(`unable to load workflow deploy`)
+		}
+		wf := a.(eval.Activity)
+		out := wf.Run(c, types.SingletonHash2(`count`, types.WrapInteger(2)))
+		expected := `{'vpc_id' => 'vpc-us-east-1', 'subnet_cidr' => '10.0.0.0/24', 'hosts' => ['vpc-us-east-1-host1', 'vpc-us-east-1-host2']}`
+		if out.String() != expected {
+			t.Errorf(`expected %s, got %s`, expected, out)
+		}
+
+		defer func() {
+			if re, ok := recover().(issue.Reported); !ok || re.Code() != eval.EVAL_TYPE_MISMATCH {
+				t.Errorf(`expected %s, got %v`, eval.EVAL_TYPE_MISMATCH, re)
+			}
+		}()
+		wf.Run(c, eval.EMPTY_MAP)
+		return nil
+	})
+
+	tests := []struct {
+		source string
+		code   issue.Code
+	}{
+		{`workflow w { output => ($x) } {
+			action a { input => ($y), output => ($x) } { $x = 1 }
+			action b { input => ($x), output => ($y) } { $y = 1 }
+		}`, eval.EVAL_ACTIVITY_CYCLE},
+		{`workflow w { output => ($x) } {
+			action a { input => ($z), output => ($x) } { $x = 1 }
+		}`, eval.EVAL_ACTIVITY_UNPRODUCIBLE_INPUT},
+		{`workflow w { output => ($x) } {
+			action a { output => ($x) } { $x = 1 }
+			action b { output => ($x) } { $x = 2 }
+		}`, eval.EVAL_ACTIVITY_DUPLICATE_OUTPUT},
+		{`workflow w { output => ($x, $y) } {
+			action a { output => ($x) } { $x = 1 }
+		}`, eval.EVAL_ACTIVITY_UNPRODUCIBLE_OUTPUT},
+		{`workflow w { output => ($x) } {
+			action a { output => ($x) } { $y = 1 }
+		}`, eval.EVAL_ACTIVITY_MISSING_OUTPUT},
+	}
+	for _, tc := range tests {
+		eval.Puppet.Try(func(c eval.Context) error {
+			defer func() {
+				if re, ok := recover().(issue.Reported); !ok || re.Code() != tc.code {
+					t.Errorf(`expected %s, got %v`, tc.code, re)
+				}
+			}()
+			c.AddDefinitions(c.ParseAndValidate(``, tc.source, false))
+			c.ResolveDefinitions()
+			a, _ := eval.Load(c, eval.NewTypedName(eval.NsActivity, `w`))
+			a.(eval.Activity).Run(c, eval.EMPTY_MAP)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Package inventory reads version 2 inventory files and resolves target specifications into
+// Target values.
+//
+// An inventory declares targets and groups of targets. Groups may be nested. Each group and target
+// may declare config, facts, and vars that are merged down the group tree so that the data of a
+// target takes precedence over the data of the groups that it is a member of, an inner group takes
+// precedence over an outer group, and the first group that a target is found in takes precedence
+// over groups found later. The config and facts are merged deeply, vars are merged shallowly.
+package inventory
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// ContextKey is the key of the context variable that holds the current *Inventory
+const ContextKey = `puppet.inventory`
+
+// Inventory is a parsed inventory file
+type Inventory struct {
+	file       string
+	targets    map[string]*target
+	names      []string
+	aliases    map[string]string
+	groups     map[string][]string
+	groupNames []string
+}
+
+type target struct {
+	name   string
+	uri    string
+	config *types.HashValue
+	facts  *types.HashValue
+	vars   *types.HashValue
+	chains [][]*groupDef
+	value  eval.PuppetObject
+}
+
+// Get returns the inventory of the given context. The inventory is loaded from the file
+// denoted by the `inventory_file` setting the first time it is requested. An empty inventory
+// is returned when that setting is unset.
+func Get(c eval.Context) *Inventory {
+	if iv, ok := c.Get(ContextKey); ok {
+		return iv.(*Inventory)
+	}
+	var inv *Inventory
+	if file := eval.GetSetting(`inventory_file`, eval.UNDEF); file != eval.UNDEF {
+		inv = Load(c, file.String())
+	} else {
+		inv = Parse(c, ``, nil)
+	}
+	c.Set(ContextKey, inv)
+	return inv
+}
+
+// Load reads and parses the inventory file at the given path. This function panics with an
+// issue.Reported if the sandbox policy of the given context does not permit access to the path,
+// if the file cannot be read, or if its contents is invalid.
+func Load(c eval.Context, file string) *Inventory {
+	eval.AssertPathAllowed(c, file)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		panic(c.Error(nil, eval.EVAL_UNABLE_TO_READ_FILE, issue.H{`path`: file, `detail`: err.Error()}))
+	}
+	return Parse(c, file, content)
+}
+
+// Parse parses the given inventory file content. The file is only used when reporting errors.
+// This function panics with an issue.Reported if the content is invalid. The reported issue
+// contains the path of the offending element, e.g. `groups[1].targets[0].uri`.
+func Parse(c eval.Context, file string, content []byte) *Inventory {
+	p := &parser{c, file}
+	inv := &Inventory{
+		file:    file,
+		targets: make(map[string]*target),
+		aliases: make(map[string]string),
+		groups:  make(map[string][]string)}
+	inv.addGroup(p, p.parse(content), nil)
+	for _, name := range inv.names {
+		t := inv.targets[name]
+		for _, chain := range t.chains {
+			for _, g := range chain {
+				t.config = under(t.config, g.config, true)
+				t.facts = under(t.facts, g.facts, true)
+				t.vars = under(t.vars, g.vars, false)
+			}
+		}
+		t.chains = nil
+	}
+	return inv
+}
+
+// File returns the path of the file that the inventory was loaded from
+func (inv *Inventory) File() string {
+	return inv.file
+}
+
+// GroupNames returns the names of all groups in the order that they were declared
+func (inv *Inventory) GroupNames() []string {
+	return inv.groupNames
+}
+
+// TargetNames returns the names of all targets in the order that they were declared
+func (inv *Inventory) TargetNames() []string {
+	return inv.names
+}
+
+// Targets returns the targets denoted by the given TargetSpec. A String may contain a comma
+// separated list of the name `all`, group names, target names, target aliases, and glob patterns
+// matching target names. A name that is not found in the inventory denotes a new target with
+// that name as its host. A target that is declared in the inventory is returned only once.
+func (inv *Inventory) Targets(c eval.Context, spec eval.Value) []eval.PuppetObject {
+	seen := make(map[eval.PuppetObject]bool)
+	targets := make([]eval.PuppetObject, 0)
+	inv.resolve(c, spec, func(t eval.PuppetObject) {
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	})
+	return targets
+}
+
+func (inv *Inventory) resolve(c eval.Context, spec eval.Value, add func(eval.PuppetObject)) {
+	switch spec.(type) {
+	case *types.StringValue:
+		for _, name := range strings.Split(spec.String(), `,`) {
+			if name = strings.TrimSpace(name); name != `` {
+				inv.resolveName(c, name, add)
+			}
+		}
+	case eval.List:
+		spec.(eval.List).Each(func(e eval.Value) { inv.resolve(c, e, add) })
+	default:
+		add(spec.(eval.PuppetObject))
+	}
+}
+
+func (inv *Inventory) resolveName(c eval.Context, name string, add func(eval.PuppetObject)) {
+	if name == `all` {
+		for _, tn := range inv.names {
+			add(inv.target(c, tn))
+		}
+		return
+	}
+	if members, ok := inv.groups[name]; ok {
+		for _, tn := range members {
+			add(inv.target(c, tn))
+		}
+		return
+	}
+	if _, ok := inv.targets[name]; ok {
+		add(inv.target(c, name))
+		return
+	}
+	if tn, ok := inv.aliases[name]; ok {
+		add(inv.target(c, tn))
+		return
+	}
+	if strings.ContainsAny(name, `*?[`) {
+		for _, tn := range inv.names {
+			if matched, _ := path.Match(name, tn); matched {
+				add(inv.target(c, tn))
+			}
+		}
+		return
+	}
+	add(types.NewTarget(c, name, nil))
+}
+
+// target returns the Target value for the target with the given name, creating it on first request
+func (inv *Inventory) target(c eval.Context, name string) eval.PuppetObject {
+	t := inv.targets[name]
+	if t.value == nil {
+		host := t.uri
+		if host == `` {
+			host = t.name
+		}
+		t.value = types.NewTarget2(c, types.WrapHash([]*types.HashEntry{
+			types.WrapHashEntry2(`host`, types.WrapString(host)),
+			types.WrapHashEntry2(`options`, orEmpty(t.config)),
+			types.WrapHashEntry2(`name`, types.WrapString(t.name)),
+			types.WrapHashEntry2(`facts`, orEmpty(t.facts)),
+			types.WrapHashEntry2(`vars`, orEmpty(t.vars))}))
+	}
+	return t.value
+}
+
+// addGroup registers the given group and its targets and nested groups. The chain contains the
+// enclosing groups, innermost first.
+func (inv *Inventory) addGroup(p *parser, g *groupDef, chain []*groupDef) {
+	chain = append([]*groupDef{g}, chain...)
+	if len(chain) > 1 {
+		if g.name == `all` {
+			p.fail(g.node, join(g.path, `name`), `the group name 'all' is reserved`)
+		}
+		if _, ok := inv.groups[g.name]; ok {
+			p.fail(g.node, join(g.path, `name`), `duplicate group name '`+g.name+`'`)
+		}
+		if _, ok := inv.targets[g.name]; ok {
+			p.fail(g.node, join(g.path, `name`), `group '`+g.name+`' has the same name as a target`)
+		}
+		if _, ok := inv.aliases[g.name]; ok {
+			p.fail(g.node, join(g.path, `name`), `group '`+g.name+`' has the same name as a target alias`)
+		}
+		inv.groups[g.name] = nil
+		inv.groupNames = append(inv.groupNames, g.name)
+	}
+
+	for _, td := range g.targets {
+		inv.addTarget(p, td, chain)
+	}
+	for _, sg := range g.groups {
+		inv.addGroup(p, sg, chain)
+	}
+}
+
+func (inv *Inventory) addTarget(p *parser, td *targetDef, chain []*groupDef) {
+	t, ok := inv.targets[td.name]
+	if ok {
+		if td.uri != `` && t.uri != `` && td.uri != t.uri {
+			p.fail(td.node, join(td.path, `uri`), `target '`+td.name+`' is declared with different uris`)
+		}
+		if t.uri == `` {
+			t.uri = td.uri
+		}
+		t.config = under(t.config, td.config, true)
+		t.facts = under(t.facts, td.facts, true)
+		t.vars = under(t.vars, td.vars, false)
+	} else {
+		if _, ok := inv.groups[td.name]; ok {
+			p.fail(td.node, td.path, `target '`+td.name+`' has the same name as a group`)
+		}
+		if _, ok := inv.aliases[td.name]; ok {
+			p.fail(td.node, td.path, `target '`+td.name+`' has the same name as a target alias`)
+		}
+		t = &target{name: td.name, uri: td.uri, config: td.config, facts: td.facts, vars: td.vars}
+		inv.targets[td.name] = t
+		inv.names = append(inv.names, td.name)
+	}
+	t.chains = append(t.chains, chain)
+
+	for _, alias := range td.aliases {
+		if other, ok := inv.aliases[alias]; ok && other != td.name {
+			p.fail(td.node, join(td.path, `alias`), `alias '`+alias+`' refers to both '`+other+`' and '`+td.name+`'`)
+		}
+		if _, ok := inv.targets[alias]; ok && alias != td.name {
+			p.fail(td.node, join(td.path, `alias`), `alias '`+alias+`' has the same name as a target`)
+		}
+		if _, ok := inv.groups[alias]; ok {
+			p.fail(td.node, join(td.path, `alias`), `alias '`+alias+`' has the same name as a group`)
+		}
+		inv.aliases[alias] = td.name
+	}
+
+	// Register the target as a member of all enclosing groups except the implicit `all`
+	for _, g := range chain[:len(chain)-1] {
+		if !contains(inv.groups[g.name], td.name) {
+			inv.groups[g.name] = append(inv.groups[g.name], td.name)
+		}
+	}
+}
+
+// under returns the given value with the entries of defaults that it lacks added. Nested hashes
+// are merged the same way when deep is true.
+func under(value, defaults *types.HashValue, deep bool) *types.HashValue {
+	if defaults == nil {
+		return value
+	}
+	if value == nil {
+		return defaults
+	}
+	entries := make([]*types.HashEntry, 0, value.Len()+defaults.Len())
+	value.EachPair(func(k, v eval.Value) {
+		if deep {
+			if vh, ok := v.(*types.HashValue); ok {
+				if dv, ok := defaults.Get(k); ok {
+					if dh, ok := dv.(*types.HashValue); ok {
+						v = under(vh, dh, true)
+					}
+				}
+			}
+		}
+		entries = append(entries, types.WrapHashEntry(k, v))
+	})
+	defaults.EachPair(func(k, v eval.Value) {
+		if !value.IncludesKey(k) {
+			entries = append(entries, types.WrapHashEntry(k, v))
+		}
+	})
+	return types.WrapHash(entries)
+}
+
+func orEmpty(h *types.HashValue) eval.Value {
+	if h == nil {
+		return eval.EMPTY_MAP
+	}
+	return h
+}
@@ -0,0 +1,138 @@
+package inventory_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/inventory"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const inventoryYAML = `version: 2
+vars:
+  role: none
+  site: top
+config:
+  transport: local
+groups:
+  - name: web
+    vars:
+      role: web
+    targets:
+      - uri: web1.example.com
+        alias: w1
+        vars:
+          site: east
+      - web2.example.com
+    groups:
+      - name: canary
+        facts:
+          canary: true
+        targets:
+          - web2.example.com
+  - name: db
+    targets:
+      - name: db1
+        uri: db1.example.com
+        config:
+          transport: ssh
+`
+
+func ExampleParse() {
+	eval.Puppet.Do(func(c eval.Context) {
+		inv := inventory.Parse(c, `inventory.yaml`, []byte(inventoryYAML))
+		fmt.Println(inv.GroupNames())
+		fmt.Println(inv.TargetNames())
+	})
+	// Output:
+	// [web canary db]
+	// [web1.example.com web2.example.com db1]
+}
+
+func ExampleInventory_Targets() {
+	eval.Puppet.Do(func(c eval.Context) {
+		inv := inventory.Parse(c, `inventory.yaml`, []byte(inventoryYAML))
+		for _, t := range inv.Targets(c, types.WrapString(`canary, w1, other.host`)) {
+			host, _ := t.Get(`host`)
+			fmt.Println(host)
+		}
+	})
+	// Output:
+	// web2.example.com
+	// web1.example.com
+	// other.host
+}
+
+func TestTargets(t *testing.T) {
+	tests := []struct {
+		spec      eval.Value
+		attribute string
+		expected  string
+	}{
+		{types.WrapString(`all`), `name`, `[web1.example.com web2.example.com db1]`},
+		{types.WrapValues([]eval.Value{types.WrapString(`web`), types.WrapString(`db`)}), `host`, `[web1.example.com web2.example.com db1.example.com]`},
+		{types.WrapString(`*.example.com,db1`), `name`, `[web1.example.com web2.example.com db1]`},
+		{types.WrapString(`w1`), `vars`, `[{'site' => 'east', 'role' => 'web'}]`},
+		{types.WrapString(`canary`), `facts`, `[{'canary' => true}]`},
+		{types.WrapString(`db1`), `options`, `[{'transport' => 'ssh'}]`},
+		{types.WrapString(`other.host`), `options`, `[{}]`},
+	}
+	eval.Puppet.Do(func(c eval.Context) {
+		inv := inventory.Parse(c, `inventory.yaml`, []byte(inventoryYAML))
+		for _, tc := range tests {
+			targets := inv.Targets(c, tc.spec)
+			values := make([]string, len(targets))
+			for i, target := range targets {
+				v, _ := target.Get(tc.attribute)
+				values[i] = v.String()
+			}
+			if actual := fmt.Sprint(values); actual != tc.expected {
+				t.Errorf(`%s %s: expected %s, got %s`, tc.spec, tc.attribute, tc.expected, actual)
+			}
+		}
+	})
+}
+
+func TestParseInvalid(t *testing.T) {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		inventory.Parse(c, `inventory.yaml`, []byte("groups:\n  - name: g\n    targets:\n      - a\n      - uri: []\n"))
+		return nil
+	})
+	re, ok := err.(issue.Reported)
+	if !ok || re.Code() != eval.EVAL_INVENTORY_INVALID {
+		t.Fatalf(`expected %s, got %v`, eval.EVAL_INVENTORY_INVALID, err)
+	}
+	if !strings.Contains(re.Error(), `groups[0].targets[1].uri`) || re.Location().Line() != 5 {
+		t.Errorf(`unexpected error %s`, re)
+	}
+}
+
+func TestLoadSandbox(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `inventory`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	inventoryFile := filepath.Join(tmpDir, `inventory.yaml`)
+	if err = ioutil.WriteFile(inventoryFile, []byte(inventoryYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = eval.Puppet.Try(func(c eval.Context) error {
+		c.SetSandbox(&eval.SandboxPolicy{FileRoots: []string{filepath.Join(tmpDir, `other`)}})
+		inventory.Load(c, inventoryFile)
+		return nil
+	})
+	if re, ok := err.(issue.Reported); !ok || re.Code() != eval.EVAL_SANDBOX_PATH_DENIED {
+		t.Errorf(`expected %s, got %v`, eval.EVAL_SANDBOX_PATH_DENIED, err)
+	}
+}
@@ -0,0 +1,251 @@
+package inventory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"gopkg.in/yaml.v3"
+)
+
+// groupDef is a group as it appears in the inventory file. The top level of the file is parsed
+// into the unnamed group `all`.
+type groupDef struct {
+	node    *yaml.Node
+	path    string
+	name    string
+	targets []*targetDef
+	groups  []*groupDef
+	config  *types.HashValue
+	facts   *types.HashValue
+	vars    *types.HashValue
+}
+
+// targetDef is a target entry in a group. An entry that is a plain string only has a uri.
+type targetDef struct {
+	node    *yaml.Node
+	path    string
+	name    string
+	uri     string
+	aliases []string
+	config  *types.HashValue
+	facts   *types.HashValue
+	vars    *types.HashValue
+}
+
+type parser struct {
+	c    eval.Context
+	file string
+}
+
+var topKeys = []string{`version`, `targets`, `groups`, `config`, `facts`, `vars`}
+var groupKeys = []string{`name`, `targets`, `groups`, `config`, `facts`, `vars`}
+var targetKeys = []string{`name`, `uri`, `alias`, `config`, `facts`, `vars`}
+
+func (p *parser) parse(content []byte) *groupDef {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		panic(p.c.Error(issue.NewLocation(p.file, 0, 0), eval.EVAL_INVENTORY_INVALID, issue.H{`path`: `(root)`, `message`: err.Error()}))
+	}
+	if len(doc.Content) == 0 {
+		return &groupDef{node: &doc, name: `all`}
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == `!!null` {
+		return &groupDef{node: root, name: `all`}
+	}
+	p.assertKind(root, `(root)`, yaml.MappingNode)
+	p.assertKeys(root, `(root)`, topKeys)
+	if v := p.lookup(root, `version`); v != nil {
+		if v.Kind != yaml.ScalarNode || v.Value != `2` {
+			p.fail(v, `version`, fmt.Sprintf(`unsupported inventory version '%s', expected 2`, v.Value))
+		}
+	}
+	return p.parseGroup(root, ``, `all`)
+}
+
+func (p *parser) parseGroup(node *yaml.Node, path, name string) *groupDef {
+	g := &groupDef{node: node, path: path, name: name}
+	if n := p.lookup(node, `targets`); n != nil {
+		tp := join(path, `targets`)
+		p.assertKind(n, tp, yaml.SequenceNode)
+		g.targets = make([]*targetDef, len(n.Content))
+		for i, tn := range n.Content {
+			g.targets[i] = p.parseTarget(tn, index(tp, i))
+		}
+	}
+	if n := p.lookup(node, `groups`); n != nil {
+		gp := join(path, `groups`)
+		p.assertKind(n, gp, yaml.SequenceNode)
+		g.groups = make([]*groupDef, len(n.Content))
+		for i, gn := range n.Content {
+			ip := index(gp, i)
+			p.assertKind(gn, ip, yaml.MappingNode)
+			p.assertKeys(gn, ip, groupKeys)
+			g.groups[i] = p.parseGroup(gn, ip, p.requiredName(gn, ip))
+		}
+	}
+	g.config = p.hash(node, path, `config`)
+	g.facts = p.hash(node, path, `facts`)
+	g.vars = p.hash(node, path, `vars`)
+	return g
+}
+
+func (p *parser) parseTarget(node *yaml.Node, path string) *targetDef {
+	if node.Kind == yaml.ScalarNode {
+		if node.Value == `` {
+			p.fail(node, path, `target must not be empty`)
+		}
+		return &targetDef{node: node, path: path, name: node.Value, uri: node.Value}
+	}
+	p.assertKind(node, path, yaml.MappingNode)
+	p.assertKeys(node, path, targetKeys)
+	t := &targetDef{node: node, path: path}
+	if n := p.lookup(node, `uri`); n != nil {
+		t.uri = p.string(n, join(path, `uri`))
+	}
+	if n := p.lookup(node, `name`); n != nil {
+		t.name = p.string(n, join(path, `name`))
+	} else if t.uri == `` {
+		p.fail(node, path, `target must have a name or a uri`)
+	} else {
+		t.name = t.uri
+	}
+	if n := p.lookup(node, `alias`); n != nil {
+		ap := join(path, `alias`)
+		if n.Kind == yaml.SequenceNode {
+			for i, an := range n.Content {
+				t.aliases = append(t.aliases, p.string(an, index(ap, i)))
+			}
+		} else {
+			t.aliases = []string{p.string(n, ap)}
+		}
+	}
+	t.config = p.hash(node, path, `config`)
+	t.facts = p.hash(node, path, `facts`)
+	t.vars = p.hash(node, path, `vars`)
+	return t
+}
+
+func (p *parser) requiredName(node *yaml.Node, path string) string {
+	n := p.lookup(node, `name`)
+	if n == nil {
+		p.fail(node, path, `group must have a name`)
+	}
+	return p.string(n, join(path, `name`))
+}
+
+func (p *parser) hash(node *yaml.Node, path, key string) *types.HashValue {
+	n := p.lookup(node, key)
+	if n == nil || n.Tag == `!!null` {
+		return nil
+	}
+	path = join(path, key)
+	p.assertKind(n, path, yaml.MappingNode)
+	return p.value(n, path).(*types.HashValue)
+}
+
+func (p *parser) string(node *yaml.Node, path string) string {
+	if node.Kind != yaml.ScalarNode || node.Tag == `!!null` || node.Value == `` {
+		p.fail(node, path, `expected a non empty string`)
+	}
+	return node.Value
+}
+
+// value converts the given node into a Data value. The order of mapping entries is retained.
+func (p *parser) value(node *yaml.Node, path string) eval.Value {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return p.value(node.Alias, path)
+	case yaml.MappingNode:
+		entries := make([]*types.HashEntry, 0, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			entries = append(entries, types.WrapHashEntry2(key, p.value(node.Content[i+1], join(path, key))))
+		}
+		return types.WrapHash(entries)
+	case yaml.SequenceNode:
+		elements := make([]eval.Value, len(node.Content))
+		for i, e := range node.Content {
+			elements[i] = p.value(e, index(path, i))
+		}
+		return types.WrapValues(elements)
+	}
+	switch node.Tag {
+	case `!!null`:
+		return eval.UNDEF
+	case `!!bool`:
+		var b bool
+		if node.Decode(&b) == nil {
+			return types.WrapBoolean(b)
+		}
+	case `!!int`:
+		if i, err := strconv.ParseInt(node.Value, 0, 64); err == nil {
+			return types.WrapInteger(i)
+		}
+	case `!!float`:
+		var f float64
+		if node.Decode(&f) == nil {
+			return types.WrapFloat(f)
+		}
+	}
+	return types.WrapString(node.Value)
+}
+
+func (p *parser) lookup(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func (p *parser) assertKeys(node *yaml.Node, path string, valid []string) {
+	for i := 0; i < len(node.Content); i += 2 {
+		k := node.Content[i]
+		if !contains(valid, k.Value) {
+			p.fail(k, join(path, k.Value), fmt.Sprintf(`unknown key '%s', expected one of %s`, k.Value, strings.Join(valid, `, `)))
+		}
+	}
+}
+
+func (p *parser) assertKind(node *yaml.Node, path string, kind yaml.Kind) {
+	if node.Kind != kind {
+		expected := `a mapping`
+		if kind == yaml.SequenceNode {
+			expected = `a sequence`
+		}
+		p.fail(node, path, `expected `+expected)
+	}
+}
+
+func (p *parser) fail(node *yaml.Node, path, message string) {
+	if path == `` {
+		path = `(root)`
+	}
+	panic(p.c.Error(issue.NewLocation(p.file, node.Line, node.Column), eval.EVAL_INVENTORY_INVALID, issue.H{`path`: path, `message`: message}))
+}
+
+func contains(strs []string, s string) bool {
+	for _, e := range strs {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == `` {
+		return key
+	}
+	return path + `.` + key
+}
+
+func index(path string, i int) string {
+	return fmt.Sprintf(`%s[%d]`, path, i)
+}
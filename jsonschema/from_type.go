@@ -0,0 +1,314 @@
+package jsonschema
+
+import (
+	"math"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// DIALECT is the URI of the JSON Schema dialect used by the schemas produced by FromType
+const DIALECT = `https://json-schema.org/draft/2020-12/schema`
+
+const defsPrefix = `#/$defs/`
+
+// FromType returns a JSON Schema, draft 2020-12, that describes the given type. Named Object types and
+// type aliases are declared under "$defs", keyed by their qualified name, and referenced using "$ref".
+// An Object type that has a parent combines a reference to its parent with its own attributes using
+// "allOf". Derived attributes are excluded since they are not part of the data of an instance. The schema
+// for a TypeSet contains no constraints of its own, only declarations for each type in the set.
+//
+// A panic with the issue EVAL_JSON_SCHEMA_UNSUPPORTED_TYPE is raised for types that have no JSON Schema
+// equivalent, such as Sensitive, Callable, Type, and Hash types whose keys are not strings.
+func FromType(t eval.Type) eval.OrderedMap {
+	sc := &schemaConverter{defs: make(map[string]eval.Value)}
+	he := []*types.HashEntry{types.WrapHashEntry2(`$schema`, types.WrapString(DIALECT))}
+	if ts, ok := t.(eval.TypeSet); ok {
+		he = append(he, types.WrapHashEntry2(`title`, types.WrapString(ts.Name())))
+		ts.Types().EachValue(func(mt eval.Value) { sc.define(mt.(eval.Type)) })
+	} else {
+		sc.schema(t).(eval.OrderedMap).EachPair(func(k, v eval.Value) {
+			he = append(he, types.WrapHashEntry(k, v))
+		})
+	}
+	if len(sc.defNames) > 0 {
+		de := make([]*types.HashEntry, len(sc.defNames))
+		for i, n := range sc.defNames {
+			de[i] = types.WrapHashEntry2(n, sc.defs[n])
+		}
+		he = append(he, types.WrapHashEntry2(`$defs`, types.WrapHash(de)))
+	}
+	return types.WrapHash(he)
+}
+
+type schemaConverter struct {
+	// Names of the declared types in the order they were encountered
+	defNames []string
+	defs     map[string]eval.Value
+}
+
+type attributeIterator interface {
+	EachAttribute(includeParent bool, consumer func(attr eval.Attribute))
+}
+
+// define adds the declaration of the given named type to "$defs" unless it has been added already and
+// returns a schema that references the declaration.
+func (sc *schemaConverter) define(t eval.Type) eval.Value {
+	n := t.Name()
+	if _, ok := sc.defs[n]; !ok {
+		sc.defNames = append(sc.defNames, n)
+
+		// Register before the conversion to allow recursive references
+		sc.defs[n] = types.WrapHash(nil)
+		if ot, ok := t.(eval.ObjectType); ok {
+			sc.defs[n] = sc.objectSchema(ot)
+		} else {
+			sc.defs[n] = sc.schema(t.(*types.TypeAliasType).ResolvedType())
+		}
+	}
+	return types.SingletonHash2(`$ref`, types.WrapString(defsPrefix+n))
+}
+
+func (sc *schemaConverter) schema(t eval.Type) eval.Value {
+	switch t := t.(type) {
+	case *types.TypeAliasType:
+		return sc.define(t)
+	case eval.ObjectType:
+		if t.Name() == `` {
+			return sc.objectSchema(t)
+		}
+		return sc.define(t)
+	case *types.AnyType:
+		return types.WrapHash(nil)
+	case *types.UndefType:
+		return typeSchema(`null`)
+	case *types.BooleanType:
+		if ps := t.Parameters(); len(ps) > 0 {
+			return types.SingletonHash2(`const`, ps[0])
+		}
+		return typeSchema(`boolean`)
+	case *types.IntegerType:
+		he := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(`integer`))}
+		if t.Min() != math.MinInt64 {
+			he = append(he, types.WrapHashEntry2(`minimum`, types.WrapInteger(t.Min())))
+		}
+		if t.Max() != math.MaxInt64 {
+			he = append(he, types.WrapHashEntry2(`maximum`, types.WrapInteger(t.Max())))
+		}
+		return types.WrapHash(he)
+	case *types.FloatType:
+		he := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(`number`))}
+		if t.Min() != -math.MaxFloat64 {
+			he = append(he, types.WrapHashEntry2(`minimum`, types.WrapFloat(t.Min())))
+		}
+		if t.Max() != math.MaxFloat64 {
+			he = append(he, types.WrapHashEntry2(`maximum`, types.WrapFloat(t.Max())))
+		}
+		return types.WrapHash(he)
+	case *types.NumericType:
+		return typeSchema(`number`)
+	case *types.ScalarDataType:
+		return types.SingletonHash2(`type`, types.WrapValues([]eval.Value{
+			types.WrapString(`string`), types.WrapString(`number`), types.WrapString(`boolean`)}))
+	case *types.StringType:
+		if t.Value() != `` {
+			return types.SingletonHash2(`const`, types.WrapString(t.Value()))
+		}
+		he := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(`string`))}
+		return types.WrapHash(appendSize(he, t.Size(), `minLength`, `maxLength`))
+	case *types.EnumType:
+		if ci, _ := t.Get(`case_insensitive`); ci.(*types.BooleanValue).Bool() {
+			panic(unsupportedType(t))
+		}
+		vs, _ := t.Get(`values`)
+		if vs.(eval.List).Len() == 0 {
+			return typeSchema(`string`)
+		}
+		return types.WrapHash([]*types.HashEntry{
+			types.WrapHashEntry2(`type`, types.WrapString(`string`)),
+			types.WrapHashEntry2(`enum`, vs)})
+	case *types.PatternType:
+		ps := t.Patterns()
+		if ps.Len() == 1 {
+			return patternSchema(ps.At(0).(*types.RegexpType))
+		}
+		return types.SingletonHash2(`anyOf`, ps.Map(func(p eval.Value) eval.Value {
+			return patternSchema(p.(*types.RegexpType))
+		}))
+	case *types.TimestampType:
+		return formatSchema(`date-time`)
+	case *types.UriType:
+		return formatSchema(`uri`)
+	case *types.OptionalType:
+		return types.SingletonHash2(`anyOf`, types.WrapValues([]eval.Value{sc.schema(t.ContainedType()), typeSchema(`null`)}))
+	case *types.NotUndefType:
+		return sc.schema(t.ContainedType())
+	case *types.VariantType:
+		ts := t.Types()
+		vs := make([]eval.Value, len(ts))
+		for i, vt := range ts {
+			vs[i] = sc.schema(vt)
+		}
+		return types.SingletonHash2(`anyOf`, types.WrapValues(vs))
+	case *types.ArrayType:
+		he := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(`array`))}
+		if _, ok := t.ElementType().(*types.AnyType); !ok {
+			he = append(he, types.WrapHashEntry2(`items`, sc.schema(t.ElementType())))
+		}
+		return types.WrapHash(appendSize(he, t.Size(), `minItems`, `maxItems`))
+	case *types.TupleType:
+		return sc.tupleSchema(t)
+	case *types.HashType:
+		return sc.hashSchema(t)
+	case *types.StructType:
+		return sc.structSchema(t)
+	default:
+		panic(unsupportedType(t))
+	}
+}
+
+// tupleSchema returns a schema that uses "prefixItems" for the types of the given Tuple. When the size
+// of the tuple allows more elements than there are types, the last type is used for "items" since it
+// applies to all remaining elements.
+func (sc *schemaConverter) tupleSchema(t *types.TupleType) eval.Value {
+	ts := t.Types()
+	sz := t.Size()
+	n := int64(len(ts))
+	he := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(`array`))}
+	var items eval.Value = types.Boolean_FALSE
+	if sz.Max() > n && n > 0 {
+		n--
+		items = sc.schema(ts[n])
+	}
+	if n > 0 {
+		pis := make([]eval.Value, n)
+		for i := int64(0); i < n; i++ {
+			pis[i] = sc.schema(ts[i])
+		}
+		he = append(he, types.WrapHashEntry2(`prefixItems`, types.WrapValues(pis)))
+	}
+	if len(ts) > 0 || sz.Max() == 0 {
+		he = append(he, types.WrapHashEntry2(`items`, items))
+	}
+	if sz.Min() > 0 {
+		he = append(he, types.WrapHashEntry2(`minItems`, types.WrapInteger(sz.Min())))
+	}
+	if sz.Max() != math.MaxInt64 && sz.Max() > n {
+		he = append(he, types.WrapHashEntry2(`maxItems`, types.WrapInteger(sz.Max())))
+	}
+	return types.WrapHash(he)
+}
+
+// hashSchema returns a schema that constrains the properties of an object. JSON object keys are always
+// strings so a Hash with a key type that isn't assignable to String has no JSON Schema equivalent.
+func (sc *schemaConverter) hashSchema(t *types.HashType) eval.Value {
+	kt := t.KeyType()
+	_, anyKey := kt.(*types.AnyType)
+	if !(anyKey || types.DefaultStringType().IsAssignable(kt, nil)) {
+		panic(unsupportedType(t))
+	}
+	he := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(`object`))}
+	if !(anyKey || kt.Equals(types.DefaultStringType(), nil)) {
+		he = append(he, types.WrapHashEntry2(`propertyNames`, sc.schema(kt)))
+	}
+	if _, ok := t.ValueType().(*types.AnyType); !ok {
+		he = append(he, types.WrapHashEntry2(`additionalProperties`, sc.schema(t.ValueType())))
+	}
+	return types.WrapHash(appendSize(he, t.Size(), `minProperties`, `maxProperties`))
+}
+
+func (sc *schemaConverter) structSchema(t *types.StructType) eval.Value {
+	es := t.Elements()
+	props := make([]*types.HashEntry, len(es))
+	required := make([]eval.Value, 0, len(es))
+	for i, e := range es {
+		props[i] = types.WrapHashEntry2(e.Name(), sc.schema(e.Value()))
+		if !e.Optional() {
+			required = append(required, types.WrapString(e.Name()))
+		}
+	}
+	return objectSchema(nil, props, required, true)
+}
+
+// objectSchema returns the schema for the attributes that the given Object type declares. Attributes
+// inherited from a parent are included through a reference to the parent.
+func (sc *schemaConverter) objectSchema(t eval.ObjectType) eval.Value {
+	props := make([]*types.HashEntry, 0)
+	required := make([]eval.Value, 0)
+	t.(attributeIterator).EachAttribute(false, func(a eval.Attribute) {
+		var as eval.Value
+		switch a.Kind() {
+		case types.DERIVED:
+			return
+		case types.CONSTANT:
+			as = types.SingletonHash2(`const`, a.Value())
+		default:
+			as = sc.schema(a.Type())
+			if a.HasValue() {
+				if !eval.Equals(a.Value(), eval.UNDEF) {
+					as = as.(eval.OrderedMap).Merge(types.SingletonHash2(`default`, a.Value()))
+				}
+			} else if a.Kind() != types.GIVEN_OR_DERIVED {
+				required = append(required, types.WrapString(a.Name()))
+			}
+		}
+		props = append(props, types.WrapHashEntry2(a.Name(), as))
+	})
+	var parent eval.Value
+	if p := t.Parent(); p != nil {
+		parent = sc.schema(p)
+	}
+	return objectSchema(parent, props, required, false)
+}
+
+// objectSchema returns a schema for an object with the given properties. The schema is combined with
+// the given parent schema using "allOf" unless the parent is nil. A closed schema doesn't permit
+// additional properties.
+func objectSchema(parent eval.Value, props []*types.HashEntry, required []eval.Value, closed bool) eval.Value {
+	he := make([]*types.HashEntry, 0, 5)
+	if parent != nil {
+		he = append(he, types.WrapHashEntry2(`allOf`, types.WrapValues([]eval.Value{parent})))
+	}
+	he = append(he, types.WrapHashEntry2(`type`, types.WrapString(`object`)))
+	if len(props) > 0 {
+		he = append(he, types.WrapHashEntry2(`properties`, types.WrapHash(props)))
+	}
+	if len(required) > 0 {
+		he = append(he, types.WrapHashEntry2(`required`, types.WrapValues(required)))
+	}
+	if closed {
+		he = append(he, types.WrapHashEntry2(`additionalProperties`, types.Boolean_FALSE))
+	}
+	return types.WrapHash(he)
+}
+
+func appendSize(he []*types.HashEntry, sz *types.IntegerType, minKey, maxKey string) []*types.HashEntry {
+	if sz.Min() > 0 {
+		he = append(he, types.WrapHashEntry2(minKey, types.WrapInteger(sz.Min())))
+	}
+	if sz.Max() != math.MaxInt64 {
+		he = append(he, types.WrapHashEntry2(maxKey, types.WrapInteger(sz.Max())))
+	}
+	return he
+}
+
+func typeSchema(typeName string) eval.Value {
+	return types.SingletonHash2(`type`, types.WrapString(typeName))
+}
+
+func formatSchema(format string) eval.Value {
+	return types.WrapHash([]*types.HashEntry{
+		types.WrapHashEntry2(`type`, types.WrapString(`string`)),
+		types.WrapHashEntry2(`format`, types.WrapString(format))})
+}
+
+func patternSchema(rx *types.RegexpType) eval.Value {
+	return types.WrapHash([]*types.HashEntry{
+		types.WrapHashEntry2(`type`, types.WrapString(`string`)),
+		types.WrapHashEntry2(`pattern`, types.WrapString(rx.PatternString()))})
+}
+
+func unsupportedType(t eval.Type) error {
+	return eval.Error(eval.EVAL_JSON_SCHEMA_UNSUPPORTED_TYPE, issue.H{`type`: t.String()})
+}
@@ -0,0 +1,362 @@
+package jsonschema_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/jsonschema"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const typeSet = `TypeSet[{
+  name => 'My',
+  version => '1.0.0',
+  pcore_version => '1.0.0',
+  types => {
+    Color => Enum[red, green, blue],
+    Point => Struct[x => Integer, y => Integer, Optional[label] => String[1, 20]],
+    Shape => {
+      attributes => {
+        color => Optional[Color],
+        origin => Point,
+        tags => Optional[Array[Pattern[/\A[a-z]+\z/], 0, 5]]
+      }
+    },
+    Circle => {
+      parent => Shape,
+      attributes => {
+        radius => Float[0.0],
+        kind => { type => String, kind => constant, value => 'circle' },
+        area => { type => Float, kind => derived }
+      }
+    },
+    Polygon => {
+      parent => Shape,
+      attributes => {
+        corners => Tuple[Point, Point, Point, 3, default],
+        labels => Hash[String, Variant[Integer, String]]
+      }
+    }
+  }
+}]`
+
+func printJSON(v eval.Value) {
+	b := bytes.NewBufferString(``)
+	serialization.DataToJson(v, b)
+	out := bytes.NewBufferString(``)
+	json.Indent(out, b.Bytes(), ``, `  `)
+	fmt.Println(out.String())
+}
+
+func ExampleFromType() {
+	eval.Puppet.Do(func(c eval.Context) {
+		ts := c.ParseType2(typeSet)
+		c.AddTypes(ts)
+		printJSON(jsonschema.FromType(ts))
+	})
+	// Output:
+	// {
+	//   "$schema": "https://json-schema.org/draft/2020-12/schema",
+	//   "title": "My",
+	//   "$defs": {
+	//     "My::Circle": {
+	//       "allOf": [
+	//         {
+	//           "$ref": "#/$defs/My::Shape"
+	//         }
+	//       ],
+	//       "type": "object",
+	//       "properties": {
+	//         "radius": {
+	//           "type": "number",
+	//           "minimum": 0
+	//         },
+	//         "kind": {
+	//           "const": "circle"
+	//         }
+	//       },
+	//       "required": [
+	//         "radius"
+	//       ]
+	//     },
+	//     "My::Shape": {
+	//       "type": "object",
+	//       "properties": {
+	//         "color": {
+	//           "anyOf": [
+	//             {
+	//               "$ref": "#/$defs/My::Color"
+	//             },
+	//             {
+	//               "type": "null"
+	//             }
+	//           ]
+	//         },
+	//         "origin": {
+	//           "$ref": "#/$defs/My::Point"
+	//         },
+	//         "tags": {
+	//           "anyOf": [
+	//             {
+	//               "type": "array",
+	//               "items": {
+	//                 "type": "string",
+	//                 "pattern": "\\A[a-z]+\\z"
+	//               },
+	//               "maxItems": 5
+	//             },
+	//             {
+	//               "type": "null"
+	//             }
+	//           ]
+	//         }
+	//       },
+	//       "required": [
+	//         "origin"
+	//       ]
+	//     },
+	//     "My::Color": {
+	//       "type": "string",
+	//       "enum": [
+	//         "red",
+	//         "green",
+	//         "blue"
+	//       ]
+	//     },
+	//     "My::Point": {
+	//       "type": "object",
+	//       "properties": {
+	//         "x": {
+	//           "type": "integer"
+	//         },
+	//         "y": {
+	//           "type": "integer"
+	//         },
+	//         "label": {
+	//           "type": "string",
+	//           "minLength": 1,
+	//           "maxLength": 20
+	//         }
+	//       },
+	//       "required": [
+	//         "x",
+	//         "y"
+	//       ],
+	//       "additionalProperties": false
+	//     },
+	//     "My::Polygon": {
+	//       "allOf": [
+	//         {
+	//           "$ref": "#/$defs/My::Shape"
+	//         }
+	//       ],
+	//       "type": "object",
+	//       "properties": {
+	//         "corners": {
+	//           "type": "array",
+	//           "prefixItems": [
+	//             {
+	//               "$ref": "#/$defs/My::Point"
+	//             },
+	//             {
+	//               "$ref": "#/$defs/My::Point"
+	//             }
+	//           ],
+	//           "items": {
+	//             "$ref": "#/$defs/My::Point"
+	//           },
+	//           "minItems": 3
+	//         },
+	//         "labels": {
+	//           "type": "object",
+	//           "additionalProperties": {
+	//             "anyOf": [
+	//               {
+	//                 "type": "integer"
+	//               },
+	//               {
+	//                 "type": "string"
+	//               }
+	//             ]
+	//           }
+	//         }
+	//       },
+	//       "required": [
+	//         "corners",
+	//         "labels"
+	//       ]
+	//     }
+	//   }
+	// }
+}
+
+func ExampleFromType_unsupported() {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		jsonschema.FromType(c.ParseType2(`Struct[name => String, password => Sensitive[String]]`))
+		return nil
+	})
+	fmt.Println(err)
+	// Output: The type Sensitive[String] has no JSON Schema equivalent
+}
+
+const schema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "array",
+  "items": { "$ref": "#/$defs/Inventory::Item" },
+  "$defs": {
+    "Inventory::Item": {
+      "type": "object",
+      "properties": {
+        "sku": { "type": "string", "pattern": "^[A-Z]{3}-[0-9]+$" },
+        "count": { "type": "integer", "minimum": 0 },
+        "price": { "type": "number", "exclusiveMinimum": 0, "minimum": 0 },
+        "status": { "enum": ["active", "retired"], "default": "active" },
+        "tags": { "type": "array", "items": { "type": "string" }, "uniqueItems": false }
+      },
+      "required": ["sku", "count"]
+    }
+  }
+}`
+
+func ExampleToTypes() {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		col := serialization.NewCollector()
+		serialization.JsonToData(`schema.json`, strings.NewReader(schema), col)
+		jsonschema.ToTypes(col.Value().(eval.OrderedMap))
+		return nil
+	})
+	fmt.Println(err)
+	// Output: The JSON Schema keyword 'exclusiveMinimum' has no Pcore type equivalent
+}
+
+func ExampleToTypes_resolve() {
+	eval.Puppet.Do(func(c eval.Context) {
+		col := serialization.NewCollector()
+		serialization.JsonToData(`schema.json`, strings.NewReader(
+			strings.NewReplacer(`"exclusiveMinimum": 0, `, ``, `, "uniqueItems": false`, ``).Replace(schema)), col)
+		t, defs := jsonschema.ToTypes(col.Value().(eval.OrderedMap))
+		c.AddTypes(defs...)
+		t = t.(eval.ResolvableType).Resolve(c)
+		fmt.Println(t)
+		fmt.Println(eval.ToString2(defs[0], types.EXPANDED))
+		fmt.Println(eval.IsInstance(t, eval.Wrap(c, []interface{}{
+			eval.New(c, defs[0], types.WrapStringToInterfaceMap(c, map[string]interface{}{
+				`sku`: `ABC-12`, `count`: 3, `price`: 2}))})))
+	})
+	// Output:
+	// Array[Inventory::Item]
+	// Object[{name => 'Inventory::Item', attributes => {'sku' => Pattern[/^[A-Z]{3}-[0-9]+$/], 'count' => Integer[0], 'price' => {'type' => Optional[Variant[Integer[0], Float[0.00000]]], 'value' => undef}, 'status' => {'type' => Enum['active', 'retired'], 'value' => 'active'}, 'tags' => {'type' => Optional[Array[String]], 'value' => undef}}}]
+	// true
+}
+
+func TestRoundtrip(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		ts := c.ParseType2(typeSet).(eval.TypeSet)
+		c.AddTypes(ts)
+		s := jsonschema.FromType(ts)
+
+		// Resolve the converted types in a separate loader since they have the same names
+		c.DoWithLoader(eval.NewParentedLoader(c.Loader()), func() {
+			_, defs := jsonschema.ToTypes(s)
+			c.AddTypes(defs...)
+			for _, dt := range defs {
+				if dt.Name() == `My::Circle` {
+					// Derived attributes are not part of the schema
+					continue
+				}
+				et, _ := ts.GetType2(dt.Name()[4:])
+				if ta, ok := et.(*types.TypeAliasType); ok {
+					et = ta.ResolvedType()
+					dt = dt.(*types.TypeAliasType).ResolvedType()
+				}
+				if !et.Equals(dt, nil) {
+					t.Errorf(`expected %s, got %s`, eval.ToString2(et, types.EXPANDED), eval.ToString2(dt, types.EXPANDED))
+				}
+			}
+		})
+	})
+}
+
+func TestToTypesInvalidValue(t *testing.T) {
+	tests := []struct {
+		schema  string
+		message string
+	}{
+		{`{"type": "object", "properties": {"a": true}, "required": "a", "additionalProperties": false}`, `'required' must be of type array, not string`},
+		{`{"type": "integer", "minimum": "1"}`, `'minimum' must be of type number, not string`},
+		{`{"type": "number", "maximum": null}`, `'maximum' must be of type number, not null`},
+		{`{"type": "string", "maxLength": [1]}`, `'maxLength' must be of type number, not array`},
+		{`{"type": "array", "prefixItems": {"type": "string"}}`, `'prefixItems' must be of type array, not object`},
+		{`{"anyOf": {"type": "string"}}`, `'anyOf' must be of type array, not object`},
+		{`{"enum": "red"}`, `'enum' must be of type array, not string`},
+		{`{"type": "object", "properties": [], "additionalProperties": false}`, `'properties' must be of type object, not array`},
+		{`{"$defs": []}`, `'$defs' must be of type object, not array`},
+		{`{"$defs": {"A": {"allOf": [true], "properties": {}}}}`, `keyword 'allOf' has no Pcore type equivalent`},
+	}
+	for _, tc := range tests {
+		err := eval.Puppet.Try(func(c eval.Context) error {
+			col := serialization.NewCollector()
+			serialization.JsonToData(`schema.json`, strings.NewReader(tc.schema), col)
+			jsonschema.ToTypes(col.Value().(eval.OrderedMap))
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf(`%s: expected error containing %q, got %v`, tc.schema, tc.message, err)
+		}
+	}
+}
+
+func TestToTypes(t *testing.T) {
+	tests := []struct {
+		schema  string
+		result  string
+		message string
+	}{
+		{`{"type": ["string", "null"], "minLength": 1}`, `Optional[String[1]]`, ``},
+		{`{"type": ["integer", "array"], "minimum": 1, "items": {"type": "string"}}`, `Variant[Integer[1], Array[String]]`, ``},
+		{`{"type": ["string", "null"], "minItems": 1}`, ``, `keyword 'minItems' has no Pcore type equivalent`},
+		{`{"type": "number", "minimum": 0}`, `Variant[Integer[0], Float[0.00000]]`, ``},
+		{`{"type": "number", "minimum": 0.5, "maximum": 0.7}`, `Float[0.50000, 0.70000]`, ``},
+		{`{"type": "string", "pattern": "^a", "maxLength": 3}`, ``, `keyword 'maxLength' has no Pcore type equivalent`},
+		{`{"type": "string", "format": "email"}`, ``, `keyword 'format' has no Pcore type equivalent`},
+		{`{"type": "string", "format": "uri", "minLength": 3}`, ``, `keyword 'minLength' has no Pcore type equivalent`},
+		{`{"type": "object", "properties": {"a": {"type": "string"}}, "required": ["a"], "additionalProperties": false}`, `Struct[{'a' => String}]`, ``},
+		{`{"type": "object", "properties": {"a": true}}`, ``, `keyword 'properties' has no Pcore type equivalent`},
+		{`{"type": "object", "properties": {"a": true}, "additionalProperties": {"type": "string"}}`, ``, `keyword 'additionalProperties' has no Pcore type equivalent`},
+	}
+	for _, tc := range tests {
+		err := eval.Puppet.Try(func(c eval.Context) error {
+			col := serialization.NewCollector()
+			serialization.JsonToData(`schema.json`, strings.NewReader(tc.schema), col)
+			if st, _ := jsonschema.ToTypes(col.Value().(eval.OrderedMap)); st.String() != tc.result {
+				t.Errorf(`%s: expected %s, got %s`, tc.schema, tc.result, st)
+			}
+			return nil
+		})
+		if tc.message == `` {
+			if err != nil {
+				t.Errorf(`%s: unexpected error %s`, tc.schema, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf(`%s: expected error containing %q, got %v`, tc.schema, tc.message, err)
+		}
+	}
+}
+
+func ExampleToTypes_number() {
+	eval.Puppet.Do(func(c eval.Context) {
+		col := serialization.NewCollector()
+		serialization.JsonToData(`schema.json`, strings.NewReader(`{"type": "number", "minimum": 0}`), col)
+		t, _ := jsonschema.ToTypes(col.Value().(eval.OrderedMap))
+		fmt.Println(eval.IsInstance(t, types.WrapInteger(3)), eval.IsInstance(t, types.WrapFloat(2.5)), eval.IsInstance(t, types.WrapInteger(-1)))
+	})
+	// Output: true true false
+}
@@ -0,0 +1,525 @@
+package jsonschema
+
+import (
+	"math"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// Keywords that don't constrain a value and therefore are ignored when converting a schema to a type
+var annotations = map[string]bool{
+	`$schema`: true, `$id`: true, `$comment`: true, `$defs`: true, `title`: true, `description`: true,
+	`default`: true, `examples`: true, `deprecated`: true, `readOnly`: true, `writeOnly`: true,
+}
+
+// ToTypes returns the Pcore types described by the given JSON Schema. The first return value is the
+// type described by the schema itself or nil when the schema has no constraints of its own, i.e. when it
+// only serves as a container for "$defs". The second return value contains one type for each entry in
+// "$defs", named by its key. The types are unresolved. The declared types must be added to the loader,
+// e.g. using eval.Context.AddTypes, before the first type is resolved since "$ref" is converted to a
+// reference to the named type.
+//
+// An entry in "$defs" that describes an object with "properties" becomes an Object type unless it
+// disallows additional properties, in which case it becomes an alias for a Struct. An object that uses
+// "allOf" with a "$ref" becomes an Object type with the referenced type as its parent. All other entries
+// become type aliases. An inline object with "properties" becomes a Struct and must therefore disallow
+// additional properties. A "number" with bounds becomes a Variant of an Integer and a Float with those
+// bounds since a Float doesn't match integer values.
+//
+// A panic with the issue EVAL_JSON_SCHEMA_UNSUPPORTED_KEYWORD is raised for keywords that have no Pcore
+// equivalent such as "not", "if", or "multipleOf", and for combinations of keywords that Pcore cannot
+// express such as "pattern" together with "maxLength". A panic with the issue EVAL_JSON_SCHEMA_INVALID_VALUE
+// is raised when the value of a keyword is of the wrong JSON type, e.g. when "required" isn't an array.
+func ToTypes(schema eval.OrderedMap) (eval.Type, []eval.Type) {
+	var defs []eval.Type
+	if dm, ok := mapValue(schema, `$defs`); ok {
+		defs = make([]eval.Type, 0, dm.Len())
+		dm.EachPair(func(k, v eval.Value) { defs = append(defs, defToType(k.String(), v)) })
+	}
+	constrained := false
+	schema.EachKey(func(k eval.Value) {
+		if !annotations[k.String()] {
+			constrained = true
+		}
+	})
+	if !constrained {
+		return nil, defs
+	}
+	return toType(schema), defs
+}
+
+func defToType(name string, s eval.Value) eval.Type {
+	if !types.QREF_PATTERN.MatchString(name) {
+		panic(eval.Error(eval.EVAL_JSON_SCHEMA_INVALID_NAME, issue.H{`name`: name}))
+	}
+	if sh, ok := s.(eval.OrderedMap); ok && isObject(sh) {
+		return objectToType(name, sh)
+	}
+	return types.NewTypeAliasType(name, nil, toType(s))
+}
+
+// isObject returns true if the given schema describes an object with properties that is open for
+// extension or that extends a referenced schema.
+func isObject(s eval.OrderedMap) bool {
+	if ps, ok := listValue(s, `allOf`); ok {
+		if ps.Len() == 1 {
+			if ph, ok := ps.At(0).(eval.OrderedMap); ok && ph.IncludesKey2(`$ref`) {
+				return true
+			}
+		}
+	}
+	return s.IncludesKey2(`properties`) && !eval.Equals(s.Get5(`additionalProperties`, types.Boolean_TRUE), types.Boolean_FALSE)
+}
+
+func objectToType(name string, s eval.OrderedMap) eval.Type {
+	assertKeywords(s, `allOf`, `type`, `properties`, `required`)
+	var parent eval.Type
+	if ao, ok := listValue(s, `allOf`); ok {
+		ph, ok := ao.At(0).(eval.OrderedMap)
+		if !(ao.Len() == 1 && ok && ph.IncludesKey2(`$ref`)) {
+			panic(unsupportedKeyword(`allOf`))
+		}
+		parent = refToType(ph)
+	}
+	required := requiredNames(s)
+	attrs := make([]*types.HashEntry, 0)
+	if pv, ok := mapValue(s, `properties`); ok {
+		pv.EachPair(func(k, v eval.Value) {
+			an := k.String()
+			ah := make([]*types.HashEntry, 0, 3)
+			if ph, ok := v.(eval.OrderedMap); ok && ph.IncludesKey2(`const`) {
+				cv := ph.Get5(`const`, nil)
+				ah = append(ah,
+					types.WrapHashEntry2(`type`, eval.Generalize(cv.PType())),
+					types.WrapHashEntry2(`kind`, types.WrapString(string(types.CONSTANT))),
+					types.WrapHashEntry2(`value`, cv))
+			} else {
+				at := toType(v)
+				if !required[an] {
+					var dv eval.Value
+					if ph, ok := v.(eval.OrderedMap); ok {
+						dv = ph.Get5(`default`, nil)
+					}
+					if dv == nil {
+						dv = eval.UNDEF
+						at = optional(at)
+					}
+					ah = append(ah, types.WrapHashEntry2(`type`, at), types.WrapHashEntry2(`value`, dv))
+				} else {
+					ah = append(ah, types.WrapHashEntry2(`type`, at))
+				}
+			}
+			attrs = append(attrs, types.WrapHashEntry2(an, types.WrapHash(ah)))
+		})
+	}
+	return types.NewObjectType(name, parent, types.SingletonHash2(`attributes`, types.WrapHash(attrs)))
+}
+
+func toType(s eval.Value) eval.Type {
+	sh, ok := s.(eval.OrderedMap)
+	if !ok {
+		if eval.Equals(s, types.Boolean_TRUE) {
+			return types.DefaultAnyType()
+		}
+		panic(unsupportedKeyword(s.String()))
+	}
+	if sh.IncludesKey2(`$ref`) {
+		return refToType(sh)
+	}
+	if ao, ok := listValue(sh, `allOf`); ok {
+		assertKeywords(sh, `allOf`)
+		if ao.Len() != 1 {
+			panic(unsupportedKeyword(`allOf`))
+		}
+		return toType(ao.At(0))
+	}
+	for _, k := range []string{`anyOf`, `oneOf`} {
+		if vs, ok := listValue(sh, k); ok {
+			assertKeywords(sh, k)
+			return variant(vs.Map(func(v eval.Value) eval.Value { return toType(v) }))
+		}
+	}
+	if cv, ok := sh.Get4(`const`); ok {
+		assertKeywords(sh, `const`, `type`)
+		return constToType(cv)
+	}
+	if ev, ok := listValue(sh, `enum`); ok {
+		assertKeywords(sh, `enum`, `type`)
+		return enumToType(ev)
+	}
+	tv, ok := sh.Get4(`type`)
+	if !ok {
+		switch {
+		case sh.IncludesKey2(`properties`) || sh.IncludesKey2(`additionalProperties`):
+			tv = types.WrapString(`object`)
+		case sh.IncludesKey2(`items`) || sh.IncludesKey2(`prefixItems`):
+			tv = types.WrapString(`array`)
+		default:
+			assertKeywords(sh)
+			return types.DefaultAnyType()
+		}
+	}
+	if tl, ok := tv.(*types.ArrayValue); ok {
+		keywords := []string{`type`}
+		tl.Each(func(tn eval.Value) {
+			tks, ok := typeKeywords[tn.String()]
+			if !ok {
+				panic(unsupportedKeyword(`type`))
+			}
+			keywords = append(keywords, tks...)
+		})
+		assertKeywords(sh, keywords...)
+		return variant(tl.Map(func(tn eval.Value) eval.Value {
+			return typedToType(tn.String(), schemaForType(sh, tn.String()))
+		}))
+	}
+	return typedToType(tv.String(), sh)
+}
+
+// typeKeywords contains the keywords that apply to each JSON type
+var typeKeywords = map[string][]string{
+	`null`:    {},
+	`boolean`: {},
+	`string`:  {`minLength`, `maxLength`, `pattern`, `format`},
+	`integer`: {`minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`},
+	`number`:  {`minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`},
+	`array`:   {`items`, `prefixItems`, `minItems`, `maxItems`},
+	`object`:  {`properties`, `required`, `additionalProperties`, `propertyNames`, `minProperties`, `maxProperties`},
+}
+
+// schemaForType returns a schema with the given type name and the keywords of the given schema that
+// apply to that type
+func schemaForType(s eval.OrderedMap, typeName string) eval.OrderedMap {
+	entries := []*types.HashEntry{types.WrapHashEntry2(`type`, types.WrapString(typeName))}
+	for _, k := range typeKeywords[typeName] {
+		if v, ok := s.Get4(k); ok {
+			entries = append(entries, types.WrapHashEntry2(k, v))
+		}
+	}
+	return types.WrapHash(entries)
+}
+
+// typedToType returns the type for the given schema using the given name of a JSON type
+func typedToType(typeName string, s eval.OrderedMap) eval.Type {
+	switch typeName {
+	case `null`:
+		assertKeywords(s, `type`)
+		return types.DefaultUndefType()
+	case `boolean`:
+		assertKeywords(s, `type`)
+		return types.DefaultBooleanType()
+	case `string`:
+		assertKeywords(s, `type`, `minLength`, `maxLength`, `pattern`, `format`)
+		if p, ok := s.Get4(`pattern`); ok {
+			// A Pattern cannot be combined with other constraints
+			assertKeywords(s, `type`, `pattern`)
+			return types.NewPatternType([]*types.RegexpType{types.NewRegexpType(p.String())})
+		}
+		if f, ok := s.Get4(`format`); ok {
+			assertKeywords(s, `type`, `format`)
+			switch f.String() {
+			case `date-time`:
+				return types.DefaultTimestampType()
+			case `uri`:
+				return types.DefaultUriType()
+			}
+			panic(unsupportedKeyword(`format`))
+		}
+		return types.NewStringType(sizeType(s, `minLength`, `maxLength`), ``)
+	case `integer`:
+		assertKeywords(s, `type`, `minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`)
+		min := int64(math.MinInt64)
+		max := int64(math.MaxInt64)
+		if v, ok := numberValue(s, `minimum`); ok {
+			min = integerBound(math.Ceil(v.Float()))
+		}
+		if v, ok := numberValue(s, `exclusiveMinimum`); ok {
+			min = integerBound(math.Floor(v.Float()) + 1)
+		}
+		if v, ok := numberValue(s, `maximum`); ok {
+			max = integerBound(math.Floor(v.Float()))
+		}
+		if v, ok := numberValue(s, `exclusiveMaximum`); ok {
+			max = integerBound(math.Ceil(v.Float()) - 1)
+		}
+		return types.NewIntegerType(min, max)
+	case `number`:
+		assertKeywords(s, `type`, `minimum`, `maximum`)
+		if !(s.IncludesKey2(`minimum`) || s.IncludesKey2(`maximum`)) {
+			return types.DefaultNumericType()
+		}
+		// A JSON number can be an integer, and a Float doesn't match integers, so the bounds
+		// are applied to both
+		min := -math.MaxFloat64
+		max := math.MaxFloat64
+		imin := int64(math.MinInt64)
+		imax := int64(math.MaxInt64)
+		if v, ok := numberValue(s, `minimum`); ok {
+			min = v.Float()
+			imin = integerBound(math.Ceil(min))
+		}
+		if v, ok := numberValue(s, `maximum`); ok {
+			max = v.Float()
+			imax = integerBound(math.Floor(max))
+		}
+		ft := types.NewFloatType(min, max)
+		if imin > imax {
+			return ft
+		}
+		return types.NewVariantType(types.NewIntegerType(imin, imax), ft)
+	case `array`:
+		assertKeywords(s, `type`, `items`, `prefixItems`, `minItems`, `maxItems`)
+		if pi, ok := listValue(s, `prefixItems`); ok {
+			return tupleToType(pi, s)
+		}
+		et := eval.Type(types.DefaultAnyType())
+		if iv, ok := s.Get4(`items`); ok {
+			et = toType(iv)
+		}
+		return types.NewArrayType(et, sizeType(s, `minItems`, `maxItems`))
+	case `object`:
+		if s.IncludesKey2(`properties`) {
+			assertKeywords(s, `type`, `properties`, `required`, `additionalProperties`)
+			// A Struct doesn't permit additional properties
+			switch av, ok := s.Get4(`additionalProperties`); {
+			case !ok:
+				panic(unsupportedKeyword(`properties`))
+			case !eval.Equals(av, types.Boolean_FALSE):
+				panic(unsupportedKeyword(`additionalProperties`))
+			}
+			return structToType(s)
+		}
+		assertKeywords(s, `type`, `propertyNames`, `additionalProperties`, `minProperties`, `maxProperties`)
+		kt := eval.Type(types.DefaultStringType())
+		if pn, ok := s.Get4(`propertyNames`); ok {
+			kt = toType(pn)
+		}
+		vt := eval.Type(types.DefaultAnyType())
+		if av, ok := s.Get4(`additionalProperties`); ok {
+			vt = toType(av)
+		}
+		return types.NewHashType(kt, vt, sizeType(s, `minProperties`, `maxProperties`))
+	default:
+		panic(unsupportedKeyword(`type`))
+	}
+}
+
+// tupleToType returns a Tuple with one type for each of the given prefix items. When "items" is a schema,
+// its type is appended to the tuple since Pcore uses the last type of a tuple for all remaining elements.
+func tupleToType(prefixItems eval.List, s eval.OrderedMap) eval.Type {
+	ts := make([]eval.Type, 0, prefixItems.Len()+1)
+	prefixItems.Each(func(v eval.Value) { ts = append(ts, toType(v)) })
+	n := int64(len(ts))
+	min := n
+	max := int64(math.MaxInt64)
+	if iv, ok := s.Get4(`items`); ok {
+		if eval.Equals(iv, types.Boolean_FALSE) {
+			max = n
+		} else {
+			ts = append(ts, toType(iv))
+		}
+	}
+	if v, ok := numberValue(s, `minItems`); ok {
+		min = v.Int()
+	}
+	if v, ok := numberValue(s, `maxItems`); ok {
+		max = v.Int()
+	}
+	return types.NewTupleType(ts, types.NewIntegerType(min, max))
+}
+
+func structToType(s eval.OrderedMap) eval.Type {
+	required := requiredNames(s)
+	es := make([]*types.StructElement, 0)
+	ps, _ := mapValue(s, `properties`)
+	ps.EachPair(func(k, v eval.Value) {
+		pn := k.String()
+		var key eval.Value = types.NewStringType(nil, pn)
+		if !required[pn] {
+			key = types.NewOptionalType(key.(eval.Type))
+		}
+		es = append(es, types.NewStructElement(key, toType(v)))
+	})
+	return types.NewStructType(es)
+}
+
+func refToType(s eval.OrderedMap) eval.Type {
+	ref := s.Get5(`$ref`, eval.EMPTY_STRING).String()
+	if !strings.HasPrefix(ref, defsPrefix) {
+		panic(eval.Error(eval.EVAL_JSON_SCHEMA_UNSUPPORTED_REFERENCE, issue.H{`ref`: ref}))
+	}
+	name := ref[len(defsPrefix):]
+	if !types.QREF_PATTERN.MatchString(name) {
+		panic(eval.Error(eval.EVAL_JSON_SCHEMA_INVALID_NAME, issue.H{`name`: name}))
+	}
+	return types.NewTypeReferenceType(name)
+}
+
+func constToType(v eval.Value) eval.Type {
+	switch v := v.(type) {
+	case *types.StringValue:
+		return types.NewStringType(nil, v.String())
+	case *types.IntegerValue:
+		return types.NewIntegerType(v.Int(), v.Int())
+	case *types.FloatValue:
+		return types.NewFloatType(v.Float(), v.Float())
+	case *types.BooleanValue:
+		return types.NewBooleanType(v.Bool())
+	case *types.UndefValue:
+		return types.DefaultUndefType()
+	default:
+		panic(unsupportedKeyword(`const`))
+	}
+}
+
+func enumToType(vs eval.List) eval.Type {
+	if vs.All(func(v eval.Value) bool { _, ok := v.(*types.StringValue); return ok }) {
+		ss := make([]string, vs.Len())
+		vs.EachWithIndex(func(v eval.Value, i int) { ss[i] = v.String() })
+		return types.NewEnumType(ss, false)
+	}
+	return variant(vs.Map(func(v eval.Value) eval.Value { return constToType(v) }))
+}
+
+// variant returns a Variant of the given types. The Variant is made Optional instead of including Undef.
+func variant(tl eval.List) eval.Type {
+	ts := make([]eval.Type, 0, tl.Len())
+	opt := false
+	tl.Each(func(v eval.Value) {
+		if _, ok := v.(*types.UndefType); ok {
+			opt = true
+		} else {
+			ts = append(ts, v.(eval.Type))
+		}
+	})
+	var t eval.Type
+	if len(ts) == 1 {
+		t = ts[0]
+	} else {
+		t = types.NewVariantType(ts...)
+	}
+	if opt {
+		t = optional(t)
+	}
+	return t
+}
+
+func optional(t eval.Type) eval.Type {
+	switch t.(type) {
+	case *types.OptionalType, *types.UndefType:
+		return t
+	}
+	return types.NewOptionalType(t)
+}
+
+func requiredNames(s eval.OrderedMap) map[string]bool {
+	required := make(map[string]bool)
+	if rv, ok := listValue(s, `required`); ok {
+		rv.Each(func(n eval.Value) { required[n.String()] = true })
+	}
+	return required
+}
+
+// integerBound returns the given whole number as an int64, clamped to the range of an int64
+func integerBound(f float64) int64 {
+	switch {
+	case f <= math.MinInt64:
+		return math.MinInt64
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+func sizeType(s eval.OrderedMap, minKey, maxKey string) *types.IntegerType {
+	min := int64(0)
+	max := int64(math.MaxInt64)
+	if v, ok := numberValue(s, minKey); ok {
+		min = v.Int()
+	}
+	if v, ok := numberValue(s, maxKey); ok {
+		max = v.Int()
+	}
+	return types.NewIntegerType(min, max)
+}
+
+// assertKeywords panics unless all keywords of the given schema are among the given keywords or annotations
+func assertKeywords(s eval.OrderedMap, keywords ...string) {
+	s.EachKey(func(k eval.Value) {
+		kw := k.String()
+		if annotations[kw] {
+			return
+		}
+		for _, ak := range keywords {
+			if kw == ak {
+				return
+			}
+		}
+		panic(unsupportedKeyword(kw))
+	})
+}
+
+// listValue returns the array value of the given keyword. It panics if the value is not an array.
+func listValue(s eval.OrderedMap, keyword string) (eval.List, bool) {
+	v, ok := s.Get4(keyword)
+	if !ok {
+		return nil, false
+	}
+	if l, ok := v.(*types.ArrayValue); ok {
+		return l, true
+	}
+	panic(invalidValue(keyword, `array`, v))
+}
+
+// mapValue returns the object value of the given keyword. It panics if the value is not an object.
+func mapValue(s eval.OrderedMap, keyword string) (eval.OrderedMap, bool) {
+	v, ok := s.Get4(keyword)
+	if !ok {
+		return nil, false
+	}
+	if m, ok := v.(eval.OrderedMap); ok {
+		return m, true
+	}
+	panic(invalidValue(keyword, `object`, v))
+}
+
+// numberValue returns the numeric value of the given keyword. It panics if the value is not a number.
+func numberValue(s eval.OrderedMap, keyword string) (eval.NumericValue, bool) {
+	v, ok := s.Get4(keyword)
+	if !ok {
+		return nil, false
+	}
+	if n, ok := v.(eval.NumericValue); ok {
+		return n, true
+	}
+	panic(invalidValue(keyword, `number`, v))
+}
+
+// jsonType returns the name of the JSON type of the given value
+func jsonType(v eval.Value) string {
+	switch v.(type) {
+	case eval.OrderedMap:
+		return `object`
+	case *types.ArrayValue:
+		return `array`
+	case *types.StringValue:
+		return `string`
+	case eval.NumericValue:
+		return `number`
+	case *types.BooleanValue:
+		return `boolean`
+	default:
+		return `null`
+	}
+}
+
+func invalidValue(keyword, expected string, v eval.Value) error {
+	return eval.Error(eval.EVAL_JSON_SCHEMA_INVALID_VALUE, issue.H{`keyword`: keyword, `expected`: expected, `actual`: jsonType(v)})
+}
+
+func unsupportedKeyword(keyword string) error {
+	return eval.Error(eval.EVAL_JSON_SCHEMA_UNSUPPORTED_KEYWORD, issue.H{`keyword`: keyword})
+}
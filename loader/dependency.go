@@ -35,6 +35,14 @@ func (l *dependencyLoader) LoadEntry(c eval.Context, name eval.TypedName) eval.L
 	return entry
 }
 
+func (l *dependencyLoader) Discover(c eval.Context, predicate func(tn eval.TypedName) bool) []eval.TypedName {
+	found := l.basicLoader.Discover(c, predicate)
+	for _, ml := range l.loaders {
+		found = mergeNames(found, ml.Discover(c, predicate))
+	}
+	return found
+}
+
 func (l *dependencyLoader) LoaderFor(moduleName string) eval.ModuleLoader {
 	return l.index[moduleName]
 }
@@ -62,6 +62,8 @@ func newFileBasedLoader(parent eval.Loader, path, moduleName string, loadables .
 
 func (l *fileBasedLoader) newSmartPath(pathType eval.PathType, moduleNameRelative bool) SmartPath {
 	switch pathType {
+	case eval.PUPPET_ACTIVITY_PATH:
+		return l.newPuppetActivityPath(moduleNameRelative)
 	case eval.PUPPET_FUNCTION_PATH:
 		return l.newPuppetFunctionPath(moduleNameRelative)
 	case eval.PUPPET_DATA_TYPE_PATH:
@@ -147,6 +149,26 @@ func (l *fileBasedLoader) LoadEntry(c eval.Context, name eval.TypedName) eval.Lo
 	return entry
 }
 
+func (l *fileBasedLoader) Discover(c eval.Context, predicate func(tn eval.TypedName) bool) []eval.TypedName {
+	found := l.parentedLoader.Discover(c, predicate)
+	if p := c.Sandbox(); p != nil && !p.Grants(eval.CAPABILITY_DISK_LOADING) {
+		return found
+	}
+	for _, sps := range l.paths {
+		for _, sp := range sps {
+			l.ensureIndexed(sp)
+		}
+	}
+	indexed := make([]eval.TypedName, 0, len(l.index))
+	for k := range l.index {
+		tn := eval.TypedNameFromMapKey(k)
+		if predicate(tn) {
+			indexed = append(indexed, tn)
+		}
+	}
+	return mergeNames(found, indexed)
+}
+
 func (l *fileBasedLoader) ModuleName() string {
 	return l.moduleName
 }
@@ -182,7 +204,7 @@ func (l *fileBasedLoader) find(c eval.Context, name eval.TypedName) eval.LoaderE
 				}
 
 				// Look for special 'init' plan
-				origins, smartPath := l.findExistingPath(l.initPlanName)
+				origins, smartPath := l.findExistingPath(c, l.initPlanName)
 				if smartPath == nil {
 					return nil
 				}
@@ -197,7 +219,7 @@ func (l *fileBasedLoader) find(c eval.Context, name eval.TypedName) eval.LoaderE
 				}
 
 				// Look for special 'init' task
-				origins, smartPath := l.findExistingPath(l.initTaskName)
+				origins, smartPath := l.findExistingPath(c, l.initTaskName)
 				if smartPath == nil {
 					return nil
 				}
@@ -212,7 +234,7 @@ func (l *fileBasedLoader) find(c eval.Context, name eval.TypedName) eval.LoaderE
 				}
 
 				// Look for special 'init_typeset' TypeSet
-				origins, smartPath := l.findExistingPath(l.initTypeSetName)
+				origins, smartPath := l.findExistingPath(c, l.initTypeSetName)
 				if smartPath == nil {
 					return nil
 				}
@@ -230,7 +252,7 @@ func (l *fileBasedLoader) find(c eval.Context, name eval.TypedName) eval.LoaderE
 		}
 	}
 
-	origins, smartPath := l.findExistingPath(name)
+	origins, smartPath := l.findExistingPath(c, name)
 	if smartPath != nil {
 		return l.instantiate(c, smartPath, name, origins)
 	}
@@ -262,7 +284,8 @@ func (l *fileBasedLoader) find(c eval.Context, name eval.TypedName) eval.LoaderE
 	return nil
 }
 
-func (l *fileBasedLoader) findExistingPath(name eval.TypedName) (origins []string, smartPath SmartPath) {
+func (l *fileBasedLoader) findExistingPath(c eval.Context, name eval.TypedName) (origins []string, smartPath SmartPath) {
+	eval.AssertCapability(c, eval.CAPABILITY_DISK_LOADING, fmt.Sprintf(`loading of %s from '%s'`, name, l.path))
 	l.lock.Lock()
 	defer l.lock.Unlock()
 
@@ -285,11 +308,16 @@ func (l *fileBasedLoader) ensureIndexed(sp SmartPath) {
 }
 
 func (l *fileBasedLoader) instantiate(c eval.Context, smartPath SmartPath, name eval.TypedName, origins []string) eval.LoaderEntry {
-	smartPath.Instantiator()(c, l, name, origins)
+	// Definitions must end up in this loader rather than in the loader of the caller or they would
+	// be lost to all other callers
+	c.DoWithLoader(l, func() {
+		smartPath.Instantiator()(c, l, name, origins)
+	})
 	return l.GetEntry(name)
 }
 
 func (l *fileBasedLoader) GetContent(c eval.Context, path string) []byte {
+	eval.AssertCapability(c, eval.CAPABILITY_DISK_LOADING, fmt.Sprintf(`loading of %s`, path))
 	content, err := ioutil.ReadFile(path)
 	if err != nil {
 		panic(eval.Error(eval.EVAL_UNABLE_TO_READ_FILE, issue.H{`path`: path, `detail`: err.Error()}))
@@ -0,0 +1,53 @@
+package loader_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func TestLoadFromChildLoader(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `childloader`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fnDir := filepath.Join(tmpDir, `mymod`, `functions`)
+	if err = os.MkdirAll(fnDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(fnDir, `hello.pp`), []byte("function mymod::hello() { 'hello' }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eval.Puppet.Set(`module_path`, types.WrapString(tmpDir))
+	defer eval.Puppet.Reset()
+
+	err = eval.Puppet.Try(func(c eval.Context) error {
+		// Each child must find the function regardless of which child caused it to be loaded
+		for i := 0; i < 2; i++ {
+			cc := impl.WithParent(c, impl.NewEvaluator, eval.NewParentedLoader(c.Loader()), c.Logger(), c.ImplementationRegistry())
+			v, err := eval.TopEvaluate(cc, cc.ParseAndValidate(``, `mymod::hello()`, false))
+			if err != nil {
+				return err
+			}
+			if v.String() != `hello` {
+				t.Errorf(`expected hello, got %s`, v)
+			}
+		}
+		if _, ok := eval.Load(c, eval.NewTypedName(eval.NsFunction, `mymod::hello`)); !ok {
+			t.Error(`function was not added to the module loader`)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
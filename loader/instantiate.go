@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/lyraproj/puppet-evaluator/eval"
@@ -77,72 +78,141 @@ func InstantiatePuppetType(ctx eval.Context, loader ContentProvidingLoader, tn e
 func InstantiatePuppetTask(ctx eval.Context, loader ContentProvidingLoader, tn eval.TypedName, sources []string) {
 	name := tn.Name()
 	metadata := ``
-	taskSource := ``
+	executables := make([]string, 0, len(sources))
 	for _, sourceRef := range sources {
 		if strings.HasSuffix(sourceRef, `.json`) {
 			metadata = sourceRef
-		} else if taskSource == `` {
-			taskSource = sourceRef
 		} else {
-			panic(eval.Error(eval.EVAL_TASK_TOO_MANY_FILES, issue.H{`name`: name, `directory`: filepath.Dir(sourceRef)}))
+			executables = append(executables, sourceRef)
 		}
 	}
 
-	if taskSource == `` {
-		panic(eval.Error(eval.EVAL_TASK_NO_EXECUTABLE_FOUND, issue.H{`name`: name, `directory`: filepath.Dir(sources[0])}))
+	if len(executables) == 0 {
+		panic(ctx.Error(nil, eval.EVAL_TASK_NO_EXECUTABLE_FOUND, issue.H{`name`: name, `directory`: filepath.Dir(sources[0])}))
 	}
-	task := createTask(ctx, loader, name, taskSource, metadata)
+	task := createTask(ctx, loader, name, executables, metadata)
 	origin := metadata
 	if origin == `` {
-		origin = taskSource
+		origin = executables[0]
 	}
 	loader.(eval.DefiningLoader).SetEntry(tn, eval.NewLoaderEntry(task, issue.NewLocation(origin, 0, 0)))
 }
 
-func createTask(ctx eval.Context, loader ContentProvidingLoader, name, taskSource, metadata string) eval.Value {
+func createTask(ctx eval.Context, loader ContentProvidingLoader, name string, executables []string, metadata string) eval.Value {
 	if metadata == `` {
-		return createTaskFromHash(ctx, name, taskSource, map[string]interface{}{})
+		return createTaskFromHash(ctx, name, executables, metadata, eval.EMPTY_MAP)
 	}
 	jsonText := loader.GetContent(ctx, metadata)
 	var parsedValue interface{}
 	d := json.NewDecoder(bytes.NewReader(jsonText))
 	d.UseNumber()
 	if err := d.Decode(&parsedValue); err != nil {
-		panic(eval.Error(eval.EVAL_TASK_BAD_JSON, issue.H{`path`: metadata, `detail`: err}))
+		panic(ctx.Error(nil, eval.EVAL_TASK_BAD_JSON, issue.H{`path`: metadata, `detail`: err}))
 	}
 	if jo, ok := parsedValue.(map[string]interface{}); ok {
-		return createTaskFromHash(ctx, name, taskSource, jo)
+		return createTaskFromHash(ctx, name, executables, metadata, types.WrapStringToInterfaceMap(ctx, jo))
 	}
-	panic(eval.Error(eval.EVAL_TASK_NOT_JSON_OBJECT, issue.H{`path`: metadata}))
+	panic(ctx.Error(nil, eval.EVAL_TASK_NOT_JSON_OBJECT, issue.H{`path`: metadata}))
 }
 
-func createTaskFromHash(ctx eval.Context, name, taskSource string, hash map[string]interface{}) eval.Value {
-	arguments := make(map[string]interface{}, 7)
-	arguments[`name`] = types.WrapString(name)
-	arguments[`executable`] = types.WrapString(taskSource)
-	for key, value := range hash {
-		if key == `parameters` || key == `output` {
-			if params, ok := value.(map[string]interface{}); ok {
-				for _, param := range params {
-					if paramHash, ok := param.(map[string]interface{}); ok {
-						if t, ok := paramHash[`type`]; ok {
-							if s, ok := t.(string); ok {
-								paramHash[`type`] = ctx.ParseType2(s)
-							}
-						} else {
-							paramHash[`type`] = types.DefaultDataType()
-						}
-					}
-				}
+// taskMetadataType is the type that the contents of a version 1 task metadata file must match
+const taskMetadataType = `Struct[
+  Optional[description] => String,
+  Optional[puppet_task_version] => Integer[1, 1],
+  Optional[supports_noop] => Boolean,
+  Optional[input_method] => Enum[stdin, environment, powershell, both],
+  Optional[parameters] => Hash[Pattern[/\A[a-z][a-z0-9_]*\z/], Struct[
+    Optional[description] => String,
+    Optional[type] => String[1],
+    Optional[sensitive] => Boolean,
+    Optional['default'] => Data]],
+  Optional[output] => Hash[Pattern[/\A[a-z][a-z0-9_]*\z/], Struct[
+    Optional[description] => String,
+    Optional[type] => String[1],
+    Optional[sensitive] => Boolean]],
+  Optional[implementations] => Array[Struct[
+    name => String[1],
+    Optional[requirements] => Array[String[1]],
+    Optional[input_method] => Enum[stdin, environment, powershell, both],
+    Optional[files] => Array[String[1]]]],
+  Optional[files] => Array[String[1]],
+  Optional['private'] => Boolean,
+  Optional[remote] => Boolean,
+  Optional[extensions] => Hash[String[1], Data]]`
+
+// validFileReference matches a module relative path to a file or directory that a task may reference
+var validFileReference = regexp.MustCompile(`\A[a-z][a-z0-9_]*/(?:files|lib|tasks)/[^/]`)
+
+func createTaskFromHash(ctx eval.Context, name string, executables []string, metadata string, hash eval.OrderedMap) eval.Value {
+	if mt := ctx.ParseType2(taskMetadataType); !eval.IsInstance(mt, hash) {
+		panic(ctx.Error(nil, eval.EVAL_TASK_BAD_METADATA, issue.H{`path`: metadata, `detail`: strings.TrimSpace(eval.DescribeMismatch(``, mt, eval.DetailedValueType(hash)))}))
+	}
+
+	executable := executables[0]
+	if iv, ok := hash.Get4(`implementations`); ok {
+		// The first implementation becomes the default executable
+		iv.(eval.List).EachWithIndex(func(impl eval.Value, i int) {
+			path := findImplementation(ctx, name, executables, impl.(eval.OrderedMap).Get5(`name`, eval.EMPTY_STRING).String())
+			if i == 0 {
+				executable = path
 			}
-		}
-		arguments[key] = value
+			assertFileReferences(ctx, name, impl.(eval.OrderedMap))
+		})
+	} else if len(executables) > 1 {
+		panic(ctx.Error(nil, eval.EVAL_TASK_TOO_MANY_FILES, issue.H{`name`: name, `directory`: filepath.Dir(executable)}))
 	}
+	assertFileReferences(ctx, name, hash)
+
+	entries := make([]*types.HashEntry, 0, hash.Len()+2)
+	entries = append(entries, types.WrapHashEntry2(`name`, types.WrapString(name)))
+	entries = append(entries, types.WrapHashEntry2(`executable`, types.WrapString(executable)))
+	hash.EachPair(func(k, v eval.Value) {
+		if key := k.String(); key == `parameters` || key == `output` {
+			v = resolveParameterTypes(ctx, v.(eval.OrderedMap))
+		}
+		entries = append(entries, types.WrapHashEntry(k, v))
+	})
 
 	if taskCtor, ok := eval.Load(ctx, eval.NewTypedName(eval.NsConstructor, `Task`)); ok {
-		return taskCtor.(eval.Function).Call(ctx, nil, types.WrapStringToInterfaceMap(ctx, arguments))
+		return taskCtor.(eval.Function).Call(ctx, nil, types.WrapHash(entries))
+	}
+	panic(ctx.Error(nil, eval.EVAL_TASK_INITIALIZER_NOT_FOUND, issue.NO_ARGS))
+}
+
+// resolveParameterTypes returns a copy of the given parameter declarations where each type string
+// is replaced by the type that it denotes. A parameter without a type is of type Data.
+func resolveParameterTypes(ctx eval.Context, params eval.OrderedMap) eval.OrderedMap {
+	return params.MapValues(func(param eval.Value) eval.Value {
+		ph := param.(eval.OrderedMap)
+		var pt eval.Value = types.DefaultDataType()
+		if ts, ok := ph.Get4(`type`); ok {
+			pt = ctx.ParseType2(ts.String())
+		}
+		return ph.Merge(types.SingletonHash2(`type`, pt))
+	})
+}
+
+// findImplementation returns the path of the executable that has the given file name
+func findImplementation(ctx eval.Context, name string, executables []string, implName string) string {
+	for _, executable := range executables {
+		if filepath.Base(executable) == implName {
+			return executable
+		}
+	}
+	panic(ctx.Error(nil, eval.EVAL_TASK_IMPLEMENTATION_NOT_FOUND, issue.H{`name`: name, `implementation`: implName, `directory`: filepath.Dir(executables[0])}))
+}
+
+// assertFileReferences asserts that each entry in the `files` array of the given hash is a module
+// relative path that doesn't escape the module
+func assertFileReferences(ctx eval.Context, name string, hash eval.OrderedMap) {
+	if fv, ok := hash.Get4(`files`); ok {
+		fv.(eval.List).Each(func(f eval.Value) {
+			file := f.String()
+			if !validFileReference.MatchString(file) || strings.Contains(file, `..`) {
+				panic(ctx.Error(nil, eval.EVAL_TASK_BAD_FILE_REFERENCE, issue.H{`name`: name, `file`: file}))
+			}
+		})
 	}
-	panic(eval.Error(eval.EVAL_TASK_INITIALIZER_NOT_FOUND, issue.NO_ARGS))
 }
 
 // Extract a single Definition and return it. Will fail and report an error unless the program contains
@@ -6,6 +6,7 @@ import (
 	"github.com/lyraproj/puppet-evaluator/impl"
 	"github.com/lyraproj/puppet-evaluator/types"
 	"reflect"
+	"strings"
 	"sync"
 )
 
@@ -109,6 +110,26 @@ func (l *basicLoader) SetEntry(name eval.TypedName, entry eval.LoaderEntry) eval
 	return entry
 }
 
+func (l *basicLoader) Discover(c eval.Context, predicate func(tn eval.TypedName) bool) []eval.TypedName {
+	found := make([]eval.TypedName, 0)
+	l.lock.RLock()
+	for k, v := range l.namedEntries {
+		if v.Value() == nil {
+			continue
+		}
+		tn := eval.TypedNameFromMapKey(k)
+		if n, ok := v.Value().(interface{ Name() string }); ok && strings.EqualFold(n.Name(), tn.Name()) {
+			// Map keys are lower case. The name of the value retains the original case
+			tn = eval.NewTypedName2(tn.Namespace(), n.Name(), tn.Authority())
+		}
+		if predicate(tn) {
+			found = append(found, tn)
+		}
+	}
+	l.lock.RUnlock()
+	return found
+}
+
 func (l *basicLoader) NameAuthority() eval.URI {
 	return eval.RUNTIME_NAME_AUTHORITY
 }
@@ -121,6 +142,10 @@ func (l *parentedLoader) LoadEntry(c eval.Context, name eval.TypedName) eval.Loa
 	return entry
 }
 
+func (l *parentedLoader) Discover(c eval.Context, predicate func(tn eval.TypedName) bool) []eval.TypedName {
+	return mergeNames(l.parent.Discover(c, predicate), l.basicLoader.Discover(c, predicate))
+}
+
 func (l *parentedLoader) NameAuthority() eval.URI {
 	return l.parent.NameAuthority()
 }
@@ -148,6 +173,35 @@ func (l *typeSetLoader) LoadEntry(c eval.Context, name eval.TypedName) eval.Load
 	return entry
 }
 
+func (l *typeSetLoader) Discover(c eval.Context, predicate func(tn eval.TypedName) bool) []eval.TypedName {
+	found := make([]eval.TypedName, 0)
+	l.typeSet.Types().EachKey(func(k eval.Value) {
+		tn := eval.NewTypedName(eval.NsType, l.typeSet.Name()+`::`+k.String())
+		if predicate(tn) {
+			found = append(found, tn)
+		}
+	})
+	return mergeNames(l.parentedLoader.Discover(c, predicate), found)
+}
+
 func (l *typeSetLoader) SetEntry(name eval.TypedName, entry eval.LoaderEntry) eval.LoaderEntry {
 	return l.parent.(eval.DefiningLoader).SetEntry(name, entry)
 }
+
+// mergeNames returns the names of a followed by the names of b that are not present in a
+func mergeNames(a, b []eval.TypedName) []eval.TypedName {
+	if len(a) == 0 {
+		return b
+	}
+	seen := make(map[string]bool, len(a))
+	for _, tn := range a {
+		seen[tn.MapKey()] = true
+	}
+	for _, tn := range b {
+		if !seen[tn.MapKey()] {
+			seen[tn.MapKey()] = true
+			a = append(a, tn)
+		}
+	}
+	return a
+}
@@ -0,0 +1,321 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// analysis is the result of parsing a document with respect to a position in that document
+type analysis struct {
+	c      eval.Context
+	uri    string
+	text   string
+	offset int
+
+	// expr is the innermost expression at the position, or nil if the document cannot be parsed
+	expr parser.Expression
+
+	// path holds the ancestors of expr, outermost first
+	path []parser.Expression
+}
+
+// analyze parses the document with the given URI and calls the given function with an analysis
+// of the given position. The definitions of the document are added to a loader that is private to
+// the call. Any error raised by the function is silently ignored since a request for information
+// about a document that contains errors is not an error in itself.
+func (s *Server) analyze(uri string, pos Position, f func(a *analysis)) {
+	text, ok := s.docs[uri]
+	if !ok {
+		return
+	}
+	c := impl.WithParent(s.c, impl.NewEvaluator, eval.NewParentedLoader(s.c.Loader()), s.c.Logger(), s.c.ImplementationRegistry())
+	eval.DoWithContext(c, func(c eval.Context) {
+		defer func() {
+			recover()
+		}()
+		a := &analysis{c: c, uri: uri, text: text, offset: offsetAt(text, pos)}
+		if prog, err := parser.CreateParser().Parse(uriToPath(uri), text, false); err == nil {
+			a.locate(prog)
+			a.define(prog)
+		}
+		f(a)
+	})
+}
+
+// locate finds the innermost expression that contains the offset of the receiver
+func (a *analysis) locate(prog parser.Expression) {
+	prog.AllContents(nil, func(path []parser.Expression, e parser.Expression) {
+		start := e.ByteOffset()
+		if a.offset < start || a.offset > start+e.ByteLength() {
+			return
+		}
+		if a.expr == nil || e.ByteLength() < a.expr.ByteLength() || e.ByteLength() == a.expr.ByteLength() && len(path) > len(a.path) {
+			a.expr = e
+			a.path = append(make([]parser.Expression, 0, len(path)), path...)
+		}
+	})
+}
+
+// define adds the definitions of the given program. Definitions that cannot be resolved are ignored
+func (a *analysis) define(prog parser.Expression) {
+	defer func() {
+		recover()
+	}()
+	a.c.AddDefinitions(prog)
+	a.c.ResolveDefinitions()
+}
+
+// parent returns the parent of the expression at the offset or nil if there is no such parent
+func (a *analysis) parent() parser.Expression {
+	if n := len(a.path); n > 0 {
+		return a.path[n-1]
+	}
+	return nil
+}
+
+// rangeOf returns the range of the given expression
+func rangeOf(e parser.Expression) Range {
+	l := e.Locator()
+	end := e.ByteOffset() + e.ByteLength()
+	return Range{
+		Start: Position{e.Line() - 1, e.Pos() - 1},
+		End:   Position{l.LineForOffset(end) - 1, l.PosOnLine(end) - 1}}
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	p := &TextDocumentPositionParams{}
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	var result interface{}
+	s.analyze(p.TextDocument.URI, p.Position, func(a *analysis) {
+		switch e := a.expr.(type) {
+		case *parser.QualifiedReference:
+			target := parser.Expression(e)
+			if acc, ok := a.parent().(*parser.AccessExpression); ok && acc.Operand() == e {
+				// Show the parameterized type
+				target = acc
+			}
+			t := a.c.ResolveType(target)
+			b := bytes.NewBufferString("```puppet\n")
+			t.ToString(b, eval.PRETTY_EXPANDED, nil)
+			b.WriteString("\n```")
+			r := rangeOf(target)
+			result = &Hover{Contents: MarkupContent{`markdown`, b.String()}, Range: &r}
+		case *parser.QualifiedName:
+			if f, ok := eval.Load(a.c, eval.NewTypedName(eval.NsFunction, e.Name())); ok {
+				b := bytes.NewBufferString("```puppet\n")
+				for i, si := range signatures(f.(eval.Function)) {
+					if i > 0 {
+						b.WriteByte('\n')
+					}
+					b.WriteString(si.Label)
+				}
+				b.WriteString("\n```")
+				r := rangeOf(e)
+				result = &Hover{Contents: MarkupContent{`markdown`, b.String()}, Range: &r}
+			}
+		}
+	})
+	return result, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	p := &TextDocumentPositionParams{}
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	var result interface{}
+	s.analyze(p.TextDocument.URI, p.Position, func(a *analysis) {
+		var names []eval.TypedName
+		switch e := a.expr.(type) {
+		case *parser.QualifiedReference:
+			names = []eval.TypedName{eval.NewTypedName(eval.NsType, e.Name())}
+		case *parser.QualifiedName:
+			names = []eval.TypedName{eval.NewTypedName(eval.NsFunction, e.Name()), eval.NewTypedName(eval.NsPlan, e.Name())}
+		case *parser.LiteralString:
+			// The name of a plan given to run_plan
+			if call, ok := a.parent().(*parser.CallNamedFunctionExpression); ok && len(call.Arguments()) > 0 && call.Arguments()[0] == e {
+				if qn, ok := call.Functor().(*parser.QualifiedName); ok && qn.Name() == `run_plan` {
+					names = []eval.TypedName{eval.NewTypedName(eval.NsPlan, e.StringValue())}
+				}
+			}
+		}
+		for _, tn := range names {
+			if loc := a.definitionOf(tn); loc != nil {
+				result = loc
+				return
+			}
+		}
+	})
+	return result, nil
+}
+
+// definitionOf returns the location of the definition of the given name, or nil if the name cannot
+// be loaded or if it isn't defined in a file, e.g. because it is defined in Go
+func (a *analysis) definitionOf(tn eval.TypedName) *Location {
+	if _, ok := eval.Load(a.c, tn); !ok {
+		return nil
+	}
+	entry := a.c.Loader().LoadEntry(a.c, tn)
+	if entry == nil || entry.Origin() == nil || entry.Origin().File() == `` {
+		return nil
+	}
+	origin := entry.Origin()
+	uri := a.uri
+	if origin.File() != uriToPath(a.uri) {
+		uri = pathToURI(origin.File())
+	}
+	// The range is empty since the byte length of a parsed definition isn't reliable
+	pos := Position{origin.Line() - 1, origin.Pos() - 1}
+	if pos.Line < 0 {
+		pos.Line = 0
+	}
+	if pos.Character < 0 {
+		pos.Character = 0
+	}
+	return &Location{uri, Range{pos, pos}}
+}
+
+func (s *Server) signatureHelp(params json.RawMessage) (interface{}, error) {
+	p := &TextDocumentPositionParams{}
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	var result interface{}
+	s.analyze(p.TextDocument.URI, p.Position, func(a *analysis) {
+		name, arg, ok := callAt(a.text, a.offset)
+		if !ok {
+			return
+		}
+		f, ok := eval.Load(a.c, eval.NewTypedName(eval.NsFunction, name))
+		if !ok {
+			return
+		}
+		sh := &SignatureHelp{Signatures: signatures(f.(eval.Function))}
+		for i, l := range f.(eval.Function).Dispatchers() {
+			ps := l.Parameters()
+			n := len(ps)
+			if n > 0 && (arg < n || ps[n-1].CapturesRest()) {
+				sh.ActiveSignature = i
+				sh.ActiveParameter = arg
+				if arg >= n {
+					sh.ActiveParameter = n - 1
+				}
+				break
+			}
+		}
+		result = sh
+	})
+	return result, nil
+}
+
+// signatures returns one signature per dispatcher of the given function. The parameters of a
+// signature are the parameters of the dispatcher followed by its block, if any.
+func signatures(f eval.Function) []SignatureInformation {
+	ds := f.Dispatchers()
+	sis := make([]SignatureInformation, len(ds))
+	for i, l := range ds {
+		ps := make([]ParameterInformation, 0, len(l.Parameters())+1)
+		for _, p := range l.Parameters() {
+			label := p.Type().String() + ` `
+			if p.CapturesRest() {
+				label += `*`
+			}
+			ps = append(ps, ParameterInformation{label + `$` + p.Name()})
+		}
+		if bt := l.Signature().BlockType(); bt != nil {
+			ps = append(ps, ParameterInformation{bt.String() + ` &$` + l.Signature().BlockName()})
+		}
+		labels := make([]string, len(ps))
+		for j, p := range ps {
+			labels[j] = p.Label
+		}
+		sis[i] = SignatureInformation{Label: f.Name() + `(` + strings.Join(labels, `, `) + `)`, Parameters: ps}
+	}
+	return sis
+}
+
+// callAt returns the name of the function whose argument list encloses the given offset together
+// with the index of the argument at the offset. The receiver of a method call counts as the first
+// argument. The text does not need to be parsable.
+func callAt(text string, offset int) (string, int, bool) {
+	type open struct {
+		name string
+		args int
+		call bool
+	}
+	stack := make([]*open, 0)
+	for i := 0; i < offset; i++ {
+		switch c := text[i]; c {
+		case '#':
+			for i+1 < offset && text[i+1] != '\n' {
+				i++
+			}
+		case '/':
+			if i+1 < offset && text[i+1] == '*' {
+				e := strings.Index(text[i+2:], `*/`)
+				if e < 0 {
+					return ``, 0, false
+				}
+				i += e + 3
+			}
+		case '\'', '"':
+			for i++; i < offset && text[i] != c; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			name, method := identifierBefore(text, i)
+			o := &open{name: name, call: name != ``}
+			if method {
+				o.args = 1
+			}
+			stack = append(stack, o)
+		case '[', '{':
+			stack = append(stack, &open{})
+		case ')', ']', '}':
+			if n := len(stack); n > 0 {
+				stack = stack[:n-1]
+			}
+		case ',':
+			if n := len(stack); n > 0 {
+				stack[n-1].args++
+			}
+		}
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if o := stack[i]; o.call {
+			return o.name, o.args, true
+		}
+	}
+	return ``, 0, false
+}
+
+// identifierBefore returns the lower case name that ends immediately before the given position,
+// disregarding whitespace, and whether or not that name is preceded by a '.'
+func identifierBefore(text string, pos int) (string, bool) {
+	end := pos
+	for end > 0 && (text[end-1] == ' ' || text[end-1] == '\t') {
+		end--
+	}
+	start := end
+	for start > 0 {
+		c := text[start-1]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == ':') {
+			break
+		}
+		start--
+	}
+	name := text[start:end]
+	if name == `` || !(name[0] >= 'a' && name[0] <= 'z') {
+		return ``, false
+	}
+	return name, start > 0 && text[start-1] == '.'
+}
@@ -0,0 +1,201 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON-RPC error codes
+const (
+	PARSE_ERROR      = -32700
+	INVALID_REQUEST  = -32600
+	METHOD_NOT_FOUND = -32601
+	INVALID_PARAMS   = -32602
+	INTERNAL_ERROR   = -32603
+)
+
+// Diagnostic severities
+const (
+	SEVERITY_ERROR   = 1
+	SEVERITY_WARNING = 2
+)
+
+type (
+	request struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id,omitempty"`
+		Method  string           `json:"method"`
+		Params  json.RawMessage  `json:"params,omitempty"`
+	}
+
+	responseError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	Position struct {
+		Line      int `json:"line"`
+		Character int `json:"character"`
+	}
+
+	Range struct {
+		Start Position `json:"start"`
+		End   Position `json:"end"`
+	}
+
+	Location struct {
+		URI   string `json:"uri"`
+		Range Range  `json:"range"`
+	}
+
+	Diagnostic struct {
+		Range    Range  `json:"range"`
+		Severity int    `json:"severity"`
+		Code     string `json:"code,omitempty"`
+		Source   string `json:"source"`
+		Message  string `json:"message"`
+	}
+
+	TextDocumentIdentifier struct {
+		URI string `json:"uri"`
+	}
+
+	TextDocumentItem struct {
+		URI        string `json:"uri"`
+		LanguageID string `json:"languageId"`
+		Version    int    `json:"version"`
+		Text       string `json:"text"`
+	}
+
+	TextDocumentPositionParams struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+		Position     Position               `json:"position"`
+	}
+
+	DidOpenTextDocumentParams struct {
+		TextDocument TextDocumentItem `json:"textDocument"`
+	}
+
+	DidChangeTextDocumentParams struct {
+		TextDocument   TextDocumentIdentifier `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+
+	DidCloseTextDocumentParams struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+	}
+
+	PublishDiagnosticsParams struct {
+		URI         string       `json:"uri"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+	}
+
+	MarkupContent struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	}
+
+	Hover struct {
+		Contents MarkupContent `json:"contents"`
+		Range    *Range        `json:"range,omitempty"`
+	}
+
+	ParameterInformation struct {
+		Label string `json:"label"`
+	}
+
+	SignatureInformation struct {
+		Label      string                 `json:"label"`
+		Parameters []ParameterInformation `json:"parameters"`
+	}
+
+	SignatureHelp struct {
+		Signatures      []SignatureInformation `json:"signatures"`
+		ActiveSignature int                    `json:"activeSignature"`
+		ActiveParameter int                    `json:"activeParameter"`
+	}
+)
+
+// readMessage reads the body of one message framed by a Content-Length header
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get(`Content-Length`))
+	if err != nil {
+		return nil, fmt.Errorf(`invalid Content-Length header: %s`, err.Error())
+	}
+	body := make([]byte, length)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// writeMessage writes the given message framed by a Content-Length header
+func writeMessage(w io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// uriToPath returns the file system path of a file URI
+func uriToPath(uri string) string {
+	if u, err := url.Parse(uri); err == nil && u.Scheme == `file` {
+		return filepath.FromSlash(u.Path)
+	}
+	return uri
+}
+
+// pathToURI returns the file URI of a file system path
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: `file`, Path: filepath.ToSlash(path)}).String()
+}
+
+// offsetAt returns the byte offset in the given text that corresponds to the given position
+func offsetAt(text string, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		nl := strings.IndexByte(text[offset:], '\n')
+		if nl < 0 {
+			return len(text)
+		}
+		offset += nl + 1
+	}
+	for c := 0; c < pos.Character && offset < len(text) && text[offset] != '\n'; c++ {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// positionAt returns the position that corresponds to the given byte offset in the given text
+func positionAt(text string, offset int) Position {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	before := text[:offset]
+	line := strings.Count(before, "\n")
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return Position{Line: line, Character: utf8.RuneCountInString(before[lineStart:])}
+}
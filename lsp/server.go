@@ -0,0 +1,221 @@
+// Package lsp provides a Language Server Protocol server for the Puppet language that communicates
+// using JSON-RPC over a pair of streams, typically stdin and stdout.
+//
+// The server publishes diagnostics for open documents and answers hover, definition, and signature
+// help requests. All names are resolved using the loaders of the context given to the server so no
+// network access is ever required.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+)
+
+// Server is a Language Server Protocol server
+type Server struct {
+	c        eval.Context
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]string
+	shutdown bool
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var requestHandlers = map[string]handler{
+	`initialize`:                 (*Server).initialize,
+	`shutdown`:                   (*Server).shutdownRequest,
+	`textDocument/hover`:         (*Server).hover,
+	`textDocument/definition`:    (*Server).definition,
+	`textDocument/signatureHelp`: (*Server).signatureHelp,
+}
+
+var notificationHandlers = map[string]handler{
+	`textDocument/didOpen`:   (*Server).didOpen,
+	`textDocument/didChange`: (*Server).didChange,
+	`textDocument/didClose`:  (*Server).didClose,
+}
+
+// NewServer creates a server that reads messages from in and writes messages to out. Names are
+// resolved using the loaders of the given context.
+func NewServer(c eval.Context, in io.Reader, out io.Writer) *Server {
+	return &Server{c: c, in: bufio.NewReader(in), out: out, docs: make(map[string]string)}
+}
+
+// Run processes messages until an exit notification is received or the input is exhausted. The
+// returned error is nil when the server exits after a shutdown request.
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if err != nil {
+			if err == io.EOF && s.shutdown {
+				return nil
+			}
+			return err
+		}
+		req := &request{}
+		if err = json.Unmarshal(body, req); err != nil {
+			if err = s.respond(nil, nil, &responseError{PARSE_ERROR, err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == `exit` {
+			if s.shutdown {
+				return nil
+			}
+			return fmt.Errorf(`exit notification received without a prior shutdown request`)
+		}
+		if err = s.dispatch(req); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) dispatch(req *request) error {
+	if req.ID == nil {
+		if h, ok := notificationHandlers[req.Method]; ok {
+			if _, err := h(s, req.Params); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	h, ok := requestHandlers[req.Method]
+	if !ok {
+		return s.respond(req.ID, nil, &responseError{METHOD_NOT_FOUND, fmt.Sprintf(`method '%s' is not supported`, req.Method)})
+	}
+	result, err := h(s, req.Params)
+	if err != nil {
+		return s.respond(req.ID, nil, &responseError{INVALID_PARAMS, err.Error()})
+	}
+	return s.respond(req.ID, result, nil)
+}
+
+func (s *Server) respond(id *json.RawMessage, result interface{}, rspErr *responseError) error {
+	msg := map[string]interface{}{`jsonrpc`: `2.0`, `id`: id}
+	if rspErr != nil {
+		msg[`error`] = rspErr
+	} else {
+		msg[`result`] = result
+	}
+	return writeMessage(s.out, msg)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, map[string]interface{}{`jsonrpc`: `2.0`, `method`: method, `params`: params})
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		`capabilities`: map[string]interface{}{
+			`textDocumentSync`:   1, // Full
+			`hoverProvider`:      true,
+			`definitionProvider`: true,
+			`signatureHelpProvider`: map[string]interface{}{
+				`triggerCharacters`: []string{`(`, `,`},
+			},
+		},
+		`serverInfo`: map[string]interface{}{`name`: `puppet-evaluator`},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	p := &DidOpenTextDocumentParams{}
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	s.docs[p.TextDocument.URI] = p.TextDocument.Text
+	return nil, s.publishDiagnostics(p.TextDocument.URI)
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	p := &DidChangeTextDocumentParams{}
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	if n := len(p.ContentChanges); n > 0 {
+		// Only full synchronization is supported so the last change holds the full text
+		s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+	}
+	return nil, s.publishDiagnostics(p.TextDocument.URI)
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	p := &DidCloseTextDocumentParams{}
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	return nil, s.notify(`textDocument/publishDiagnostics`, &PublishDiagnosticsParams{p.TextDocument.URI, []Diagnostic{}})
+}
+
+func (s *Server) publishDiagnostics(uri string) error {
+	return s.notify(`textDocument/publishDiagnostics`, &PublishDiagnosticsParams{uri, s.diagnostics(uri)})
+}
+
+// diagnostics validates the document with the given URI and returns the issues that were found
+func (s *Server) diagnostics(uri string) []Diagnostic {
+	text := s.docs[uri]
+	logger := eval.NewArrayLogger()
+	c := impl.WithParent(s.c, impl.NewEvaluator, s.c.Loader(), logger, s.c.ImplementationRegistry())
+
+	var failure interface{}
+	func() {
+		defer func() { failure = recover() }()
+		c.ParseAndValidate(uriToPath(uri), text, false)
+	}()
+
+	ds := make([]Diagnostic, 0)
+	for _, level := range []eval.LogLevel{eval.ERR, eval.WARNING} {
+		for _, e := range logger.Entries(level) {
+			if re, ok := e.(*eval.ReportedEntry); ok {
+				ds = append(ds, diagnostic(text, re.Issue()))
+			}
+		}
+	}
+	if failure != nil && len(logger.Entries(eval.ERR)) == 0 {
+		// Validation errors are logged before the validation fails so a failure without logged
+		// errors stems from the parser
+		if ri, ok := failure.(issue.Reported); ok {
+			ds = append(ds, diagnostic(text, ri))
+		} else {
+			ds = append(ds, Diagnostic{Severity: SEVERITY_ERROR, Source: `puppet`, Message: fmt.Sprint(failure)})
+		}
+	}
+	return ds
+}
+
+func diagnostic(text string, ri issue.Reported) Diagnostic {
+	d := Diagnostic{Severity: SEVERITY_ERROR, Code: string(ri.Code()), Source: `puppet`, Message: ri.Error()}
+	if ri.Severity() != issue.SEVERITY_ERROR {
+		d.Severity = SEVERITY_WARNING
+	}
+	if loc := ri.Location(); loc != nil && loc.Line() > 0 {
+		pos := Position{loc.Line() - 1, loc.Pos() - 1}
+		if pos.Character < 0 {
+			pos.Character = 0
+		}
+		// Let the range span the token that starts at the location
+		start := offsetAt(text, pos)
+		end := start
+		for end < len(text) && !strings.ContainsRune(" \t\r\n", rune(text[end])) {
+			end++
+		}
+		d.Range = Range{pos, positionAt(text, end)}
+	}
+	return d
+}
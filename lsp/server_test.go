@@ -0,0 +1,144 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/lsp"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+// session runs a server that reads the given messages and returns the messages that it writes
+func session(t *testing.T, messages ...interface{}) []map[string]interface{} {
+	t.Helper()
+	in := bytes.NewBufferString(``)
+	for _, m := range messages {
+		body, _ := json.Marshal(m)
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	out := bytes.NewBufferString(``)
+	if err := lsp.NewServer(eval.Puppet.RootContext(), in, out).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := make([]map[string]interface{}, 0)
+	r := bufio.NewReader(out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(header.Get(`Content-Length`))
+		body := make([]byte, n)
+		io.ReadFull(r, body)
+		m := map[string]interface{}{}
+		if err = json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, m)
+	}
+}
+
+func request(id int, method string, params interface{}) interface{} {
+	return map[string]interface{}{`jsonrpc`: `2.0`, `id`: id, `method`: method, `params`: params}
+}
+
+func notification(method string, params interface{}) interface{} {
+	return map[string]interface{}{`jsonrpc`: `2.0`, `method`: method, `params`: params}
+}
+
+func position(uri string, line, character int) interface{} {
+	return map[string]interface{}{
+		`textDocument`: map[string]interface{}{`uri`: uri},
+		`position`:     map[string]interface{}{`line`: line, `character`: character}}
+}
+
+func open(uri, text string) interface{} {
+	return notification(`textDocument/didOpen`, map[string]interface{}{
+		`textDocument`: map[string]interface{}{`uri`: uri, `languageId`: `puppet`, `version`: 1, `text`: text}})
+}
+
+func toJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func TestServer(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `lsp`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	fnDir := filepath.Join(tmpDir, `mymod`, `functions`)
+	if err = os.MkdirAll(fnDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(fnDir, `greet.pp`), []byte("# Greets\nfunction mymod::greet(String $name) >> String {\n  \"hello ${name}\"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	eval.Puppet.Set(`module_path`, types.WrapString(tmpDir))
+	defer eval.Puppet.Reset()
+
+	uri := `file:///tmp/test.pp`
+	text := "type Small = Integer[0, 9]\n" +
+		"function twice(Small $n) { $n * 2 }\n" +
+		"notice(twice(3), mymod::greet('x'))\n" +
+		"$x = String[1, 2]\n"
+	msgs := session(t,
+		request(1, `initialize`, map[string]interface{}{}),
+		open(uri, text),
+		open(`file:///tmp/bad.pp`, "$x = [1,\n"),
+		request(2, `textDocument/hover`, position(uri, 0, 14)),
+		request(3, `textDocument/hover`, position(uri, 3, 6)),
+		request(4, `textDocument/hover`, position(uri, 2, 2)),
+		request(5, `textDocument/definition`, position(uri, 2, 8)),
+		request(6, `textDocument/definition`, position(uri, 1, 17)),
+		request(7, `textDocument/definition`, position(uri, 2, 20)),
+		request(8, `textDocument/signatureHelp`, position(uri, 2, 30)),
+		request(12, `textDocument/signatureHelp`, position(uri, 2, 16)),
+		request(9, `textDocument/hover`, position(uri, 3, 1)),
+		request(10, `unknown`, nil),
+		request(11, `shutdown`, nil),
+		notification(`exit`, nil))
+	greetURI := `file://` + filepath.ToSlash(filepath.Join(tmpDir, `mymod`, `functions`, `greet.pp`))
+	expected := []string{
+		`{"id":1,"jsonrpc":"2.0","result":{"capabilities":{"definitionProvider":true,"hoverProvider":true,"signatureHelpProvider":{"triggerCharacters":["(",","]},"textDocumentSync":1},"serverInfo":{"name":"puppet-evaluator"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[],"uri":"file:///tmp/test.pp"}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[{"code":"LEX_UNEXPECTED_TOKEN","message":"unexpected token 'EOF' (file: /tmp/bad.pp, line: 2, column: 1)","range":{"end":{"character":0,"line":1},"start":{"character":0,"line":1}},"severity":1,"source":"puppet"}],"uri":"file:///tmp/bad.pp"}}`,
+		"{\"id\":2,\"jsonrpc\":\"2.0\",\"result\":{\"contents\":{\"kind\":\"markdown\",\"value\":\"```puppet\\nInteger[0, 9]\\n```\"},\"range\":{\"end\":{\"character\":26,\"line\":0},\"start\":{\"character\":13,\"line\":0}}}}",
+		"{\"id\":3,\"jsonrpc\":\"2.0\",\"result\":{\"contents\":{\"kind\":\"markdown\",\"value\":\"```puppet\\nString[1, 2]\\n```\"},\"range\":{\"end\":{\"character\":17,\"line\":3},\"start\":{\"character\":5,\"line\":3}}}}",
+		"{\"id\":4,\"jsonrpc\":\"2.0\",\"result\":{\"contents\":{\"kind\":\"markdown\",\"value\":\"```puppet\\nnotice(Any *$1)\\n```\"},\"range\":{\"end\":{\"character\":6,\"line\":2},\"start\":{\"character\":0,\"line\":2}}}}",
+		`{"id":5,"jsonrpc":"2.0","result":{"range":{"end":{"character":0,"line":1},"start":{"character":0,"line":1}},"uri":"file:///tmp/test.pp"}}`,
+		`{"id":6,"jsonrpc":"2.0","result":{"range":{"end":{"character":5,"line":0},"start":{"character":5,"line":0}},"uri":"file:///tmp/test.pp"}}`,
+		`{"id":7,"jsonrpc":"2.0","result":{"range":{"end":{"character":0,"line":1},"start":{"character":0,"line":1}},"uri":"` + greetURI + `"}}`,
+		`{"id":8,"jsonrpc":"2.0","result":{"activeParameter":0,"activeSignature":0,"signatures":[{"label":"mymod::greet(String $name)","parameters":[{"label":"String $name"}]}]}}`,
+		`{"id":12,"jsonrpc":"2.0","result":{"activeParameter":0,"activeSignature":0,"signatures":[{"label":"notice(Any *$1)","parameters":[{"label":"Any *$1"}]}]}}`,
+		`{"id":9,"jsonrpc":"2.0","result":null}`,
+		`{"error":{"code":-32601,"message":"method 'unknown' is not supported"},"id":10,"jsonrpc":"2.0"}`,
+		`{"id":11,"jsonrpc":"2.0","result":null}`,
+	}
+	if len(msgs) != len(expected) {
+		t.Fatalf("expected %d messages, got %d", len(expected), len(msgs))
+	}
+	for i, m := range msgs {
+		if actual := toJSON(m); actual != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], actual)
+		}
+	}
+}
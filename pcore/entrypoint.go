@@ -3,6 +3,7 @@ package pcore
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"path/filepath"
 	"sync"
 
@@ -15,7 +16,7 @@ import (
 	// not used) at this point
 	"context"
 	_ "github.com/lyraproj/puppet-evaluator/functions"
-	"github.com/lyraproj/puppet-evaluator/threadlocal"
+	_ "github.com/lyraproj/puppet-evaluator/transport"
 	"github.com/lyraproj/puppet-parser/parser"
 	"github.com/lyraproj/puppet-parser/validator"
 )
@@ -39,6 +40,11 @@ func init() {
 	eval.Puppet = puppet
 	puppet.DefineSetting(`environment`, types.DefaultStringType(), types.WrapString(`production`))
 	puppet.DefineSetting(`environmentpath`, types.DefaultStringType(), nil)
+	puppet.DefineSetting(`inventory_file`, types.DefaultStringType(), nil)
+	puppet.DefineSetting(`integer_promotion`, types.DefaultBooleanType(), types.WrapBoolean(false))
+	puppet.DefineSetting(`max_collection_size`, types.NewIntegerType(0, math.MaxInt64), types.WrapInteger(0))
+	puppet.DefineSetting(`max_stack_depth`, types.NewIntegerType(0, math.MaxInt64), types.WrapInteger(0))
+	puppet.DefineSetting(`max_steps`, types.NewIntegerType(0, math.MaxInt64), types.WrapInteger(0))
 	puppet.DefineSetting(`module_path`, types.DefaultStringType(), nil)
 	puppet.DefineSetting(`strict`, types.NewEnumType([]string{`off`, `warning`, `error`}, true), types.WrapString(`warning`))
 	puppet.DefineSetting(`tasks`, types.DefaultBooleanType(), types.WrapBoolean(false))
@@ -110,6 +116,9 @@ func (p *pcoreImpl) EnvironmentLoader() eval.Loader {
 		s := p.settings[`module_path`]
 		mds := make([]eval.ModuleLoader, 0)
 		loadables := []eval.PathType{eval.PUPPET_FUNCTION_PATH, eval.PUPPET_DATA_TYPE_PATH, eval.PLAN_PATH, eval.TASK_PATH}
+		if eval.IsTruthy(p.settings[`workflow`].get()) {
+			loadables = append(loadables, eval.PUPPET_ACTIVITY_PATH)
+		}
 		if s.isSet() {
 			modulesPath := s.get().String()
 			fis, err := ioutil.ReadDir(modulesPath)
@@ -177,8 +186,7 @@ func (p *pcoreImpl) RootContext() eval.Context {
 	InitializePuppet()
 	c := impl.WithParent(context.Background(), impl.NewEvaluator, eval.NewParentedLoader(p.EnvironmentLoader()), p.logger, topImplRegistry)
 	types.InitTypeSetType(c)
-	threadlocal.Init()
-	threadlocal.Set(eval.PuppetContextKey, c)
+	eval.SetCurrentContext(c)
 	return c
 }
 
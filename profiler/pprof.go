@@ -0,0 +1,165 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Field numbers of the messages in the pprof profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// pprofWriter encodes a profile.proto message. Strings are collected in a string table that is
+// written last.
+type pprofWriter struct {
+	strings map[string]int64
+	table   []string
+}
+
+// WritePprof writes the recorded call paths to the given writer as a gzip compressed profile in
+// the protocol buffer format that is read by the Go pprof tool. Each function, plan, and lambda
+// appears as a frame. The samples hold the number of calls, the exclusive wall time, and the
+// exclusive allocated bytes of each call path.
+func (p *Profile) WritePprof(w io.Writer) error {
+	p.lock.Lock()
+	entries := make([]*Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, e)
+	}
+	samples := make([]*sample, 0, len(p.samples))
+	for _, s := range p.samples {
+		samples = append(samples, s)
+	}
+	msg := (&pprofWriter{strings: map[string]int64{``: 0}, table: []string{``}}).encode(p.start, entries, samples)
+	p.lock.Unlock()
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(msg); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (pw *pprofWriter) encode(start time.Time, entries []*Entry, samples []*sample) []byte {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+	sort.Slice(samples, func(i, j int) bool { return samples[i].wall > samples[j].wall })
+
+	b := proto.NewBuffer(nil)
+	for _, st := range [][2]string{{`calls`, `count`}, {`wall`, `nanoseconds`}, {`alloc_space`, `bytes`}} {
+		message(b, profileSampleType, pw.valueType(st[0], st[1]))
+	}
+	for _, s := range samples {
+		m := proto.NewBuffer(nil)
+		ids := make([]uint64, len(s.path))
+		for i, e := range s.path {
+			ids[i] = e.id
+		}
+		packed(m, sampleLocationID, ids...)
+		packed(m, sampleValue, uint64(s.calls), uint64(s.wall), s.bytes)
+		message(b, profileSample, m)
+	}
+
+	// Each entry is given one location and one function with the same id
+	for _, e := range entries {
+		line := proto.NewBuffer(nil)
+		varint(line, lineFunctionID, e.id)
+		varint(line, lineLine, uint64(e.Line))
+		m := proto.NewBuffer(nil)
+		varint(m, locationID, e.id)
+		message(m, locationLine, line)
+		message(b, profileLocation, m)
+	}
+	for _, e := range entries {
+		m := proto.NewBuffer(nil)
+		varint(m, functionID, e.id)
+		varint(m, functionName, uint64(pw.str(e.Label())))
+		varint(m, functionSystemName, uint64(pw.str(e.Name)))
+		file := e.File
+		if file == `` {
+			file = goFile
+		}
+		varint(m, functionFilename, uint64(pw.str(file)))
+		varint(m, functionStartLine, uint64(e.Line))
+		message(b, profileFunction, m)
+	}
+
+	varint(b, profileTimeNanos, uint64(start.UnixNano()))
+	varint(b, profileDurationNanos, uint64(time.Since(start)))
+	message(b, profilePeriodType, pw.valueType(`wall`, `nanoseconds`))
+	varint(b, profilePeriod, 1)
+
+	// The string table must be written last since the messages above add to it
+	for _, s := range pw.table {
+		b.EncodeVarint(profileStringTable<<3 | proto.WireBytes)
+		b.EncodeStringBytes(s)
+	}
+	return b.Bytes()
+}
+
+func (pw *pprofWriter) valueType(typ, unit string) *proto.Buffer {
+	m := proto.NewBuffer(nil)
+	varint(m, valueTypeType, uint64(pw.str(typ)))
+	varint(m, valueTypeUnit, uint64(pw.str(unit)))
+	return m
+}
+
+// str returns the index of the given string in the string table
+func (pw *pprofWriter) str(s string) int64 {
+	if i, ok := pw.strings[s]; ok {
+		return i
+	}
+	i := int64(len(pw.table))
+	pw.strings[s] = i
+	pw.table = append(pw.table, s)
+	return i
+}
+
+func message(b *proto.Buffer, field uint64, m *proto.Buffer) {
+	b.EncodeVarint(field<<3 | proto.WireBytes)
+	b.EncodeRawBytes(m.Bytes())
+}
+
+func varint(b *proto.Buffer, field uint64, v uint64) {
+	b.EncodeVarint(field<<3 | proto.WireVarint)
+	b.EncodeVarint(v)
+}
+
+func packed(b *proto.Buffer, field uint64, vs ...uint64) {
+	m := proto.NewBuffer(nil)
+	for _, v := range vs {
+		m.EncodeVarint(v)
+	}
+	b.EncodeVarint(field<<3 | proto.WireBytes)
+	b.EncodeRawBytes(m.Bytes())
+}
@@ -0,0 +1,219 @@
+// Package profiler records the calls of functions and lambdas made by the evaluator.
+//
+// A Profile is created with NewProfile and its Profiler is assigned to an eval.Context using
+// SetProfiler. The profile then records the number of calls, the inclusive and exclusive wall
+// time, and the allocated bytes of each Puppet function, plan, lambda, and Go function that is
+// called using the context, or using a context that is forked from it. The recorded data is
+// presented by WriteReport as a sorted text report and by WritePprof as a profile that the Go pprof
+// tool can read.
+//
+// Allocations are measured using the allocation counters of the Go runtime. The counters are
+// process wide, so allocations that other goroutines make during a call are attributed to the call.
+package profiler
+
+import (
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// An Entry holds what has been recorded for one function, plan, or lambda
+type Entry struct {
+	// Name is the name of the function or plan, or "lambda"
+	Name string
+
+	// File and Line denote the definition of a function, plan, or lambda written in the Puppet
+	// language. The File is empty for a function written in Go.
+	File string
+	Line int
+
+	// Calls is the number of calls
+	Calls int
+
+	// Inclusive is the time spent in the calls, including the time spent in the calls that they
+	// make. A recursive call is only counted once.
+	Inclusive time.Duration
+
+	// Exclusive is the time spent in the calls, excluding the time spent in the calls that they make
+	Exclusive time.Duration
+
+	// InclusiveBytes and ExclusiveBytes are the bytes allocated during the calls, with and without
+	// the bytes allocated by the calls that they make
+	InclusiveBytes uint64
+	ExclusiveBytes uint64
+
+	// id identifies the entry in a pprof profile
+	id uint64
+}
+
+// Label returns the name of the entry followed by the location of its definition, if any
+func (e *Entry) Label() string {
+	if e.File == `` {
+		return e.Name
+	}
+	return e.Name + ` (` + e.File + `:` + strconv.Itoa(e.Line) + `)`
+}
+
+type entryKey struct {
+	name string
+	file string
+	line int
+}
+
+// A sample holds what has been recorded for one call path. It is the unit of a pprof profile.
+type sample struct {
+	// path holds the entries of the call path, innermost entry first
+	path  []*Entry
+	calls int64
+	wall  time.Duration
+	bytes uint64
+}
+
+// Profile is a profile that is recorded by the profilers that it creates. It is safe to use a
+// profile from multiple goroutines.
+type Profile struct {
+	lock    sync.Mutex
+	start   time.Time
+	entries map[entryKey]*Entry
+	samples map[string]*sample
+}
+
+// NewProfile creates an empty profile
+func NewProfile() *Profile {
+	return &Profile{start: time.Now(), entries: make(map[entryKey]*Entry), samples: make(map[string]*sample)}
+}
+
+// Profiler returns a new eval.Profiler that records into the receiver
+func (p *Profile) Profiler() eval.Profiler {
+	return &recorder{profile: p}
+}
+
+// Entries returns copies of the recorded entries sorted by exclusive time in descending order
+func (p *Profile) Entries() []Entry {
+	p.lock.Lock()
+	entries := make([]Entry, 0, len(p.entries))
+	for _, e := range p.entries {
+		entries = append(entries, *e)
+	}
+	p.lock.Unlock()
+	sort.Slice(entries, func(i, j int) bool {
+		ei, ej := &entries[i], &entries[j]
+		if ei.Exclusive != ej.Exclusive {
+			return ei.Exclusive > ej.Exclusive
+		}
+		return ei.Label() < ej.Label()
+	})
+	return entries
+}
+
+func (p *Profile) entry(name string, location issue.Location) *Entry {
+	key := entryKey{name: name}
+	if location != nil {
+		key.file = location.File()
+		key.line = location.Line()
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	e, ok := p.entries[key]
+	if !ok {
+		e = &Entry{Name: key.name, File: key.file, Line: key.line, id: uint64(len(p.entries) + 1)}
+		p.entries[key] = e
+	}
+	return e
+}
+
+// record adds one returned call to the receiver. The stack holds the frames of the calls that are
+// active when the call returns, innermost frame last.
+func (p *Profile) record(stack []frame, inclusive, exclusive time.Duration, inclusiveBytes, exclusiveBytes uint64) {
+	n := len(stack)
+	e := stack[n-1].entry
+	ids := make([]string, n)
+	path := make([]*Entry, n)
+	recursive := false
+	for i := range stack {
+		se := stack[n-1-i].entry
+		path[i] = se
+		ids[i] = strconv.FormatUint(se.id, 10)
+		if i > 0 && se == e {
+			recursive = true
+		}
+	}
+	key := strings.Join(ids, `,`)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	e.Calls++
+	e.Exclusive += exclusive
+	e.ExclusiveBytes += exclusiveBytes
+	if !recursive {
+		e.Inclusive += inclusive
+		e.InclusiveBytes += inclusiveBytes
+	}
+	s, ok := p.samples[key]
+	if !ok {
+		s = &sample{path: path}
+		p.samples[key] = s
+	}
+	s.calls++
+	s.wall += exclusive
+	s.bytes += exclusiveBytes
+}
+
+type frame struct {
+	entry      *Entry
+	start      time.Time
+	bytes      uint64
+	childTime  time.Duration
+	childBytes uint64
+}
+
+// recorder is the eval.Profiler of one goroutine
+type recorder struct {
+	profile *Profile
+	stack   []frame
+}
+
+func (r *recorder) Enter(name string, location issue.Location) {
+	r.stack = append(r.stack, frame{entry: r.profile.entry(name, location), bytes: allocatedBytes(), start: time.Now()})
+}
+
+func (r *recorder) Exit() {
+	now := time.Now()
+	bytes := allocatedBytes()
+	n := len(r.stack)
+	if n == 0 {
+		return
+	}
+	f := &r.stack[n-1]
+	inclusive := now.Sub(f.start)
+	inclusiveBytes := bytes - f.bytes
+	r.profile.record(r.stack, inclusive, inclusive-f.childTime, inclusiveBytes, inclusiveBytes-f.childBytes)
+	r.stack = r.stack[:n-1]
+	if n > 1 {
+		parent := &r.stack[n-2]
+		parent.childTime += inclusive
+		parent.childBytes += inclusiveBytes
+	}
+}
+
+func (r *recorder) Fork() eval.Profiler {
+	return r.profile.Profiler()
+}
+
+const allocsMetric = `/gc/heap/allocs:bytes`
+
+// allocatedBytes returns the total number of bytes allocated on the heap by the process
+func allocatedBytes() uint64 {
+	s := []metrics.Sample{{Name: allocsMetric}}
+	metrics.Read(s)
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
@@ -0,0 +1,149 @@
+package profiler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/profiler"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const src = `function fib(Integer $n) >> Integer {
+  if $n < 2 { $n } else { fib($n - 1) + fib($n - 2) }
+}
+[10, 1].map |$n| { fib($n) }
+`
+
+func profile(t *testing.T) *profiler.Profile {
+	p := profiler.NewProfile()
+	eval.Puppet.Do(func(c eval.Context) {
+		c.SetProfiler(p.Profiler())
+		expr := c.ParseAndValidate(`test.pp`, src, false)
+		c.AddDefinitions(expr)
+		if _, err := eval.TopEvaluate(c, expr); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return p
+}
+
+func ExampleProfile_Entries() {
+	p := profiler.NewProfile()
+	eval.Puppet.Do(func(c eval.Context) {
+		c.SetProfiler(p.Profiler())
+		expr := c.ParseAndValidate(`test.pp`, src, false)
+		c.AddDefinitions(expr)
+		eval.TopEvaluate(c, expr)
+	})
+	entries := p.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Label() < entries[j].Label() })
+	for _, e := range entries {
+		fmt.Println(e.Calls, e.Label())
+	}
+	// Output:
+	// 178 fib (test.pp:1)
+	// 2 lambda (test.pp:4)
+	// 1 map
+}
+
+func TestProfile_times(t *testing.T) {
+	entries := map[string]profiler.Entry{}
+	for _, e := range profile(t).Entries() {
+		entries[e.Name] = e
+	}
+	fib, lambda, m := entries[`fib`], entries[`lambda`], entries[`map`]
+	if fib.Exclusive > fib.Inclusive || fib.Inclusive > lambda.Inclusive || lambda.Inclusive > m.Inclusive {
+		t.Errorf(`inconsistent times fib %s/%s, lambda %s, map %s`, fib.Inclusive, fib.Exclusive, lambda.Inclusive, m.Inclusive)
+	}
+	if m.Inclusive-m.Exclusive < lambda.Inclusive {
+		t.Errorf(`the time of the lambda is not excluded from the time of map`)
+	}
+}
+
+func TestProfile_WriteReport(t *testing.T) {
+	b := bytes.NewBufferString(``)
+	if err := profile(t).WriteReport(b); err != nil {
+		t.Fatal(err)
+	}
+	report := b.String()
+	for _, s := range []string{`Calls`, `fib (test.pp:1)`, `lambda (test.pp:4)`, `map`, `test.pp`, `<go>`} {
+		if !strings.Contains(report, s) {
+			t.Errorf("report does not contain %q:\n%s", s, report)
+		}
+	}
+}
+
+func TestProfile_WritePprof(t *testing.T) {
+	b := bytes.NewBufferString(``)
+	if err := profile(t).WritePprof(b); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Count the top level fields and collect the string table
+	counts := map[uint64]int{}
+	var strs []string
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(msg)
+		if n <= 0 {
+			t.Fatal(`invalid varint`)
+		}
+		msg = msg[n:]
+		return v
+	}
+	for len(msg) > 0 {
+		key := uvarint()
+		field := key >> 3
+		counts[field]++
+		if key&7 == proto.WireVarint {
+			uvarint()
+			continue
+		}
+		n := uvarint()
+		if field == 6 {
+			strs = append(strs, string(msg[:n]))
+		}
+		msg = msg[n:]
+	}
+	// 3 sample types, 3 functions and 3 locations, and samples for the paths map, map/lambda,
+	// and map/lambda/fib and a sample for each depth of the recursive fib calls
+	if counts[1] != 3 || counts[4] != 3 || counts[5] != 3 || counts[2] != 12 {
+		t.Errorf(`unexpected field counts %v`, counts)
+	}
+	if len(strs) == 0 || strs[0] != `` || !strings.Contains(strings.Join(strs, `|`), `|fib (test.pp:1)|`) {
+		t.Errorf(`unexpected string table %q`, strs)
+	}
+}
+
+func TestProfile_Fork(t *testing.T) {
+	p := profiler.NewProfile()
+	eval.Puppet.Do(func(c eval.Context) {
+		c.SetProfiler(p.Profiler())
+		fc := c.Fork()
+		if fc.Profiler() == nil || fc.Profiler() == c.Profiler() {
+			t.Fatal(`expected the fork to get its own profiler`)
+		}
+		fc.Profiler().Enter(`f`, nil)
+		fc.Profiler().Exit()
+	})
+	if entries := p.Entries(); len(entries) != 1 || entries[0].Name != `f` || entries[0].Calls != 1 {
+		t.Errorf(`unexpected entries %v`, entries)
+	}
+}
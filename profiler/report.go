@@ -0,0 +1,73 @@
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// goFile is the file that functions written in Go are reported under
+const goFile = `<go>`
+
+// fileEntry holds the totals of the entries defined in one file
+type fileEntry struct {
+	file      string
+	calls     int
+	exclusive time.Duration
+	bytes     uint64
+}
+
+// WriteReport writes a text report of the recorded entries to the given writer. The first table
+// lists the entries sorted by exclusive time. The second table lists the totals of the entries of
+// each file, with all functions written in Go grouped under "<go>".
+func (p *Profile) WriteReport(w io.Writer) error {
+	entries := p.Entries()
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Calls\tInclusive\tExclusive\tAllocated\t\tFunction")
+	files := make(map[string]*fileEntry)
+	for i := range entries {
+		e := &entries[i]
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d B\t\t%s\n", e.Calls, duration(e.Inclusive), duration(e.Exclusive), e.ExclusiveBytes, e.Label())
+		file := e.File
+		if file == `` {
+			file = goFile
+		}
+		fe, ok := files[file]
+		if !ok {
+			fe = &fileEntry{file: file}
+			files[file] = fe
+		}
+		fe.calls += e.Calls
+		fe.exclusive += e.Exclusive
+		fe.bytes += e.ExclusiveBytes
+	}
+
+	sorted := make([]*fileEntry, 0, len(files))
+	for _, fe := range files {
+		sorted = append(sorted, fe)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].exclusive != sorted[j].exclusive {
+			return sorted[i].exclusive > sorted[j].exclusive
+		}
+		return sorted[i].file < sorted[j].file
+	})
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Calls\tExclusive\tAllocated\t\tFile")
+	for _, fe := range sorted {
+		fmt.Fprintf(tw, "%d\t%s\t%d B\t\t%s\n", fe.calls, duration(fe.exclusive), fe.bytes, fe.file)
+	}
+	return tw.Flush()
+}
+
+// duration formats the given duration in milliseconds so that the columns of the report align
+func duration(d time.Duration) string {
+	return fmt.Sprintf(`%.3fms`, float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,20 @@
+package proto
+
+import (
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// Field_Type is the annotation that assigns protobuf field numbers to an attribute. The number is an
+// Integer, or an Array with one Integer for each type of a Variant attribute that maps to a oneof. The
+// range 19000 to 19999 is reserved by protobuf.
+var Field_Type eval.ObjectType
+
+func init() {
+	Field_Type = eval.NewObjectType(`Protobuf::Field`, `Annotation{
+    attributes => {
+      number => Variant[
+        Variant[Integer[1, 18999], Integer[20000, 536870911]],
+        Array[Variant[Integer[1, 18999], Integer[20000, 536870911]], 1]]
+    }
+  }`)
+}
@@ -0,0 +1,186 @@
+package proto
+
+import (
+	"math"
+	"strings"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/serialization"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+var scalars = map[descriptor.FieldDescriptorProto_Type]*element{
+	descriptor.FieldDescriptorProto_TYPE_STRING: {
+		ptype: descriptor.FieldDescriptorProto_TYPE_STRING,
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			b.EncodeStringBytes(v.String())
+		}},
+	descriptor.FieldDescriptorProto_TYPE_INT64: {
+		ptype: descriptor.FieldDescriptorProto_TYPE_INT64,
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			iv, ok := v.(*types.IntegerValue)
+			if !ok {
+				// A BigIntegerValue cannot be represented by an int64
+				panic(c.Error(nil, eval.EVAL_INTEGER_OVERFLOW, issue.H{`left`: v, `operator`: `as`, `right`: `int64`}))
+			}
+			b.EncodeVarint(uint64(iv.Int()))
+		}},
+	descriptor.FieldDescriptorProto_TYPE_DOUBLE: {
+		ptype: descriptor.FieldDescriptorProto_TYPE_DOUBLE,
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			b.EncodeFixed64(math.Float64bits(v.(eval.NumericValue).Float()))
+		}},
+	descriptor.FieldDescriptorProto_TYPE_BOOL: {
+		ptype: descriptor.FieldDescriptorProto_TYPE_BOOL,
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			if v.(*types.BooleanValue).Bool() {
+				b.EncodeVarint(1)
+			} else {
+				b.EncodeVarint(0)
+			}
+		}},
+	descriptor.FieldDescriptorProto_TYPE_BYTES: {
+		ptype: descriptor.FieldDescriptorProto_TYPE_BYTES,
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			b.EncodeRawBytes(v.(*types.BinaryValue).Bytes())
+		}},
+}
+
+var timestampElement = &element{
+	ptype:    descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+	typeName: `.google.protobuf.Timestamp`,
+	encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+		ts, err := ptypes.TimestampProto(v.(*types.TimestampValue).Time())
+		if err != nil {
+			panic(eval.Error(eval.EVAL_FAILURE, issue.H{`message`: err.Error()}))
+		}
+		b.EncodeMessage(ts)
+	}}
+
+var durationElement = &element{
+	ptype:    descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+	typeName: `.google.protobuf.Duration`,
+	encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+		b.EncodeMessage(ptypes.DurationProto(v.(*types.TimespanValue).Duration()))
+	}}
+
+// dataElement encodes any value as a puppet.datapb.Data using the rich data serialization
+var dataElement = &element{
+	ptype:    descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+	typeName: dataTypeName,
+	encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+		pc := NewProtoConsumer()
+		serialization.NewSerializer(c, eval.EMPTY_MAP).Convert(v, pc)
+		b.EncodeMessage(pc.Value())
+	}}
+
+func wrapperElement(e *element) *element {
+	name := e.ptype.String()[len(`TYPE_`):]
+	name = name[:1] + strings.ToLower(name[1:])
+	return &element{
+		ptype:    descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		typeName: wrapperNamespace + name + `Value`,
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			wb := protobuf.NewBuffer(nil)
+			if !isZero(v) {
+				encodeElement(c, wb, 1, e, v)
+			}
+			b.EncodeRawBytes(wb.Bytes())
+		}}
+}
+
+func (s *Schema) messageElement(m *message) *element {
+	return &element{
+		ptype:    descriptor.FieldDescriptorProto_TYPE_MESSAGE,
+		typeName: s.messageName(m),
+		encode: func(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+			b.EncodeRawBytes(m.marshal(c, v.(eval.PuppetObject)))
+		}}
+}
+
+// Marshal encodes the given object into the wire format of the message for its type
+func (s *Schema) Marshal(c eval.Context, o eval.PuppetObject) []byte {
+	m, ok := s.messages[o.PType().Name()]
+	if !ok {
+		panic(eval.Error(eval.EVAL_NO_PROTO_MESSAGE, issue.H{`type`: o.PType().Name()}))
+	}
+	return m.marshal(c, o)
+}
+
+func (m *message) marshal(c eval.Context, o eval.PuppetObject) []byte {
+	b := protobuf.NewBuffer(nil)
+	for _, af := range m.fields {
+		v := af.attr.Get(o)
+		if eval.Equals(v, eval.UNDEF) {
+			continue
+		}
+		if len(af.fields) == 1 && af.fields[0].typ == nil {
+			af.fields[0].encode(c, b, v)
+			continue
+		}
+		for _, f := range af.fields {
+			if eval.IsInstance(f.typ, v) {
+				encodeElement(c, b, f.desc.GetNumber(), f.elem, v)
+				break
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+func (f *field) encode(c eval.Context, b *protobuf.Buffer, v eval.Value) {
+	num := f.desc.GetNumber()
+	switch {
+	case f.key != nil:
+		v.(eval.OrderedMap).EachPair(func(k, ev eval.Value) {
+			eb := protobuf.NewBuffer(nil)
+			encodeElement(c, eb, 1, f.key, k)
+			encodeElement(c, eb, 2, f.elem, ev)
+			b.EncodeVarint(uint64(num)<<3 | protobuf.WireBytes)
+			b.EncodeRawBytes(eb.Bytes())
+		})
+	case f.desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+		v.(eval.List).Each(func(ev eval.Value) { encodeElement(c, b, num, f.elem, ev) })
+	default:
+		if f.elem.typeName == `` && isZero(v) {
+			// Scalars with default values are not transmitted
+			return
+		}
+		encodeElement(c, b, num, f.elem, v)
+	}
+}
+
+// encodeElement appends the tag of the field with the given number followed by the given value
+func encodeElement(c eval.Context, b *protobuf.Buffer, num int32, e *element, v eval.Value) {
+	var wt uint64
+	switch e.ptype {
+	case descriptor.FieldDescriptorProto_TYPE_INT64, descriptor.FieldDescriptorProto_TYPE_BOOL:
+		wt = protobuf.WireVarint
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		wt = protobuf.WireFixed64
+	default:
+		wt = protobuf.WireBytes
+	}
+	b.EncodeVarint(uint64(num)<<3 | wt)
+	e.encode(c, b, v)
+}
+
+func isZero(v eval.Value) bool {
+	switch v := v.(type) {
+	case *types.StringValue:
+		return v.String() == ``
+	case *types.IntegerValue:
+		return v.Int() == 0
+	case *types.FloatValue:
+		return v.Float() == 0
+	case *types.BooleanValue:
+		return !v.Bool()
+	case *types.BinaryValue:
+		return len(v.Bytes()) == 0
+	}
+	return false
+}
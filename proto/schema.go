@@ -0,0 +1,500 @@
+package proto
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// A Schema describes one protobuf message for each Object type that it was created from and for each
+// Object type that those types refer to. The schema is available as a FileDescriptorProto and as the source
+// of a .proto file. Instances of the Object types are encoded into their messages using Marshal.
+//
+// A message is named by the last segment of the name of its Object type. All segments are joined when that
+// name is already used by another message, e.g. B::Foo becomes BFoo when A::Foo becomes Foo.
+//
+// A message contains one field for each attribute of its Object type, including attributes inherited from
+// parents, since protobuf has no notion of inheritance. Constant and derived attributes are excluded.
+//
+// Field numbers are given by a Protobuf::Field annotation on each attribute, e.g.
+//
+//	attributes => { name => { type => String, annotations => { Protobuf::Field => { number => 1 } } } }
+//
+// An attribute that maps to a oneof is annotated with one number for each type of its Variant. When no
+// attribute is annotated, the fields are numbered in the order that the attributes are declared. That
+// order is stable as long as attributes are only added at the end, so it is not used for the types that
+// have a parent or a Variant attribute. The schema cannot be created when numbers are missing or conflict.
+//
+// Attributes are mapped as follows:
+//
+// - String, Enum, and Pattern map to string, Integer to int64, Float to double, Boolean to bool, and Binary to bytes
+// - Timestamp and Timespan map to google.protobuf.Timestamp and google.protobuf.Duration
+// - Object types map to their messages
+// - An Optional scalar maps to its google.protobuf wrapper type, e.g. google.protobuf.StringValue
+// - Array maps to a repeated field and Hash to a map, provided that the contained types map to a single field
+// - A Variant maps to a oneof, provided that each of its types maps to a distinct single field
+//
+// All other types map to the generic puppet.datapb.Data message.
+type Schema struct {
+	file     *descriptor.FileDescriptorProto
+	messages map[string]*message
+	order    []*message
+}
+
+type message struct {
+	typ    eval.ObjectType
+	desc   *descriptor.DescriptorProto
+	fields []*attributeField
+}
+
+// attributeField is the field of an attribute or, when the attribute is a Variant, the oneof that contains
+// one field for each type of the Variant
+type attributeField struct {
+	attr   eval.Attribute
+	fields []*field
+}
+
+type field struct {
+	desc *descriptor.FieldDescriptorProto
+
+	// The type that a value must be an instance of to be encoded in this field. Only used by oneof members
+	typ eval.Type
+
+	// The map key or nil if this isn't a map
+	key  *element
+	elem *element
+}
+
+// element describes how a single value of a field is declared and encoded
+type element struct {
+	ptype    descriptor.FieldDescriptorProto_Type
+	typeName string
+
+	// encode appends the encoded value, without a tag, to the given buffer
+	encode func(c eval.Context, b *protobuf.Buffer, v eval.Value)
+}
+
+type attributeIterator interface {
+	EachAttribute(includeParent bool, consumer func(attr eval.Attribute))
+}
+
+const (
+	dataTypeName     = `.puppet.datapb.Data`
+	dataImport       = `datapb/data.proto`
+	durationImport   = `google/protobuf/duration.proto`
+	timestampImport  = `google/protobuf/timestamp.proto`
+	wrappersImport   = `google/protobuf/wrappers.proto`
+	wrapperNamespace = `.google.protobuf.`
+)
+
+// NewSchema creates a Schema with the given protobuf package name for the given Object types
+func NewSchema(packageName string, ts []eval.Type) *Schema {
+	s := &Schema{
+		file: &descriptor.FileDescriptorProto{
+			Name:    str(strings.Replace(packageName, `.`, `/`, -1) + `.proto`),
+			Package: str(packageName),
+			Syntax:  str(`proto3`),
+		},
+		messages: make(map[string]*message),
+	}
+	for _, t := range ts {
+		if ot, ok := t.(eval.ObjectType); ok {
+			s.addMessage(ot)
+		}
+	}
+	sort.Strings(s.file.Dependency)
+	return s
+}
+
+// NewTypeSetSchema is like NewSchema but creates the schema for the types of the given TypeSet. The
+// package name is the lower cased name of the TypeSet with '::' replaced by '.'.
+func NewTypeSetSchema(ts eval.TypeSet) *Schema {
+	tl := make([]eval.Type, 0, ts.Types().Len())
+	ts.Types().EachValue(func(t eval.Value) { tl = append(tl, t.(eval.Type)) })
+	return NewSchema(strings.ToLower(strings.Replace(ts.Name(), `::`, `.`, -1)), tl)
+}
+
+// FileDescriptor returns the descriptor of the protobuf file that declares the messages of the receiver
+func (s *Schema) FileDescriptor() *descriptor.FileDescriptorProto {
+	return s.file
+}
+
+// Write writes the source of the .proto file that declares the messages of the receiver
+func (s *Schema) Write(w io.Writer) {
+	b := bytes.NewBufferString("// Code generated by the puppet-evaluator protobuf generator. DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "syntax = \"proto3\";\n\npackage %s;\n\n", s.file.GetPackage())
+	if len(s.file.Dependency) > 0 {
+		for _, dep := range s.file.Dependency {
+			fmt.Fprintf(b, "import %q;\n", dep)
+		}
+		b.WriteByte('\n')
+	}
+	for i, m := range s.order {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		s.writeMessage(b, m)
+	}
+	w.Write(b.Bytes())
+}
+
+func (s *Schema) writeMessage(b *bytes.Buffer, m *message) {
+	fmt.Fprintf(b, "// %s is the message for the Pcore type %s\n", m.desc.GetName(), m.typ.Name())
+	fmt.Fprintf(b, "message %s {\n", m.desc.GetName())
+	for _, af := range m.fields {
+		if len(af.fields) == 1 && af.fields[0].desc.OneofIndex == nil {
+			s.writeField(b, `  `, af.fields[0])
+			continue
+		}
+		fmt.Fprintf(b, "  oneof %s {\n", af.attr.Name())
+		for _, f := range af.fields {
+			s.writeField(b, `    `, f)
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+}
+
+func (s *Schema) writeField(b *bytes.Buffer, indent string, f *field) {
+	b.WriteString(indent)
+	if f.key != nil {
+		fmt.Fprintf(b, "map<%s, %s>", s.typeString(f.key), s.typeString(f.elem))
+	} else {
+		if f.desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+			b.WriteString(`repeated `)
+		}
+		b.WriteString(s.typeString(f.elem))
+	}
+	fmt.Fprintf(b, " %s = %d;\n", f.desc.GetName(), f.desc.GetNumber())
+}
+
+// typeString returns the name of the given element type as it is written in a .proto file
+func (s *Schema) typeString(e *element) string {
+	if e.typeName == `` {
+		return strings.ToLower(strings.TrimPrefix(e.ptype.String(), `TYPE_`))
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(e.typeName, `.`), s.file.GetPackage()+`.`)
+}
+
+// addMessage adds the message for the given Object type unless it has been added already and returns
+// the message.
+func (s *Schema) addMessage(t eval.ObjectType) *message {
+	if m, ok := s.messages[t.Name()]; ok {
+		return m
+	}
+	m := &message{typ: t, desc: &descriptor.DescriptorProto{Name: str(s.uniqueMessageName(t.Name()))}}
+
+	// Register before the fields are created to allow recursive references
+	s.messages[t.Name()] = m
+	s.order = append(s.order, m)
+	s.file.MessageType = append(s.file.MessageType, m.desc)
+
+	num := int32(1)
+	var numbers [][]int32
+	t.(attributeIterator).EachAttribute(true, func(a eval.Attribute) {
+		if a.Kind() == types.CONSTANT || a.Kind() == types.DERIVED {
+			return
+		}
+		af := &attributeField{attr: a}
+		if fs := s.oneofFields(m, a, num); fs != nil {
+			af.fields = fs
+		} else {
+			af.fields = []*field{s.attributeField(m, a.Name(), num, a.Type())}
+		}
+		for _, f := range af.fields {
+			m.desc.Field = append(m.desc.Field, f.desc)
+		}
+		num += int32(len(af.fields))
+		m.fields = append(m.fields, af)
+		numbers = append(numbers, fieldNumbers(a))
+	})
+	m.assignNumbers(numbers)
+	return m
+}
+
+// uniqueMessageName returns the message name for the type with the given qualified name. It is the last
+// segment of the name unless that segment is already used by another message, in which case all segments
+// are joined, e.g. B::Foo becomes BFoo when A::Foo is named Foo.
+func (s *Schema) uniqueMessageName(name string) string {
+	mn := name
+	if i := strings.LastIndex(name, `::`); i >= 0 {
+		mn = name[i+2:]
+	}
+	if s.hasMessageName(mn) {
+		mn = strings.Replace(name, `::`, ``, -1)
+		if s.hasMessageName(mn) {
+			panic(eval.Error(eval.EVAL_PROTO_MESSAGE_NAME_CONFLICT, issue.H{`type`: name, `name`: mn}))
+		}
+	}
+	return mn
+}
+
+func (s *Schema) hasMessageName(name string) bool {
+	for _, m := range s.order {
+		if m.desc.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// assignNumbers assigns the given annotated field numbers of each attribute to its fields. The fields keep
+// the numbers given by their position when no attribute is annotated and that position is stable.
+func (m *message) assignNumbers(numbers [][]int32) {
+	annotated := false
+	for _, ns := range numbers {
+		if ns != nil {
+			annotated = true
+			break
+		}
+	}
+	used := make(map[int32]eval.Attribute, len(m.desc.Field))
+	for i, af := range m.fields {
+		ns := numbers[i]
+		if ns == nil {
+			if annotated || m.typ.Parent() != nil || af.fields[0].desc.OneofIndex != nil {
+				panic(eval.Error(eval.EVAL_PROTO_FIELD_NUMBER_REQUIRED, issue.H{`type`: m.typ.Name(), `label`: af.attr.Label()}))
+			}
+		} else {
+			if len(ns) != len(af.fields) {
+				panic(eval.Error(eval.EVAL_PROTO_FIELD_NUMBER_COUNT, issue.H{`label`: af.attr.Label(), `expected`: len(af.fields), `actual`: len(ns)}))
+			}
+			for fi, f := range af.fields {
+				f.desc.Number = &ns[fi]
+			}
+		}
+		for _, f := range af.fields {
+			n := f.desc.GetNumber()
+			if other, ok := used[n]; ok {
+				panic(eval.Error(eval.EVAL_PROTO_FIELD_NUMBER_CONFLICT, issue.H{`number`: n, `label`: af.attr.Label(), `other`: other.Label()}))
+			}
+			used[n] = af.attr
+		}
+	}
+}
+
+// fieldNumbers returns the numbers of the Protobuf::Field annotation of the given attribute or nil when the
+// attribute has no such annotation
+func fieldNumbers(a eval.Attribute) []int32 {
+	av, ok := a.InitHash().Get4(`annotations`)
+	if !ok {
+		return nil
+	}
+	as, ok := av.(eval.OrderedMap)
+	if !ok {
+		return nil
+	}
+	var numbers []int32
+	as.EachPair(func(k, v eval.Value) {
+		if at, ok := k.(eval.Type); !ok || at.Name() != Field_Type.Name() {
+			return
+		}
+		nm, _ := Field_Type.Member(`number`)
+		nt := nm.(eval.Attribute).Type()
+		nv := eval.AssertInstance(func() string { return `Protobuf::Field number of ` + a.Label() }, nt, v.(eval.OrderedMap).Get5(`number`, eval.UNDEF))
+		switch n := nv.(type) {
+		case eval.List:
+			numbers = make([]int32, n.Len())
+			n.EachWithIndex(func(e eval.Value, i int) { numbers[i] = int32(e.(*types.IntegerValue).Int()) })
+		case *types.IntegerValue:
+			numbers = []int32{int32(n.Int())}
+		}
+	})
+	return numbers
+}
+
+func (s *Schema) messageName(m *message) string {
+	return `.` + s.file.GetPackage() + `.` + m.desc.GetName()
+}
+
+// oneofFields returns one field for each type of the given Variant attribute or nil when the attribute
+// is not a Variant or when its types cannot be mapped to distinct fields.
+func (s *Schema) oneofFields(m *message, a eval.Attribute, num int32) []*field {
+	t := unwrap(a.Type())
+	if ot, ok := t.(*types.OptionalType); ok {
+		t = unwrap(ot.ContainedType())
+	}
+	vt, ok := t.(*types.VariantType)
+	if !ok {
+		return nil
+	}
+	ts := vt.Types()
+	elems := make([]*element, 0, len(ts))
+	seen := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		if _, ok := t.(*types.UndefType); ok {
+			continue
+		}
+		e := s.element(t)
+		if e == nil || e.typeName == dataTypeName {
+			return nil
+		}
+		id := e.ptype.String() + e.typeName
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		elems = append(elems, e)
+	}
+
+	oi := int32(len(m.desc.OneofDecl))
+	m.desc.OneofDecl = append(m.desc.OneofDecl, &descriptor.OneofDescriptorProto{Name: str(a.Name())})
+	fs := make([]*field, 0, len(elems))
+	for _, t := range ts {
+		if _, ok := t.(*types.UndefType); ok {
+			continue
+		}
+		e := elems[len(fs)]
+		suffix := s.typeString(e)
+		if i := strings.LastIndex(suffix, `.`); i >= 0 {
+			suffix = suffix[i+1:]
+		}
+		f := &field{desc: fieldDesc(a.Name()+`_`+issue.CamelToSnakeCase(suffix), num, e), typ: t, elem: e}
+		f.desc.OneofIndex = &oi
+		fs = append(fs, f)
+		num++
+	}
+	return fs
+}
+
+// attributeField returns the field for an attribute with the given name and type
+func (s *Schema) attributeField(m *message, name string, num int32, t eval.Type) *field {
+	t = unwrap(t)
+	optional := false
+	if ot, ok := t.(*types.OptionalType); ok {
+		optional = true
+		t = unwrap(ot.ContainedType())
+	}
+
+	switch t := t.(type) {
+	case *types.ArrayType:
+		if e := s.element(t.ElementType()); e != nil {
+			f := &field{desc: fieldDesc(name, num, e), elem: e}
+			f.desc.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			return f
+		}
+	case *types.HashType:
+		var key *element
+		switch unwrap(t.KeyType()).(type) {
+		case *types.StringType, *types.EnumType, *types.PatternType:
+			key = scalars[descriptor.FieldDescriptorProto_TYPE_STRING]
+		case *types.IntegerType:
+			key = scalars[descriptor.FieldDescriptorProto_TYPE_INT64]
+		}
+		if e := s.element(t.ValueType()); key != nil && e != nil {
+			return s.mapField(m, name, num, key, e)
+		}
+	default:
+		if e := s.element(t); e != nil {
+			if optional && e.typeName == `` {
+				e = s.wrapper(e)
+			}
+			return &field{desc: fieldDesc(name, num, e), elem: e}
+		}
+	}
+	s.addDependency(dataImport)
+	return &field{desc: fieldDesc(name, num, dataElement), elem: dataElement}
+}
+
+// mapField returns a repeated field of a nested map entry message with the given key and value
+func (s *Schema) mapField(m *message, name string, num int32, key, value *element) *field {
+	en := issue.SnakeToCamelCase(name) + `Entry`
+	m.desc.NestedType = append(m.desc.NestedType, &descriptor.DescriptorProto{
+		Name:    str(en),
+		Field:   []*descriptor.FieldDescriptorProto{fieldDesc(`key`, 1, key), fieldDesc(`value`, 2, value)},
+		Options: &descriptor.MessageOptions{MapEntry: &[]bool{true}[0]},
+	})
+	entry := &element{ptype: descriptor.FieldDescriptorProto_TYPE_MESSAGE, typeName: s.messageName(m) + `.` + en}
+	f := &field{desc: fieldDesc(name, num, entry), key: key, elem: value}
+	f.desc.Label = descriptor.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+// element returns the element for a single value of the given type or nil if the type has no such element
+func (s *Schema) element(t eval.Type) *element {
+	switch t := unwrap(t).(type) {
+	case *types.StringType, *types.EnumType, *types.PatternType:
+		return scalars[descriptor.FieldDescriptorProto_TYPE_STRING]
+	case *types.IntegerType:
+		return scalars[descriptor.FieldDescriptorProto_TYPE_INT64]
+	case *types.FloatType:
+		return scalars[descriptor.FieldDescriptorProto_TYPE_DOUBLE]
+	case *types.BooleanType:
+		return scalars[descriptor.FieldDescriptorProto_TYPE_BOOL]
+	case *types.BinaryType:
+		return scalars[descriptor.FieldDescriptorProto_TYPE_BYTES]
+	case *types.TimestampType:
+		s.addDependency(timestampImport)
+		return timestampElement
+	case *types.TimespanType:
+		s.addDependency(durationImport)
+		return durationElement
+	case eval.ObjectType:
+		if t.Name() == `` || t.Equals(types.DefaultObjectType(), nil) {
+			return nil
+		}
+		return s.messageElement(s.addMessage(t))
+	}
+	return nil
+}
+
+// wrapper returns the element of the google.protobuf wrapper message for the given scalar
+func (s *Schema) wrapper(e *element) *element {
+	s.addDependency(wrappersImport)
+	return wrapperElement(e)
+}
+
+func (s *Schema) addDependency(dep string) {
+	for _, d := range s.file.Dependency {
+		if d == dep {
+			return
+		}
+	}
+	s.file.Dependency = append(s.file.Dependency, dep)
+}
+
+func fieldDesc(name string, num int32, e *element) *descriptor.FieldDescriptorProto {
+	fd := &descriptor.FieldDescriptorProto{
+		Name:     str(name),
+		Number:   &num,
+		Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     e.ptype.Enum(),
+		JsonName: str(jsonName(name)),
+	}
+	if e.typeName != `` {
+		fd.TypeName = str(e.typeName)
+	}
+	return fd
+}
+
+// jsonName returns the lower camel cased form of the given name
+func jsonName(name string) string {
+	cc := issue.SnakeToCamelCase(name)
+	return strings.ToLower(cc[:1]) + cc[1:]
+}
+
+// unwrap returns the resolved type of aliases and the contained type of NotUndef
+func unwrap(t eval.Type) eval.Type {
+	for {
+		switch ut := t.(type) {
+		case *types.TypeAliasType:
+			t = ut.ResolvedType()
+		case *types.NotUndefType:
+			t = ut.ContainedType()
+		default:
+			return t
+		}
+	}
+}
+
+func str(s string) *string {
+	return &s
+}
@@ -0,0 +1,292 @@
+package proto_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/lyraproj/data-protobuf/datapb"
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	pcproto "github.com/lyraproj/puppet-evaluator/proto"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+const typeSet = `TypeSet[{
+  name => 'My::Shop',
+  version => '1.0.0',
+  pcore_version => '1.0.0',
+  types => {
+    Address => {
+      attributes => {
+        street => String,
+        zip_code => Optional[String]
+      }
+    },
+    Customer => {
+      attributes => {
+        name => { type => String[1], annotations => { Protobuf::Field => { number => 1 } } },
+        since => { type => Timestamp, annotations => { Protobuf::Field => { number => 2 } } },
+        address => { type => Optional[Address], annotations => { Protobuf::Field => { number => 3 } } },
+        tags => { type => Array[String], annotations => { Protobuf::Field => { number => 4 } } },
+        points => { type => Hash[String, Integer], annotations => { Protobuf::Field => { number => 5 } } },
+        contact => { type => Variant[String, Address], annotations => { Protobuf::Field => { number => [6, 7] } } },
+        extra => { type => Data, annotations => { Protobuf::Field => { number => 8 } } },
+        kind => { type => String, kind => constant, value => 'customer' }
+      }
+    },
+    Vip => {
+      parent => Customer,
+      attributes => {
+        level => { type => Integer[1, 5], value => 1, annotations => { Protobuf::Field => { number => 9 } } }
+      }
+    }
+  }
+}]`
+
+func ExampleSchema_Write() {
+	eval.Puppet.Do(func(c eval.Context) {
+		ts := c.ParseType2(typeSet)
+		c.AddTypes(ts)
+		pcproto.NewTypeSetSchema(ts.(eval.TypeSet)).Write(os.Stdout)
+	})
+	// Output:
+	// // Code generated by the puppet-evaluator protobuf generator. DO NOT EDIT.
+	//
+	// syntax = "proto3";
+	//
+	// package my.shop;
+	//
+	// import "datapb/data.proto";
+	// import "google/protobuf/timestamp.proto";
+	// import "google/protobuf/wrappers.proto";
+	//
+	// // Address is the message for the Pcore type My::Shop::Address
+	// message Address {
+	//   string street = 1;
+	//   google.protobuf.StringValue zip_code = 2;
+	// }
+	//
+	// // Customer is the message for the Pcore type My::Shop::Customer
+	// message Customer {
+	//   string name = 1;
+	//   google.protobuf.Timestamp since = 2;
+	//   Address address = 3;
+	//   repeated string tags = 4;
+	//   map<string, int64> points = 5;
+	//   oneof contact {
+	//     string contact_string = 6;
+	//     Address contact_address = 7;
+	//   }
+	//   puppet.datapb.Data extra = 8;
+	// }
+	//
+	// // Vip is the message for the Pcore type My::Shop::Vip
+	// message Vip {
+	//   string name = 1;
+	//   google.protobuf.Timestamp since = 2;
+	//   Address address = 3;
+	//   repeated string tags = 4;
+	//   map<string, int64> points = 5;
+	//   oneof contact {
+	//     string contact_string = 6;
+	//     Address contact_address = 7;
+	//   }
+	//   puppet.datapb.Data extra = 8;
+	//   int64 level = 9;
+	// }
+}
+
+type address struct {
+	Street  string                `protobuf:"bytes,1,opt,name=street"`
+	ZipCode *wrappers.StringValue `protobuf:"bytes,2,opt,name=zip_code"`
+}
+
+func (m *address) Reset()         { *m = address{} }
+func (m *address) String() string { return proto.CompactTextString(m) }
+func (*address) ProtoMessage()    {}
+
+type isContact interface {
+	isContact()
+}
+
+type contactString struct {
+	ContactString string `protobuf:"bytes,6,opt,name=contact_string,oneof"`
+}
+
+type contactAddress struct {
+	ContactAddress *address `protobuf:"bytes,7,opt,name=contact_address,oneof"`
+}
+
+func (*contactString) isContact()  {}
+func (*contactAddress) isContact() {}
+
+// vip is a hand written equivalent of the Go code that protoc would generate for the Vip message
+type vip struct {
+	Name    string               `protobuf:"bytes,1,opt,name=name"`
+	Since   *timestamp.Timestamp `protobuf:"bytes,2,opt,name=since"`
+	Address *address             `protobuf:"bytes,3,opt,name=address"`
+	Tags    []string             `protobuf:"bytes,4,rep,name=tags"`
+	Points  map[string]int64     `protobuf:"bytes,5,rep,name=points" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Contact isContact            `protobuf_oneof:"contact"`
+	Extra   *datapb.Data         `protobuf:"bytes,8,opt,name=extra"`
+	Level   int64                `protobuf:"varint,9,opt,name=level"`
+}
+
+func (m *vip) Reset()         { *m = vip{} }
+func (m *vip) String() string { return proto.CompactTextString(m) }
+func (*vip) ProtoMessage()    {}
+
+func (*vip) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return nil, nil, nil, []interface{}{(*contactString)(nil), (*contactAddress)(nil)}
+}
+
+func TestSchema_Marshal(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		ts := c.ParseType2(typeSet).(eval.TypeSet)
+		c.AddTypes(ts)
+		s := pcproto.NewTypeSetSchema(ts)
+
+		at, _ := ts.GetType2(`Address`)
+		vt, _ := ts.GetType2(`Vip`)
+		since := time.Date(2018, 12, 24, 10, 30, 0, 0, time.UTC)
+		v := eval.New(c, vt, types.WrapStringToInterfaceMap(c, map[string]interface{}{
+			`name`:    `Bob`,
+			`since`:   types.WrapTimestamp(since),
+			`address`: eval.New(c, at, types.WrapStringToInterfaceMap(c, map[string]interface{}{`street`: `Main St`, `zip_code`: ``})),
+			`tags`:    []string{`a`, `b`},
+			`points`:  map[string]interface{}{`x`: 3},
+			`contact`: eval.New(c, at, types.SingletonHash2(`street`, types.WrapString(`Side St`))),
+			`extra`:   map[string]interface{}{`note`: `hi`},
+			`level`:   2,
+		})).(eval.PuppetObject)
+
+		m := &vip{}
+		if err := proto.Unmarshal(s.Marshal(c, v), m); err != nil {
+			t.Fatal(err)
+		}
+		expected := &vip{
+			Name:    `Bob`,
+			Since:   &timestamp.Timestamp{Seconds: since.Unix()},
+			Address: &address{Street: `Main St`, ZipCode: &wrappers.StringValue{}},
+			Tags:    []string{`a`, `b`},
+			Points:  map[string]int64{`x`: 3},
+			Contact: &contactAddress{&address{Street: `Side St`}},
+			Level:   2,
+		}
+		extra := m.Extra
+		m.Extra = nil
+		if !reflect.DeepEqual(expected, m) {
+			t.Errorf("expected %s, got %s", expected, m)
+		}
+		if fmt.Sprint(pcproto.FromPBData(extra)) != `{'note' => 'hi'}` {
+			t.Errorf("unexpected extra %s", extra)
+		}
+	})
+}
+
+func TestSchema_fieldNumbers(t *testing.T) {
+	tests := []struct {
+		types string
+		code  issue.Code
+	}{
+		{`{ A => { attributes => { a => String, b => Integer } } }`, ``},
+		{`{ A => { attributes => { a => String } }, B => { parent => A, attributes => { b => String } } }`, eval.EVAL_PROTO_FIELD_NUMBER_REQUIRED},
+		{`{ A => { attributes => { a => Variant[String, Integer] } } }`, eval.EVAL_PROTO_FIELD_NUMBER_REQUIRED},
+		{`{ A => { attributes => { a => { type => String, annotations => { Protobuf::Field => { number => 2 } } }, b => Integer } } }`,
+			eval.EVAL_PROTO_FIELD_NUMBER_REQUIRED},
+		{`{ A => { attributes => { a => { type => Variant[String, Integer], annotations => { Protobuf::Field => { number => 1 } } } } } }`,
+			eval.EVAL_PROTO_FIELD_NUMBER_COUNT},
+		{`{ A => { attributes => {
+        a => { type => String, annotations => { Protobuf::Field => { number => 1 } } },
+        b => { type => Variant[String, Integer], annotations => { Protobuf::Field => { number => [2, 1] } } } } } }`,
+			eval.EVAL_PROTO_FIELD_NUMBER_CONFLICT},
+		{`{ A => { attributes => { a => { type => String, annotations => { Protobuf::Field => { number => 19000 } } } } } }`,
+			eval.EVAL_TYPE_MISMATCH},
+	}
+	for _, tc := range tests {
+		err := eval.Puppet.Try(func(c eval.Context) error {
+			ts := c.ParseType2(`TypeSet[{ name => 'Numbers', version => '1.0.0', pcore_version => '1.0.0', types => ` + tc.types + ` }]`)
+			c.AddTypes(ts)
+			pcproto.NewTypeSetSchema(ts.(eval.TypeSet))
+			return nil
+		})
+		if tc.code == `` {
+			if err != nil {
+				t.Errorf(`%s: unexpected error %s`, tc.types, err)
+			}
+		} else if re, ok := err.(issue.Reported); !ok || re.Code() != tc.code {
+			t.Errorf(`%s: expected %s, got %v`, tc.types, tc.code, err)
+		}
+	}
+}
+
+func TestSchema_MarshalBigInteger(t *testing.T) {
+	eval.Puppet.Set(`integer_promotion`, types.WrapBoolean(true))
+	defer eval.Puppet.Reset()
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		ts := c.ParseType2(`TypeSet[{ name => 'Big', version => '1.0.0', pcore_version => '1.0.0', types => { A => { attributes => { n => Integer } } } }]`).(eval.TypeSet)
+		c.AddTypes(ts)
+		s := pcproto.NewTypeSetSchema(ts)
+		at, _ := ts.GetType2(`A`)
+		n, err := eval.TopEvaluate(c, c.ParseAndValidate(``, `1 << 64`, false))
+		if err != nil {
+			return err
+		}
+		s.Marshal(c, eval.New(c, at, n).(eval.PuppetObject))
+		return nil
+	})
+	if re, ok := err.(issue.Reported); !ok || re.Code() != eval.EVAL_INTEGER_OVERFLOW {
+		t.Errorf(`expected %s, got %v`, eval.EVAL_INTEGER_OVERFLOW, err)
+	}
+}
+
+func TestSchema_messageNames(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		c.AddDefinitions(c.ParseAndValidate(``, `
+      type A::Foo = Object[attributes => { x => String }]
+      type B::Foo = Object[attributes => { a => A::Foo }]`, false))
+		c.ResolveDefinitions()
+		var ts []eval.Type
+		for _, n := range []string{`A::Foo`, `B::Foo`} {
+			tp, _ := eval.Load(c, eval.NewTypedName(eval.NsType, n))
+			ts = append(ts, tp.(eval.Type))
+		}
+		b := bytes.NewBufferString(``)
+		pcproto.NewSchema(`foo`, ts).Write(b)
+		src := b.String()
+		for _, decl := range []string{"message Foo {", "message BFoo {", "  Foo a = 1;"} {
+			if !strings.Contains(src, decl) {
+				t.Errorf("expected schema to contain %q:\n%s", decl, src)
+			}
+		}
+	})
+
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		c.AddDefinitions(c.ParseAndValidate(``, `
+      type A::Foo = Object[attributes => { x => String }]
+      type BFoo = Object[attributes => { x => String }]
+      type B::Foo = Object[attributes => { x => String }]`, false))
+		c.ResolveDefinitions()
+		var ts []eval.Type
+		for _, n := range []string{`A::Foo`, `BFoo`, `B::Foo`} {
+			tp, _ := eval.Load(c, eval.NewTypedName(eval.NsType, n))
+			ts = append(ts, tp.(eval.Type))
+		}
+		pcproto.NewSchema(`foo`, ts)
+		return nil
+	})
+	if re, ok := err.(issue.Reported); !ok || re.Code() != eval.EVAL_PROTO_MESSAGE_NAME_CONFLICT {
+		t.Errorf(`expected %s, got %v`, eval.EVAL_PROTO_MESSAGE_NAME_CONFLICT, err)
+	}
+}
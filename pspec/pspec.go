@@ -0,0 +1,422 @@
+// Package pspec runs tests that are written in the Puppet Spec language.
+//
+// A spec file is a Puppet program that calls the following functions:
+//
+//	Examples(description, children...)  groups examples and other groups
+//	Example(description, Given(...), assertions...)  declares one example
+//	Given(sources...)  declares the sources that are evaluated by an example
+//	Source(sources...)  declares sources that can be passed to Given
+//	Evaluates_to(value)  asserts that the sources evaluate to the given value
+//	Error(message, kind, issue_code)  asserts that the evaluation fails
+//	Notice(matchers...)  asserts the notices that are logged by the evaluation
+//
+// A Given that is passed to Examples is evaluated before the sources of each example in the group.
+// The Error assertion uses the constructor of the Error type. Its message must be a substring of
+// the message of the evaluation error and its optional issue code must equal the code of the error.
+// The matchers of Notice are strings that must equal a logged notice or regular expressions that
+// must match it. The notices that are logged must match the given matchers in number and order.
+//
+// Each example is evaluated using a fresh context with its own scope, loader, and logger. The
+// functions, plans, and types that the spec file defines are visible to the examples.
+//
+// RunTests loads spec files and runs each example as a Go subtest:
+//
+//	func TestSpecs(t *testing.T) {
+//	  pspec.RunTests(t, `testdata/*.pspec`)
+//	}
+package pspec
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// A Node is an Examples or an Example
+type Node interface {
+	// Description returns the description of the node
+	Description() string
+
+	run(t *testing.T, c eval.Context, given []string)
+}
+
+// Examples is a group of examples
+type Examples struct {
+	description string
+	given       []string
+	children    []Node
+}
+
+// Description returns the description of the group
+func (e *Examples) Description() string {
+	return e.description
+}
+
+// Children returns the examples and groups of the group
+func (e *Examples) Children() []Node {
+	return e.children
+}
+
+func (e *Examples) run(t *testing.T, c eval.Context, given []string) {
+	given = concat(given, e.given)
+	for _, child := range e.children {
+		child := child
+		t.Run(child.Description(), func(t *testing.T) { child.run(t, c, given) })
+	}
+}
+
+// Example is one example
+type Example struct {
+	description string
+	given       []string
+	assertions  []assertion
+}
+
+// Description returns the description of the example
+func (e *Example) Description() string {
+	return e.description
+}
+
+func (e *Example) run(t *testing.T, c eval.Context, given []string) {
+	if err := e.Check(c, given...); err != nil {
+		t.Error(err)
+	}
+}
+
+// Check evaluates the given sources followed by the sources of the example using a new context
+// that is parented by the given context. It returns an error that describes the first assertion
+// that fails, or nil if all assertions hold.
+func (e *Example) Check(c eval.Context, given ...string) error {
+	logger := eval.NewArrayLogger()
+	ec := impl.WithParent(c, impl.NewEvaluator, eval.NewParentedLoader(c.Loader()), logger, c.ImplementationRegistry())
+	result, err := evaluate(ec, concat(given, e.given))
+
+	var expected *errorAssertion
+	for _, a := range e.assertions {
+		if ea, ok := a.(*errorAssertion); ok {
+			expected = ea
+			break
+		}
+	}
+	if err != nil {
+		if expected == nil {
+			return err
+		}
+		if ae := expected.match(err); ae != nil {
+			return ae
+		}
+	} else if expected != nil {
+		return fmt.Errorf(`expected an error with message containing '%s', but the evaluation produced %s`, expected.Message(), result)
+	}
+
+	notices := logger.Entries(eval.NOTICE)
+	messages := make([]string, len(notices))
+	for i, n := range notices {
+		messages[i] = n.Message()
+	}
+	for _, a := range e.assertions {
+		if ae := a.check(result, messages); ae != nil {
+			return ae
+		}
+	}
+	return nil
+}
+
+// evaluate parses and evaluates the given sources in order and returns the result of the last one
+func evaluate(c eval.Context, sources []string) (result eval.Value, err error) {
+	result = eval.UNDEF
+	eval.DoWithContext(c, func(c eval.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if re, ok := r.(error); ok {
+					err = re
+					return
+				}
+				panic(r)
+			}
+		}()
+		for _, source := range sources {
+			expr := c.ParseAndValidate(``, source, false)
+			c.AddDefinitions(expr)
+			var re issue.Reported
+			if result, re = eval.TopEvaluate(c, expr); re != nil {
+				err = re
+				return
+			}
+		}
+	})
+	return
+}
+
+type assertion interface {
+	// check returns an error unless the given result and notices satisfy the assertion
+	check(result eval.Value, notices []string) error
+}
+
+type evaluatesTo struct {
+	expected eval.Value
+}
+
+func (a *evaluatesTo) check(result eval.Value, notices []string) error {
+	if eval.PuppetEquals(a.expected, result) {
+		return nil
+	}
+	return fmt.Errorf(`expected evaluation to produce %s, got %s`, a.expected, result)
+}
+
+// errorAssertion is an Error that is passed to Example. It is matched against the evaluation error.
+type errorAssertion struct {
+	eval.ErrorObject
+}
+
+func (a *errorAssertion) check(result eval.Value, notices []string) error {
+	return nil
+}
+
+func (a *errorAssertion) match(err error) error {
+	if !strings.Contains(err.Error(), a.Message()) {
+		return fmt.Errorf(`expected an error with message containing '%s', got '%s'`, a.Message(), err.Error())
+	}
+	if code := a.IssueCode(); code != `` {
+		re, ok := err.(issue.Reported)
+		if !ok || string(re.Code()) != code {
+			return fmt.Errorf(`expected an error with issue code %s, got '%s'`, code, err.Error())
+		}
+	}
+	return nil
+}
+
+type noticeAssertion struct {
+	matchers []eval.Value
+}
+
+func (a *noticeAssertion) check(result eval.Value, notices []string) error {
+	if len(notices) != len(a.matchers) {
+		return fmt.Errorf(`expected %d notices, got %d: %q`, len(a.matchers), len(notices), notices)
+	}
+	for i, m := range a.matchers {
+		n := notices[i]
+		switch m := m.(type) {
+		case *types.RegexpValue:
+			if !m.Regexp().MatchString(n) {
+				return fmt.Errorf(`expected notice %d to match %s, got '%s'`, i+1, m, n)
+			}
+		default:
+			if s := m.String(); s != n {
+				return fmt.Errorf(`expected notice %d to be '%s', got '%s'`, i+1, s, n)
+			}
+		}
+	}
+	return nil
+}
+
+// givenSources is the result of Given and Source
+type givenSources struct {
+	sources []string
+}
+
+// Load evaluates the spec file with the given name and content and returns the Examples and
+// Example values that its top level statements produce. The functions, plans, and types that the
+// file defines are added to the loader of the given context.
+func Load(c eval.Context, file, content string) (nodes []Node, err error) {
+	loader := eval.NewParentedLoader(c.Loader())
+	for _, f := range specFunctions {
+		rf := f.Resolve(c)
+		loader.SetEntry(eval.NewTypedName(eval.NsConstructor, rf.Name()), eval.NewLoaderEntry(rf, nil))
+	}
+	sc := impl.WithParent(c, impl.NewEvaluator, loader, c.Logger(), c.ImplementationRegistry())
+	eval.DoWithContext(sc, func(sc eval.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if re, ok := r.(error); ok {
+					err = re
+					return
+				}
+				panic(r)
+			}
+		}()
+		expr := sc.ParseAndValidate(file, content, false)
+		c.AddDefinitions(expr)
+		c.ResolveDefinitions()
+		for _, s := range statements(expr) {
+			if _, ok := s.(parser.Definition); ok {
+				continue
+			}
+			v, re := eval.TopEvaluate(sc, s)
+			if re != nil {
+				err = re
+				return
+			}
+			if rv, ok := v.(*types.RuntimeValue); ok {
+				if n, ok := rv.Interface().(Node); ok {
+					nodes = append(nodes, n)
+				}
+			}
+		}
+	})
+	return
+}
+
+// LoadFile reads and loads the spec file at the given path
+func LoadFile(c eval.Context, path string) ([]Node, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(c, path, string(content))
+}
+
+// RunTests loads the spec files that match the given glob patterns and runs them as subtests of
+// the given test. Each file becomes a subtest named after its base name with a nested subtest for
+// each group and example. Each file is loaded using its own fork of a context created by
+// eval.Puppet, so the definitions of one file are not visible to the others.
+func RunTests(t *testing.T, patterns ...string) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Fatalf(`no spec files match %s`, strings.Join(patterns, `, `))
+	}
+	eval.Puppet.Do(func(c eval.Context) {
+		for _, file := range files {
+			file := file
+			t.Run(filepath.Base(file), func(t *testing.T) {
+				fc := c.Fork()
+				nodes, err := LoadFile(fc, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, n := range nodes {
+					n := n
+					t.Run(n.Description(), func(t *testing.T) { n.run(t, fc, nil) })
+				}
+			})
+		}
+	})
+}
+
+func statements(expr parser.Expression) []parser.Expression {
+	if p, ok := expr.(*parser.Program); ok {
+		expr = p.Body()
+	}
+	if b, ok := expr.(*parser.BlockExpression); ok {
+		return b.Statements()
+	}
+	return []parser.Expression{expr}
+}
+
+func concat(a, b []string) []string {
+	r := make([]string, 0, len(a)+len(b))
+	return append(append(r, a...), b...)
+}
+
+func illegalArgument(function string, arg eval.Value) issue.Reported {
+	return eval.Error(eval.EVAL_ILLEGAL_SPEC_ARGUMENT, issue.H{`function`: function, `type`: eval.GenericValueType(arg).String()})
+}
+
+// unwrap returns the Go value of a value created by one of the spec functions
+func unwrap(arg eval.Value) interface{} {
+	if rv, ok := arg.(*types.RuntimeValue); ok {
+		return rv.Interface()
+	}
+	return nil
+}
+
+func sourcesOf(function string, args []eval.Value) []string {
+	var sources []string
+	for _, arg := range args {
+		if s, ok := arg.(*types.StringValue); ok {
+			sources = append(sources, s.String())
+		} else if g, ok := unwrap(arg).(*givenSources); ok {
+			sources = append(sources, g.sources...)
+		} else {
+			panic(illegalArgument(function, arg))
+		}
+	}
+	return sources
+}
+
+var specFunctions = []eval.ResolvableFunction{
+	eval.BuildFunction(`Examples`, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.Param(`String`)
+		d.RepeatedParam(`Any`)
+		d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+			e := &Examples{description: args[0].String()}
+			for _, arg := range args[1:] {
+				switch a := unwrap(arg).(type) {
+				case Node:
+					e.children = append(e.children, a)
+				case *givenSources:
+					e.given = append(e.given, a.sources...)
+				default:
+					panic(illegalArgument(`Examples`, arg))
+				}
+			}
+			return types.WrapRuntime(e)
+		})
+	}}),
+
+	eval.BuildFunction(`Example`, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.Param(`String`)
+		d.RepeatedParam(`Any`)
+		d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+			e := &Example{description: args[0].String()}
+			for _, arg := range args[1:] {
+				if eo, ok := arg.(eval.ErrorObject); ok {
+					e.assertions = append(e.assertions, &errorAssertion{eo})
+					continue
+				}
+				switch a := unwrap(arg).(type) {
+				case assertion:
+					e.assertions = append(e.assertions, a)
+				case *givenSources:
+					e.given = append(e.given, a.sources...)
+				default:
+					panic(illegalArgument(`Example`, arg))
+				}
+			}
+			return types.WrapRuntime(e)
+		})
+	}}),
+
+	eval.BuildFunction(`Given`, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.RepeatedParam(`Any`)
+		d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+			return types.WrapRuntime(&givenSources{sourcesOf(`Given`, args)})
+		})
+	}}),
+
+	eval.BuildFunction(`Source`, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.RepeatedParam(`String`)
+		d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+			return types.WrapRuntime(&givenSources{sourcesOf(`Source`, args)})
+		})
+	}}),
+
+	eval.BuildFunction(`Evaluates_to`, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.Param(`Any`)
+		d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+			return types.WrapRuntime(&evaluatesTo{args[0]})
+		})
+	}}),
+
+	eval.BuildFunction(`Notice`, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.RepeatedParam(`Variant[String,Regexp]`)
+		d.Function(func(c eval.Context, args []eval.Value) eval.Value {
+			return types.WrapRuntime(&noticeAssertion{args})
+		})
+	}}),
+}
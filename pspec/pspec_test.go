@@ -0,0 +1,75 @@
+package pspec_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/pspec"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func TestSpecs(t *testing.T) {
+	pspec.RunTests(t, `testdata/*.pspec`)
+}
+
+func ExampleLoad() {
+	eval.Puppet.Do(func(c eval.Context) {
+		nodes, err := pspec.Load(c, `example.pspec`, `
+Examples('collections',
+  Example('concatenates', Given('[1] + [2]'), Evaluates_to([1, 2])),
+  Example('merges', Given('{a => 1} + {b => 2}'), Evaluates_to({a => 1, b => 2})))`)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, n := range nodes {
+			fmt.Println(n.Description())
+			for _, child := range n.(*pspec.Examples).Children() {
+				fmt.Println(` `, child.Description(), child.(*pspec.Example).Check(c))
+			}
+		}
+	})
+	// Output:
+	// collections
+	//   concatenates <nil>
+	//   merges <nil>
+}
+
+func TestExample_Check_failures(t *testing.T) {
+	tests := map[string]string{
+		`Example('x', Given('1 + 1'), Evaluates_to(3))`:                        `expected evaluation to produce 3, got 2`,
+		`Example('x', Given('1 + 1'), Error('Division by zero'))`:              `expected an error with message containing 'Division by zero', but the evaluation produced 2`,
+		`Example('x', Given('1 / 0'), Error('Division', undef, 'EVAL_OTHER'))`: `expected an error with issue code EVAL_OTHER`,
+		`Example('x', Given('1 / 0'), Evaluates_to(3))`:                        `Division by zero`,
+		`Example('x', Given('notice(a)'), Notice('b'))`:                        `expected notice 1 to be 'b', got 'a'`,
+		`Example('x', Given('notice(a)'), Notice(/^b/))`:                       `expected notice 1 to match /^b/, got 'a'`,
+		`Example('x', Given('notice(a)'), Notice())`:                           `expected 0 notices, got 1`,
+	}
+	for src, expected := range tests {
+		eval.Puppet.Do(func(c eval.Context) {
+			nodes, err := pspec.Load(c, `test.pspec`, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = nodes[0].(*pspec.Example).Check(c)
+			if err == nil {
+				t.Errorf(`%s: expected a failure`, src)
+			} else if !strings.Contains(err.Error(), expected) {
+				t.Errorf(`%s: expected error containing '%s', got '%s'`, src, expected, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoad_illegalArgument(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		_, err := pspec.Load(c, `test.pspec`, `Examples('x', 3)`)
+		if err == nil || !strings.Contains(err.Error(), `Examples does not accept an Integer`) {
+			t.Errorf(`unexpected error %v`, err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package repl
+
+import "strings"
+
+// incomplete returns true when the given source has unbalanced brackets, an unterminated string or
+// comment, or a heredoc that lacks its end tag, i.e. when more input is needed before it can be parsed
+func incomplete(src string) bool {
+	depth := 0
+	heredocs := make([]string, 0)
+	n := len(src)
+	for i := 0; i < n; i++ {
+		switch c := src[i]; c {
+		case '#':
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			i--
+		case '/':
+			if i+1 < n && src[i+1] == '*' {
+				e := strings.Index(src[i+2:], `*/`)
+				if e < 0 {
+					return true
+				}
+				i += e + 3
+			} else if regexpAllowed(src, i) {
+				if e := skipRegexp(src, i+1); e < n {
+					i = e
+				}
+			}
+		case '\'', '"':
+			i = skipString(src, i+1, c)
+			if i >= n {
+				return true
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '@':
+			if i+1 < n && src[i+1] == '(' {
+				e := strings.IndexByte(src[i+2:], ')')
+				if e < 0 {
+					return true
+				}
+				heredocs = append(heredocs, heredocTag(src[i+2:i+2+e]))
+				i += e + 2
+			}
+		case '\n':
+			for _, tag := range heredocs {
+				if i = skipHeredoc(src, i+1, tag); i >= n {
+					return true
+				}
+			}
+			heredocs = heredocs[:0]
+		}
+	}
+	return depth > 0 || len(heredocs) > 0
+}
+
+// skipString returns the position of the unescaped quote that ends the string that starts at the given
+// position, or the length of the source when no such quote exists
+func skipString(src string, i int, quote byte) int {
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(src)
+}
+
+// regexpAllowed returns true when a slash at the given position starts a regular expression rather than
+// being a division operator, i.e. when it isn't preceded by something that yields a value
+func regexpAllowed(src string, i int) bool {
+	s := strings.TrimRight(src[:i], " \t\r\n")
+	if s == `` {
+		return true
+	}
+	c := s[len(s)-1]
+	switch {
+	case c == ')' || c == ']' || c == '}' || c == '"' || c == '\'':
+		return false
+	case isWordChar(c):
+		start := len(s) - 1
+		for start > 0 && isWordChar(s[start-1]) {
+			start--
+		}
+		if start > 0 && s[start-1] == '$' {
+			// A variable
+			return false
+		}
+		switch s[start:] {
+		case `and`, `or`, `not`, `in`, `if`, `elsif`, `unless`, `case`, `node`:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// skipRegexp returns the position of the unescaped slash that ends the regular expression that starts at
+// the given position, or the length of the source when the line ends before such a slash is found
+func skipRegexp(src string, i int) int {
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '/':
+			return i
+		case '\n':
+			return len(src)
+		}
+	}
+	return len(src)
+}
+
+// heredocTag returns the end tag of a heredoc given the text between the parentheses of @(...)
+func heredocTag(spec string) string {
+	if e := strings.IndexAny(spec, `:/`); e >= 0 {
+		spec = spec[:e]
+	}
+	return strings.Trim(strings.TrimSpace(spec), `"`)
+}
+
+// skipHeredoc returns the position of the newline that ends the line that holds the given end tag,
+// starting the search at the given position. The length of the source is returned when no such line exists
+func skipHeredoc(src string, i int, tag string) int {
+	for i < len(src) {
+		e := strings.IndexByte(src[i:], '\n')
+		var line string
+		if e < 0 {
+			line = src[i:]
+			e = len(src) - i
+		} else {
+			line = src[i : i+e]
+		}
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, `|`))
+		line = strings.TrimSpace(strings.TrimPrefix(line, `-`))
+		if line == tag {
+			if i+e == len(src) {
+				// The end tag is on the last line. Position at its end so that the caller sees a complete source
+				return len(src) - 1
+			}
+			return i + e
+		}
+		i += e + 1
+	}
+	return len(src)
+}
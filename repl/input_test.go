@@ -0,0 +1,30 @@
+package repl
+
+import "testing"
+
+func TestIncomplete(t *testing.T) {
+	tests := map[string]bool{
+		"1 + 2\n":                        false,
+		"{ a => [1,\n":                   true,
+		"{ a => [1,\n 2] }\n":            false,
+		"'unterminated\n":                true,
+		"'{' # {\n":                      false,
+		"/* comment\n":                   true,
+		"$x = @(EOT)\n text\n":           true,
+		"$x = @(\"EOT\":json/)\n text\n": true,
+		"$x = @(EOT)\n text\n  |- EOT\n": false,
+		"[@(A), @(B)]\na\nA\nb\nB\n":     false,
+		"[@(A), @(B)]\na\nA\nb\n":        true,
+		"$r = \"a\" =~ /{/\n":            false,
+		"$r = \"a\" =~ /}/ {\n":          true,
+		"case $x { /[}]/: { 1 } }\n":     false,
+		"if $x =~ /(/ {\n":               true,
+		"$x = (4 / 2) / {\n":             true,
+		"$x = [8 / 2 / 1]\n":             false,
+	}
+	for src, expected := range tests {
+		if actual := incomplete(src); actual != expected {
+			t.Errorf("incomplete(%q) returned %t, expected %t", src, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package repl provides an interactive read-eval-print loop for the Puppet language.
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+const (
+	PROMPT              = `>> `
+	CONTINUATION_PROMPT = `.. `
+)
+
+const help = `Enter Puppet language code to evaluate it. Input continues on the next line while brackets
+are unbalanced or a heredoc lacks its end tag. Variables, functions, and types that are defined
+remain available in subsequent input.
+
+Input is read one line at a time and there is no line editing, so pressing TAB does not complete
+a word as you type. Instead, end a line with a TAB and press Enter to list the completions of its
+last word. The line is then discarded instead of being evaluated.
+
+Commands:
+  :type <expr>   show the detailed type of the value of the expression
+  :load <file>   evaluate the contents of a file
+  :help          show this help
+  :quit          exit
+`
+
+// Repl evaluates input using one Context so that its global scope and its defining loader are kept between inputs
+type Repl struct {
+	c       eval.Context
+	out     io.Writer
+	pending strings.Builder
+	done    bool
+}
+
+// NewRepl creates a Repl that evaluates input using the given context and writes the results to the given writer
+func NewRepl(c eval.Context, out io.Writer) *Repl {
+	return &Repl{c: c, out: out}
+}
+
+// Done returns true once a :quit or :exit command has been entered
+func (r *Repl) Done() bool {
+	return r.done
+}
+
+// Prompt returns the prompt that precedes the next line of input
+func (r *Repl) Prompt() string {
+	if r.pending.Len() > 0 {
+		return CONTINUATION_PROMPT
+	}
+	return PROMPT
+}
+
+// Input adds a line of input. The accumulated input is evaluated, or executed when it is a command,
+// as soon as it is complete. Errors are written to the output of the receiver.
+func (r *Repl) Input(line string) {
+	if r.pending.Len() == 0 {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == `` {
+			return
+		}
+		if strings.HasPrefix(trimmed, `:`) {
+			r.command(trimmed[1:])
+			return
+		}
+	}
+	r.pending.WriteString(line)
+	r.pending.WriteByte('\n')
+	src := r.pending.String()
+	if incomplete(src) {
+		return
+	}
+	r.pending.Reset()
+	r.guard(func() { r.print(r.evaluate(``, src)) })
+}
+
+// Run reads lines from the given reader and passes them to Input until the reader is exhausted or
+// the Repl is done. Each line is preceded by a prompt. A line that ends with a TAB is not passed to
+// Input. The completions of its last word are written instead.
+//
+// Run does no line editing. A front end that uses a line editor should call Input for each line
+// and use Complete as its completion callback.
+func (r *Repl) Run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for !r.done {
+		io.WriteString(r.out, r.Prompt())
+		if !scanner.Scan() {
+			io.WriteString(r.out, "\n")
+			break
+		}
+		line := scanner.Text()
+		if strings.HasSuffix(line, "\t") {
+			fmt.Fprintln(r.out, strings.Join(r.Complete(strings.TrimRight(line, "\t")), `  `))
+			continue
+		}
+		r.Input(line)
+	}
+}
+
+// Complete returns the possible completions of the last word in the given line. A word that starts
+// with '$' is completed using the variables of the global scope, a word that starts with an upper
+// case letter is completed using the types of the loaders, and other words are completed using the
+// functions of the loaders.
+func (r *Repl) Complete(line string) []string {
+	i := len(line)
+	for i > 0 && isWordChar(line[i-1]) {
+		i--
+	}
+	variable := i > 0 && line[i-1] == '$'
+	word := line[i:]
+
+	var names []string
+	switch {
+	case variable:
+		for _, n := range r.c.Scope().VariableNames() {
+			names = append(names, `$`+n)
+		}
+		word = `$` + word
+	case word == ``:
+		return []string{}
+	case word[0] >= 'A' && word[0] <= 'Z':
+		names = r.discover(eval.NsType)
+	default:
+		names = r.discover(eval.NsFunction)
+	}
+
+	found := make([]string, 0)
+	lw := strings.ToLower(word)
+	for _, n := range names {
+		if strings.HasPrefix(strings.ToLower(n), lw) {
+			found = append(found, n)
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+func (r *Repl) discover(ns eval.Namespace) []string {
+	tns := r.c.Loader().Discover(r.c, func(tn eval.TypedName) bool { return tn.Namespace() == ns })
+	names := make([]string, 0, len(tns))
+	for _, tn := range tns {
+		n := tn.Name()
+		if ns == eval.NsType && n == strings.ToLower(n) {
+			// Names found on disk are lower case
+			parts := strings.Split(n, `::`)
+			for i, p := range parts {
+				parts[i] = strings.ToUpper(p[:1]) + p[1:]
+			}
+			n = strings.Join(parts, `::`)
+		}
+		names = append(names, n)
+	}
+	return names
+}
+
+func isWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == ':'
+}
+
+func (r *Repl) command(cmd string) {
+	arg := ``
+	if i := strings.IndexAny(cmd, " \t"); i > 0 {
+		arg = strings.TrimSpace(cmd[i+1:])
+		cmd = cmd[:i]
+	}
+	switch cmd {
+	case `type`:
+		r.guard(func() { fmt.Fprintln(r.out, eval.DetailedValueType(r.evaluate(``, arg)).String()) })
+	case `load`:
+		r.guard(func() {
+			content, err := ioutil.ReadFile(arg)
+			if err != nil {
+				panic(eval.Error(eval.EVAL_UNABLE_TO_READ_FILE, issue.H{`path`: arg, `detail`: err.Error()}))
+			}
+			r.print(r.evaluate(arg, string(content)))
+		})
+	case `help`:
+		io.WriteString(r.out, help)
+	case `quit`, `exit`:
+		r.done = true
+	default:
+		fmt.Fprintf(r.out, "Unknown command ':%s'. Use :help to list the available commands\n", cmd)
+	}
+}
+
+// evaluate parses the given source, adds its definitions to the defining loader, and evaluates it
+func (r *Repl) evaluate(name, src string) eval.Value {
+	expr := r.c.ParseAndValidate(name, src, false)
+	r.c.AddDefinitions(expr)
+	result, err := eval.TopEvaluate(r.c, expr)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func (r *Repl) print(v eval.Value) {
+	v.ToString(r.out, eval.PRETTY, nil)
+	io.WriteString(r.out, "\n")
+}
+
+// guard calls the given function and writes any error that it raises to the output
+func (r *Repl) guard(f func()) {
+	defer func() {
+		if e := recover(); e != nil {
+			if err, ok := e.(error); ok {
+				fmt.Fprintln(r.out, err.Error())
+				return
+			}
+			panic(e)
+		}
+	}()
+	f()
+}
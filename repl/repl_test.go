@@ -0,0 +1,68 @@
+package repl_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/repl"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func ExampleRepl_Run() {
+	input := `$x = 3
+function twice(Integer $n) { $n * 2 }
+twice($x)
+type Small = Integer[0, 9]
+{
+  a => twice(4) =~ Small,
+  b => @(EOT)
+    hello
+    | EOT
+}
+:type [$x, 'y']
+$x = 4
+:quit
+`
+	eval.Puppet.Do(func(c eval.Context) {
+		repl.NewRepl(c, os.Stdout).Run(strings.NewReader(input))
+	})
+	// Output:
+	// >> 3
+	// >> undef
+	// >> 6
+	// >> undef
+	// >> .. .. .. .. .. {
+	//   'a' => true,
+	//   'b' => "hello\n"
+	// }
+	// >> Tuple[Integer[3, 3], String]
+	// >> Cannot reassign variable '$x' (line: 1, column: 1)
+	// >>
+}
+
+func ExampleRepl_Complete() {
+	eval.Puppet.Do(func(c eval.Context) {
+		r := repl.NewRepl(c, os.Stdout)
+		r.Input(`$value = 1`)
+		r.Input(`$var = 2`)
+		r.Input(`function my_function() { 'x' }`)
+		r.Input(`type MyType = String`)
+		fmt.Println(r.Complete(`notice($va`))
+		fmt.Println(r.Complete(`my_f`))
+		fmt.Println(r.Complete(`Integer[1] + MyT`))
+		fmt.Println(r.Complete(`Timest`))
+	})
+	// Output:
+	// 1
+	// 2
+	// undef
+	// undef
+	// [$value $var]
+	// [my_function]
+	// [MyType]
+	// [Timestamp]
+}
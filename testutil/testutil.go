@@ -0,0 +1,301 @@
+// Package testutil helps unit testing Go functions that call Puppet functions.
+//
+// NewContext creates a Context whose loader is pre-seeded with the definitions found in a map of
+// Puppet sources. Any function can be replaced by a stub using Stub, or by a mock that expects a
+// table of calls using Expect. Stubs and mocks are only visible to the context that they are added
+// to, so tests that use different contexts can run in parallel. Everything that is logged using the
+// context is captured and can be examined using Messages, AssertLogged, and AssertNotLogged.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// Context is an eval.Context that is bound to a test
+type Context struct {
+	eval.Context
+	tb     testing.TB
+	loader *mockLoader
+	logger *eval.ArrayLogger
+}
+
+// NewContext creates a Context for the given test. The functions, plans, and types defined in the
+// given sources are added to its loader. The sources are keyed by file name. Statements other than
+// definitions are ignored. The test fails immediately if a source cannot be parsed.
+func NewContext(tb testing.TB, sources map[string]string) *Context {
+	tb.Helper()
+	root := eval.Puppet.RootContext()
+	loader := &mockLoader{DefiningLoader: eval.NewParentedLoader(root.Loader()), parent: root.Loader(), stubs: make(map[string]eval.Function)}
+	logger := eval.NewArrayLogger()
+	c := &Context{
+		Context: impl.WithParent(context.Background(), impl.NewEvaluator, loader, logger, root.ImplementationRegistry()),
+		tb:      tb,
+		loader:  loader,
+		logger:  logger,
+	}
+
+	files := make([]string, 0, len(sources))
+	for file := range sources {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	c.do(func(ec eval.Context) {
+		for _, file := range files {
+			ec.AddDefinitions(ec.ParseAndValidate(file, sources[file], false))
+		}
+		ec.ResolveDefinitions()
+	})
+	return c
+}
+
+// Evaluate parses and evaluates the given source and returns the result. The test fails
+// immediately if the evaluation fails.
+func (c *Context) Evaluate(source string) (result eval.Value) {
+	c.tb.Helper()
+	c.do(func(ec eval.Context) {
+		expr := ec.ParseAndValidate(``, source, false)
+		ec.AddDefinitions(expr)
+		var err error
+		if result, err = eval.TopEvaluate(ec, expr); err != nil {
+			panic(err)
+		}
+	})
+	return
+}
+
+// Call calls the function with the given name and returns the result. The arguments are converted
+// to values using eval.Wrap. The test fails immediately if the call fails.
+func (c *Context) Call(name string, args ...interface{}) (result eval.Value) {
+	c.tb.Helper()
+	c.do(func(ec eval.Context) {
+		result = eval.Call(ec, name, wrapAll(ec, args), nil)
+	})
+	return
+}
+
+// do calls the given function with the receiver as the current context. A panic with an error ends
+// the test.
+func (c *Context) do(f func(eval.Context)) {
+	c.tb.Helper()
+	var err error
+	eval.DoWithContext(c.Context, func(ec eval.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				if e, ok := r.(error); ok {
+					err = e
+					return
+				}
+				panic(r)
+			}
+		}()
+		f(ec)
+	})
+	if err != nil {
+		c.tb.Fatal(err)
+	}
+}
+
+// Log returns the logger that captures everything that is logged using the receiver
+func (c *Context) Log() *eval.ArrayLogger {
+	return c.logger
+}
+
+// Messages returns the messages that have been logged on the given level
+func (c *Context) Messages(level eval.LogLevel) []string {
+	entries := c.logger.Entries(level)
+	messages := make([]string, len(entries))
+	for i, e := range entries {
+		messages[i] = e.Message()
+	}
+	return messages
+}
+
+// AssertLogged fails the test unless a message that contains the given text has been logged on the
+// given level
+func (c *Context) AssertLogged(level eval.LogLevel, text string) {
+	c.tb.Helper()
+	messages := c.Messages(level)
+	for _, m := range messages {
+		if strings.Contains(m, text) {
+			return
+		}
+	}
+	c.tb.Errorf(`expected a %s message containing '%s', got %q`, level, text, messages)
+}
+
+// AssertNotLogged fails the test if a message that contains the given text has been logged on the
+// given level
+func (c *Context) AssertNotLogged(level eval.LogLevel, text string) {
+	c.tb.Helper()
+	for _, m := range c.Messages(level) {
+		if strings.Contains(m, text) {
+			c.tb.Errorf(`unexpected %s message '%s'`, level, m)
+		}
+	}
+}
+
+// Stub replaces the function with the given name with the given Go function. The returned mock
+// records the calls.
+func (c *Context) Stub(name string, f eval.DispatchFunctionWithBlock) *Mock {
+	m := &Mock{name: name, tb: c.tb, stub: f}
+	c.loader.stub(c.Context, m)
+	return m
+}
+
+// A Call is a call that a mock expects
+type Call struct {
+	// Args are the expected arguments. They are converted to values using eval.Wrap and compared
+	// to the actual arguments using eval.PuppetEquals.
+	Args []interface{}
+
+	// Result is the value that the call returns. It is converted to a value using eval.Wrap.
+	Result interface{}
+}
+
+// Expect replaces the function with the given name with a mock that expects the given calls in the
+// given order. A call that doesn't match the next expected call fails the test and returns undef.
+// The test also fails if it ends before all expected calls have been made.
+func (c *Context) Expect(name string, calls ...Call) *Mock {
+	m := &Mock{name: name, tb: c.tb, expected: calls}
+	c.loader.stub(c.Context, m)
+	c.tb.Cleanup(m.verify)
+	return m
+}
+
+// A Mock replaces a function and records the calls that are made to it. It is safe to call a mock
+// from multiple goroutines.
+type Mock struct {
+	name     string
+	tb       testing.TB
+	stub     eval.DispatchFunctionWithBlock
+	expected []Call
+	lock     sync.Mutex
+	calls    [][]eval.Value
+}
+
+// Calls returns the arguments of the calls that have been made to the mock
+func (m *Mock) Calls() [][]eval.Value {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([][]eval.Value(nil), m.calls...)
+}
+
+func (m *Mock) call(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+	m.lock.Lock()
+	n := len(m.calls)
+	m.calls = append(m.calls, args)
+	m.lock.Unlock()
+
+	if m.stub != nil {
+		return m.stub(c, args, block)
+	}
+	if n >= len(m.expected) {
+		m.tb.Errorf(`unexpected call %d of %s%s, expected %d calls`, n+1, m.name, argString(args), len(m.expected))
+		return eval.UNDEF
+	}
+	call := m.expected[n]
+	expected := wrapAll(c, call.Args)
+	ok := len(expected) == len(args)
+	for i := 0; ok && i < len(args); i++ {
+		ok = eval.PuppetEquals(expected[i], args[i])
+	}
+	if !ok {
+		m.tb.Errorf(`call %d of %s: expected arguments %s, got %s`, n+1, m.name, argString(expected), argString(args))
+		return eval.UNDEF
+	}
+	return eval.Wrap(c, call.Result)
+}
+
+func (m *Mock) verify() {
+	m.lock.Lock()
+	n := len(m.calls)
+	m.lock.Unlock()
+	if n < len(m.expected) {
+		m.tb.Errorf(`expected %d calls of %s, got %d`, len(m.expected), m.name, n)
+	}
+}
+
+// mockLoader is a DefiningLoader that finds the stubbed functions before it consults its parent
+type mockLoader struct {
+	eval.DefiningLoader
+	parent eval.Loader
+	lock   sync.RWMutex
+	stubs  map[string]eval.Function
+}
+
+func (l *mockLoader) stub(c eval.Context, m *Mock) {
+	f := eval.BuildFunction(m.name, nil, []eval.DispatchCreator{func(d eval.Dispatch) {
+		d.RepeatedParam(`Any`)
+		d.OptionalBlock(`Callable`)
+		d.Function2(m.call)
+	}}).Resolve(c)
+	tn := eval.NewTypedName(eval.NsFunction, m.name)
+	l.lock.Lock()
+	l.stubs[tn.MapKey()] = f
+	l.lock.Unlock()
+}
+
+func (l *mockLoader) LoadEntry(c eval.Context, name eval.TypedName) eval.LoaderEntry {
+	l.lock.RLock()
+	f, ok := l.stubs[name.MapKey()]
+	l.lock.RUnlock()
+	if ok {
+		return eval.NewLoaderEntry(f, nil)
+	}
+	return l.DefiningLoader.LoadEntry(c, name)
+}
+
+func (l *mockLoader) Discover(c eval.Context, predicate func(tn eval.TypedName) bool) []eval.TypedName {
+	found := l.DefiningLoader.Discover(c, predicate)
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	for _, f := range l.stubs {
+		tn := eval.NewTypedName(eval.NsFunction, f.Name())
+		if !predicate(tn) {
+			continue
+		}
+		known := false
+		for _, n := range found {
+			if n.MapKey() == tn.MapKey() {
+				known = true
+				break
+			}
+		}
+		if !known {
+			found = append(found, tn)
+		}
+	}
+	return found
+}
+
+func (l *mockLoader) Parent() eval.Loader {
+	return l.parent
+}
+
+func wrapAll(c eval.Context, args []interface{}) []eval.Value {
+	values := make([]eval.Value, len(args))
+	for i, a := range args {
+		values[i] = eval.Wrap(c, a)
+	}
+	return values
+}
+
+// argFormat presents arguments the way they are written in the Puppet language
+var argFormat = eval.NewFormatContext(types.DefaultAnyType(), types.DEFAULT_PROGRAM_FORMAT, types.DEFAULT_INDENTATION)
+
+func argString(args []eval.Value) string {
+	s := make([]string, len(args))
+	for i, a := range args {
+		s[i] = eval.ToString2(a, argFormat)
+	}
+	return fmt.Sprintf(`(%s)`, strings.Join(s, `, `))
+}
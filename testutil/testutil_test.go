@@ -0,0 +1,125 @@
+package testutil_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/testutil"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+var sources = map[string]string{
+	`greet.pp`: `
+function greet(String $name) >> String {
+  notice("greeting ${name}")
+  "${salutation()} ${name}"
+}`,
+	`salutation.pp`: `
+function salutation() >> String {
+  'Hello'
+}`,
+}
+
+func TestContext_Call(t *testing.T) {
+	c := testutil.NewContext(t, sources)
+	if r := c.Call(`greet`, `Alice`); r.String() != `Hello Alice` {
+		t.Errorf(`unexpected result %s`, r)
+	}
+	c.AssertLogged(eval.NOTICE, `greeting Alice`)
+	c.AssertNotLogged(eval.WARNING, `greeting`)
+}
+
+func TestContext_Stub(t *testing.T) {
+	t.Parallel()
+	c := testutil.NewContext(t, sources)
+	m := c.Stub(`salutation`, func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+		return eval.Wrap(c, `Howdy`)
+	})
+	if r := c.Evaluate(`greet('Bob')`); r.String() != `Howdy Bob` {
+		t.Errorf(`unexpected result %s`, r)
+	}
+	if len(m.Calls()) != 1 {
+		t.Errorf(`expected 1 call, got %d`, len(m.Calls()))
+	}
+}
+
+func TestContext_Stub_scopedToContext(t *testing.T) {
+	t.Parallel()
+	c := testutil.NewContext(t, sources)
+	c.Stub(`salutation`, func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+		return eval.Wrap(c, `Hi`)
+	})
+	other := testutil.NewContext(t, sources)
+	if r := other.Call(`greet`, `Carol`); r.String() != `Hello Carol` {
+		t.Errorf(`stub leaked into other context: %s`, r)
+	}
+}
+
+func TestContext_Stub_builtin(t *testing.T) {
+	t.Parallel()
+	c := testutil.NewContext(t, nil)
+	m := c.Stub(`notice`, func(c eval.Context, args []eval.Value, block eval.Lambda) eval.Value {
+		return eval.UNDEF
+	})
+	c.Evaluate(`notice('silenced')`)
+	c.AssertNotLogged(eval.NOTICE, `silenced`)
+	if calls := m.Calls(); len(calls) != 1 || calls[0][0].String() != `silenced` {
+		t.Errorf(`unexpected calls %v`, calls)
+	}
+}
+
+func TestContext_Expect(t *testing.T) {
+	t.Parallel()
+	c := testutil.NewContext(t, nil)
+	c.Expect(`lookup_port`,
+		testutil.Call{Args: []interface{}{`web`}, Result: 80},
+		testutil.Call{Args: []interface{}{`db`}, Result: 5432})
+	r := c.Evaluate(`['web', 'db'].map |$s| { lookup_port($s) }`)
+	if r.String() != `[80, 5432]` {
+		t.Errorf(`unexpected result %s`, r)
+	}
+}
+
+// recorder is a testing.TB that records the reported failures
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestContext_Expect_failures(t *testing.T) {
+	r := &recorder{TB: t}
+	c := testutil.NewContext(r, nil)
+	c.Expect(`lookup_port`, testutil.Call{Args: []interface{}{`web`}, Result: 80})
+	c.Evaluate(`lookup_port('db')`)
+	c.Evaluate(`lookup_port('web')`)
+	c.AssertLogged(eval.NOTICE, `nothing`)
+	expected := []string{
+		`call 1 of lookup_port: expected arguments ('web'), got ('db')`,
+		`unexpected call 2 of lookup_port('web'), expected 1 calls`,
+		`expected a notice message containing 'nothing', got []`,
+	}
+	if strings.Join(r.errors, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected failures:\n%s", strings.Join(r.errors, "\n"))
+	}
+}
+
+func TestContext_Expect_missingCalls(t *testing.T) {
+	var r *recorder
+	t.Run(`mock`, func(t *testing.T) {
+		r = &recorder{TB: t}
+		c := testutil.NewContext(r, nil)
+		c.Expect(`lookup_port`, testutil.Call{Args: []interface{}{`web`}, Result: 80}, testutil.Call{Args: []interface{}{`db`}, Result: 5432})
+		c.Evaluate(`lookup_port('web')`)
+	})
+	if len(r.errors) != 1 || r.errors[0] != `expected 2 calls of lookup_port, got 1` {
+		t.Errorf(`unexpected failures %q`, r.errors)
+	}
+}
@@ -0,0 +1,246 @@
+// Package transport contains the built-in implementations of eval.Transport
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+type local struct{}
+
+func init() {
+	eval.RegisterTransport(&local{})
+}
+
+func (t *local) Name() string {
+	return `local`
+}
+
+func (t *local) Available(c eval.Context, target eval.PuppetObject) bool {
+	return true
+}
+
+func (t *local) RunCommand(c eval.Context, target eval.PuppetObject, command string, options eval.OrderedMap) (eval.OrderedMap, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == `windows` {
+		cmd = exec.CommandContext(c, `cmd.exe`, `/C`, command)
+	} else {
+		cmd = exec.CommandContext(c, `/bin/sh`, `-c`, command)
+	}
+	return commandResult(cmd, `command`)
+}
+
+func (t *local) RunScript(c eval.Context, target eval.PuppetObject, script string, arguments []string, options eval.OrderedMap) (eval.OrderedMap, error) {
+	eval.AssertPathAllowed(c, script)
+	return commandResult(exec.CommandContext(c, script, arguments...), `script`)
+}
+
+func (t *local) RunTask(c eval.Context, target eval.PuppetObject, task eval.PuppetObject, arguments eval.OrderedMap, options eval.OrderedMap) (eval.OrderedMap, error) {
+	name := stringAttribute(task, `name`)
+	executable, inputMethod := types.TaskImplementation(task, localFeatures())
+	switch inputMethod {
+	case ``:
+		inputMethod = `both`
+	case `stdin`, `environment`, `powershell`, `both`:
+	default:
+		panic(c.Error(nil, eval.EVAL_TASK_BAD_INPUT_METHOD, issue.H{`name`: name, `input_method`: inputMethod}))
+	}
+
+	arguments = arguments.Merge(types.SingletonHash2(`_task`, types.WrapString(name)))
+	var cmd *exec.Cmd
+	if inputMethod == `powershell` {
+		args := []string{`-NoProfile`, `-NonInteractive`, `-NoLogo`, `-ExecutionPolicy`, `Bypass`, `-File`, executable}
+		arguments.EachPair(func(k, v eval.Value) { args = append(args, `-`+k.String(), argString(v)) })
+		cmd = exec.CommandContext(c, `powershell.exe`, args...)
+	} else {
+		cmd = exec.CommandContext(c, executable)
+	}
+	if inputMethod == `stdin` || inputMethod == `both` {
+		cmd.Stdin = bytes.NewBufferString(toJSON(arguments))
+	}
+	if inputMethod == `environment` || inputMethod == `both` {
+		cmd.Env = os.Environ()
+		arguments.EachPair(func(k, v eval.Value) {
+			cmd.Env = append(cmd.Env, fmt.Sprintf(`PT_%s=%s`, k, argString(v)))
+		})
+	}
+
+	stdout, stderr, exitCode, err := run(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var value eval.OrderedMap
+	if parsed, ok := fromJSON(c, stdout); ok {
+		value = parsed
+	} else {
+		value = types.SingletonHash2(`_output`, types.WrapString(stdout))
+	}
+	if exitCode != 0 && !value.IncludesKey2(`_error`) {
+		msg := fmt.Sprintf(`The task failed with exit code %d`, exitCode)
+		if stderr != `` {
+			msg += `: ` + strings.TrimSpace(stderr)
+		}
+		value = value.Merge(types.SingletonHash2(`_error`, errorHash(msg, `puppetlabs.tasks/task-error`, exitCode)))
+	}
+	return value, nil
+}
+
+func (t *local) UploadFile(c eval.Context, target eval.PuppetObject, source, destination string, options eval.OrderedMap) (eval.OrderedMap, error) {
+	err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(destination, rel)
+		if info.IsDir() {
+			return os.MkdirAll(dest, info.Mode().Perm())
+		}
+		return copyFile(path, dest, info.Mode().Perm())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return types.SingletonHash2(`_output`, types.WrapString(fmt.Sprintf(`Uploaded '%s' to '%s:%s'`, source, stringAttribute(target, `host`), destination))), nil
+}
+
+// localFeatures returns the features that a task implementation may require when it runs locally
+func localFeatures() []string {
+	if runtime.GOOS == `windows` {
+		return []string{`powershell`}
+	}
+	return []string{`shell`}
+}
+
+// argString returns a String argument verbatim and any other argument in JSON form
+func argString(v eval.Value) string {
+	if sv, ok := v.(*types.StringValue); ok {
+		return sv.String()
+	}
+	return toJSON(v)
+}
+
+// commandResult runs the given command and returns a hash with its stdout, stderr, and exit_code
+func commandResult(cmd *exec.Cmd, kind string) (eval.OrderedMap, error) {
+	stdout, stderr, exitCode, err := run(cmd)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*types.HashEntry{
+		types.WrapHashEntry2(`stdout`, types.WrapString(stdout)),
+		types.WrapHashEntry2(`stderr`, types.WrapString(stderr)),
+		types.WrapHashEntry2(`exit_code`, types.WrapInteger(int64(exitCode)))}
+	if exitCode != 0 {
+		entries = append(entries, types.WrapHashEntry2(`_error`,
+			errorHash(fmt.Sprintf(`The %s failed with exit code %d`, kind, exitCode), `puppetlabs.tasks/`+kind+`-error`, exitCode)))
+	}
+	return types.WrapHash(entries), nil
+}
+
+func copyFile(source, destination string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func errorHash(msg, kind string, exitCode int) eval.OrderedMap {
+	return types.WrapHash([]*types.HashEntry{
+		types.WrapHashEntry2(`msg`, types.WrapString(msg)),
+		types.WrapHashEntry2(`kind`, types.WrapString(kind)),
+		types.WrapHashEntry2(`details`, types.SingletonHash2(`exit_code`, types.WrapInteger(int64(exitCode))))})
+}
+
+// run runs the given command and returns its output and exit code. An error is returned when the
+// command could not be started or when it did not exit normally.
+func run(cmd *exec.Cmd) (stdout, stderr string, exitCode int, err error) {
+	var outBuf, errBuf bytes.Buffer
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+	if err = cmd.Run(); err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() >= 0 {
+			err = nil
+			exitCode = ee.ExitCode()
+		}
+	}
+	return outBuf.String(), errBuf.String(), exitCode, err
+}
+
+func stringAttribute(o eval.PuppetObject, name string) string {
+	if v, ok := o.Get(name); ok && v != eval.UNDEF {
+		return v.String()
+	}
+	return ``
+}
+
+func toJSON(value eval.Value) string {
+	b, _ := json.Marshal(toGo(value))
+	return string(b)
+}
+
+// toGo converts the given Data value into a value suitable for json.Marshal
+func toGo(value eval.Value) interface{} {
+	switch value.(type) {
+	case *types.UndefValue:
+		return nil
+	case *types.BooleanValue:
+		return value.(*types.BooleanValue).Bool()
+	case *types.IntegerValue:
+		return value.(*types.IntegerValue).Int()
+	case *types.FloatValue:
+		return value.(*types.FloatValue).Float()
+	case *types.StringValue:
+		return value.String()
+	case *types.HashValue:
+		m := make(map[string]interface{}, value.(*types.HashValue).Len())
+		value.(*types.HashValue).EachPair(func(k, v eval.Value) { m[k.String()] = toGo(v) })
+		return m
+	case eval.List:
+		a := make([]interface{}, value.(eval.List).Len())
+		value.(eval.List).EachWithIndex(func(v eval.Value, i int) { a[i] = toGo(v) })
+		return a
+	default:
+		return value.String()
+	}
+}
+
+func fromJSON(c eval.Context, s string) (eval.OrderedMap, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, `{`) {
+		return nil, false
+	}
+	var parsed interface{}
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+	if d.Decode(&parsed) != nil {
+		return nil, false
+	}
+	if h, ok := eval.Wrap(c, parsed).(eval.OrderedMap); ok {
+		return h, true
+	}
+	return nil, false
+}
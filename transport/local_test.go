@@ -0,0 +1,123 @@
+package transport_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+// runPlan calls a plan with the given body. It returns the string form of the result, or the
+// code of the issue that was reported.
+func runPlan(body string) (result string, code issue.Code) {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		c.AddDefinitions(c.ParseAndValidate(``, `plan test() { `+body+` }`, false))
+		c.ResolveDefinitions()
+		p, _ := eval.Load(c, eval.NewTypedName(eval.NsPlan, `test`))
+		result = p.(eval.Function).Call(c, nil).String()
+		return nil
+	})
+	if err != nil {
+		re, ok := err.(issue.Reported)
+		if !ok {
+			panic(err)
+		}
+		code = re.Code()
+	}
+	return
+}
+
+func TestPlanLocalTransport(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `plan`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	taskFile := filepath.Join(tmpDir, `task.sh`)
+	if err = ioutil.WriteFile(taskFile, []byte("#!/bin/sh\necho \"$PT_message\"\ncat\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		body   string
+		result string
+		code   issue.Code
+	}{
+		{`run_command('echo hello', 'localhost').first()['stdout']`, "hello\n", ``},
+		{`run_command('exit 3', 'localhost', '_catch_errors' => true).first().error().details()['exit_code']`, `3`, ``},
+		{`run_command('exit 3', 'localhost')`, ``, eval.EVAL_RUN_FAILED},
+		{`run_command('echo hello', 'some.host')`, ``, eval.EVAL_UNKNOWN_TRANSPORT},
+		{`run_task(Task(name => 'x', executable => '` + taskFile + `', input_method => 'environment'), 'localhost', message => 'hi').first().value()`,
+			"{'_output' => \"hi\\n\"}", ``},
+		{`run_task(Task(name => 'x', executable => '` + taskFile + `', input_method => 'stdin'), 'localhost', message => 'hi').first().value()`,
+			`{'_task' => 'x', 'message' => 'hi'}`, ``},
+		{`run_task(Task(name => 'x', executable => '` + taskFile + `', input_method => 'stdin'), 'localhost,localhost').count()`, `2`, ``},
+		{`run_task(Task(name => 'x', executable => '` + taskFile + `', input_method => 'stdin'), 'localhost', message => 'hi', '_noop' => true, '_run_as' => 'root').first().value()`,
+			`{'_task' => 'x', 'message' => 'hi'}`, ``},
+	}
+	eval.Puppet.Set(`tasks`, types.WrapBoolean(true))
+	defer eval.Puppet.Reset()
+	for _, tc := range tests {
+		if result, code := runPlan(tc.body); code != tc.code || result != tc.result {
+			t.Errorf(`%s: expected '%s%s', got '%s%s'`, tc.body, tc.result, tc.code, result, code)
+		}
+	}
+}
+
+func TestPlanOnlyFunction(t *testing.T) {
+	eval.Puppet.Try(func(c eval.Context) error {
+		_, err := eval.TopEvaluate(c, c.ParseAndValidate(``, `run_command('echo hello', 'localhost')`, false))
+		if err == nil || err.Code() != eval.EVAL_PLAN_ONLY_FUNCTION {
+			t.Errorf(`expected %s, got %v`, eval.EVAL_PLAN_ONLY_FUNCTION, err)
+		}
+		return nil
+	})
+}
+
+func TestPlanInventory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `inventory`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	inventoryFile := filepath.Join(tmpDir, `inventory.yaml`)
+	err = ioutil.WriteFile(inventoryFile, []byte(`version: 2
+config:
+  transport: local
+groups:
+  - name: web
+    targets:
+      - uri: web1.example.com
+        alias: w1
+      - web2.example.com
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		body   string
+		result string
+		code   issue.Code
+	}{
+		{`get_targets('all').map |$t| { $t.name() }`, `['web1.example.com', 'web2.example.com']`, ``},
+		{`get_target('w1').name()`, `web1.example.com`, ``},
+		{`get_target('web')`, ``, eval.EVAL_EXPECTED_ONE_TARGET},
+		{`run_command('echo hello', 'w1').names()`, `['web1.example.com']`, ``},
+	}
+	eval.Puppet.Set(`tasks`, types.WrapBoolean(true))
+	eval.Puppet.Set(`inventory_file`, types.WrapString(inventoryFile))
+	defer eval.Puppet.Reset()
+	for _, tc := range tests {
+		if result, code := runPlan(tc.body); code != tc.code || result != tc.result {
+			t.Errorf(`%s: expected '%s%s', got '%s%s'`, tc.body, tc.result, tc.code, result, code)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package typecheck provides a static analysis of Puppet language programs that infers the types of
+// expressions without evaluating them.
+//
+// The inferred types are members of the same type lattice that is used at runtime, so a reported
+// mismatch is one that the runtime would raise too. The analysis is conservative. A warning is only
+// produced when the inferred types prove that something is wrong. Expressions that cannot be
+// inferred get the type Any and are never reported.
+package typecheck
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/impl"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// wellKnownVariables are always defined at runtime and never reported as undefined
+var wellKnownVariables = map[string]bool{
+	`facts`:        true,
+	`trusted`:      true,
+	`server_facts`: true,
+	`settings`:     true,
+}
+
+type checker struct {
+	c        eval.Context
+	globals  map[string]bool
+	warnings []issue.Reported
+
+	// functions holds the function and plan definitions that remain to be checked
+	functions []*parser.FunctionDefinition
+
+	// returnType is the declared return type of the function that is checked, or nil
+	returnType eval.Type
+}
+
+// scope holds the types of the variables that are assigned in a local scope. Lambdas can see the
+// variables of their enclosing scope. Functions and plans can only see their parameters and the
+// variables of the global scope of the context.
+type scope struct {
+	parent *scope
+	vars   map[string]eval.Type
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent, make(map[string]eval.Type)}
+}
+
+func (s *scope) get(name string) (eval.Type, bool) {
+	for ; s != nil; s = s.parent {
+		if t, ok := s.vars[name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Check performs a static analysis of the given program and returns the problems that it finds as
+// warnings. The problems are:
+//
+//   - function calls where the inferred argument types cannot match any of the function's signatures
+//   - function bodies and return values that cannot match the declared return type
+//   - parameter default values that cannot match the declared parameter type
+//   - case options that can never be selected
+//   - references to variables that are not defined
+//
+// Functions and types are resolved using the loaders of the given context. Definitions in the given
+// program are resolved using a loader that is private to the call so the loaders of the context are
+// not modified. Variables in the global scope of the context are considered to be defined.
+func Check(c eval.Context, program parser.Expression) []issue.Reported {
+	k := &checker{globals: make(map[string]bool)}
+	for _, n := range c.Scope().VariableNames() {
+		k.globals[n] = true
+	}
+	cc := impl.WithParent(c, impl.NewEvaluator, eval.NewParentedLoader(c.Loader()), c.Logger(), c.ImplementationRegistry())
+	eval.DoWithContext(cc, func(cc eval.Context) {
+		k.c = cc
+		k.define(program)
+		global := newScope(nil)
+		k.infer(program, global)
+
+		// Functions and plans are checked last so that they can see all variables of the global scope
+		for i := 0; i < len(k.functions); i++ {
+			k.function(k.functions[i], global)
+		}
+	})
+	if k.warnings == nil {
+		return make([]issue.Reported, 0)
+	}
+	sort.SliceStable(k.warnings, func(i, j int) bool {
+		li, lj := k.warnings[i].Location(), k.warnings[j].Location()
+		if li.File() != lj.File() {
+			return li.File() < lj.File()
+		}
+		if li.Line() != lj.Line() {
+			return li.Line() < lj.Line()
+		}
+		return li.Pos() < lj.Pos()
+	})
+	return k.warnings
+}
+
+// define adds the definitions of the given program. Definitions that cannot be resolved are ignored
+func (k *checker) define(program parser.Expression) {
+	defer func() {
+		recover()
+	}()
+	k.c.AddDefinitions(program)
+	k.c.ResolveDefinitions()
+}
+
+func (k *checker) warning(code issue.Code, args issue.H, location parser.Expression) {
+	k.warnings = append(k.warnings, issue.NewReported(code, issue.SEVERITY_WARNING, args, location))
+}
+
+// mismatch adds a warning about a value of the actual type where the expected type is required
+func (k *checker) mismatch(pfx string, expected, actual eval.Type, location parser.Expression) {
+	k.warning(eval.EVAL_CHECK_TYPE_MISMATCH, issue.H{`detail`: strings.TrimSpace(eval.DescribeMismatch(pfx, expected, actual))}, location)
+}
+
+// resolveType resolves the given type expression, or returns nil if it cannot be resolved
+func (k *checker) resolveType(e parser.Expression) (t eval.Type) {
+	defer func() {
+		if recover() != nil {
+			t = nil
+		}
+	}()
+	return k.c.ResolveType(e)
+}
+
+// isTypeExpression returns true if the given expression is a type reference or a parameterized type reference
+func isTypeExpression(e parser.Expression) bool {
+	switch e := e.(type) {
+	case *parser.QualifiedReference:
+		return true
+	case *parser.AccessExpression:
+		_, ok := e.Operand().(*parser.QualifiedReference)
+		return ok
+	}
+	return false
+}
+
+// isLiteral returns true if the given expression is built from literal values only
+func isLiteral(e parser.Expression) bool {
+	switch e := e.(type) {
+	case parser.LiteralValue, *parser.LiteralUndef, *parser.LiteralDefault:
+		return true
+	case *parser.LiteralList:
+		for _, el := range e.Elements() {
+			if !isLiteral(el) {
+				return false
+			}
+		}
+		return true
+	case *parser.LiteralHash:
+		for _, en := range e.Entries() {
+			ke := en.(*parser.KeyedEntry)
+			if !(isLiteral(ke.Key()) && isLiteral(ke.Value())) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// literal evaluates the given literal expression, or returns nil if the evaluation fails
+func (k *checker) literal(e parser.Expression) (v eval.Value) {
+	defer func() {
+		if recover() != nil {
+			v = nil
+		}
+	}()
+	return eval.Evaluate(k.c, e)
+}
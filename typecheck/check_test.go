@@ -0,0 +1,125 @@
+package typecheck_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/typecheck"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+
+	// Initialize pcore
+	_ "github.com/lyraproj/puppet-evaluator/pcore"
+)
+
+func check(c eval.Context, src string) []issue.Reported {
+	program, err := parser.CreateParser().Parse(`test.pp`, src, false)
+	if err != nil {
+		panic(err)
+	}
+	return typecheck.Check(c, program)
+}
+
+func ExampleCheck() {
+	src := `
+function double(Integer $x, Integer $y = 'one') >> Integer {
+  $x * $y + $z
+}
+
+function kind(Integer[0,10] $x) >> String {
+  case $x {
+    'ten': { 'text' }
+    20: { 'big' }
+    Integer: { 'int' }
+    5: { 'five' }
+    default: { 'other' }
+  }
+}
+
+function greet(String $name) >> String {
+  if $name == '' {
+    return 42
+  }
+  "hello ${name}"
+}
+
+double('two')
+greet(double(3))
+[1, 2].map |$v| { $v + $w }
+`
+	eval.Puppet.Do(func(c eval.Context) {
+		for _, w := range check(c, src) {
+			fmt.Println(w.Error())
+		}
+	})
+	// Output:
+	// Type mismatch: default value of parameter $y expects an Integer value, got String (file: test.pp, line: 2, column: 42)
+	// Variable '$z' is not defined (file: test.pp, line: 3, column: 13)
+	// Case option 'ten' is unreachable: it never matches a value of type Integer[0, 10] (file: test.pp, line: 8, column: 5)
+	// Case option 20 is unreachable: it never matches a value of type Integer[0, 10] (file: test.pp, line: 9, column: 5)
+	// Case option 5 is unreachable: a preceding option matches every value of type Integer (file: test.pp, line: 11, column: 5)
+	// Case option default is unreachable: the preceding options match every value of type Integer[0, 10] (file: test.pp, line: 12, column: 5)
+	// Type mismatch: returned value expects a String value, got Integer (file: test.pp, line: 18, column: 12)
+	// Call to 'double' can never succeed: parameter 1 expects an Integer value, got String (file: test.pp, line: 23, column: 1)
+	// Call to 'greet' can never succeed: parameter 1 expects a String value, got Integer (file: test.pp, line: 24, column: 1)
+	// Variable '$w' is not defined (file: test.pp, line: 25, column: 24)
+}
+
+func TestCheck_noFalsePositives(t *testing.T) {
+	src := `
+function f(Optional[Integer] $x = undef, String *$rest) >> Optional[Integer] {
+  $y = [1, 2].map |Integer $i| { $i * $x }
+  if 'abc' =~ /a(b)c/ {
+    notice($1)
+  }
+  $s = @("EOT")
+    value ${y[0]} ${rest}
+    | EOT
+  [$p, $q] = [1, 'x']
+  notice("${p} ${q} ${s} ${facts['os']} ${::global}")
+  case $x {
+    undef: { undef }
+    1, 2.0: { $x }
+    Integer[5]: { 5 }
+    default: { 4 }
+  }
+}
+$a = f(1)
+f(*[$a, 'b'])
+f(undef, 'a', 'b')
+Integer('3') + $a + $preset
+
+function g(Integer $i) { $i }
+if $a == 1 { $b = 1 } else { $b = 'a' }
+g($b)
+unless $a == 1 { $c = 3 }
+g($c)
+case $a {
+  1: { $d = 'a' }
+  default: { $d = 2 }
+}
+g($d)
+`
+	eval.Puppet.Do(func(c eval.Context) {
+		c.Scope().Set(`preset`, types.WrapInteger(1))
+		if ws := check(c, src); len(ws) > 0 {
+			for _, w := range ws {
+				t.Error(w.Error())
+			}
+		}
+	})
+}
+
+func TestCheck_severity(t *testing.T) {
+	eval.Puppet.Do(func(c eval.Context) {
+		ws := check(c, `notice($x)`)
+		if len(ws) != 1 {
+			t.Fatalf(`expected one warning, got %d`, len(ws))
+		}
+		if ws[0].Severity() != issue.SEVERITY_WARNING || ws[0].Code() != eval.EVAL_CHECK_UNDEFINED_VARIABLE {
+			t.Errorf(`unexpected warning %s: %s`, ws[0].Code(), ws[0].Error())
+		}
+	})
+}
@@ -0,0 +1,497 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+	"github.com/lyraproj/puppet-parser/parser"
+)
+
+// infer checks the given expression and the expressions that it contains and returns the inferred
+// type of the value that the expression evaluates to
+func (k *checker) infer(e parser.Expression, s *scope) eval.Type {
+	if e == nil || e.IsNop() {
+		return types.DefaultUndefType()
+	}
+	if isTypeExpression(e) {
+		if t := k.resolveType(e); t != nil {
+			return types.NewTypeType(t)
+		}
+		k.visit(e, s)
+		return types.DefaultTypeType()
+	}
+	if isLiteral(e) {
+		if v := k.literal(e); v != nil {
+			return eval.DetailedValueType(v)
+		}
+		return types.DefaultAnyType()
+	}
+
+	switch e := e.(type) {
+	case *parser.Program:
+		return k.infer(e.Body(), s)
+	case *parser.BlockExpression:
+		var t eval.Type = types.DefaultUndefType()
+		for _, st := range e.Statements() {
+			t = k.infer(st, s)
+		}
+		return t
+	case *parser.ParenthesizedExpression:
+		return k.infer(e.Expr(), s)
+	case *parser.VariableExpression:
+		return k.variable(e, s)
+	case *parser.AssignmentExpression:
+		return k.assignment(e, s)
+	case *parser.ArithmeticExpression:
+		return k.arithmetic(e, s)
+	case *parser.ConcatenatedString, *parser.HeredocExpression, *parser.TextExpression, *parser.QualifiedName:
+		k.visit(e, s)
+		return types.DefaultStringType()
+	case *parser.ComparisonExpression, *parser.MatchExpression, *parser.InExpression,
+		*parser.AndExpression, *parser.OrExpression, *parser.NotExpression:
+		k.visit(e, s)
+		return types.DefaultBooleanType()
+	case *parser.LiteralList:
+		ts := make([]eval.Type, len(e.Elements()))
+		for i, el := range e.Elements() {
+			ts[i] = k.infer(el, s)
+		}
+		return types.NewTupleType(ts, nil)
+	case *parser.LiteralHash:
+		var kt, vt eval.Type
+		for _, en := range e.Entries() {
+			ke := en.(*parser.KeyedEntry)
+			kt = union(kt, k.infer(ke.Key(), s))
+			vt = union(vt, k.infer(ke.Value(), s))
+		}
+		return types.NewHashType(kt, vt, nil)
+	case *parser.IfExpression:
+		k.infer(e.Test(), s)
+		return k.alternatives(s, e.Then(), e.Else())
+	case *parser.UnlessExpression:
+		k.infer(e.Test(), s)
+		return k.alternatives(s, e.Then(), e.Else())
+	case *parser.CaseExpression:
+		return k.caseExpression(e, s)
+	case *parser.SelectorExpression:
+		k.infer(e.Lhs(), s)
+		var t eval.Type
+		for _, se := range e.Selectors() {
+			entry := se.(*parser.SelectorEntry)
+			k.infer(entry.Matching(), s)
+			t = union(t, k.infer(entry.Value(), s))
+		}
+		if t == nil {
+			t = types.DefaultAnyType()
+		}
+		return t
+	case *parser.CallNamedFunctionExpression:
+		return k.callNamedFunction(e, s)
+	case *parser.CallMethodExpression:
+		return k.callMethod(e, s)
+	case *parser.LambdaExpression:
+		ls := newScope(s)
+		k.parameters(e.Parameters(), ls)
+		k.infer(e.Body(), ls)
+		return types.DefaultCallableType()
+	case *parser.PlanDefinition:
+		k.functions = append(k.functions, &e.FunctionDefinition)
+		return types.DefaultAnyType()
+	case *parser.FunctionDefinition:
+		k.functions = append(k.functions, e)
+		return types.DefaultAnyType()
+	case parser.Definition, *parser.NodeDefinition, *parser.SiteDefinition:
+		// Other definitions have scoping rules of their own and are not checked
+		return types.DefaultAnyType()
+	}
+	k.visit(e, s)
+	return types.DefaultAnyType()
+}
+
+// visit infers the types of the expressions that are directly contained in the given expression
+func (k *checker) visit(e parser.Expression, s *scope) {
+	e.Contents(nil, func(path []parser.Expression, ce parser.Expression) {
+		k.infer(ce, s)
+	})
+}
+
+func (k *checker) variable(e *parser.VariableExpression, s *scope) eval.Type {
+	name, ok := e.Name()
+	if !ok {
+		// Numeric variables are set by regexp matches
+		return types.DefaultAnyType()
+	}
+	if t, ok := s.get(name); ok {
+		return t
+	}
+	if !(strings.Contains(name, `::`) || k.globals[name] || wellKnownVariables[name]) {
+		k.warning(eval.EVAL_CHECK_UNDEFINED_VARIABLE, issue.H{`name`: name}, e)
+	}
+	return types.DefaultAnyType()
+}
+
+func (k *checker) assignment(e *parser.AssignmentExpression, s *scope) eval.Type {
+	t := k.infer(e.Rhs(), s)
+	if e.Operator() != `=` {
+		k.infer(e.Lhs(), s)
+		return types.DefaultAnyType()
+	}
+	k.assign(e.Lhs(), t, s)
+	return t
+}
+
+// assign assigns the given type to the variables of the given left hand side of an assignment
+func (k *checker) assign(lhs parser.Expression, t eval.Type, s *scope) {
+	switch lhs := lhs.(type) {
+	case *parser.VariableExpression:
+		if name, ok := lhs.Name(); ok {
+			s.vars[name] = t
+		}
+	case *parser.LiteralList:
+		var ts []eval.Type
+		if tt, ok := t.(*types.TupleType); ok && len(tt.Types()) == len(lhs.Elements()) {
+			ts = tt.Types()
+		}
+		for i, el := range lhs.Elements() {
+			var et eval.Type = types.DefaultAnyType()
+			if ts != nil {
+				et = ts[i]
+			}
+			k.assign(el, et, s)
+		}
+	default:
+		k.infer(lhs, s)
+	}
+}
+
+func (k *checker) arithmetic(e *parser.ArithmeticExpression, s *scope) eval.Type {
+	l := k.infer(e.Lhs(), s)
+	r := k.infer(e.Rhs(), s)
+	switch {
+	case isInteger(l) && isInteger(r):
+		return types.DefaultIntegerType()
+	case isNumeric(l) && isNumeric(r):
+		return types.DefaultFloatType()
+	}
+	return types.DefaultAnyType()
+}
+
+// parameters declares the given parameters in the given scope and checks that their default values
+// match their declared types
+func (k *checker) parameters(params []parser.Expression, s *scope) {
+	for _, pe := range params {
+		p := pe.(*parser.Parameter)
+		var t eval.Type = types.DefaultAnyType()
+		if p.Type() != nil {
+			if pt := k.resolveType(p.Type()); pt != nil {
+				t = pt
+			}
+		}
+		if p.Value() != nil {
+			if dt := k.infer(p.Value(), s); disjoint(t, dt) {
+				k.mismatch(fmt.Sprintf(`default value of parameter $%s`, p.Name()), t, dt, p.Value())
+			}
+		}
+		if p.CapturesRest() {
+			t = types.NewArrayType(t, nil)
+		}
+		s.vars[p.Name()] = t
+	}
+}
+
+// function checks the body of the given function or plan definition. The body can see the parameters
+// of the definition and the variables of the given global scope.
+func (k *checker) function(e *parser.FunctionDefinition, global *scope) {
+	fs := newScope(global)
+	k.parameters(e.Parameters(), fs)
+	k.returnType = nil
+	if e.ReturnType() != nil {
+		k.returnType = k.resolveType(e.ReturnType())
+	}
+	if bt := k.infer(e.Body(), fs); disjoint(k.returnType, bt) {
+		location := parser.Expression(e)
+		if b, ok := e.Body().(*parser.BlockExpression); ok && len(b.Statements()) > 0 {
+			location = b.Statements()[len(b.Statements())-1]
+		}
+		k.mismatch(fmt.Sprintf(`value returned from '%s'`, e.Name()), k.returnType, bt, location)
+	}
+	k.returnType = nil
+}
+
+// caseExpression checks that each option of the given case expression can be selected and returns
+// the common type of the values of the options
+func (k *checker) caseExpression(e *parser.CaseExpression, s *scope) eval.Type {
+	tt := k.infer(e.Test(), s)
+	var covering []eval.Type
+	var arms []parser.Expression
+	hasDefault := false
+	exhaustive := false
+	for _, oe := range e.Options() {
+		o := oe.(*parser.CaseOption)
+		for _, v := range o.Values() {
+			if _, ok := v.(*parser.LiteralDefault); ok {
+				hasDefault = true
+				if exhaustive {
+					k.warning(eval.EVAL_CHECK_UNREACHABLE_CASE_OPTION, issue.H{`option`: `default`,
+						`detail`: fmt.Sprintf(`the preceding options match every value of type %s`, tt)}, v)
+				}
+				continue
+			}
+			vt := k.infer(v, s)
+			switch v.(type) {
+			case *parser.LiteralList, *parser.LiteralHash:
+				// The elements of arrays and hashes are matched individually
+				continue
+			}
+			mt, isType := matchType(vt)
+			if caseDisjoint(mt, tt, isType) {
+				k.warning(eval.EVAL_CHECK_UNREACHABLE_CASE_OPTION, issue.H{`option`: optionLabel(v, vt),
+					`detail`: fmt.Sprintf(`it never matches a value of type %s`, tt)}, v)
+			} else {
+				for _, ct := range covering {
+					if eval.IsAssignable(ct, mt) {
+						k.warning(eval.EVAL_CHECK_UNREACHABLE_CASE_OPTION, issue.H{`option`: optionLabel(v, vt),
+							`detail`: fmt.Sprintf(`a preceding option matches every value of type %s`, ct)}, v)
+						break
+					}
+				}
+			}
+			if isType {
+				covering = append(covering, mt)
+				exhaustive = exhaustive || eval.IsAssignable(mt, tt)
+			}
+		}
+		arms = append(arms, o.Then())
+	}
+	if !hasDefault {
+		// No option is selected when no value matches
+		arms = append(arms, nil)
+	}
+	return k.alternatives(s, arms...)
+}
+
+// alternatives checks the given expressions of which at most one is evaluated, e.g. the arms of an
+// if expression, and returns the common type of their values. Each expression is checked in a scope
+// of its own. A variable that is assigned in one of them is then assigned to the given scope with
+// the common type of the values that it can have after the evaluation.
+func (k *checker) alternatives(s *scope, arms ...parser.Expression) eval.Type {
+	var result eval.Type
+	assigned := make([]map[string]eval.Type, len(arms))
+	names := make(map[string]bool)
+	for i, arm := range arms {
+		as := newScope(s)
+		result = union(result, k.infer(arm, as))
+		assigned[i] = as.vars
+		for name := range as.vars {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		var t eval.Type
+		for _, vars := range assigned {
+			if at, ok := vars[name]; ok {
+				t = union(t, at)
+			} else if pt, ok := s.get(name); ok {
+				t = union(t, pt)
+			} else {
+				t = union(t, types.DefaultUndefType())
+			}
+		}
+		s.vars[name] = t
+	}
+	return result
+}
+
+// matchType returns the type of the values that a case option with a value of the given type can
+// match and true if the value is a type. Strings and regexps are used as patterns and SemVer values
+// also match strings.
+func matchType(vt eval.Type) (eval.Type, bool) {
+	switch vt := vt.(type) {
+	case *types.TypeType:
+		return vt.ContainedType(), true
+	case *types.StringType, *types.RegexpType:
+		return types.DefaultStringType(), false
+	case *types.SemVerType, *types.SemVerRangeType:
+		return types.DefaultAnyType(), false
+	}
+	return vt, false
+}
+
+// caseDisjoint returns true if no value of the test type tt can match an option that matches values
+// of type mt
+func caseDisjoint(mt, tt eval.Type, isType bool) bool {
+	if !disjoint(mt, tt) {
+		return false
+	}
+	if !isType {
+		if nc := numericCounterpart(mt); nc != nil {
+			return disjoint(nc, tt)
+		}
+	}
+	return true
+}
+
+// optionLabel returns a label for the given case option value
+func optionLabel(v parser.Expression, vt eval.Type) string {
+	if tt, ok := vt.(*types.TypeType); ok {
+		return tt.ContainedType().String()
+	}
+	if ls, ok := v.(*parser.LiteralString); ok {
+		return fmt.Sprintf(`'%s'`, ls.StringValue())
+	}
+	return strings.TrimSpace(v.String())
+}
+
+// arguments returns the inferred types of the given arguments, or nil if the number of arguments
+// cannot be determined because an argument is unfolded
+func (k *checker) arguments(args []parser.Expression, s *scope) []eval.Type {
+	ts := make([]eval.Type, len(args))
+	unfolded := false
+	for i, a := range args {
+		ts[i] = k.infer(a, s)
+		if _, ok := a.(*parser.UnfoldExpression); ok {
+			unfolded = true
+		}
+	}
+	if unfolded {
+		return nil
+	}
+	return ts
+}
+
+func (k *checker) callNamedFunction(e *parser.CallNamedFunctionExpression, s *scope) eval.Type {
+	args := k.arguments(e.Arguments(), s)
+	k.infer(e.Lambda(), s)
+	fn := e.Functor()
+	if isTypeExpression(fn) {
+		// A call to a type creates a new instance of that type
+		if t := k.resolveType(fn); t != nil {
+			return t
+		}
+		return types.DefaultAnyType()
+	}
+	qn, ok := fn.(*parser.QualifiedName)
+	if !ok {
+		k.infer(fn, s)
+		return types.DefaultAnyType()
+	}
+	if qn.Name() == `return` {
+		k.checkReturn(e, args)
+		return types.DefaultAnyType()
+	}
+	return k.call(e, qn.Name(), args)
+}
+
+func (k *checker) callMethod(e *parser.CallMethodExpression, s *scope) eval.Type {
+	na, ok := e.Functor().(*parser.NamedAccessExpression)
+	if !ok {
+		k.visit(e, s)
+		return types.DefaultAnyType()
+	}
+	receiver := k.infer(na.Lhs(), s)
+	args := k.arguments(e.Arguments(), s)
+	k.infer(e.Lambda(), s)
+	qn, ok := na.Rhs().(*parser.QualifiedName)
+	if !ok || args == nil {
+		return types.DefaultAnyType()
+	}
+	return k.call(e, qn.Name(), append([]eval.Type{receiver}, args...))
+}
+
+// checkReturn checks that the argument of a call to return matches the declared return type of the
+// function that is checked
+func (k *checker) checkReturn(e *parser.CallNamedFunctionExpression, args []eval.Type) {
+	if k.returnType == nil || len(args) > 1 || args == nil {
+		return
+	}
+	location := parser.Expression(e)
+	var rt eval.Type = types.DefaultUndefType()
+	if len(args) == 1 {
+		rt = args[0]
+		location = e.Arguments()[0]
+	}
+	if disjoint(k.returnType, rt) {
+		k.mismatch(`returned value`, k.returnType, rt, location)
+	}
+}
+
+// call checks that a function with the given name accepts arguments of the given types and returns
+// the common return type of the dispatchers of that function that accept them
+func (k *checker) call(e parser.Expression, name string, args []eval.Type) eval.Type {
+	if args == nil {
+		return types.DefaultAnyType()
+	}
+	f := k.loadFunction(name)
+	if f == nil {
+		return types.DefaultAnyType()
+	}
+	ds := f.Dispatchers()
+	var rt eval.Type
+	matched := false
+	for _, d := range ds {
+		if accepts(d.Signature(), args) {
+			matched = true
+			rt = union(rt, returnType(d.Signature()))
+		}
+	}
+	if !matched && len(ds) > 0 {
+		sgs := make([]eval.Signature, len(ds))
+		for i, d := range ds {
+			sgs[i] = d.Signature()
+		}
+		k.warning(eval.EVAL_CHECK_ARGUMENTS_MISMATCH, issue.H{
+			`name`: name, `detail`: strings.TrimSpace(eval.DescribeSignatures(sgs, types.NewTupleType(args, nil), nil))}, e)
+	}
+	if rt == nil {
+		rt = types.DefaultAnyType()
+	}
+	return rt
+}
+
+// loadFunction returns the function with the given name, or nil if it cannot be loaded
+func (k *checker) loadFunction(name string) (f eval.Function) {
+	defer func() {
+		if recover() != nil {
+			f = nil
+		}
+	}()
+	if v, ok := eval.Load(k.c, eval.NewTypedName(eval.NsFunction, name)); ok {
+		f, _ = v.(eval.Function)
+	}
+	return
+}
+
+// accepts returns false when the given signature provably cannot accept arguments of the given types
+func accepts(sg eval.Signature, args []eval.Type) bool {
+	pt, ok := sg.ParametersType().(*types.TupleType)
+	if !ok {
+		return true
+	}
+	n := int64(len(args))
+	if sz := pt.Size(); n < sz.Min() || n > sz.Max() {
+		return false
+	}
+	ts := pt.Types()
+	if len(ts) == 0 {
+		return true
+	}
+	for i, a := range args {
+		if i >= len(ts) {
+			i = len(ts) - 1
+		}
+		if disjoint(ts[i], a) {
+			return false
+		}
+	}
+	return true
+}
+
+func returnType(sg eval.Signature) eval.Type {
+	if rt := sg.ReturnType(); rt != nil {
+		return rt
+	}
+	return types.DefaultAnyType()
+}
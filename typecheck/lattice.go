@@ -0,0 +1,122 @@
+package typecheck
+
+import (
+	"math"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+// disjoint returns true when no value can be an instance of both of the given types. The answer is
+// conservative in that false is returned whenever the lattice cannot prove that the types are disjoint.
+func disjoint(a, b eval.Type) bool {
+	if a == nil || b == nil || eval.IsAssignable(a, b) || eval.IsAssignable(b, a) {
+		return false
+	}
+	if m, ok := members(a); ok {
+		return allDisjoint(m, b)
+	}
+	if m, ok := members(b); ok {
+		return allDisjoint(m, a)
+	}
+
+	switch at := a.(type) {
+	case *types.TypeReferenceType:
+		return false
+	case *types.IntegerType:
+		if bt, ok := b.(*types.IntegerType); ok {
+			return at.Max() < bt.Min() || bt.Max() < at.Min()
+		}
+	case *types.FloatType:
+		if bt, ok := b.(*types.FloatType); ok {
+			return at.Max() < bt.Min() || bt.Max() < at.Min()
+		}
+	case *types.StringType:
+		if bt, ok := b.(*types.StringType); ok {
+			return disjoint(at.Size(), bt.Size())
+		}
+	}
+	if _, ok := b.(*types.TypeReferenceType); ok {
+		return false
+	}
+
+	ga := eval.Generalize(a)
+	gb := eval.Generalize(b)
+	return !(eval.IsAssignable(ga, gb) || eval.IsAssignable(gb, ga))
+}
+
+// members returns the types that make up the given Variant, Optional, or type alias
+func members(t eval.Type) ([]eval.Type, bool) {
+	switch t := t.(type) {
+	case *types.TypeAliasType:
+		return []eval.Type{t.ResolvedType()}, true
+	case *types.OptionalType:
+		return []eval.Type{types.DefaultUndefType(), t.ContainedType()}, true
+	case *types.VariantType:
+		return t.Types(), true
+	}
+	return nil, false
+}
+
+func allDisjoint(ts []eval.Type, t eval.Type) bool {
+	for _, m := range ts {
+		if !disjoint(m, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// numericCounterpart returns the Float type that corresponds to an Integer type and vice versa, or
+// nil when there is no such type. Case options are matched using equality and an Integer is equal
+// to a Float that has the same value.
+func numericCounterpart(t eval.Type) eval.Type {
+	switch t := t.(type) {
+	case *types.IntegerType:
+		return types.NewFloatType(float64(t.Min()), float64(t.Max()))
+	case *types.FloatType:
+		min, max := math.Ceil(t.Min()), math.Floor(t.Max())
+		if min > max {
+			return nil
+		}
+		return types.NewIntegerType(toInt(min), toInt(max))
+	}
+	return nil
+}
+
+// toInt converts the given integral float to an int64, clamped to the range of an int64
+func toInt(f float64) int64 {
+	switch {
+	case f <= math.MinInt64:
+		return math.MinInt64
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// union returns the common type of the given types where a nil type is ignored
+func union(a, b eval.Type) eval.Type {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return eval.CommonType(a, b)
+}
+
+// isInteger returns true if the given type is an Integer type
+func isInteger(t eval.Type) bool {
+	_, ok := t.(*types.IntegerType)
+	return ok
+}
+
+// isNumeric returns true if the given type is an Integer or a Float type
+func isNumeric(t eval.Type) bool {
+	switch t.(type) {
+	case *types.IntegerType, *types.FloatType:
+		return true
+	}
+	return false
+}
@@ -10,6 +10,7 @@ import (
 	"math"
 	"reflect"
 	"sort"
+	"sync"
 )
 
 type (
@@ -22,6 +23,13 @@ type (
 		reducedType  *ArrayType
 		detailedType eval.Type
 		elements     []eval.Value
+
+		// vector is the persistent representation of the elements of arrays that are created by
+		// Add and AddAll so that arrays that are built one element at the time share structure with
+		// the array they were derived from. The elements slice of such an array is materialized
+		// once, on demand.
+		vector    *persistentVector
+		sliceOnce sync.Once
 	}
 )
 
@@ -62,7 +70,7 @@ func init() {
 				case *BinaryValue:
 					return arg.(*BinaryValue).AsArray()
 				default:
-					return arg.(eval.IterableValue).Iterator().AsArray()
+					return eval.AsLimitedArray(c, arg.(eval.IterableValue).Iterator())
 				}
 			})
 		},
@@ -365,26 +373,20 @@ func WrapArray3(iv eval.List) *ArrayValue {
 }
 
 func (av *ArrayValue) Add(ov eval.Value) eval.List {
-	return WrapValues(append(av.elements, ov))
+	return &ArrayValue{vector: av.persistent().push(ov)}
 }
 
 func (av *ArrayValue) AddAll(ov eval.List) eval.List {
-	if ar, ok := ov.(*ArrayValue); ok {
-		return WrapValues(append(av.elements, ar.elements...))
-	}
-
-	aLen := len(av.elements)
-	sLen := aLen + ov.Len()
-	el := make([]eval.Value, sLen)
-	copy(el, av.elements)
-	for idx := aLen; idx < sLen; idx++ {
-		el[idx] = ov.At(idx - aLen)
+	if ov.Len() == 0 {
+		return av
 	}
-	return WrapValues(el)
+	v := av.persistent()
+	ov.Each(func(e eval.Value) { v = v.push(e) })
+	return &ArrayValue{vector: v}
 }
 
 func (av *ArrayValue) All(predicate eval.Predicate) bool {
-	for _, e := range av.elements {
+	for _, e := range av.slice() {
 		if !predicate(e) {
 			return false
 		}
@@ -393,7 +395,7 @@ func (av *ArrayValue) All(predicate eval.Predicate) bool {
 }
 
 func (av *ArrayValue) Any(predicate eval.Predicate) bool {
-	for _, e := range av.elements {
+	for _, e := range av.slice() {
 		if predicate(e) {
 			return true
 		}
@@ -402,11 +404,14 @@ func (av *ArrayValue) Any(predicate eval.Predicate) bool {
 }
 
 func (av *ArrayValue) AppendTo(slice []eval.Value) []eval.Value {
-	return append(slice, av.elements...)
+	return append(slice, av.slice()...)
 }
 
 func (av *ArrayValue) At(i int) eval.Value {
-	if i >= 0 && i < len(av.elements) {
+	if i >= 0 && i < av.Len() {
+		if av.vector != nil {
+			return av.vector.at(i)
+		}
 		return av.elements[i]
 	}
 	return _UNDEF
@@ -431,25 +436,26 @@ func (av *ArrayValue) DetailedType() eval.Type {
 }
 
 func (av *ArrayValue) Each(consumer eval.Consumer) {
-	for _, e := range av.elements {
+	for _, e := range av.slice() {
 		consumer(e)
 	}
 }
 
 func (av *ArrayValue) EachWithIndex(consumer eval.IndexedConsumer) {
-	for i, e := range av.elements {
+	for i, e := range av.slice() {
 		consumer(e, i)
 	}
 }
 
 func (av *ArrayValue) EachSlice(n int, consumer eval.SliceConsumer) {
-	top := len(av.elements)
+	elements := av.slice()
+	top := len(elements)
 	for i := 0; i < top; i += n {
 		e := i + n
 		if e > top {
 			e = top
 		}
-		consumer(WrapValues(av.elements[i:e]))
+		consumer(WrapValues(elements[i:e]))
 	}
 }
 
@@ -458,26 +464,27 @@ func (av *ArrayValue) ElementType() eval.Type {
 }
 
 func (av *ArrayValue) Equals(o interface{}, g eval.Guard) bool {
+	elements := av.slice()
 	if ov, ok := o.(*ArrayValue); ok {
-		if top := len(av.elements); top == len(ov.elements) {
-			for idx := 0; idx < top; idx++ {
-				if !av.elements[idx].Equals(ov.elements[idx], g) {
+		if others := ov.slice(); len(elements) == len(others) {
+			for idx, e := range elements {
+				if !e.Equals(others[idx], g) {
 					return false
 				}
 			}
 			return true
 		}
 	}
-	if len(av.elements) == 2 {
+	if len(elements) == 2 {
 		if he, ok := o.(*HashEntry); ok {
-			return av.elements[0].Equals(he.key, g) && av.elements[1].Equals(he.value, g)
+			return elements[0].Equals(he.key, g) && elements[1].Equals(he.value, g)
 		}
 	}
 	return false
 }
 
 func (av *ArrayValue) Find(predicate eval.Predicate) (eval.Value, bool) {
-	for _, e := range av.elements {
+	for _, e := range av.slice() {
 		if predicate(e) {
 			return e, true
 		}
@@ -486,10 +493,11 @@ func (av *ArrayValue) Find(predicate eval.Predicate) (eval.Value, bool) {
 }
 
 func (av *ArrayValue) Flatten() eval.List {
-	for _, e := range av.elements {
+	elements := av.slice()
+	for _, e := range elements {
 		switch e.(type) {
 		case *ArrayValue, *HashEntry:
-			return WrapValues(flattenElements(av.elements, make([]eval.Value, 0, len(av.elements)*2)))
+			return WrapValues(flattenElements(elements, make([]eval.Value, 0, len(elements)*2)))
 		}
 	}
 	return av
@@ -499,7 +507,7 @@ func flattenElements(elements, receiver []eval.Value) []eval.Value {
 	for _, e := range elements {
 		switch e.(type) {
 		case *ArrayValue:
-			receiver = flattenElements(e.(*ArrayValue).elements, receiver)
+			receiver = flattenElements(e.(*ArrayValue).slice(), receiver)
 		case *HashEntry:
 			he := e.(*HashEntry)
 			receiver = flattenElements([]eval.Value{he.key, he.value}, receiver)
@@ -511,7 +519,7 @@ func flattenElements(elements, receiver []eval.Value) []eval.Value {
 }
 
 func (av *ArrayValue) IsEmpty() bool {
-	return len(av.elements) == 0
+	return av.Len() == 0
 }
 
 func (av *ArrayValue) IsHashStyle() bool {
@@ -523,12 +531,16 @@ func (av *ArrayValue) Iterator() eval.Iterator {
 }
 
 func (av *ArrayValue) Len() int {
+	if av.vector != nil {
+		return av.vector.len()
+	}
 	return len(av.elements)
 }
 
 func (av *ArrayValue) Map(mapper eval.Mapper) eval.List {
-	mapped := make([]eval.Value, len(av.elements))
-	for i, e := range av.elements {
+	elements := av.slice()
+	mapped := make([]eval.Value, len(elements))
+	for i, e := range elements {
 		mapped[i] = mapper(e)
 	}
 	return WrapValues(mapped)
@@ -538,11 +550,11 @@ func (av *ArrayValue) Reduce(redactor eval.BiMapper) eval.Value {
 	if av.IsEmpty() {
 		return _UNDEF
 	}
-	return reduceSlice(av.elements[1:], av.At(0), redactor)
+	return reduceSlice(av.slice()[1:], av.At(0), redactor)
 }
 
 func (av *ArrayValue) Reduce2(initialValue eval.Value, redactor eval.BiMapper) eval.Value {
-	return reduceSlice(av.elements, initialValue, redactor)
+	return reduceSlice(av.slice(), initialValue, redactor)
 }
 
 func (av *ArrayValue) Reflect(c eval.Context) reflect.Value {
@@ -552,7 +564,7 @@ func (av *ArrayValue) Reflect(c eval.Context) reflect.Value {
 	}
 	s := reflect.MakeSlice(at, av.Len(), av.Len())
 	rf := c.Reflector()
-	for i, e := range av.elements {
+	for i, e := range av.slice() {
 		rf.ReflectTo(e, s.Index(i))
 	}
 	return s
@@ -566,7 +578,7 @@ func (av *ArrayValue) ReflectTo(c eval.Context, value reflect.Value) {
 	}
 	s := reflect.MakeSlice(vt, av.Len(), av.Len())
 	rf := c.Reflector()
-	for i, e := range av.elements {
+	for i, e := range av.slice() {
 		rf.ReflectTo(e, s.Index(i))
 	}
 	if ptr {
@@ -581,7 +593,7 @@ func (av *ArrayValue) ReflectTo(c eval.Context, value reflect.Value) {
 func (av *ArrayValue) Reject(predicate eval.Predicate) eval.List {
 	all := true
 	selected := make([]eval.Value, 0)
-	for _, e := range av.elements {
+	for _, e := range av.slice() {
 		if !predicate(e) {
 			selected = append(selected, e)
 		} else {
@@ -597,7 +609,7 @@ func (av *ArrayValue) Reject(predicate eval.Predicate) eval.List {
 func (av *ArrayValue) Select(predicate eval.Predicate) eval.List {
 	all := true
 	selected := make([]eval.Value, 0)
-	for _, e := range av.elements {
+	for _, e := range av.slice() {
 		if predicate(e) {
 			selected = append(selected, e)
 		} else {
@@ -611,7 +623,7 @@ func (av *ArrayValue) Select(predicate eval.Predicate) eval.List {
 }
 
 func (av *ArrayValue) Slice(i int, j int) eval.List {
-	return WrapValues(av.elements[i:j])
+	return WrapValues(av.slice()[i:j])
 }
 
 type arraySorter struct {
@@ -636,8 +648,9 @@ func (s *arraySorter) Swap(i, j int) {
 }
 
 func (av *ArrayValue) Sort(comparator eval.Comparator) eval.List {
-	s := &arraySorter{make([]eval.Value, len(av.elements)), comparator}
-	copy(s.values, av.elements)
+	elements := av.slice()
+	s := &arraySorter{make([]eval.Value, len(elements)), comparator}
+	copy(s.values, elements)
 	sort.Sort(s)
 	return WrapValues(s.values)
 }
@@ -651,6 +664,7 @@ func (av *ArrayValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect
 }
 
 func (av *ArrayValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format, delim byte, g eval.RDetect) {
+	elements := av.slice()
 	if g == nil {
 		g = make(eval.RDetect)
 	} else if g[av] {
@@ -682,7 +696,7 @@ func (av *ArrayValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format
 		b.Write(delims[:1])
 	}
 
-	top := len(av.elements)
+	top := len(elements)
 	if top > 0 {
 		mapped := make([]string, top)
 		arrayOrHash := make([]bool, top)
@@ -692,7 +706,7 @@ func (av *ArrayValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format
 		if cf == nil {
 			cf = DEFAULT_CONTAINER_FORMATS
 		}
-		for idx, v := range av.elements {
+		for idx, v := range elements {
 			arrayOrHash[idx] = isContainer(v, s)
 			mapped[idx] = childToString(v, childrenIndent.Subsequent(), s, cf, g)
 		}
@@ -743,21 +757,22 @@ func (av *ArrayValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format
 }
 
 func (av *ArrayValue) Unique() eval.List {
-	top := len(av.elements)
+	elements := av.slice()
+	top := len(elements)
 	if top < 2 {
 		return av
 	}
 
 	result := make([]eval.Value, 0, top)
 	exists := make(map[eval.HashKey]bool, top)
-	for _, v := range av.elements {
+	for _, v := range elements {
 		key := eval.ToKey(v)
 		if !exists[key] {
 			exists[key] = true
 			result = append(result, v)
 		}
 	}
-	if len(result) == len(av.elements) {
+	if len(result) == len(elements) {
 		return av
 	}
 	return WrapValues(result)
@@ -797,15 +812,16 @@ func (av *ArrayValue) PType() eval.Type {
 
 func (av *ArrayValue) prtvDetailedType() eval.Type {
 	if av.detailedType == nil {
-		if len(av.elements) == 0 {
+		elements := av.slice()
+		if len(elements) == 0 {
 			av.detailedType = av.prtvReducedType()
 		} else {
-			types := make([]eval.Type, len(av.elements))
+			types := make([]eval.Type, len(elements))
 			av.detailedType = NewTupleType(types, nil)
 			for idx, _ := range types {
 				types[idx] = DefaultAnyType()
 			}
-			for idx, element := range av.elements {
+			for idx, element := range elements {
 				types[idx] = eval.DetailedValueType(element)
 			}
 		}
@@ -815,14 +831,15 @@ func (av *ArrayValue) prtvDetailedType() eval.Type {
 
 func (av *ArrayValue) prtvReducedType() *ArrayType {
 	if av.reducedType == nil {
-		top := len(av.elements)
+		elements := av.slice()
+		top := len(elements)
 		if top == 0 {
 			av.reducedType = EmptyArrayType()
 		} else {
 			av.reducedType = NewArrayType(DefaultAnyType(), NewIntegerType(int64(top), int64(top)))
-			elemType := av.elements[0].PType()
+			elemType := elements[0].PType()
 			for idx := 1; idx < top; idx++ {
-				elemType = commonType(elemType, av.elements[idx].PType())
+				elemType = commonType(elemType, elements[idx].PType())
 			}
 			av.reducedType.typ = elemType
 		}
@@ -837,3 +854,22 @@ func reduceSlice(slice []eval.Value, initialValue eval.Value, redactor eval.BiMa
 	}
 	return memo
 }
+
+// persistent returns the persistent representation of the elements of the receiver
+func (av *ArrayValue) persistent() *persistentVector {
+	if av.vector != nil {
+		return av.vector
+	}
+	return newVector(av.elements)
+}
+
+// slice returns the elements of the receiver as a slice. The slice is materialized from the
+// persistent representation when needed and must not be modified.
+func (av *ArrayValue) slice() []eval.Value {
+	if av.vector != nil {
+		av.sliceOnce.Do(func() {
+			av.elements = av.vector.appendTo(make([]eval.Value, 0, av.vector.len()))
+		})
+	}
+	return av.elements
+}
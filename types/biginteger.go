@@ -0,0 +1,128 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+// BigIntegerValue is an arbitrary-precision Integer. It is only used for values that cannot
+// be represented by an int64 and is produced by integer arithmetic when integer promotion is
+// enabled. Its type is the unbounded Integer type.
+type BigIntegerValue struct {
+	value *big.Int
+}
+
+// WrapBigInteger returns an IntegerValue when the given value fits in an int64 and a
+// BigIntegerValue otherwise. The given value must not be modified after this call.
+func WrapBigInteger(value *big.Int) eval.NumericValue {
+	if value.IsInt64() {
+		return WrapInteger(value.Int64())
+	}
+	return &BigIntegerValue{value}
+}
+
+// ToBigInt returns the given value as a *big.Int when it is an IntegerValue or a BigIntegerValue
+func ToBigInt(v eval.Value) (*big.Int, bool) {
+	switch v.(type) {
+	case *IntegerValue:
+		return big.NewInt(v.(*IntegerValue).Int()), true
+	case *BigIntegerValue:
+		return v.(*BigIntegerValue).value, true
+	default:
+		return nil, false
+	}
+}
+
+func (bv *BigIntegerValue) Abs() eval.NumericValue {
+	if bv.Sign() < 0 {
+		return WrapBigInteger(big.NewInt(0).Neg(bv.value))
+	}
+	return bv
+}
+
+// BigInt returns the value of the receiver. The returned value must not be modified.
+func (bv *BigIntegerValue) BigInt() *big.Int {
+	return bv.value
+}
+
+func (bv *BigIntegerValue) Equals(o interface{}, g eval.Guard) bool {
+	if ov, ok := o.(*BigIntegerValue); ok {
+		return bv.value.Cmp(ov.value) == 0
+	}
+	return false
+}
+
+func (bv *BigIntegerValue) Float() float64 {
+	f, _ := big.NewFloat(0).SetInt(bv.value).Float64()
+	return f
+}
+
+// Int returns math.MinInt64 or math.MaxInt64 depending on the sign of the receiver since its value
+// is always outside of the int64 range
+func (bv *BigIntegerValue) Int() int64 {
+	if bv.Sign() < 0 {
+		return math.MinInt64
+	}
+	return math.MaxInt64
+}
+
+func (bv *BigIntegerValue) PType() eval.Type {
+	return DefaultIntegerType()
+}
+
+func (bv *BigIntegerValue) Reflect(c eval.Context) reflect.Value {
+	return reflect.ValueOf(bv.value)
+}
+
+func (bv *BigIntegerValue) ReflectTo(c eval.Context, value reflect.Value) {
+	if !value.CanSet() {
+		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_UNSETTABLE, issue.H{`kind`: reflect.Ptr.String()}))
+	}
+	rv := reflect.ValueOf(big.NewInt(0).Set(bv.value))
+	switch {
+	case value.Kind() == reflect.Interface, value.Type() == rv.Type():
+		value.Set(rv)
+	case value.Type() == rv.Type().Elem():
+		value.Set(rv.Elem())
+	default:
+		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rv.Type().String(), `actual`: value.Kind().String()}))
+	}
+}
+
+// Sign returns -1 or 1 depending on the sign of the receiver
+func (bv *BigIntegerValue) Sign() int {
+	return bv.value.Sign()
+}
+
+func (bv *BigIntegerValue) String() string {
+	return bv.value.String()
+}
+
+func (bv *BigIntegerValue) ToKey(b *bytes.Buffer) {
+	b.WriteByte(1)
+	b.WriteByte(HK_BIG_INTEGER)
+	b.WriteString(bv.value.Text(62))
+}
+
+func (bv *BigIntegerValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect) {
+	f := eval.GetFormat(s.FormatMap(), bv.PType())
+	switch f.FormatChar() {
+	case 'x', 'X', 'o', 'd':
+		fmt.Fprintf(b, f.OrigFormat(), bv.value)
+	case 'p', 'b', 'B':
+		writeIntegerString(b, f, bv.value.Text(integerRadix(f.FormatChar())), false)
+	case 'e', 'E', 'f', 'g', 'G', 'a', 'A':
+		WrapFloat(bv.Float()).ToString(b, eval.NewFormatContext(DefaultFloatType(), f, s.Indentation()), g)
+	case 's':
+		f.ApplyStringFlags(b, bv.value.String(), f.IsAlt())
+	default:
+		panic(s.UnsupportedFormat(bv.PType(), `dxXobBeEfgGaAsp`, f))
+	}
+}
@@ -152,8 +152,10 @@ func BinaryFromFile(c eval.Context, path string) *BinaryValue {
 // found or not.
 //
 // The function will only return false if the given file does not exist. It will panic
-// with an issue.Reported on all other errors.
+// with an issue.Reported on all other errors, including when the sandbox policy of the
+// given context does not permit access to the path.
 func BinaryFromFile2(c eval.Context, path string) (*BinaryValue, bool) {
+	eval.AssertPathAllowed(c, path)
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		stat, serr := os.Stat(path)
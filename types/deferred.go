@@ -7,6 +7,7 @@ import (
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-parser/parser"
 	"io"
+	"reflect"
 )
 
 var deferredType eval.ObjectType
@@ -115,6 +116,12 @@ func (e *deferred) InitHash() eval.OrderedMap {
 }
 
 func (e *deferred) Resolve(c eval.Context) eval.Value {
+	return e.resolve(c, func(a eval.Value) eval.Value { return ResolveDeferred(c, a) })
+}
+
+// resolve calls the function of the receiver, or looks up its variable, using arguments that
+// are resolved by the given function
+func (e *deferred) resolve(c eval.Context, resolveArg func(eval.Value) eval.Value) eval.Value {
 	fn := e.name
 
 	var args []eval.Value
@@ -122,20 +129,20 @@ func (e *deferred) Resolve(c eval.Context) eval.Value {
 		vn := fn[1:]
 		vv, ok := c.Scope().Get(vn)
 		if !ok {
-			panic(eval.Error(eval.EVAL_UNKNOWN_VARIABLE, issue.H{`name`: vn}))
+			panic(c.Error(nil, eval.EVAL_UNKNOWN_VARIABLE, issue.H{`name`: vn}))
 		}
 		if e.arguments.Len() == 0 {
 			// No point digging with zero arguments
 			return vv
 		}
 		fn = `dig`
-		args = append(make([]eval.Value, 0, 1 + e.arguments.Len()), vv)
+		args = append(make([]eval.Value, 0, 1+e.arguments.Len()), vv)
 	} else {
 		args = make([]eval.Value, 0, e.arguments.Len())
 	}
 	args = e.arguments.AppendTo(args)
 	for i, a := range args {
-		args[i] = ResolveDeferred(c, a)
+		args[i] = resolveArg(a)
 	}
 	return eval.Call(c, fn, args, nil)
 }
@@ -143,19 +150,120 @@ func (e *deferred) Resolve(c eval.Context) eval.Value {
 // ResolveDeferred will resolve all occurences of a DeferredValue in its
 // given argument. Array and Hash arguments will be resolved recursively.
 func ResolveDeferred(c eval.Context, a eval.Value) eval.Value {
-	switch a.(type) {
-	case Deferred:
-		a = a.(Deferred).Resolve(c)
+	return NewDeferredResolver(nil).Resolve(c, a)
+}
+
+// DeferredResolver resolves all Deferred values found in a tree of values
+type DeferredResolver struct {
+	allowed map[string]bool
+}
+
+// NewDeferredResolver creates a resolver that resolves the Deferred values that call the given
+// functions. All Deferred values are resolved when allowedFunctions is nil. References to
+// variables are always resolved. A DeferredExpression is only resolved when allowedFunctions
+// is nil.
+func NewDeferredResolver(allowedFunctions []string) *DeferredResolver {
+	r := &DeferredResolver{}
+	if allowedFunctions != nil {
+		r.allowed = make(map[string]bool, len(allowedFunctions))
+		for _, f := range allowedFunctions {
+			r.allowed[f] = true
+		}
+	}
+	return r
+}
+
+// Resolve returns a copy of the given value where each Deferred value that the receiver is allowed
+// to resolve has been replaced by its resolved value. Arrays, Hashes, the attributes of Objects, and
+// the contents of Sensitive values are resolved recursively. A value that is the result of a
+// resolution is also resolved. Containers that contain nothing to resolve are retained as is.
+//
+// This function panics with an issue.Reported when a container is found to contain itself or when
+// a Deferred value resolves to a value that contains that Deferred value.
+func (r *DeferredResolver) Resolve(c eval.Context, value eval.Value) eval.Value {
+	return r.resolve(c, value, make(map[interface{}]bool))
+}
+
+// resolve resolves the given value. The path contains the containers that are currently being
+// resolved. A Deferred value is represented by its string form in the path so that a Deferred
+// that resolves to an equal Deferred is detected.
+func (r *DeferredResolver) resolve(c eval.Context, value eval.Value, path map[interface{}]bool) eval.Value {
+	switch value.(type) {
+	case *deferred, *deferredExpr, *ArrayValue, *HashValue, *SensitiveValue, eval.PuppetObject:
+		if _, ok := value.(eval.Type); ok {
+			return value
+		}
+		var key interface{} = value
+		if d, ok := value.(*deferred); ok {
+			key = d.String()
+		}
+		if reflect.ValueOf(value).Kind() == reflect.Ptr {
+			if path[key] {
+				panic(c.Error(nil, eval.EVAL_DEFERRED_RECURSION, issue.H{`type_name`: value.PType().Name()}))
+			}
+			path[key] = true
+			defer delete(path, key)
+		}
+	default:
+		return value
+	}
+
+	resolveElement := func(e eval.Value) eval.Value { return r.resolve(c, e, path) }
+	switch value.(type) {
+	case *deferred:
+		d := value.(*deferred)
+		if r.allowed == nil || d.name[0] == '$' || r.allowed[d.name] {
+			return r.resolve(c, d.resolve(c, resolveElement), path)
+		}
+		return value
+	case *deferredExpr:
+		if r.allowed == nil {
+			return r.resolve(c, value.(*deferredExpr).Resolve(c), path)
+		}
+		return value
 	case *ArrayValue:
-		a = a.(*ArrayValue).Map(func(v eval.Value) eval.Value {
-			return ResolveDeferred(c, v)
+		av := value.(*ArrayValue)
+		changed := false
+		elements := make([]eval.Value, av.Len())
+		av.EachWithIndex(func(e eval.Value, i int) {
+			elements[i] = resolveElement(e)
+			changed = changed || elements[i] != e
 		})
+		if changed {
+			return WrapValues(elements)
+		}
+		return value
 	case *HashValue:
-		a = a.(*HashValue).MapEntries(func(v eval.MapEntry) eval.MapEntry {
-			return WrapHashEntry(ResolveDeferred(c, v.Key()), ResolveDeferred(c, v.Value()))
+		hv := value.(*HashValue)
+		changed := false
+		entries := make([]*HashEntry, 0, hv.Len())
+		hv.EachPair(func(k, v eval.Value) {
+			rk := resolveElement(k)
+			rv := resolveElement(v)
+			changed = changed || rk != k || rv != v
+			entries = append(entries, WrapHashEntry(rk, rv))
 		})
+		if changed {
+			return WrapHash(entries)
+		}
+		return value
+	case *SensitiveValue:
+		sv := value.(*SensitiveValue)
+		if rv := resolveElement(sv.Unwrap()); rv != sv.Unwrap() {
+			return WrapSensitive(rv)
+		}
+		return value
+	default:
+		o := value.(eval.PuppetObject)
+		ih := o.InitHash()
+		if rh := resolveElement(ih); rh != ih {
+			if ot, ok := o.PType().(eval.ObjectType); ok {
+				return NewObjectValue2(c, ot, rh.(*HashValue))
+			}
+			return eval.New(c, o.PType(), rh)
+		}
+		return value
 	}
-	return a
 }
 
 func NewDeferredExpression(expression parser.Expression) Deferred {
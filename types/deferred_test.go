@@ -0,0 +1,58 @@
+package types_test
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func ExampleDeferredResolver_Resolve() {
+	eval.Puppet.Try(func(c eval.Context) error {
+		c.AddDefinitions(c.ParseAndValidate(``, `type Holder = Object[attributes => { value => Any }]`, false))
+		c.ResolveDefinitions()
+		v, _ := eval.TopEvaluate(c, c.ParseAndValidate(``, `{
+			'plain' => 'x',
+			'list' => [Deferred('sprintf', ['%d', Deferred('sprintf', ['%s', 1])])],
+			'secret' => Sensitive(Deferred('sprintf', ['%s', 'pw'])),
+			'holder' => Holder(Deferred('sprintf', ['%s', 'h1']))
+		}`, false))
+
+		r := types.NewDeferredResolver(nil).Resolve(c, v).(eval.OrderedMap)
+		fmt.Println(r.Get5(`plain`, eval.UNDEF))
+		fmt.Println(r.Get5(`list`, eval.UNDEF))
+		fmt.Println(r.Get5(`secret`, eval.UNDEF).(*types.SensitiveValue).Unwrap())
+		fmt.Println(r.Get5(`holder`, eval.UNDEF))
+		return nil
+	})
+	// Output:
+	// x
+	// ['1']
+	// pw
+	// Holder('value' => 'h1')
+}
+
+func ExampleNewDeferredResolver() {
+	eval.Puppet.Try(func(c eval.Context) error {
+		v, _ := eval.TopEvaluate(c, c.ParseAndValidate(``, `[Deferred('sprintf', ['%s', 'a']), Deferred('split', ['a,b', ','])]`, false))
+		fmt.Println(types.NewDeferredResolver([]string{`sprintf`}).Resolve(c, v))
+		return nil
+	})
+	// Output: ['a', Deferred('name' => 'split', 'arguments' => ['a,b', ','])]
+}
+
+func ExampleDeferredResolver_Resolve_recursion() {
+	eval.Puppet.Try(func(c eval.Context) error {
+		c.AddDefinitions(c.ParseAndValidate(``, `function loop() { Deferred('loop') }`, false))
+		c.ResolveDefinitions()
+		defer func() {
+			if re, ok := recover().(issue.Reported); ok {
+				fmt.Println(re.Code())
+			}
+		}()
+		types.NewDeferredResolver(nil).Resolve(c, types.NewDeferred(`loop`))
+		return nil
+	})
+	// Output: EVAL_DEFERRED_RECURSION
+}
@@ -81,13 +81,13 @@ func (f *function) CallGoReflected(c eval.Context, args []reflect.Value) []refle
 	rt := args[0].Type()
 	m, ok := rt.MethodByName(f.goName)
 	if !ok {
-		panic(eval.Error(eval.EVAL_INSTANCE_DOES_NOT_RESPOND, issue.H{`type`: rt.String(), `message`: f.goName}))
+		panic(c.Error(nil, eval.EVAL_INSTANCE_DOES_NOT_RESPOND, issue.H{`type`: rt.String(), `message`: f.goName}))
 	}
 
 	mt := m.Type
 	pc := mt.NumIn()
-	if pc != len(args) {
-		panic(eval.Error(eval.EVAL_TYPE_MISMATCH, issue.H{`detail`: eval.DescribeSignatures(
+	if !mt.IsVariadic() && pc != len(args) || mt.IsVariadic() && len(args) < pc-1 {
+		panic(c.Error(nil, eval.EVAL_TYPE_MISMATCH, issue.H{`detail`: eval.DescribeSignatures(
 			[]eval.Signature{f.CallableType().(*CallableType)}, NewTupleType([]eval.Type{}, NewIntegerType(int64(pc-1), int64(pc-1))), nil)}))
 	}
 	result := m.Func.Call(args)
@@ -100,7 +100,7 @@ func (f *function) CallGoReflected(c eval.Context, args []reflect.Value) []refle
 			if re, ok := err.(issue.Reported); ok {
 				panic(re)
 			}
-			panic(eval.Error(eval.EVAL_GO_FUNCTION_ERROR, issue.H{`name`: f.goName, `error`: err}))
+			panic(c.Error(nil, eval.EVAL_GO_FUNCTION_ERROR, issue.H{`name`: f.goName, `error`: err}))
 		}
 		result = result[:oc]
 	}
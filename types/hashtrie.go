@@ -0,0 +1,126 @@
+package types
+
+import (
+	"math/bits"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+const (
+	trieBits = 5
+	trieMask = 1<<trieBits - 1
+)
+
+type (
+	// hashTrieNode is an immutable hash array mapped trie (HAMT) that maps a HashKey to an int.
+	// Nodes at a depth where all bits of the hash have been consumed are collision nodes. Their
+	// entries are searched linearly and their bitmap is unused.
+	hashTrieNode struct {
+		bitmap  uint32
+		entries []hashTrieEntry
+	}
+
+	// hashTrieEntry is either a key/value pair or, when node is non nil, a sub trie
+	hashTrieEntry struct {
+		key   eval.HashKey
+		value int
+		node  *hashTrieNode
+	}
+)
+
+var emptyHashTrie = &hashTrieNode{}
+
+// newHashTrie creates a trie that maps the key of each entry to the index of that entry
+func newHashTrie(entries []*HashEntry) *hashTrieNode {
+	t := emptyHashTrie
+	for i, e := range entries {
+		t = t.assoc(eval.ToKey(e.key), i)
+	}
+	return t
+}
+
+func hashKeyHash(key eval.HashKey) uint32 {
+	// 32 bit FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// assoc returns a trie where the given key is mapped to the given value
+func (n *hashTrieNode) assoc(key eval.HashKey, value int) *hashTrieNode {
+	return n.put(0, hashKeyHash(key), key, value)
+}
+
+// lookup returns the value that the given key is mapped to
+func (n *hashTrieNode) lookup(key eval.HashKey) (int, bool) {
+	hash := hashKeyHash(key)
+	for shift := uint(0); ; shift += trieBits {
+		if shift >= 32 {
+			for _, e := range n.entries {
+				if e.key == key {
+					return e.value, true
+				}
+			}
+			return 0, false
+		}
+		bit := uint32(1) << ((hash >> shift) & trieMask)
+		if n.bitmap&bit == 0 {
+			return 0, false
+		}
+		e := &n.entries[bits.OnesCount32(n.bitmap&(bit-1))]
+		if e.node == nil {
+			if e.key == key {
+				return e.value, true
+			}
+			return 0, false
+		}
+		n = e.node
+	}
+}
+
+func (n *hashTrieNode) put(shift uint, hash uint32, key eval.HashKey, value int) *hashTrieNode {
+	if shift >= 32 {
+		for i, e := range n.entries {
+			if e.key == key {
+				return n.with(i, hashTrieEntry{key: key, value: value})
+			}
+		}
+		entries := make([]hashTrieEntry, len(n.entries)+1)
+		copy(entries, n.entries)
+		entries[len(n.entries)] = hashTrieEntry{key: key, value: value}
+		return &hashTrieNode{entries: entries}
+	}
+
+	bit := uint32(1) << ((hash >> shift) & trieMask)
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+	if n.bitmap&bit == 0 {
+		entries := make([]hashTrieEntry, len(n.entries)+1)
+		copy(entries, n.entries[:idx])
+		entries[idx] = hashTrieEntry{key: key, value: value}
+		copy(entries[idx+1:], n.entries[idx:])
+		return &hashTrieNode{n.bitmap | bit, entries}
+	}
+
+	e := n.entries[idx]
+	switch {
+	case e.node != nil:
+		e.node = e.node.put(shift+trieBits, hash, key, value)
+	case e.key == key:
+		e.value = value
+	default:
+		// Two different keys share the bits consumed so far. Push both down into a sub trie
+		sub := emptyHashTrie.put(shift+trieBits, hashKeyHash(e.key), e.key, e.value)
+		e = hashTrieEntry{node: sub.put(shift+trieBits, hash, key, value)}
+	}
+	return n.with(idx, e)
+}
+
+func (n *hashTrieNode) with(idx int, e hashTrieEntry) *hashTrieNode {
+	entries := make([]hashTrieEntry, len(n.entries))
+	copy(entries, n.entries)
+	entries[idx] = e
+	return &hashTrieNode{n.bitmap, entries}
+}
@@ -9,6 +9,7 @@ import (
 	"math"
 	"reflect"
 	"sort"
+	"sync"
 )
 
 type (
@@ -28,6 +29,15 @@ type (
 		detailedType eval.Type
 		entries      []*HashEntry
 		index        map[eval.HashKey]int
+
+		// vector and trie are the persistent representation of the entries and the index of hashes
+		// that are created by Merge so that hashes that are built one entry at the time share
+		// structure with the hash they were derived from. The entries slice of such a hash is
+		// materialized once, on demand. The index is only used by hashes without a trie and is
+		// likewise created once, on demand.
+		vector    *persistentVector
+		trie      *hashTrieNode
+		sliceOnce sync.Once
 	}
 
 	MutableHashValue struct {
@@ -291,7 +301,7 @@ func (t *HashType) IsAssignable(o eval.Type, g eval.Guard) bool {
 
 func (t *HashType) IsInstance(o eval.Value, g eval.Guard) bool {
 	if v, ok := o.(*HashValue); ok && t.size.IsInstance3(v.Len()) {
-		for _, entry := range v.entries {
+		for _, entry := range v.slice() {
 			if !(GuardedIsInstance(t.keyType, entry.key, g) && GuardedIsInstance(t.valueType, entry.value, g)) {
 				return false
 			}
@@ -714,7 +724,7 @@ func (hv *HashValue) AddAll(v eval.List) eval.List {
 }
 
 func (hv *HashValue) All(predicate eval.Predicate) bool {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if !predicate(e) {
 			return false
 		}
@@ -723,7 +733,7 @@ func (hv *HashValue) All(predicate eval.Predicate) bool {
 }
 
 func (hv *HashValue) AllPairs(predicate eval.BiPredicate) bool {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if !predicate(e.key, e.value) {
 			return false
 		}
@@ -732,7 +742,7 @@ func (hv *HashValue) AllPairs(predicate eval.BiPredicate) bool {
 }
 
 func (hv *HashValue) AllKeysAreStrings() bool {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if _, ok := e.key.(*StringValue); !ok {
 			return false
 		}
@@ -741,7 +751,7 @@ func (hv *HashValue) AllKeysAreStrings() bool {
 }
 
 func (hv *HashValue) Any(predicate eval.Predicate) bool {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if predicate(e) {
 			return true
 		}
@@ -750,7 +760,7 @@ func (hv *HashValue) Any(predicate eval.Predicate) bool {
 }
 
 func (hv *HashValue) AnyPair(predicate eval.BiPredicate) bool {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if predicate(e.key, e.value) {
 			return true
 		}
@@ -759,49 +769,55 @@ func (hv *HashValue) AnyPair(predicate eval.BiPredicate) bool {
 }
 
 func (hv *HashValue) AppendEntriesTo(entries []*HashEntry) []*HashEntry {
-	return append(entries, hv.entries...)
+	return append(entries, hv.slice()...)
 }
 
 func (hv *HashValue) AppendTo(slice []eval.Value) []eval.Value {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		slice = append(slice, e)
 	}
 	return slice
 }
 
 func (hv *HashValue) AsArray() eval.List {
-	values := make([]eval.Value, len(hv.entries))
-	for idx, entry := range hv.entries {
+	entries := hv.slice()
+	values := make([]eval.Value, len(entries))
+	for idx, entry := range entries {
 		values[idx] = WrapValues([]eval.Value{entry.key, entry.value})
 	}
 	return WrapValues(values)
 }
 
 func (hv *HashValue) At(i int) eval.Value {
-	if i >= 0 && i < len(hv.entries) {
+	if i >= 0 && i < hv.Len() {
+		if hv.vector != nil {
+			return hv.vector.at(i)
+		}
 		return hv.entries[i]
 	}
 	return _UNDEF
 }
 
 func (hv *HashValue) Delete(key eval.Value) eval.List {
-	if idx, ok := hv.valueIndex()[eval.ToKey(key)]; ok {
-		return WrapHash(append(hv.entries[:idx], hv.entries[idx+1:]...))
-	}
-	return hv
+	return hv.DeleteAll(SingletonArray(key))
 }
 
 func (hv *HashValue) DeleteAll(keys eval.List) eval.List {
-	entries := hv.entries
-	valueIndex := hv.valueIndex()
+	deleted := make(map[int]bool, keys.Len())
 	keys.Each(func(key eval.Value) {
-		if idx, ok := valueIndex[eval.ToKey(key)]; ok {
-			entries = append(hv.entries[:idx], hv.entries[idx+1:]...)
+		if idx, ok := hv.indexOf(eval.ToKey(key)); ok {
+			deleted[idx] = true
 		}
 	})
-	if len(hv.entries) == len(entries) {
+	if len(deleted) == 0 {
 		return hv
 	}
+	entries := make([]*HashEntry, 0, hv.Len()-len(deleted))
+	for idx, e := range hv.slice() {
+		if !deleted[idx] {
+			entries = append(entries, e)
+		}
+	}
 	return WrapHash(entries)
 }
 
@@ -818,30 +834,31 @@ func (hv *HashValue) Entries() eval.List {
 }
 
 func (hv *HashValue) Each(consumer eval.Consumer) {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		consumer(e)
 	}
 }
 
 func (hv *HashValue) EachSlice(n int, consumer eval.SliceConsumer) {
-	top := len(hv.entries)
+	entries := hv.slice()
+	top := len(entries)
 	for i := 0; i < top; i += n {
 		e := i + n
 		if e > top {
 			e = top
 		}
-		consumer(WrapValues(ValueSlice(hv.entries[i:e])))
+		consumer(WrapValues(ValueSlice(entries[i:e])))
 	}
 }
 
 func (hv *HashValue) EachKey(consumer eval.Consumer) {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		consumer(e.key)
 	}
 }
 
 func (hv *HashValue) Find(predicate eval.Predicate) (eval.Value, bool) {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if predicate(e) {
 			return e, true
 		}
@@ -850,32 +867,36 @@ func (hv *HashValue) Find(predicate eval.Predicate) (eval.Value, bool) {
 }
 
 func (hv *HashValue) Flatten() eval.List {
-	els := make([]eval.Value, 0, len(hv.entries)*2)
-	for _, he := range hv.entries {
+	entries := hv.slice()
+	els := make([]eval.Value, 0, len(entries)*2)
+	for _, he := range entries {
 		els = append(els, he.key, he.value)
 	}
 	return WrapValues(els).Flatten()
 }
 
 func (hv *HashValue) Map(mapper eval.Mapper) eval.List {
-	mapped := make([]eval.Value, len(hv.entries))
-	for i, e := range hv.entries {
+	entries := hv.slice()
+	mapped := make([]eval.Value, len(entries))
+	for i, e := range entries {
 		mapped[i] = mapper(e)
 	}
 	return WrapValues(mapped)
 }
 
 func (hv *HashValue) MapEntries(mapper eval.EntryMapper) eval.OrderedMap {
-	mapped := make([]*HashEntry, len(hv.entries))
-	for i, e := range hv.entries {
+	entries := hv.slice()
+	mapped := make([]*HashEntry, len(entries))
+	for i, e := range entries {
 		mapped[i] = mapper(e).(*HashEntry)
 	}
 	return WrapHash(mapped)
 }
 
 func (hv *HashValue) MapValues(mapper eval.Mapper) eval.OrderedMap {
-	mapped := make([]*HashEntry, len(hv.entries))
-	for i, e := range hv.entries {
+	entries := hv.slice()
+	mapped := make([]*HashEntry, len(entries))
+	for i, e := range entries {
 		mapped[i] = WrapHashEntry(e.key, mapper(e.value))
 	}
 	return WrapHash(mapped)
@@ -883,7 +904,7 @@ func (hv *HashValue) MapValues(mapper eval.Mapper) eval.OrderedMap {
 
 func (hv *HashValue) Select(predicate eval.Predicate) eval.List {
 	selected := make([]*HashEntry, 0)
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if predicate(e) {
 			selected = append(selected, e)
 		}
@@ -893,7 +914,7 @@ func (hv *HashValue) Select(predicate eval.Predicate) eval.List {
 
 func (hv *HashValue) SelectPairs(predicate eval.BiPredicate) eval.OrderedMap {
 	selected := make([]*HashEntry, 0)
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if predicate(e.key, e.value) {
 			selected = append(selected, e)
 		}
@@ -911,7 +932,7 @@ func (hv *HashValue) Reflect(c eval.Context) reflect.Value {
 	valueType := ht.Elem()
 	m := reflect.MakeMapWithSize(ht, hv.Len())
 	rf := c.Reflector()
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		m.SetMapIndex(rf.Reflect2(e.key, keyType), rf.Reflect2(e.value, valueType))
 	}
 	return m
@@ -933,7 +954,7 @@ func (hv *HashValue) ReflectTo(c eval.Context, value reflect.Value) {
 	valueType := ht.Elem()
 	m := reflect.MakeMapWithSize(ht, hv.Len())
 	rf := c.Reflector()
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		m.SetMapIndex(rf.Reflect2(e.key, keyType), rf.Reflect2(e.value, valueType))
 	}
 	if ptr {
@@ -949,16 +970,16 @@ func (hv *HashValue) Reduce(redactor eval.BiMapper) eval.Value {
 	if hv.IsEmpty() {
 		return _UNDEF
 	}
-	return reduceEntries(hv.entries[1:], hv.At(0), redactor)
+	return reduceEntries(hv.slice()[1:], hv.At(0), redactor)
 }
 
 func (hv *HashValue) Reduce2(initialValue eval.Value, redactor eval.BiMapper) eval.Value {
-	return reduceEntries(hv.entries, initialValue, redactor)
+	return reduceEntries(hv.slice(), initialValue, redactor)
 }
 
 func (hv *HashValue) Reject(predicate eval.Predicate) eval.List {
 	selected := make([]*HashEntry, 0)
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if !predicate(e) {
 			selected = append(selected, e)
 		}
@@ -968,7 +989,7 @@ func (hv *HashValue) Reject(predicate eval.Predicate) eval.List {
 
 func (hv *HashValue) RejectPairs(predicate eval.BiPredicate) eval.OrderedMap {
 	selected := make([]*HashEntry, 0)
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		if !predicate(e.key, e.value) {
 			selected = append(selected, e)
 		}
@@ -977,30 +998,29 @@ func (hv *HashValue) RejectPairs(predicate eval.BiPredicate) eval.OrderedMap {
 }
 
 func (hv *HashValue) EachPair(consumer eval.BiConsumer) {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		consumer(e.key, e.value)
 	}
 }
 
 func (hv *HashValue) EachValue(consumer eval.Consumer) {
-	for _, e := range hv.entries {
+	for _, e := range hv.slice() {
 		consumer(e.value)
 	}
 }
 
 func (hv *HashValue) EachWithIndex(consumer eval.IndexedConsumer) {
-	for i, e := range hv.entries {
+	for i, e := range hv.slice() {
 		consumer(e, i)
 	}
 }
 
 func (hv *HashValue) Equals(o interface{}, g eval.Guard) bool {
+	entries := hv.slice()
 	if ov, ok := o.(*HashValue); ok {
-		if top := len(hv.entries); top == len(ov.entries) {
-			ovIndex := ov.valueIndex()
-			for key, idx := range hv.valueIndex() {
-				var ovIdx int
-				if ovIdx, ok = ovIndex[key]; !(ok && hv.entries[idx].Equals(ov.entries[ovIdx], g)) {
+		if others := ov.slice(); len(entries) == len(others) {
+			for _, e := range entries {
+				if ovIdx, ok := ov.indexOf(eval.ToKey(e.key)); !(ok && e.Equals(others[ovIdx], g)) {
 					return false
 				}
 			}
@@ -1035,45 +1055,45 @@ func (hv *HashValue) Get6(key string, dflt eval.Producer) eval.Value {
 }
 
 func (hv *HashValue) GetEntry(key string) (eval.MapEntry, bool) {
-	if pos, ok := hv.valueIndex()[eval.HashKey(key)]; ok {
-		return hv.entries[pos], true
+	if pos, ok := hv.indexOf(eval.HashKey(key)); ok {
+		return hv.At(pos).(*HashEntry), true
 	}
 	return nil, false
 }
 
 func (hv *HashValue) get(key eval.HashKey) (eval.Value, bool) {
-	if pos, ok := hv.valueIndex()[key]; ok {
-		return hv.entries[pos].value, true
+	if pos, ok := hv.indexOf(key); ok {
+		return hv.At(pos).(*HashEntry).value, true
 	}
 	return _UNDEF, false
 }
 
 func (hv *HashValue) get2(key eval.HashKey, dflt eval.Value) eval.Value {
-	if pos, ok := hv.valueIndex()[key]; ok {
-		return hv.entries[pos].value
+	if pos, ok := hv.indexOf(key); ok {
+		return hv.At(pos).(*HashEntry).value
 	}
 	return dflt
 }
 
 func (hv *HashValue) get3(key eval.HashKey, dflt eval.Producer) eval.Value {
-	if pos, ok := hv.valueIndex()[key]; ok {
-		return hv.entries[pos].value
+	if pos, ok := hv.indexOf(key); ok {
+		return hv.At(pos).(*HashEntry).value
 	}
 	return dflt()
 }
 
 func (hv *HashValue) IncludesKey(o eval.Value) bool {
-	_, ok := hv.valueIndex()[eval.ToKey(o)]
+	_, ok := hv.indexOf(eval.ToKey(o))
 	return ok
 }
 
 func (hv *HashValue) IncludesKey2(key string) bool {
-	_, ok := hv.valueIndex()[eval.HashKey(key)]
+	_, ok := hv.indexOf(eval.HashKey(key))
 	return ok
 }
 
 func (hv *HashValue) IsEmpty() bool {
-	return len(hv.entries) == 0
+	return hv.Len() == 0
 }
 
 func (hv *HashValue) IsHashStyle() bool {
@@ -1087,29 +1107,47 @@ func (hv *HashValue) Iterator() eval.Iterator {
 }
 
 func (hv *HashValue) Keys() eval.List {
-	keys := make([]eval.Value, len(hv.entries))
-	for idx, entry := range hv.entries {
+	entries := hv.slice()
+	keys := make([]eval.Value, len(entries))
+	for idx, entry := range entries {
 		keys[idx] = entry.key
 	}
 	return WrapValues(keys)
 }
 
 func (hv *HashValue) Len() int {
+	if hv.vector != nil {
+		return hv.vector.len()
+	}
 	return len(hv.entries)
 }
 
 func (hv *HashValue) Merge(o eval.OrderedMap) eval.OrderedMap {
-	return WrapHash(hv.mergeEntries(o))
+	oh := o.(*HashValue)
+	if oh.Len() == 0 {
+		return hv
+	}
+	v, t := hv.persistent()
+	for _, entry := range oh.slice() {
+		key := eval.ToKey(entry.key)
+		if idx, ok := t.lookup(key); ok {
+			v = v.set(idx, entry)
+		} else {
+			t = t.assoc(key, v.len())
+			v = v.push(entry)
+		}
+	}
+	return &HashValue{vector: v, trie: t}
 }
 
 func (hv *HashValue) mergeEntries(o eval.OrderedMap) []*HashEntry {
+	entries := hv.slice()
 	oh := o.(*HashValue)
-	index := hv.valueIndex()
-	selfLen := len(hv.entries)
-	all := make([]*HashEntry, selfLen, selfLen+len(oh.entries))
-	copy(all, hv.entries)
-	for _, entry := range oh.entries {
-		if idx, ok := index[eval.ToKey(entry.key)]; ok {
+	selfLen := len(entries)
+	all := make([]*HashEntry, selfLen, selfLen+len(oh.slice()))
+	copy(all, entries)
+	for _, entry := range oh.slice() {
+		if idx, ok := hv.indexOf(eval.ToKey(entry.key)); ok {
 			all[idx] = entry
 		} else {
 			all = append(all, entry)
@@ -1119,7 +1157,7 @@ func (hv *HashValue) mergeEntries(o eval.OrderedMap) []*HashEntry {
 }
 
 func (hv *HashValue) Slice(i int, j int) eval.List {
-	return WrapHash(hv.entries[i:j])
+	return WrapHash(hv.slice()[i:j])
 }
 
 type hashSorter struct {
@@ -1146,8 +1184,9 @@ func (s *hashSorter) Swap(i, j int) {
 // Sort reorders the associations of this hash by applying the comparator
 // to the keys
 func (hv *HashValue) Sort(comparator eval.Comparator) eval.List {
-	s := &hashSorter{make([]*HashEntry, len(hv.entries)), comparator}
-	copy(s.entries, hv.entries)
+	entries := hv.slice()
+	s := &hashSorter{make([]*HashEntry, len(entries)), comparator}
+	copy(s.entries, entries)
 	sort.Sort(s)
 	return WrapHash(s.entries)
 }
@@ -1161,6 +1200,7 @@ func (hv *HashValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect)
 }
 
 func (hv *HashValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format, delim byte, g eval.RDetect) {
+	entries := hv.slice()
 	if g == nil {
 		g = make(eval.RDetect)
 	} else if g[hv] {
@@ -1196,7 +1236,7 @@ func (hv *HashValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format,
 			io.WriteString(b, "\n")
 		}
 
-		top := len(hv.entries)
+		top := len(entries)
 		if top > 0 {
 			sep := f.Separator(`,`)
 			assoc := f.Separator2(` => `)
@@ -1217,7 +1257,7 @@ func (hv *HashValue) ToString2(b io.Writer, s eval.FormatContext, f eval.Format,
 			}
 
 			last := top - 1
-			for idx, entry := range hv.entries {
+			for idx, entry := range entries {
 				k := entry.Key()
 				io.WriteString(b, padding)
 				if isContainer(k, s) {
@@ -1264,8 +1304,9 @@ func (hv *HashValue) Unique() eval.List {
 }
 
 func (hv *HashValue) Values() eval.List {
-	values := make([]eval.Value, len(hv.entries))
-	for idx, entry := range hv.entries {
+	entries := hv.slice()
+	values := make([]eval.Value, len(entries))
+	for idx, entry := range entries {
 		values[idx] = entry.value
 	}
 	return WrapValues(values)
@@ -1273,14 +1314,15 @@ func (hv *HashValue) Values() eval.List {
 
 func (hv *HashValue) prtvDetailedType() eval.Type {
 	if hv.detailedType == nil {
-		top := len(hv.entries)
+		entries := hv.slice()
+		top := len(entries)
 		if top == 0 {
 			hv.detailedType = hv.prtvReducedType()
 			return hv.detailedType
 		}
 
 		structEntries := make([]*StructElement, top)
-		for idx, entry := range hv.entries {
+		for idx, entry := range entries {
 			if ks, ok := entry.key.(*StringValue); ok {
 				structEntries[idx] = NewStructElement(ks, DefaultAnyType())
 				continue
@@ -1292,23 +1334,23 @@ func (hv *HashValue) prtvDetailedType() eval.Type {
 		}
 		hv.detailedType = NewStructType(structEntries)
 
-		for _, entry := range hv.entries {
+		for _, entry := range entries {
 			if sv, ok := entry.key.(*StringValue); !ok || len(sv.String()) == 0 {
-				firstEntry := hv.entries[0]
+				firstEntry := entries[0]
 				commonKeyType := eval.DetailedValueType(firstEntry.key)
 				commonValueType := eval.DetailedValueType(firstEntry.value)
 				for idx := 1; idx < top; idx++ {
-					entry := hv.entries[idx]
+					entry := entries[idx]
 					commonKeyType = commonType(commonKeyType, eval.DetailedValueType(entry.key))
 					commonValueType = commonType(commonValueType, eval.DetailedValueType(entry.value))
 				}
-				sz := int64(len(hv.entries))
+				sz := int64(len(entries))
 				hv.detailedType = NewHashType(commonKeyType, commonValueType, NewIntegerType(sz, sz))
 				return hv.detailedType
 			}
 		}
 
-		for idx, entry := range hv.entries {
+		for idx, entry := range entries {
 			structEntries[idx] = NewStructElement(entry.key, eval.DetailedValueType(entry.value))
 		}
 	}
@@ -1317,18 +1359,19 @@ func (hv *HashValue) prtvDetailedType() eval.Type {
 
 func (hv *HashValue) prtvReducedType() eval.Type {
 	if hv.reducedType == nil {
-		top := len(hv.entries)
+		entries := hv.slice()
+		top := len(entries)
 		if top == 0 {
 			hv.reducedType = EmptyHashType()
 		} else {
 			sz := int64(top)
 			ht := NewHashType(DefaultAnyType(), DefaultAnyType(), NewIntegerType(sz, sz))
 			hv.reducedType = ht
-			firstEntry := hv.entries[0]
+			firstEntry := entries[0]
 			commonKeyType := firstEntry.key.PType()
 			commonValueType := firstEntry.value.PType()
 			for idx := 1; idx < top; idx++ {
-				entry := hv.entries[idx]
+				entry := entries[idx]
 				commonKeyType = commonType(commonKeyType, entry.key.PType())
 				commonValueType = commonType(commonValueType, entry.value.PType())
 			}
@@ -1339,14 +1382,51 @@ func (hv *HashValue) prtvReducedType() eval.Type {
 	return hv.reducedType
 }
 
+// indexOf returns the index of the entry with the given key
+func (hv *HashValue) indexOf(key eval.HashKey) (int, bool) {
+	if hv.trie != nil {
+		return hv.trie.lookup(key)
+	}
+	idx, ok := hv.valueIndex()[key]
+	return idx, ok
+}
+
+// persistent returns the persistent representation of the entries and index of the receiver
+func (hv *HashValue) persistent() (*persistentVector, *hashTrieNode) {
+	if hv.vector != nil {
+		return hv.vector, hv.trie
+	}
+	entries := make([]eval.Value, len(hv.entries))
+	for i, e := range hv.entries {
+		entries[i] = e
+	}
+	return newVector(entries), newHashTrie(hv.entries)
+}
+
+// slice returns the entries of the receiver as a slice. The slice is materialized from the
+// persistent representation when needed and must not be modified.
+func (hv *HashValue) slice() []*HashEntry {
+	if hv.vector != nil {
+		hv.sliceOnce.Do(func() {
+			entries := make([]*HashEntry, 0, hv.vector.len())
+			for _, e := range hv.vector.appendTo(make([]eval.Value, 0, hv.vector.len())) {
+				entries = append(entries, e.(*HashEntry))
+			}
+			hv.entries = entries
+		})
+	}
+	return hv.entries
+}
+
+// valueIndex returns the index of a hash that has no trie. The index is created once, on demand.
 func (hv *HashValue) valueIndex() map[eval.HashKey]int {
-	if hv.index == nil {
-		result := make(map[eval.HashKey]int, len(hv.entries))
+	hv.sliceOnce.Do(func() {
+		index := make(map[eval.HashKey]int, len(hv.entries))
 		for idx, entry := range hv.entries {
-			result[eval.ToKey(entry.key)] = idx
+			index[eval.ToKey(entry.key)] = idx
 		}
-		hv.index = result
-	}
+		hv.index = index
+	})
 	return hv.index
 }
 
@@ -1358,8 +1438,12 @@ func NewMutableHash() *MutableHashValue {
 // is not thread safe
 func (hv *MutableHashValue) PutAll(o eval.OrderedMap) {
 	hv.entries = hv.mergeEntries(o)
+	hv.reducedType = nil
 	hv.detailedType = nil
 	hv.index = nil
+	hv.vector = nil
+	hv.trie = nil
+	hv.sliceOnce = sync.Once{}
 }
 
 // Put adds or replaces the given key/value association in this hash
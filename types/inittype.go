@@ -224,7 +224,6 @@ func (t *InitType) String() string {
 }
 
 func (t *InitType) Parameters() []eval.Value {
-	t.assertInitialized()
 	if t.initArgs.Len() == 0 {
 		if t.typ == nil {
 			return eval.EMPTY_VALUES
@@ -250,9 +249,14 @@ func (t *InitType) PType() eval.Type {
 	return &TypeType{t}
 }
 
+// assertInitialized resolves the constructor of a type that wasn't resolved with an explicit Context
 func (t *InitType) assertInitialized() {
 	if t.typ != nil && t.ctor == nil {
-		t.Resolve(eval.CurrentContext())
+		c, ok := eval.TryCurrentContext()
+		if !ok {
+			panic(eval.Error(eval.EVAL_UNRESOLVED_TYPE, issue.H{`typeString`: t.typ.Name()}))
+		}
+		t.Resolve(c)
 	}
 }
 
@@ -165,6 +165,11 @@ func NewIntegerType2(limits ...eval.Value) *IntegerType {
 	if argc == 0 {
 		return integerType_DEFAULT
 	}
+	for _, l := range limits {
+		if _, ok := l.(*BigIntegerValue); ok {
+			panic(errors.NewArgumentsError(`Integer[]`, `min and max must be within the 64-bit integer range`))
+		}
+	}
 	min, ok := toInt(limits[0])
 	if !ok {
 		if _, ok = limits[0].(*DefaultValue); !ok {
@@ -240,6 +245,15 @@ func (t *IntegerType) IsInstance(o eval.Value, g eval.Guard) bool {
 	if n, ok := toInt(o); ok {
 		return t.IsInstance2(n)
 	}
+	if bv, ok := o.(*BigIntegerValue); ok {
+		// A BigIntegerValue is always outside of the int64 range. Bounds are limited to that range and the
+		// edges of the range are open ends, so a type without an upper bound matches all big positive values
+		// and a type without a lower bound matches all big negative values
+		if bv.Sign() > 0 {
+			return t.max == math.MaxInt64
+		}
+		return t.min == math.MinInt64
+	}
 	return false
 }
 
@@ -429,43 +443,7 @@ func (iv *IntegerValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDete
 		fmt.Fprintf(b, f.OrigFormat(), iv.Int())
 	case 'p', 'b', 'B':
 		longVal := iv.Int()
-		intString := strconv.FormatInt(longVal, integerRadix(f.FormatChar()))
-		totWidth := 0
-		if f.Width() > 0 {
-			totWidth = f.Width()
-		}
-		numWidth := 0
-		if f.Precision() > 0 {
-			numWidth = f.Precision()
-		}
-
-		if numWidth > 0 && numWidth < len(intString) && f.FormatChar() == 'p' {
-			intString = intString[:numWidth]
-		}
-
-		zeroPad := numWidth - len(intString)
-
-		pfx := ``
-		if f.IsAlt() && longVal != 0 && !(f.FormatChar() == 'o' && zeroPad > 0) {
-			pfx = integerPrefixRadix(f.FormatChar())
-		}
-		computedFieldWidth := len(pfx) + intMax(numWidth, len(intString))
-
-		for spacePad := totWidth - computedFieldWidth; spacePad > 0; spacePad-- {
-			b.Write([]byte{' '})
-		}
-
-		io.WriteString(b, pfx)
-		if zeroPad > 0 {
-			padChar := []byte{'0'}
-			if f.FormatChar() == 'p' {
-				padChar = []byte{' '}
-			}
-			for ; zeroPad > 0; zeroPad-- {
-				b.Write(padChar)
-			}
-		}
-		io.WriteString(b, intString)
+		writeIntegerString(b, f, strconv.FormatInt(longVal, integerRadix(f.FormatChar())), longVal == 0)
 	case 'e', 'E', 'f', 'g', 'G', 'a', 'A':
 		WrapFloat(iv.Float()).ToString(b, eval.NewFormatContext(DefaultFloatType(), f, s.Indentation()), g)
 	case 'c':
@@ -479,6 +457,47 @@ func (iv *IntegerValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDete
 	}
 }
 
+// writeIntegerString writes the given string representation of an integer using the width, precision, and
+// alternative flag of the given format.
+func writeIntegerString(b io.Writer, f eval.Format, intString string, isZero bool) {
+	totWidth := 0
+	if f.Width() > 0 {
+		totWidth = f.Width()
+	}
+	numWidth := 0
+	if f.Precision() > 0 {
+		numWidth = f.Precision()
+	}
+
+	if numWidth > 0 && numWidth < len(intString) && f.FormatChar() == 'p' {
+		intString = intString[:numWidth]
+	}
+
+	zeroPad := numWidth - len(intString)
+
+	pfx := ``
+	if f.IsAlt() && !isZero && !(f.FormatChar() == 'o' && zeroPad > 0) {
+		pfx = integerPrefixRadix(f.FormatChar())
+	}
+	computedFieldWidth := len(pfx) + intMax(numWidth, len(intString))
+
+	for spacePad := totWidth - computedFieldWidth; spacePad > 0; spacePad-- {
+		b.Write([]byte{' '})
+	}
+
+	io.WriteString(b, pfx)
+	if zeroPad > 0 {
+		padChar := []byte{'0'}
+		if f.FormatChar() == 'p' {
+			padChar = []byte{' '}
+		}
+		for ; zeroPad > 0; zeroPad-- {
+			b.Write(padChar)
+		}
+	}
+	io.WriteString(b, intString)
+}
+
 func intMax(a int, b int) int {
 	if a > b {
 		return a
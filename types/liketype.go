@@ -164,7 +164,9 @@ func navigate(c eval.Context, value eval.Value, member string) (eval.Context, ev
 		} else {
 			if m, ok := typ.MetaType().Member(member); ok {
 				if c == nil {
-					c = eval.CurrentContext()
+					if c, ok = eval.TryCurrentContext(); !ok {
+						return nil, nil, false
+					}
 				}
 				return c, m.Call(c, typ, nil, []eval.Value{}), true
 			}
@@ -173,7 +175,9 @@ func navigate(c eval.Context, value eval.Value, member string) (eval.Context, ev
 		if po, ok := value.PType().(eval.TypeWithCallableMembers); ok {
 			if m, ok := po.Member(member); ok {
 				if c == nil {
-					c = eval.CurrentContext()
+					if c, ok = eval.TryCurrentContext(); !ok {
+						return nil, nil, false
+					}
 				}
 				return c, m.Call(c, value, nil, []eval.Value{}), true
 			}
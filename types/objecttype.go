@@ -306,7 +306,7 @@ func (t *objectType) Equals(other interface{}, guard eval.Guard) bool {
 
 func (t *objectType) FromReflectedValue(c eval.Context, src reflect.Value) eval.PuppetObject {
 	if t.goType != nil {
-		return NewReflectedValue(t, src).(eval.PuppetObject)
+		return NewReflectedValue(c, t, src).(eval.PuppetObject)
 	}
 	if src.Kind() == reflect.Ptr {
 		src = src.Elem()
@@ -814,6 +814,9 @@ func (t *objectType) ToReflectedValue(c eval.Context, src eval.PuppetObject, des
 			t.resolvedParent().ToReflectedValue(c, src, f)
 			continue
 		}
+		if isEmbeddedInterface(&field) {
+			continue
+		}
 		an := rf.FieldName(&field)
 		if av, ok := src.Get(an); ok {
 			rf.ReflectTo(av, f)
@@ -973,7 +976,7 @@ func (t *objectType) appendAttributeValues(c eval.Context, entries []*HashEntry,
 		}
 		if field.Anonymous && i == 0 && t.parent != nil {
 			entries = t.resolvedParent().appendAttributeValues(c, entries, &sf)
-		} else {
+		} else if !isEmbeddedInterface(&field) {
 			entries = append(entries, WrapHashEntry2(rf.FieldName(&field), wrap(c, sf)))
 		}
 	}
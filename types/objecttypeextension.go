@@ -250,9 +250,8 @@ func (te *objectTypeExtension) testAssignable(paramValues *hash.StringHash, g ev
 // This method is only called when the given value is found to be an instance of the base type of
 // this extension.
 func (te *objectTypeExtension) testInstance(o eval.Value, g eval.Guard) bool {
-	c := eval.CurrentContext()
 	return te.parameters.AllPair(func(key string, v1 interface{}) bool {
 		v2, ok := te.baseType.GetValue(key, o)
-		return ok && eval.PuppetMatch(c, v2, v1.(eval.Value))
+		return ok && eval.PuppetMatch(nil, v2, v1.(eval.Value))
 	})
 }
@@ -1,7 +1,6 @@
 package types
 
 import (
-	"fmt"
 	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/puppet-evaluator/eval"
 	"io"
@@ -47,16 +46,16 @@ func AllocObjectValue(c eval.Context, typ eval.ObjectType) eval.Object {
 		if rf.Kind() == reflect.Ptr && rf.Elem().Kind() == reflect.Struct {
 			rf = rf.Elem()
 		}
-		return &reflectedObject{typedObject{typ}, reflect.New(rf).Elem()}
+		return &reflectedObject{typedObject{typ}, c, reflect.New(rf).Elem()}
 	}
 	return &attributeSlice{typedObject{typ}, eval.EMPTY_VALUES}
 }
 
-func NewReflectedValue(typ eval.ObjectType, value reflect.Value) eval.Object {
+func NewReflectedValue(c eval.Context, typ eval.ObjectType, value reflect.Value) eval.Object {
 	if value.Kind() == reflect.Func {
 		return &reflectedFunc{typedObject{typ}, value}
 	}
-	return &reflectedObject{typedObject{typ}, value}
+	return &reflectedObject{typedObject{typ}, c, value}
 }
 
 func NewObjectValue(c eval.Context, typ eval.ObjectType, values []eval.Value) (ov eval.Object) {
@@ -174,6 +173,9 @@ func makeValueHash(pi eval.AttributesInfo, values []eval.Value) *HashValue {
 
 type reflectedObject struct {
 	typedObject
+
+	// c is the context that created the object. It is used when wrapping the field values
+	c     eval.Context
 	value reflect.Value
 }
 
@@ -183,47 +185,8 @@ func (o *reflectedObject) Call(c eval.Context, method eval.ObjFunc, args []eval.
 		return nil, false
 	}
 
-	mt := m.Type
-	rf := c.Reflector()
-	var vat reflect.Type
-
-	// argc, the number of arguments + the mandatory call receiver
-	argc := len(args) + 1
-
-	// number of expected arguments
-	top := mt.NumIn()
-	last := top - 1
-
-	if mt.IsVariadic() {
-		if argc < last {
-			// Must be at least expected number of arguments minus one (variadic can have a zero count)
-			panic(fmt.Errorf("Agrument count error. Expected at least %d, got %d", last, argc))
-		}
-
-		// Slice big enough to hold all variadics
-		vat = mt.In(last).Elem()
-	} else {
-		if top != argc {
-			panic(fmt.Errorf("Agrument count error. Expected %d, got %d", top, argc))
-		}
-	}
-
-	rfArgs := make([]reflect.Value, argc)
-	rfArgs[0] = o.value
-
-	for i, arg := range args {
-		pn := i + 1
-		var tp reflect.Type
-		if pn >= last && vat != nil {
-			tp = vat
-		} else {
-			tp = mt.In(pn)
-		}
-		av := reflect.New(tp).Elem()
-		rf.ReflectTo(arg, av)
-		rfArgs[pn] = av
-	}
-
+	// The first argument is the call receiver
+	rfArgs := append([]reflect.Value{o.value}, reflectArgs(c, method.GoName(), m.Type, 1, args)...)
 	result := method.(eval.CallableGoMember).CallGoReflected(c, rfArgs)
 
 	switch len(result) {
@@ -257,7 +220,7 @@ func (o *reflectedObject) ReflectTo(c eval.Context, value reflect.Value) {
 	if o.value.Kind() == reflect.Struct && value.Kind() == reflect.Ptr {
 		value.Set(o.value.Addr())
 	} else {
-		value.Set(o.value)
+		setReflected(o.value, value)
 	}
 }
 
@@ -312,7 +275,7 @@ func (o *reflectedObject) Get(key string) (eval.Value, bool) {
 		}
 		rf := o.structVal().FieldByName(attr.GoName())
 		if rf.IsValid() {
-			return wrap(nil, rf), true
+			return wrap(o.c, rf), true
 		}
 		a := pi.Attributes()[idx]
 		if a.Kind() == GIVEN_OR_DERIVED {
@@ -355,11 +318,10 @@ func (o *reflectedObject) InitHash() eval.OrderedMap {
 
 	entries := make([]*HashEntry, 0, nc)
 	oe := o.structVal()
-	c := eval.CurrentContext()
 	for _, attr := range pi.Attributes() {
 		gn := attr.GoName()
 		if gn != `` {
-			v := wrapReflected(c, oe.FieldByName(gn))
+			v := wrapReflected(o.c, oe.FieldByName(gn))
 			if !(attr.HasValue() && eval.Equals(v, attr.Value()) || attr.Kind() == GIVEN_OR_DERIVED && v.Equals(_UNDEF, nil)) {
 				entries = append(entries, WrapHashEntry2(attr.Name(), v))
 			}
@@ -383,20 +345,7 @@ type reflectedFunc struct {
 
 func (o *reflectedFunc) Call(c eval.Context, method eval.ObjFunc, args []eval.Value, block eval.Lambda) (eval.Value, bool) {
 	mt := o.function.Type()
-	rf := c.Reflector()
-	rfArgs := make([]reflect.Value, len(args))
-	for i, arg := range args {
-		av := reflect.New(mt.In(i)).Elem()
-		rf.ReflectTo(arg, av)
-		rfArgs[i] = av
-	}
-
-	pc := mt.NumIn()
-	if pc != len(args) {
-		panic(eval.Error(eval.EVAL_TYPE_MISMATCH, issue.H{`detail`: eval.DescribeSignatures(
-			[]eval.Signature{method.CallableType().(*CallableType)}, NewTupleType([]eval.Type{}, NewIntegerType(int64(pc-1), int64(pc-1))), nil)}))
-	}
-	result := o.function.Call(rfArgs)
+	result := o.function.Call(reflectArgs(c, method.GoName(), mt, 0, args))
 
 	oc := mt.NumOut()
 
@@ -407,7 +356,7 @@ func (o *reflectedFunc) Call(c eval.Context, method eval.ObjFunc, args []eval.Va
 			if re, ok := err.(issue.Reported); ok {
 				panic(re)
 			}
-			panic(eval.Error(eval.EVAL_GO_FUNCTION_ERROR, issue.H{`name`: mt.Name(), `error`: err}))
+			panic(c.Error(nil, eval.EVAL_GO_FUNCTION_ERROR, issue.H{`name`: mt.Name(), `error`: err}))
 		}
 		result = result[:oc]
 	}
@@ -0,0 +1,171 @@
+package types_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func ExampleArrayValue_Add() {
+	a := types.WrapValues([]eval.Value{types.WrapInteger(1)})
+	b := a.Add(types.WrapInteger(2))
+	c := a.Add(types.WrapInteger(3))
+	fmt.Println(a, b, c)
+
+	// Output: [1] [1, 2] [1, 3]
+}
+
+func ExampleHashValue_Merge() {
+	a := types.SingletonHash2(`a`, types.WrapInteger(1))
+	b := a.Merge(types.SingletonHash2(`b`, types.WrapInteger(2)))
+	c := b.Merge(types.SingletonHash2(`a`, types.WrapInteger(3)))
+	fmt.Println(a, b, c)
+
+	// Output: {'a' => 1} {'a' => 1, 'b' => 2} {'a' => 3, 'b' => 2}
+}
+
+func TestPersistentArray(t *testing.T) {
+	var a eval.List = types.WrapValues(nil)
+	arrays := make([]eval.List, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		arrays = append(arrays, a)
+		a = a.Add(types.WrapInteger(int64(i)))
+	}
+	for n, a := range arrays {
+		if a.Len() != n {
+			t.Fatalf(`expected length %d, got %d`, n, a.Len())
+		}
+		for i := 0; i < n; i++ {
+			if a.At(i).(*types.IntegerValue).Int() != int64(i) {
+				t.Fatalf(`expected element %d of array of length %d to be %d, got %s`, i, n, i, a.At(i))
+			}
+		}
+		if n > 0 && a.(*types.ArrayValue).AppendTo(nil)[n-1].String() != fmt.Sprint(n-1) {
+			t.Fatalf(`unexpected last element of array of length %d`, n)
+		}
+	}
+}
+
+func TestPersistentHash(t *testing.T) {
+	var h eval.OrderedMap = types.WrapHash(nil)
+	for i := 0; i < 2000; i++ {
+		h = h.Merge(types.SingletonHash2(fmt.Sprintf(`k%d`, i%1500), types.WrapInteger(int64(i))))
+	}
+	if h.Len() != 1500 {
+		t.Fatalf(`expected length 1500, got %d`, h.Len())
+	}
+	for i := 0; i < 1500; i++ {
+		key := fmt.Sprintf(`k%d`, i)
+		expected := int64(i)
+		if i < 500 {
+			expected += 1500
+		}
+		if v, ok := h.Get4(key); !ok || v.(*types.IntegerValue).Int() != expected {
+			t.Fatalf(`expected %s to be %d, got %v`, key, expected, v)
+		}
+		if e := h.At(i).(*types.HashEntry); e.Key().String() != key {
+			t.Fatalf(`expected entry %d to have key %s, got %s`, i, key, e.Key())
+		}
+	}
+	if h.Delete(types.WrapString(`k3`)).Len() != 1499 || h.Len() != 1500 {
+		t.Fatalf(`delete must not modify the original hash`)
+	}
+}
+
+// TestPersistentConcurrentRead must be run with -race to be meaningful
+func TestPersistentConcurrentRead(t *testing.T) {
+	a := types.WrapValues([]eval.Value{types.WrapInteger(1), types.WrapInteger(2)}).Add(types.WrapInteger(3))
+	b := types.WrapValues([]eval.Value{types.WrapInteger(1)})
+	c := b.Add(types.WrapInteger(2))
+	h := types.SingletonHash2(`a`, types.WrapInteger(1)).Merge(types.SingletonHash2(`b`, types.WrapInteger(2)))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sum := int64(0)
+			a.Each(func(v eval.Value) { sum += v.(*types.IntegerValue).Int() })
+			if sum != 6 || a.Len() != 3 || a.At(2).String() != `3` {
+				t.Errorf(`unexpected array %s`, a)
+			}
+			if b.Add(types.WrapInteger(3)).Len() != 2 || c.Len() != 2 {
+				t.Errorf(`unexpected array %s`, c)
+			}
+			if v, ok := h.Get4(`b`); !ok || v.String() != `2` || h.Keys().Len() != 2 {
+				t.Errorf(`unexpected hash %s`, h)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestHashConcurrentEquals must be run with -race to be meaningful
+func TestHashConcurrentEquals(t *testing.T) {
+	entries := func() []*types.HashEntry {
+		return []*types.HashEntry{
+			types.WrapHashEntry2(`a`, types.WrapInteger(1)),
+			types.WrapHashEntry2(`b`, types.WrapInteger(2))}
+	}
+	p := types.WrapHash(entries())
+	q := types.WrapHash(entries())
+	h := types.SingletonHash2(`a`, types.WrapInteger(1)).Merge(types.SingletonHash2(`b`, types.WrapInteger(2)))
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !(p.Equals(q, nil) && q.Equals(h, nil) && h.Equals(p, nil)) {
+				t.Errorf(`expected %s, %s, and %s to be equal`, p, q, h)
+			}
+			if m := p.Merge(h); !m.Equals(q, nil) {
+				t.Errorf(`unexpected merge result %s`, m)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// benchmarkReduce evaluates a reduce expression that builds a collection from an array with the given size
+func benchmarkReduce(b *testing.B, source string, size int) {
+	elements := make([]string, size)
+	for i := range elements {
+		elements[i] = fmt.Sprint(i)
+	}
+	eval.Puppet.Do(func(c eval.Context) {
+		expr := c.ParseAndValidate(``, fmt.Sprintf(source, strings.Join(elements, `,`)), false)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := eval.TopEvaluate(c, expr); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkReduceArray1000(b *testing.B) {
+	benchmarkReduce(b, `[%s].reduce([]) |$m, $x| { $m << $x }`, 1000)
+}
+
+func BenchmarkReduceArray10000(b *testing.B) {
+	benchmarkReduce(b, `[%s].reduce([]) |$m, $x| { $m << $x }`, 10000)
+}
+
+func BenchmarkReduceArray100000(b *testing.B) {
+	benchmarkReduce(b, `[%s].reduce([]) |$m, $x| { $m << $x }`, 100000)
+}
+
+func BenchmarkReduceHash1000(b *testing.B) {
+	benchmarkReduce(b, `[%s].reduce({}) |$m, $x| { $m + { $x => $x } }`, 1000)
+}
+
+func BenchmarkReduceHash10000(b *testing.B) {
+	benchmarkReduce(b, `[%s].reduce({}) |$m, $x| { $m + { $x => $x } }`, 10000)
+}
+
+func BenchmarkReduceHash100000(b *testing.B) {
+	benchmarkReduce(b, `[%s].reduce({}) |$m, $x| { $m + { $x => $x } }`, 100000)
+}
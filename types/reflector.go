@@ -1,11 +1,14 @@
 package types
 
 import (
+	"context"
 	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/errors"
 	"github.com/lyraproj/puppet-evaluator/eval"
 	"github.com/lyraproj/semver/semver"
 	"math"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -154,6 +157,52 @@ func ParseTagHash(c eval.Context, tag string) (eval.OrderedMap, bool) {
 }
 
 var errorType = reflect.TypeOf((*error)(nil)).Elem()
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var evalContextType = reflect.TypeOf((*eval.Context)(nil)).Elem()
+
+// isContextType returns true if the given type is a context.Context or an eval.Context
+func isContextType(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface && t.Implements(contextType) && evalContextType.Implements(t)
+}
+
+// reflectArgs converts the given arguments to the types of the parameters of the given function type, starting
+// with the parameter at index first. A parameter of type eval.Context or context.Context doesn't consume an
+// argument. It is assigned the given context instead.
+func reflectArgs(c eval.Context, name string, mt reflect.Type, first int, args []eval.Value) []reflect.Value {
+	rf := c.Reflector()
+	top := mt.NumIn()
+	rfArgs := make([]reflect.Value, 0, top-first)
+	argc := len(args)
+	ai := 0
+	expected := 0
+	for pn := first; pn < top; pn++ {
+		pt := mt.In(pn)
+		if isContextType(pt) {
+			rfArgs = append(rfArgs, reflect.ValueOf(c))
+			continue
+		}
+		if pn == top-1 && mt.IsVariadic() {
+			et := pt.Elem()
+			for ; ai < argc; ai++ {
+				av := reflect.New(et).Elem()
+				rf.ReflectTo(args[ai], av)
+				rfArgs = append(rfArgs, av)
+			}
+			return rfArgs
+		}
+		expected++
+		if ai < argc {
+			av := reflect.New(pt).Elem()
+			rf.ReflectTo(args[ai], av)
+			rfArgs = append(rfArgs, av)
+			ai++
+		}
+	}
+	if expected != argc {
+		panic(errors.NewIllegalArgumentCount(name, strconv.Itoa(expected), argc))
+	}
+	return rfArgs
+}
 
 func (r *reflector) FunctionDeclFromReflect(name string, mt reflect.Type, withReceiver bool) eval.OrderedMap {
 	returnsError := false
@@ -198,16 +247,19 @@ func (r *reflector) FunctionDeclFromReflect(name string, mt reflect.Type, withRe
 		ix = 1
 	}
 
-	if pc == ix {
+	// Context parameters are injected when the function is called and are not part of the signature
+	ps := make([]eval.Type, 0, pc-ix)
+	for p := ix; p < pc; p++ {
+		if at := mt.In(p); !isContextType(at) {
+			ps = append(ps, wrapReflectedType(r.c, at))
+		}
+	}
+	if len(ps) == 0 {
 		pt = EmptyTupleType()
 	} else {
-		ps := make([]eval.Type, pc-ix)
-		for p := ix; p < pc; p++ {
-			ps[p-ix] = wrapReflectedType(r.c, mt.In(p))
-		}
 		var sz *IntegerType
 		if mt.IsVariadic() {
-			last := pc - ix - 1
+			last := len(ps) - 1
 			ps[last] = ps[last].(*ArrayType).ElementType()
 			sz = NewIntegerType(int64(last), math.MaxInt64)
 		}
@@ -244,13 +296,13 @@ func (r *reflector) InitializerFromTagged(typeName string, parent eval.Type, tg
 		if nf > 0 {
 			es := make([]*HashEntry, 0, nf)
 			for i, f := range fs {
-				if i == 0 && f.Anonymous {
+				if i == 0 && f.Anonymous && f.Type.Kind() == reflect.Struct {
 					// Parent
 					pt = reflect.PtrTo(f.Type)
 					continue
 				}
-				if f.PkgPath != `` {
-					// Unexported
+				if f.PkgPath != `` || isEmbeddedInterface(&f) {
+					// Unexported or embedded interface
 					continue
 				}
 
@@ -419,6 +471,31 @@ func typeName(prefix string, aliases map[string]string, rt reflect.Type) string
 	return prefix + name
 }
 
+// setReflected assigns rv to dest. A new pointer is allocated when dest is a pointer to the type of rv and
+// rv is dereferenced when it is a pointer to the type of dest.
+func setReflected(rv, dest reflect.Value) {
+	rt := rv.Type()
+	dt := dest.Type()
+	switch {
+	case rt.AssignableTo(dt):
+	case dt.Kind() == reflect.Ptr && rt.AssignableTo(dt.Elem()):
+		pv := reflect.New(dt.Elem())
+		pv.Elem().Set(rv)
+		rv = pv
+	case rt.Kind() == reflect.Ptr && rt.Elem().AssignableTo(dt):
+		rv = rv.Elem()
+	default:
+		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rt.String(), `actual`: dt.String()}))
+	}
+	dest.Set(rv)
+}
+
+// isEmbeddedInterface returns true if the given field is an embedded interface. The methods of such a field
+// are promoted to the struct that embeds it. The field itself is not an attribute.
+func isEmbeddedInterface(f *reflect.StructField) bool {
+	return f.Anonymous && f.Type.Kind() == reflect.Interface
+}
+
 func assertSettable(value *reflect.Value) {
 	if !value.CanSet() {
 		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_UNSETTABLE, issue.H{`kind`: value.Type().String()}))
@@ -242,11 +242,7 @@ func (r *RegexpValue) Reflect(c eval.Context) reflect.Value {
 }
 
 func (r *RegexpValue) ReflectTo(c eval.Context, dest reflect.Value) {
-	rv := r.Reflect(c).Elem()
-	if !rv.Type().AssignableTo(dest.Type()) {
-		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rv.Type().String(), `actual`: dest.Type().String()}))
-	}
-	dest.Set(rv)
+	setReflected(r.Reflect(c), dest)
 }
 
 func (r *RegexpValue) String() string {
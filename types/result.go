@@ -0,0 +1,312 @@
+package types
+
+import (
+	"io"
+
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
+var Result_Type eval.ObjectType
+
+var ResultSet_Type eval.ObjectType
+
+func init() {
+	Result_Type = newObjectType(`Result`, `{
+    attributes => {
+      'target' => Target,
+      'value' => Hash[String[1], Data],
+      'ok' => { type => Boolean, kind => derived },
+      'error' => { type => Optional[Error], kind => derived },
+      'message' => { type => Optional[String], kind => derived }
+    },
+    functions => {
+      '[]' => Callable[[String[1]], Data]
+    }
+  }`, func(ctx eval.Context, args []eval.Value) eval.Value {
+		return NewResult(args[0].(eval.PuppetObject), args[1].(*HashValue))
+	}, func(ctx eval.Context, args []eval.Value) eval.Value {
+		h := args[0].(*HashValue)
+		return NewResult(h.Get5(`target`, _UNDEF).(eval.PuppetObject), h.Get5(`value`, eval.EMPTY_MAP).(*HashValue))
+	})
+
+	ResultSet_Type = newObjectType(`ResultSet`, `{
+    attributes => {
+      'results' => Array[Result],
+      'count' => { type => Integer, kind => derived },
+      'empty' => { type => Boolean, kind => derived },
+      'ok' => { type => Boolean, kind => derived },
+      'ok_set' => { type => ResultSet, kind => derived },
+      'error_set' => { type => ResultSet, kind => derived },
+      'first' => { type => Optional[Result], kind => derived },
+      'names' => { type => Array[String[1]], kind => derived },
+      'targets' => { type => Array[Target], kind => derived }
+    },
+    functions => {
+      'find' => Callable[[String[1]], Optional[Result]],
+      '[]' => Callable[[Integer], Optional[Result]]
+    }
+  }`, func(ctx eval.Context, args []eval.Value) eval.Value {
+		return newResultSetFromList(args[0].(eval.List))
+	}, func(ctx eval.Context, args []eval.Value) eval.Value {
+		return newResultSetFromList(args[0].(*HashValue).Get5(`results`, eval.EMPTY_ARRAY).(eval.List))
+	})
+}
+
+// ResultValue is the outcome of running a command, script, or task on a single Target. An
+// error outcome is a value that contains an `_error` hash with the keys `msg`, `kind`,
+// `issue_code`, and `details`.
+type ResultValue struct {
+	target eval.PuppetObject
+	value  eval.OrderedMap
+}
+
+// NewResult creates a Result for the given target and value
+func NewResult(target eval.PuppetObject, value eval.OrderedMap) *ResultValue {
+	return &ResultValue{target, value}
+}
+
+// NewErrorResult creates an error Result for the given target
+func NewErrorResult(target eval.PuppetObject, message, kind string, details eval.OrderedMap) *ResultValue {
+	if details == nil {
+		details = eval.EMPTY_MAP
+	}
+	return &ResultValue{target, SingletonHash2(`_error`, WrapHash([]*HashEntry{
+		WrapHashEntry2(`msg`, WrapString(message)),
+		WrapHashEntry2(`kind`, WrapString(kind)),
+		WrapHashEntry2(`details`, details)}))}
+}
+
+func (r *ResultValue) Call(c eval.Context, method eval.ObjFunc, args []eval.Value, block eval.Lambda) (result eval.Value, ok bool) {
+	if method.Name() == `[]` {
+		return r.value.Get5(args[0].String(), _UNDEF), true
+	}
+	return nil, false
+}
+
+func (r *ResultValue) Equals(other interface{}, g eval.Guard) bool {
+	if o, ok := other.(*ResultValue); ok {
+		return r.target.Equals(o.target, g) && r.value.Equals(o.value, g)
+	}
+	return false
+}
+
+// Error returns the Error of the receiver or nil if the receiver is ok
+func (r *ResultValue) Error(c eval.Context) eval.ErrorObject {
+	eh, ok := r.value.Get4(`_error`)
+	if !ok {
+		return nil
+	}
+	h, ok := eh.(*HashValue)
+	if !ok {
+		return eval.NewError(c, eh.String(), ``, ``, nil, nil)
+	}
+	var details eval.OrderedMap
+	if d, ok := h.Get4(`details`); ok {
+		details, _ = d.(eval.OrderedMap)
+	}
+	return eval.NewError(c, h.Get5(`msg`, eval.EMPTY_STRING).String(), h.Get5(`kind`, eval.EMPTY_STRING).String(),
+		h.Get5(`issue_code`, eval.EMPTY_STRING).String(), nil, details)
+}
+
+func (r *ResultValue) Get(key string) (value eval.Value, ok bool) {
+	switch key {
+	case `target`:
+		return r.target, true
+	case `value`:
+		return r.value, true
+	case `ok`:
+		return WrapBoolean(r.Ok()), true
+	case `error`:
+		// Creating an Error doesn't require a Context
+		if err := r.Error(nil); err != nil {
+			return err, true
+		}
+		return _UNDEF, true
+	case `message`:
+		return r.value.Get5(`_output`, _UNDEF), true
+	}
+	return nil, false
+}
+
+func (r *ResultValue) InitHash() eval.OrderedMap {
+	return WrapHash([]*HashEntry{WrapHashEntry2(`target`, r.target), WrapHashEntry2(`value`, r.value)})
+}
+
+// Ok returns true unless the value of the receiver contains an `_error` entry
+func (r *ResultValue) Ok() bool {
+	return !r.value.IncludesKey2(`_error`)
+}
+
+func (r *ResultValue) PType() eval.Type {
+	return Result_Type
+}
+
+func (r *ResultValue) String() string {
+	return eval.ToString(r)
+}
+
+// Target returns the target of the receiver
+func (r *ResultValue) Target() eval.PuppetObject {
+	return r.target
+}
+
+func (r *ResultValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect) {
+	ObjectToString(r, s, b, g)
+}
+
+// Value returns the value of the receiver
+func (r *ResultValue) Value() eval.OrderedMap {
+	return r.value
+}
+
+// ResultSetValue is the outcome of running a command, script, or task on a set of Targets
+type ResultSetValue struct {
+	results []*ResultValue
+}
+
+// NewResultSet creates a ResultSet that contains the given results
+func NewResultSet(results []*ResultValue) *ResultSetValue {
+	return &ResultSetValue{results}
+}
+
+func newResultSetFromList(results eval.List) *ResultSetValue {
+	rs := make([]*ResultValue, results.Len())
+	results.EachWithIndex(func(r eval.Value, i int) { rs[i] = r.(*ResultValue) })
+	return &ResultSetValue{rs}
+}
+
+func (rs *ResultSetValue) Call(c eval.Context, method eval.ObjFunc, args []eval.Value, block eval.Lambda) (result eval.Value, ok bool) {
+	switch method.Name() {
+	case `find`:
+		if r, ok := rs.Find(args[0].String()); ok {
+			return r, true
+		}
+		return _UNDEF, true
+	case `[]`:
+		ix := int(args[0].(*IntegerValue).Int())
+		if ix < 0 {
+			ix += len(rs.results)
+		}
+		if ix >= 0 && ix < len(rs.results) {
+			return rs.results[ix], true
+		}
+		return _UNDEF, true
+	}
+	return nil, false
+}
+
+func (rs *ResultSetValue) Equals(other interface{}, g eval.Guard) bool {
+	if o, ok := other.(*ResultSetValue); ok && len(rs.results) == len(o.results) {
+		for i, r := range rs.results {
+			if !r.Equals(o.results[i], g) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// ErrorSet returns a ResultSet with the results of the receiver that are not ok
+func (rs *ResultSetValue) ErrorSet() *ResultSetValue {
+	return rs.filter(false)
+}
+
+// Find returns the result for the target with the given name
+func (rs *ResultSetValue) Find(name string) (*ResultValue, bool) {
+	for _, r := range rs.results {
+		if TargetName(r.target) == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (rs *ResultSetValue) Get(key string) (value eval.Value, ok bool) {
+	switch key {
+	case `results`:
+		return rs.resultList(), true
+	case `count`:
+		return WrapInteger(int64(len(rs.results))), true
+	case `empty`:
+		return WrapBoolean(len(rs.results) == 0), true
+	case `ok`:
+		return WrapBoolean(rs.Ok()), true
+	case `ok_set`:
+		return rs.OkSet(), true
+	case `error_set`:
+		return rs.ErrorSet(), true
+	case `first`:
+		if len(rs.results) > 0 {
+			return rs.results[0], true
+		}
+		return _UNDEF, true
+	case `names`:
+		names := make([]eval.Value, len(rs.results))
+		for i, r := range rs.results {
+			names[i] = WrapString(TargetName(r.target))
+		}
+		return WrapValues(names), true
+	case `targets`:
+		targets := make([]eval.Value, len(rs.results))
+		for i, r := range rs.results {
+			targets[i] = r.target
+		}
+		return WrapValues(targets), true
+	}
+	return nil, false
+}
+
+func (rs *ResultSetValue) InitHash() eval.OrderedMap {
+	return SingletonHash2(`results`, rs.resultList())
+}
+
+// Ok returns true if all results of the receiver are ok
+func (rs *ResultSetValue) Ok() bool {
+	for _, r := range rs.results {
+		if !r.Ok() {
+			return false
+		}
+	}
+	return true
+}
+
+// OkSet returns a ResultSet with the results of the receiver that are ok
+func (rs *ResultSetValue) OkSet() *ResultSetValue {
+	return rs.filter(true)
+}
+
+func (rs *ResultSetValue) PType() eval.Type {
+	return ResultSet_Type
+}
+
+// Results returns the results of the receiver. The returned slice must not be modified.
+func (rs *ResultSetValue) Results() []*ResultValue {
+	return rs.results
+}
+
+func (rs *ResultSetValue) String() string {
+	return eval.ToString(rs)
+}
+
+func (rs *ResultSetValue) ToString(b io.Writer, s eval.FormatContext, g eval.RDetect) {
+	ObjectToString(rs, s, b, g)
+}
+
+func (rs *ResultSetValue) filter(ok bool) *ResultSetValue {
+	results := make([]*ResultValue, 0, len(rs.results))
+	for _, r := range rs.results {
+		if r.Ok() == ok {
+			results = append(results, r)
+		}
+	}
+	return &ResultSetValue{results}
+}
+
+func (rs *ResultSetValue) resultList() *ArrayValue {
+	results := make([]eval.Value, len(rs.results))
+	for i, r := range rs.results {
+		results[i] = r
+	}
+	return WrapValues(results)
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/lyraproj/puppet-evaluator/errors"
 	"github.com/lyraproj/puppet-evaluator/eval"
-	"github.com/lyraproj/issue/issue"
 	"github.com/lyraproj/semver/semver"
 	"reflect"
 	"github.com/lyraproj/puppet-evaluator/utils"
@@ -259,11 +258,7 @@ func (v *SemVerValue) Reflect(c eval.Context) reflect.Value {
 }
 
 func (v *SemVerValue) ReflectTo(c eval.Context, dest reflect.Value) {
-	rv := v.Reflect(c)
-	if !rv.Type().AssignableTo(dest.Type()) {
-		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rv.Type().String(), `actual`: dest.Type().String()}))
-	}
-	dest.Set(rv)
+	setReflected(v.Reflect(c), dest)
 }
 
 func (v *SemVerValue)  CanSerializeAsString() bool {
@@ -1,10 +1,41 @@
 package types
 
+import "github.com/lyraproj/puppet-evaluator/eval"
+
+var Target_Type eval.ObjectType
+
 func init() {
-	newObjectType(`Target`, `{
+	Target_Type = newObjectType(`Target`, `{
 	attributes => {
 	  host => String[1],
-	  options => { type => Hash[String[1], Data], value => {} }
+	  options => { type => Hash[String[1], Data], value => {} },
+	  name => { type => Optional[String[1]], value => undef },
+	  facts => { type => Hash[String[1], Data], value => {} },
+	  vars => { type => Hash[String[1], Data], value => {} }
 	}
 }`)
+
+	newTypeAlias(`TargetSpec`, `Variant[String[1], Target, Array[TargetSpec]]`)
+}
+
+// NewTarget creates a Target for the given host. The options may be nil.
+func NewTarget(c eval.Context, host string, options eval.OrderedMap) eval.PuppetObject {
+	if options == nil {
+		options = eval.EMPTY_MAP
+	}
+	return NewObjectValue(c, Target_Type, []eval.Value{WrapString(host), options}).(eval.PuppetObject)
+}
+
+// NewTarget2 creates a Target from the given hash of attributes
+func NewTarget2(c eval.Context, attributes *HashValue) eval.PuppetObject {
+	return NewObjectValue2(c, Target_Type, attributes).(eval.PuppetObject)
+}
+
+// TargetName returns the name of the given Target, or its host if it has no name
+func TargetName(target eval.PuppetObject) string {
+	if n, ok := target.Get(`name`); ok && n != eval.UNDEF {
+		return n.String()
+	}
+	h, _ := target.Get(`host`)
+	return h.String()
 }
@@ -1,5 +1,14 @@
 package types
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+)
+
 func init() {
 	newObjectType(`Task`, `{
     attributes => {
@@ -15,18 +24,19 @@ func init() {
       # Puppet Task version
       puppet_task_version => { type => Integer, value => 1 },
 
-      # Type, description, and sensitive property of each parameter 
+      # Type, description, sensitive property, and default of each parameter
       parameters => {
         type => Optional[Hash[
           Pattern[/\A[a-z][a-z0-9_]*\z/],
           Struct[
             Optional[description] => String,
             Optional[sensitive] => Boolean,
+            Optional['default'] => Data,
             type => Type]]],
         value => undef
       },
 
-       # Type, description, and sensitive property of each output 
+       # Type, description, and sensitive property of each output
       output => {
         type => Optional[Hash[
           Pattern[/\A[a-z][a-z0-9_]*\z/],
@@ -38,7 +48,123 @@ func init() {
       },
 
       supports_noop => { type => Boolean, value => false },
-      input_method => { type => String, value => 'both' },
+      input_method => { type => Enum[stdin, environment, powershell, both], value => 'both' },
+
+      # Alternative executables, each one residing in the same directory as the executable. The
+      # first implementation whose requirements are met by the features of a target is used.
+      implementations => {
+        type => Optional[Array[Struct[
+          name => String[1],
+          Optional[requirements] => Array[String[1]],
+          Optional[input_method] => Enum[stdin, environment, powershell, both],
+          Optional[files] => Array[String[1]]]]],
+        value => undef
+      },
+
+      # Module relative paths of additional files that the task needs
+      files => { type => Optional[Array[String[1]]], value => undef },
+
+      'private' => { type => Boolean, value => false },
+      remote => { type => Boolean, value => false },
+      extensions => { type => Optional[Hash[String[1], Data]], value => undef }
     }
  }`)
 }
+
+// TaskParametersType returns the Struct type that the parameters of the given Task must match.
+// A parameter that has a default value, or whose type is Optional, may be omitted. A Task that
+// lacks parameter metadata accepts any parameters.
+func TaskParametersType(task eval.PuppetObject) eval.Type {
+	pv, ok := task.Get(`parameters`)
+	if !ok || pv == _UNDEF {
+		return NewHashType(DefaultStringType(), DefaultAnyType(), nil)
+	}
+	params := pv.(*HashValue)
+	elements := make([]*StructElement, 0, params.Len())
+	params.EachPair(func(k, v eval.Value) {
+		param := v.(*HashValue)
+		pt := param.Get5(`type`, DefaultDataType()).(eval.Type)
+		if param.IncludesKey2(`default`) {
+			elements = append(elements, NewStructElement(NewOptionalType3(k.String()), pt))
+		} else {
+			elements = append(elements, NewStructElement2(k.String(), pt))
+		}
+	})
+	return NewStructType(elements)
+}
+
+// ValidateTaskParameters asserts that the given parameters match the parameters declared by the
+// given Task and returns them with the default values of omitted parameters added. Meta
+// parameters, i.e. parameters that start with an underscore, are not validated. This function
+// panics with an issue.Reported when the parameters do not match.
+func ValidateTaskParameters(task eval.PuppetObject, params eval.OrderedMap) eval.OrderedMap {
+	pt := TaskParametersType(task)
+	eval.AssertInstance(func() string { return fmt.Sprintf(`Task %s:`, stringAttribute(task, `name`)) }, pt,
+		params.RejectPairs(func(k, v eval.Value) bool { return strings.HasPrefix(k.String(), `_`) }))
+
+	if st, ok := pt.(*StructType); ok {
+		params = addTaskDefaults(task, st, params)
+	}
+	return params
+}
+
+func addTaskDefaults(task eval.PuppetObject, st *StructType, params eval.OrderedMap) eval.OrderedMap {
+	pv, _ := task.Get(`parameters`)
+	var defaults []*HashEntry
+	for _, e := range st.elements {
+		if params.IncludesKey2(e.name) {
+			continue
+		}
+		if dv, ok := pv.(*HashValue).Get5(e.name, _EMPTY_MAP).(*HashValue).Get4(`default`); ok {
+			defaults = append(defaults, WrapHashEntry2(e.name, dv))
+		}
+	}
+	if len(defaults) == 0 {
+		return params
+	}
+	return params.Merge(WrapHash(defaults))
+}
+
+// TaskImplementation returns the path of the executable and the input method to use when running
+// the given Task on a target with the given features. The first implementation whose requirements
+// are all included in the features is selected. The executable of the Task is used when it declares
+// no implementations. This function panics with an issue.Reported if no implementation is suitable.
+func TaskImplementation(task eval.PuppetObject, features []string) (executable, inputMethod string) {
+	executable = stringAttribute(task, `executable`)
+	inputMethod = stringAttribute(task, `input_method`)
+	iv, ok := task.Get(`implementations`)
+	if !ok || iv == _UNDEF {
+		return
+	}
+
+	iv, ok = iv.(*ArrayValue).Find(func(e eval.Value) bool {
+		rq := e.(*HashValue).Get5(`requirements`, _EMPTY_ARRAY).(*ArrayValue)
+		return rq.All(func(r eval.Value) bool { return containsString(features, r.String()) })
+	})
+	if !ok {
+		panic(eval.Error(eval.EVAL_TASK_NO_SUITABLE_IMPLEMENTATION, issue.H{`name`: stringAttribute(task, `name`), `features`: strings.Join(features, `, `)}))
+	}
+
+	impl := iv.(*HashValue)
+	executable = filepath.Join(filepath.Dir(executable), impl.Get5(`name`, _EMPTY_STRING).String())
+	if im, ok := impl.Get4(`input_method`); ok {
+		inputMethod = im.String()
+	}
+	return
+}
+
+func stringAttribute(o eval.PuppetObject, name string) string {
+	if v, ok := o.Get(name); ok && v != _UNDEF {
+		return v.String()
+	}
+	return ``
+}
+
+func containsString(strs []string, s string) bool {
+	for _, e := range strs {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
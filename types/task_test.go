@@ -0,0 +1,135 @@
+package types_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lyraproj/issue/issue"
+	"github.com/lyraproj/puppet-evaluator/eval"
+	"github.com/lyraproj/puppet-evaluator/types"
+)
+
+func ExampleValidateTaskParameters() {
+	eval.Puppet.Do(func(c eval.Context) {
+		task, _ := eval.TopEvaluate(c, c.ParseAndValidate(``, `Task(
+			name => 'greet',
+			executable => 'greet.sh',
+			parameters => { message => { type => String }, greeting => { type => String, 'default' => 'hello' } })`, false))
+		fmt.Println(types.ValidateTaskParameters(task.(eval.PuppetObject), types.SingletonHash2(`message`, types.WrapString(`hi`))))
+	})
+	// Output: {'message' => 'hi', 'greeting' => 'hello'}
+}
+
+func ExampleTaskImplementation() {
+	eval.Puppet.Do(func(c eval.Context) {
+		task, _ := eval.TopEvaluate(c, c.ParseAndValidate(``, `Task(
+			name => 'multi',
+			executable => '/tasks/multi.sh',
+			implementations => [
+				{ name => 'multi.ps1', requirements => ['powershell'] },
+				{ name => 'multi.sh', requirements => ['shell'], input_method => 'environment' }])`, false))
+		fmt.Println(types.TaskImplementation(task.(eval.PuppetObject), []string{`shell`}))
+	})
+	// Output: /tasks/multi.sh environment
+}
+
+func TestTaskMetadata(t *testing.T) {
+	tmpDir, err := ioutil.TempDir(``, `modules`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	tasksDir := filepath.Join(tmpDir, `mymod`, `tasks`)
+	if err = os.MkdirAll(tasksDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		`echo.sh`: "#!/bin/sh\ncat\n",
+		`echo.json`: `{"input_method": "stdin", "parameters": {
+			"message": {"type": "String[1]"},
+			"count": {"type": "Optional[Integer]"},
+			"greeting": {"type": "String", "default": "hello"}}}`,
+		`multi.sh`:  "#!/bin/sh\necho \"$PT_message\"\n",
+		`multi.ps1`: "Write-Output $message\n",
+		`multi.json`: `{"implementations": [
+			{"name": "multi.ps1", "requirements": ["powershell"]},
+			{"name": "multi.sh", "requirements": ["shell"], "input_method": "environment"}]}`,
+		`bad_key.sh`:     "#!/bin/sh\n",
+		`bad_key.json`:   `{"colour": "blue"}`,
+		`bad_input.sh`:   "#!/bin/sh\n",
+		`bad_input.json`: `{"input_method": "argv"}`,
+		`bad_impl.sh`:    "#!/bin/sh\n",
+		`bad_impl.json`:  `{"implementations": [{"name": "bad_impl.rb"}]}`,
+		`bad_file.sh`:    "#!/bin/sh\n",
+		`bad_file.json`:  `{"files": ["mymod/files/../../etc/passwd"]}`,
+		`two.sh`:         "#!/bin/sh\n",
+		`two.py`:         "print('two')\n",
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(tasksDir, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		task   string
+		params string
+		result string
+		code   issue.Code
+	}{
+		{`mymod::echo`, `{message => 'hi'}`, `{'message' => 'hi', 'greeting' => 'hello'}`, ``},
+		{`mymod::echo`, `{message => 'hi', greeting => 'bye', count => 2}`, `{'message' => 'hi', 'greeting' => 'bye', 'count' => 2}`, ``},
+		{`mymod::echo`, `{message => 'hi', '_noop' => true}`, `{'message' => 'hi', '_noop' => true, 'greeting' => 'hello'}`, ``},
+		{`mymod::echo`, `{message => 1}`, ``, eval.EVAL_TYPE_MISMATCH},
+		{`mymod::echo`, `{}`, ``, eval.EVAL_TYPE_MISMATCH},
+		{`mymod::echo`, `{message => 'hi', other => 1}`, ``, eval.EVAL_TYPE_MISMATCH},
+		{`mymod::multi`, `{message => 'hi'}`, `{'message' => 'hi'}`, ``},
+		{`mymod::bad_key`, `{}`, ``, eval.EVAL_TASK_BAD_METADATA},
+		{`mymod::bad_input`, `{}`, ``, eval.EVAL_TASK_BAD_METADATA},
+		{`mymod::bad_impl`, `{}`, ``, eval.EVAL_TASK_IMPLEMENTATION_NOT_FOUND},
+		{`mymod::bad_file`, `{}`, ``, eval.EVAL_TASK_BAD_FILE_REFERENCE},
+		{`mymod::two`, `{}`, ``, eval.EVAL_TASK_TOO_MANY_FILES},
+	}
+
+	// The environment loader must be created after the module_path is set
+	eval.Puppet.Reset()
+	eval.Puppet.Set(`tasks`, types.WrapBoolean(true))
+	eval.Puppet.Set(`module_path`, types.WrapString(tmpDir))
+	defer eval.Puppet.Reset()
+	for _, tc := range tests {
+		var result string
+		err := eval.Puppet.Try(func(c eval.Context) error {
+			task, ok := eval.Load(c, eval.NewTypedName(eval.NsTask, tc.task))
+			if !ok {
+				return fmt.Errorf(`unable to load task %s`, tc.task)
+			}
+			params, _ := eval.TopEvaluate(c, c.ParseAndValidate(``, tc.params, false))
+			result = types.ValidateTaskParameters(task.(eval.PuppetObject), params.(eval.OrderedMap)).String()
+			return nil
+		})
+		var code issue.Code
+		if re, ok := err.(issue.Reported); ok {
+			code = re.Code()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != tc.code || result != tc.result {
+			t.Errorf(`%s %s: expected '%s%s', got '%s%s'`, tc.task, tc.params, tc.result, tc.code, result, code)
+		}
+	}
+}
+
+func TestTaskNoSuitableImplementation(t *testing.T) {
+	err := eval.Puppet.Try(func(c eval.Context) error {
+		task, _ := eval.TopEvaluate(c, c.ParseAndValidate(``,
+			`Task(name => 'x', executable => 'x.sh', implementations => [{ name => 'x.rb', requirements => ['ruby'] }])`, false))
+		types.TaskImplementation(task.(eval.PuppetObject), []string{`shell`})
+		return nil
+	})
+	if re, ok := err.(issue.Reported); !ok || re.Code() != eval.EVAL_TASK_NO_SUITABLE_IMPLEMENTATION {
+		t.Errorf(`expected %s, got %v`, eval.EVAL_TASK_NO_SUITABLE_IMPLEMENTATION, err)
+	}
+}
@@ -452,11 +452,7 @@ func (tv *TimespanValue) Reflect(c eval.Context) reflect.Value {
 }
 
 func (tv *TimespanValue) ReflectTo(c eval.Context, dest reflect.Value) {
-	rv := tv.Reflect(c)
-	if !rv.Type().AssignableTo(dest.Type()) {
-		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rv.Type().String(), `actual`: dest.Type().String()}))
-	}
-	dest.Set(rv)
+	setReflected(tv.Reflect(c), dest)
 }
 
 // Seconds returns a positive integer, 0 - 59 denoting seconds of minute
@@ -366,11 +366,7 @@ func (tv *TimestampValue) Reflect(c eval.Context) reflect.Value {
 }
 
 func (tv *TimestampValue) ReflectTo(c eval.Context, dest reflect.Value) {
-	rv := tv.Reflect(c)
-	if !rv.Type().AssignableTo(dest.Type()) {
-		panic(eval.Error(eval.EVAL_ATTEMPT_TO_SET_WRONG_KIND, issue.H{`expected`: rv.Type().String(), `actual`: dest.Type().String()}))
-	}
-	dest.Set(rv)
+	setReflected(tv.Reflect(c), dest)
 }
 
 func (tv *TimestampValue) Time() time.Time {
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
+	"net/url"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -22,6 +24,7 @@ import (
 const (
 	NO_STRING = "\x00"
 
+	HK_BIG_INTEGER   = byte('I')
 	HK_BINARY        = byte('B')
 	HK_BOOLEAN       = byte('b')
 	HK_DEFAULT       = byte('d')
@@ -380,6 +383,7 @@ func init() {
 	eval.NewTypeAlias = newTypeAlias
 	eval.NewTypeSet = newTypeSet
 	eval.NewGoType = newGoType
+	eval.NewGoType2 = newGoType2
 	eval.RegisterResolvableType = registerResolvableType
 	eval.NewGoConstructor = newGoConstructor
 	eval.NewGoConstructor2 = newGoConstructor2
@@ -495,6 +499,12 @@ func newGoType(name string, zeroValue interface{}) eval.ObjectType {
 	return t
 }
 
+func newGoType2(name, parentName string, zeroValue interface{}) eval.ObjectType {
+	t := NewObjectType(name, NewTypeReferenceType(parentName), zeroValue)
+	registerResolvableType(t)
+	return t
+}
+
 func registerResolvableType(tp eval.ResolvableType) {
 	resolvableTypesLock.Lock()
 	resolvableTypes = append(resolvableTypes, tp)
@@ -551,6 +561,8 @@ func wrap(c eval.Context, v interface{}) (pv eval.Value) {
 		pv = WrapInteger(int64(v.(int32)))
 	case int64:
 		pv = WrapInteger(v.(int64))
+	case *big.Int:
+		pv = WrapBigInteger(v.(*big.Int))
 	case byte:
 		pv = WrapInteger(int64(v.(byte)))
 	case int:
@@ -608,10 +620,6 @@ func wrap(c eval.Context, v interface{}) (pv eval.Value) {
 }
 
 func wrapReflected(c eval.Context, vr reflect.Value) (pv eval.Value) {
-	if c == nil {
-		c = eval.CurrentContext()
-	}
-
 	// Invalid shouldn't happen, but needs a check
 	if !vr.IsValid() {
 		return _UNDEF
@@ -642,7 +650,12 @@ func wrapReflected(c eval.Context, vr reflect.Value) (pv eval.Value) {
 		}
 	}
 
-	pv, ok := WrapPrimitive(vr)
+	pv, ok := wrapGoValue(vi)
+	if ok {
+		return pv
+	}
+
+	pv, ok = WrapPrimitive(vr)
 	if ok {
 		return pv
 	}
@@ -685,6 +698,38 @@ func wrapReflected(c eval.Context, vr reflect.Value) (pv eval.Value) {
 	return pv
 }
 
+// wrapGoValue wraps a time.Time, time.Duration, regexp.Regexp, url.URL, or semver.Version, or a pointer to
+// one of them, in the corresponding Timestamp, Timespan, Regexp, URI, or SemVer value.
+func wrapGoValue(vr reflect.Value) (pv eval.Value, ok bool) {
+	if !vr.CanInterface() {
+		return
+	}
+	ok = true
+	switch v := vr.Interface().(type) {
+	case time.Time:
+		pv = WrapTimestamp(v)
+	case *time.Time:
+		pv = WrapTimestamp(*v)
+	case time.Duration:
+		pv = WrapTimespan(v)
+	case *time.Duration:
+		pv = WrapTimespan(*v)
+	case regexp.Regexp:
+		pv = WrapRegexp2(&v)
+	case *regexp.Regexp:
+		pv = WrapRegexp2(v)
+	case url.URL:
+		pv = WrapURI(&v)
+	case *url.URL:
+		pv = WrapURI(v)
+	case semver.Version:
+		pv = WrapSemVer(v)
+	default:
+		ok = false
+	}
+	return
+}
+
 func WrapPrimitive(vr reflect.Value) (pv eval.Value, ok bool) {
 	ok = true
 	switch vr.Kind() {
@@ -706,7 +751,12 @@ func WrapPrimitive(vr reflect.Value) (pv eval.Value, ok bool) {
 	return
 }
 
+// loadFromImplementarionRegistry finds the type registered for the given reflected type. A nil
+// Context has no registry.
 func loadFromImplementarionRegistry(c eval.Context, vt reflect.Type) (eval.Type, bool) {
+	if c == nil {
+		return nil, false
+	}
 	if t, ok := c.ImplementationRegistry().ReflectedToType(vt); ok {
 		return t, true
 	}
@@ -737,12 +787,17 @@ var wellknowns = map[reflect.Type]eval.Type{
 }
 
 func wrapReflectedType(c eval.Context, vt reflect.Type) (pt eval.Type) {
-	if c == nil {
-		c = eval.CurrentContext()
-	}
-
 	var ok bool
 	pt, ok = loadFromImplementarionRegistry(c, vt)
+	if !ok {
+		pt, ok = goPTypes[vt]
+	}
+	if !ok && vt.Kind() == reflect.Ptr {
+		// A pointer can be nil so the type is optional
+		if pt, ok = goPTypes[vt.Elem()]; ok {
+			pt = NewOptionalType(pt)
+		}
+	}
 	if !ok {
 		pt, ok = primitivePTypes[vt.Kind()]
 		if !ok && vt.Kind() == reflect.Ptr {
@@ -776,6 +831,16 @@ func wrapReflectedType(c eval.Context, vt reflect.Type) (pt eval.Type) {
 	return
 }
 
+// goPTypes maps standard Go types that have a corresponding Puppet type. Their kind alone, e.g. the
+// int64 kind of a time.Duration, doesn't reveal the correct type.
+var goPTypes = map[reflect.Type]eval.Type{
+	reflect.TypeOf(time.Time{}):                   DefaultTimestampType(),
+	reflect.TypeOf(time.Duration(0)):              DefaultTimespanType(),
+	reflect.TypeOf(regexp.Regexp{}):               DefaultRegexpType(),
+	reflect.TypeOf(url.URL{}):                     DefaultUriType(),
+	reflect.TypeOf((*semver.Version)(nil)).Elem(): DefaultSemVerType(),
+}
+
 var primitivePTypes = map[reflect.Kind]eval.Type{
 	reflect.String:  DefaultStringType(),
 	reflect.Int:     DefaultIntegerType(),
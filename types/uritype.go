@@ -9,6 +9,7 @@ import (
 	"github.com/lyraproj/issue/issue"
 	"io"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 )
@@ -338,6 +339,10 @@ func (t *UriType) Parameters() []eval.Value {
 	}
 }
 
+func (t *UriType) ReflectType(c eval.Context) (reflect.Type, bool) {
+	return reflect.TypeOf(&url.URL{}), true
+}
+
 func (t *UriType)  CanSerializeAsString() bool {
   return true
 }
@@ -434,6 +439,14 @@ func (u *UriValue) Get(key string) (eval.Value, bool) {
 	return _UNDEF, false
 }
 
+func (u *UriValue) Reflect(c eval.Context) reflect.Value {
+	return reflect.ValueOf(u.URL())
+}
+
+func (u *UriValue) ReflectTo(c eval.Context, dest reflect.Value) {
+	setReflected(u.Reflect(c), dest)
+}
+
 func (u *UriValue)  CanSerializeAsString() bool {
   return true
 }
@@ -0,0 +1,164 @@
+package types
+
+import "github.com/lyraproj/puppet-evaluator/eval"
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	vectorMask  = vectorWidth - 1
+)
+
+type (
+	// vectorNode is a node in the trie of a persistentVector. Leaf nodes hold values and all other
+	// nodes hold children.
+	vectorNode struct {
+		children []*vectorNode
+		values   []eval.Value
+	}
+
+	// persistentVector is an immutable vector that shares structure with the vector that it was
+	// derived from. Appending an element or replacing an element is O(log32 n). The last 1 - 32
+	// elements are kept in a tail outside of the trie so that most appends only copy the tail.
+	persistentVector struct {
+		count int
+		shift uint
+		root  *vectorNode
+		tail  []eval.Value
+	}
+)
+
+var emptyVector = &persistentVector{shift: vectorBits, root: &vectorNode{}}
+
+// newVector creates a vector that contains the given values. The values slice is shared with
+// the vector and must not be modified after this call.
+func newVector(values []eval.Value) *persistentVector {
+	top := len(values)
+	if top == 0 {
+		return emptyVector
+	}
+	off := tailOffset(top)
+	root := emptyVector.root
+	shift := emptyVector.shift
+	for i := 0; i < off; i += vectorWidth {
+		root, shift = pushLeaf(root, shift, i, &vectorNode{values: values[i : i+vectorWidth : i+vectorWidth]})
+	}
+	return &persistentVector{top, shift, root, values[off:top:top]}
+}
+
+func tailOffset(count int) int {
+	if count < vectorWidth {
+		return 0
+	}
+	return ((count - 1) >> vectorBits) << vectorBits
+}
+
+// appendTo appends all elements of the receiver to the given slice and returns the result
+func (v *persistentVector) appendTo(slice []eval.Value) []eval.Value {
+	return append(appendNode(v.shift, v.root, slice), v.tail...)
+}
+
+func (v *persistentVector) at(i int) eval.Value {
+	off := tailOffset(v.count)
+	if i >= off {
+		return v.tail[i-off]
+	}
+	n := v.root
+	for level := v.shift; level > 0; level -= vectorBits {
+		n = n.children[(i>>level)&vectorMask]
+	}
+	return n.values[i&vectorMask]
+}
+
+func (v *persistentVector) len() int {
+	return v.count
+}
+
+// push returns a vector with the given element appended to the elements of the receiver
+func (v *persistentVector) push(e eval.Value) *persistentVector {
+	tl := len(v.tail)
+	if tl < vectorWidth {
+		tail := make([]eval.Value, tl+1)
+		copy(tail, v.tail)
+		tail[tl] = e
+		return &persistentVector{v.count + 1, v.shift, v.root, tail}
+	}
+	root, shift := pushLeaf(v.root, v.shift, tailOffset(v.count), &vectorNode{values: v.tail})
+	return &persistentVector{v.count + 1, shift, root, []eval.Value{e}}
+}
+
+// set returns a vector where the element at the given index has been replaced with the given element
+func (v *persistentVector) set(i int, e eval.Value) *persistentVector {
+	off := tailOffset(v.count)
+	if i >= off {
+		tail := make([]eval.Value, len(v.tail))
+		copy(tail, v.tail)
+		tail[i-off] = e
+		return &persistentVector{v.count, v.shift, v.root, tail}
+	}
+	return &persistentVector{v.count, v.shift, setNode(v.shift, v.root, i, e), v.tail}
+}
+
+func appendNode(level uint, n *vectorNode, slice []eval.Value) []eval.Value {
+	if level == 0 {
+		return append(slice, n.values...)
+	}
+	for _, c := range n.children {
+		slice = appendNode(level-vectorBits, c, slice)
+	}
+	return slice
+}
+
+// pushLeaf adds a full leaf to the trie with the given root and shift. The stored argument is the
+// number of elements that are already stored in the trie. The new root and shift are returned.
+func pushLeaf(root *vectorNode, shift uint, stored int, leaf *vectorNode) (*vectorNode, uint) {
+	if stored>>vectorBits >= 1<<shift {
+		// Root is full, so the trie must grow one level
+		return &vectorNode{children: []*vectorNode{root, newVectorPath(shift, leaf)}}, shift + vectorBits
+	}
+	return pushLeafAt(shift, root, stored, leaf), shift
+}
+
+func pushLeafAt(level uint, parent *vectorNode, index int, leaf *vectorNode) *vectorNode {
+	sub := (index >> level) & vectorMask
+	cl := len(parent.children)
+	var child *vectorNode
+	switch {
+	case level == vectorBits:
+		child = leaf
+	case sub < cl:
+		child = pushLeafAt(level-vectorBits, parent.children[sub], index, leaf)
+	default:
+		child = newVectorPath(level-vectorBits, leaf)
+	}
+	if sub < cl {
+		children := make([]*vectorNode, cl)
+		copy(children, parent.children)
+		children[sub] = child
+		return &vectorNode{children: children}
+	}
+	children := make([]*vectorNode, cl+1)
+	copy(children, parent.children)
+	children[cl] = child
+	return &vectorNode{children: children}
+}
+
+func newVectorPath(level uint, leaf *vectorNode) *vectorNode {
+	if level == 0 {
+		return leaf
+	}
+	return &vectorNode{children: []*vectorNode{newVectorPath(level-vectorBits, leaf)}}
+}
+
+func setNode(level uint, n *vectorNode, i int, e eval.Value) *vectorNode {
+	if level == 0 {
+		values := make([]eval.Value, len(n.values))
+		copy(values, n.values)
+		values[i&vectorMask] = e
+		return &vectorNode{values: values}
+	}
+	sub := (i >> level) & vectorMask
+	children := make([]*vectorNode, len(n.children))
+	copy(children, n.children)
+	children[sub] = setNode(level-vectorBits, n.children[sub], i, e)
+	return &vectorNode{children: children}
+}